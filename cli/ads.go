@@ -0,0 +1,102 @@
+package cli
+
+import (
+	"context"
+	"dcard-backend/domain"
+	"dcard-backend/usecase"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+)
+
+const adsUsage = `Usage:
+  dcard-backend ads import --tenant id --file ads.csv [--format csv|ndjson]
+  dcard-backend ads export --tenant id --file ads.csv [--format csv|ndjson] [--advertiser id]`
+
+// RunAds runs the ads subcommand with the arguments following "ads"
+func RunAds(args []string, atu domain.AdTransferUsecase, stdout io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(adsUsage)
+	}
+
+	flags := flag.NewFlagSet("ads "+args[0], flag.ContinueOnError)
+	flags.SetOutput(stdout)
+	tenantID := flags.String("tenant", "", "tenant whose ads are imported or exported")
+	file := flags.String("file", "", "path of the file to read or write, - for stdin/stdout")
+	format := flags.String("format", "", "csv or ndjson, inferred from the file extension if omitted")
+	advertiserID := flags.Int64("advertiser", 0, "export only the ads of the advertiser, or every ad if omitted")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	if *tenantID == "" {
+		return fmt.Errorf("--tenant is required\n%s", adsUsage)
+	}
+	if *file == "" {
+		return fmt.Errorf("--file is required\n%s", adsUsage)
+	}
+	if *format == "" {
+		*format = usecase.FormatFromFilename(*file)
+	}
+
+	ctx := domain.WithActor(domain.WithTenant(context.Background(), *tenantID), cliActor())
+	switch args[0] {
+	case "import":
+		return importAds(ctx, atu, *file, *format, stdout)
+	case "export":
+		return exportAds(ctx, atu, *file, *format, *advertiserID, stdout)
+	}
+	return fmt.Errorf("unknown command %q\n%s", args[0], adsUsage)
+}
+
+// cliActor identifies the user running the command in the audit log
+func cliActor() string {
+	current, err := user.Current()
+	if err != nil {
+		return "cli"
+	}
+	return "cli:" + current.Username
+}
+
+func importAds(ctx context.Context, atu domain.AdTransferUsecase, path string, format string, stdout io.Writer) error {
+	var reader io.Reader = os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		reader = file
+	}
+
+	result, err := atu.Import(ctx, reader, format)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(stdout, "Imported %d ads\n", result.Imported)
+	for _, importErr := range result.Errors {
+		fmt.Fprintf(stdout, "line %d: %s\n", importErr.Line, importErr.Message)
+	}
+	if len(result.Errors) > 0 {
+		return fmt.Errorf("%d lines failed to import", len(result.Errors))
+	}
+	return nil
+}
+
+func exportAds(ctx context.Context, atu domain.AdTransferUsecase, path string, format string, advertiserID int64, stdout io.Writer) error {
+	if path == "-" {
+		return atu.Export(ctx, stdout, format, advertiserID)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return atu.Export(ctx, file, format, advertiserID)
+}
@@ -0,0 +1,67 @@
+package cli_test
+
+import (
+	"bytes"
+	"context"
+	"dcard-backend/cli"
+	"dcard-backend/domain"
+	"dcard-backend/domain/mocks"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestRunAds_FileNotProvided_ShouldReturnError(t *testing.T) {
+	mockAdTransferUsecase := mocks.NewAdTransferUsecase(t)
+
+	var stdout bytes.Buffer
+	err := cli.RunAds([]string{"import", "--tenant", "team-a"}, mockAdTransferUsecase, &stdout)
+
+	assert.Error(t, err)
+}
+
+func TestRunAds_TenantNotProvided_ShouldReturnError(t *testing.T) {
+	mockAdTransferUsecase := mocks.NewAdTransferUsecase(t)
+
+	var stdout bytes.Buffer
+	err := cli.RunAds([]string{"export", "--file", "ads.csv"}, mockAdTransferUsecase, &stdout)
+
+	assert.Error(t, err)
+}
+
+func TestRunAds_ImportWithFailedLines_ShouldPrintLinesAndReturnError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ads.csv")
+	os.WriteFile(path, []byte("title,startAt,endAt\n"), 0o644)
+
+	mockResult := domain.ImportResult{
+		Imported: 1,
+		Errors:   []domain.ImportError{{Line: 3, Message: "Fail"}},
+	}
+	mockAdTransferUsecase := mocks.NewAdTransferUsecase(t)
+	mockAdTransferUsecase.On("Import", mock.MatchedBy(func(c context.Context) bool {
+		tenantID, _ := domain.TenantFromContext(c)
+		return tenantID == "team-a"
+	}), mock.Anything, domain.FormatCSV).Return(mockResult, nil).Once()
+
+	var stdout bytes.Buffer
+	err := cli.RunAds([]string{"import", "--tenant", "team-a", "--file", path}, mockAdTransferUsecase, &stdout)
+
+	assert.Error(t, err)
+	assert.Equal(t, "Imported 1 ads\nline 3: Fail\n", stdout.String())
+}
+
+func TestRunAds_Export_ShouldWriteFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ads.ndjson")
+
+	mockAdTransferUsecase := mocks.NewAdTransferUsecase(t)
+	mockAdTransferUsecase.On("Export", mock.Anything, mock.Anything, domain.FormatNDJSON, int64(0)).Return(nil).Once()
+
+	var stdout bytes.Buffer
+	err := cli.RunAds([]string{"export", "--tenant", "team-a", "--file", path}, mockAdTransferUsecase, &stdout)
+
+	assert.NoError(t, err)
+	assert.FileExists(t, path)
+}
@@ -1,6 +1,13 @@
 package config
 
 import (
+	"dcard-backend/domain"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/joho/godotenv"
 )
 
@@ -11,3 +18,76 @@ func LoadEnv() error {
 	}
 	return nil
 }
+
+// GetEnvSeconds reads an environment variable holding a number of seconds, and returns
+// fallback if it is not set or not a number
+func GetEnvSeconds(key string, fallback time.Duration) time.Duration {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return time.Duration(value) * time.Second
+}
+
+// GetEnvInt reads an environment variable holding an integer, and returns fallback if it is
+// not set or not an integer
+func GetEnvInt(key string, fallback int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+// GetEnvBool reads an environment variable holding a boolean, and returns fallback if it
+// is not set or not a boolean
+func GetEnvBool(key string, fallback bool) bool {
+	value, err := strconv.ParseBool(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+// GetConflictPolicy reads AD_CONFLICT_POLICY, which defaults to warn
+func GetConflictPolicy() domain.ConflictPolicy {
+	policy := domain.ConflictPolicy(os.Getenv("AD_CONFLICT_POLICY"))
+	switch policy {
+	case domain.ConflictPolicyReject, domain.ConflictPolicyWarn, domain.ConflictPolicyAllow:
+		return policy
+	case "":
+		return domain.ConflictPolicyWarn
+	}
+	log.Printf("Unknown AD_CONFLICT_POLICY %q, using %q", policy, domain.ConflictPolicyWarn)
+	return domain.ConflictPolicyWarn
+}
+
+// GetEnvList reads an environment variable holding comma separated values, and returns nil if
+// it is not set
+func GetEnvList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// GetEnvTenantKeys reads an environment variable holding comma separated tenant:key pairs,
+// e.g. team-a:secret1,team-b:secret2, and returns the tenant of each key. A tenant may have
+// several keys, so that keys can be rotated; malformed pairs are skipped.
+func GetEnvTenantKeys(key string) map[string]string {
+	tenants := map[string]string{}
+	for _, pair := range strings.Split(os.Getenv(key), ",") {
+		tenantID, tenantKey, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok || tenantID == "" || tenantKey == "" {
+			if pair != "" {
+				log.Printf("Skipping a malformed pair of %s", key)
+			}
+			continue
+		}
+		tenants[tenantKey] = tenantID
+	}
+	return tenants
+}
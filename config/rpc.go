@@ -0,0 +1,22 @@
+package config
+
+import (
+	"errors"
+	"os"
+
+	"google.golang.org/grpc/credentials"
+)
+
+// GetRPCCredentials loads the certificate and key at GRPC_TLS_CERT and GRPC_TLS_KEY for the
+// gRPC server. It returns nil if neither is set, in which case the calls are served in
+// plaintext and the gRPC API must only be reachable on a private network
+func GetRPCCredentials() (credentials.TransportCredentials, error) {
+	cert, key := os.Getenv("GRPC_TLS_CERT"), os.Getenv("GRPC_TLS_KEY")
+	if cert == "" && key == "" {
+		return nil, nil
+	}
+	if cert == "" || key == "" {
+		return nil, errors.New("GRPC_TLS_CERT and GRPC_TLS_KEY must be set together")
+	}
+	return credentials.NewServerTLSFromFile(cert, key)
+}
@@ -1,17 +1,43 @@
 package controller
 
 import (
+	"errors"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 
 	"dcard-backend/domain"
 )
 
+// UserIDHeader identifies the viewer of GET /api/v1/ad when the userId query is not provided
+const UserIDHeader = "X-User-ID"
+
 type AdController struct {
 	AdUsecase domain.AdUsecase
 }
 
+func getStatusCode(err error) int {
+	switch {
+	case errors.Is(err, domain.ErrTimeout):
+		return http.StatusGatewayTimeout
+	case errors.Is(err, domain.ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, domain.ErrBadParamInput):
+		return http.StatusBadRequest
+	case errors.Is(err, domain.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, domain.ErrUnauthorized):
+		return http.StatusUnauthorized
+	case errors.Is(err, domain.ErrPreconditionFailed):
+		return http.StatusPreconditionFailed
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
 // PostAd       godoc
 // @Summary     Admin API
 // @Description Create an ad
@@ -19,9 +45,15 @@ type AdController struct {
 // @Accept      json
 // @Produce     json
 // @Param       ad body domain.Ad True "Add an ad"
+// @Param       Idempotency-Key header string false "Key to safely retry the request"
 // @Success     200 {object} domain.SuccessResponse
 // @Failure     400 {object} domain.ErrorResponse
+// @Failure     401 {object} domain.ErrorResponse
+// @Failure     409 {object} domain.ErrorResponse
+// @Failure     422 {object} domain.ErrorResponse
 // @Failure     500 {object} domain.ErrorResponse
+// @Failure     504 {object} domain.ErrorResponse
+// @Security    ApiKeyAuth
 // @Router      /ad [post]
 func (ac *AdController) PostAd(ctx *gin.Context) {
 	var ad domain.Ad
@@ -32,13 +64,348 @@ func (ac *AdController) PostAd(ctx *gin.Context) {
 
 	err := ac.AdUsecase.Create(ctx.Request.Context(), &ad)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, domain.ErrorResponse{Message: err.Error()})
+		ctx.JSON(getStatusCode(err), domain.ErrorResponse{Message: err.Error()})
 		return
 	}
 
 	ctx.JSON(http.StatusOK, domain.SuccessResponse{Message: "Ad insert successfully"})
 }
 
+// GetAd        godoc
+// @Summary     Admin API
+// @Description Get an ad with its targeting, creatives and status
+// @Tags        ad
+// @Produce     json
+// @Param       id path int true "Ad id"
+// @Success     200 {object} domain.Ad
+// @Header      200 {string} ETag "Version of the ad"
+// @Failure     400 {object} domain.ErrorResponse
+// @Failure     401 {object} domain.ErrorResponse
+// @Failure     404 {object} domain.ErrorResponse
+// @Failure     500 {object} domain.ErrorResponse
+// @Failure     504 {object} domain.ErrorResponse
+// @Security    ApiKeyAuth
+// @Router      /ad/{id} [get]
+func (ac *AdController) GetAd(ctx *gin.Context) {
+	id, ok := parseAdID(ctx)
+	if !ok {
+		return
+	}
+
+	ad, err := ac.AdUsecase.GetByID(ctx.Request.Context(), id)
+	if err != nil {
+		ctx.JSON(getStatusCode(err), domain.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	ctx.Header("ETag", etag(ad.Version))
+	ctx.JSON(http.StatusOK, ad)
+}
+
+// etag returns the ETag of an ad at a version
+func etag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// parseIfMatch returns the versions of the ETags listed in the If-Match header, which are empty
+// for "*" so that any version is replaced. A missing header is required, so that a client never
+// overwrites changes it has not read, and any other header, including a weak ETag that If-Match
+// never matches, fails the precondition.
+func parseIfMatch(ctx *gin.Context) ([]int64, bool) {
+	header := strings.TrimSpace(ctx.GetHeader("If-Match"))
+	if header == "" {
+		ctx.JSON(http.StatusPreconditionRequired, domain.ErrorResponse{Message: "If-Match should be the ETag of the ad, or * to replace any version"})
+		return nil, false
+	}
+	if header == "*" {
+		return nil, true
+	}
+
+	var versions []int64
+	for _, tag := range strings.Split(header, ",") {
+		tag, ok := strings.CutPrefix(strings.TrimSpace(tag), `"`)
+		if ok {
+			tag, ok = strings.CutSuffix(tag, `"`)
+		}
+		version, err := strconv.ParseInt(tag, 10, 64)
+		if !ok || err != nil || version <= 0 {
+			ctx.JSON(http.StatusPreconditionFailed, domain.ErrorResponse{Message: "If-Match should list ETags of the ad"})
+			return nil, false
+		}
+		versions = append(versions, version)
+	}
+	return versions, true
+}
+
+// PutAd        godoc
+// @Summary     Admin API
+// @Description Replace an ad along with its targeting and creatives, keeping its status
+// @Tags        ad
+// @Accept      json
+// @Produce     json
+// @Param       id path int true "Ad id"
+// @Param       ad body domain.Ad True "New values of the ad"
+// @Param       If-Match header string true "ETags of the versions the values are based on, or * to replace any version"
+// @Success     200 {object} domain.SuccessResponse
+// @Header      200 {string} ETag "New version of the ad"
+// @Failure     400 {object} domain.ErrorResponse
+// @Failure     401 {object} domain.ErrorResponse
+// @Failure     404 {object} domain.ErrorResponse
+// @Failure     409 {object} domain.ErrorResponse
+// @Failure     412 {object} domain.ErrorResponse
+// @Failure     428 {object} domain.ErrorResponse
+// @Failure     500 {object} domain.ErrorResponse
+// @Failure     504 {object} domain.ErrorResponse
+// @Security    ApiKeyAuth
+// @Router      /ad/{id} [put]
+func (ac *AdController) PutAd(ctx *gin.Context) {
+	id, ok := parseAdID(ctx)
+	if !ok {
+		return
+	}
+
+	var ad domain.Ad
+	if err := ctx.Bind(&ad); err != nil {
+		ctx.JSON(http.StatusBadRequest, domain.ErrorResponse{Message: err.Error()})
+		return
+	}
+	ad.ID = id
+
+	if ad.IfMatch, ok = parseIfMatch(ctx); !ok {
+		return
+	}
+
+	if err := ac.AdUsecase.Update(ctx.Request.Context(), &ad); err != nil {
+		ctx.JSON(getStatusCode(err), domain.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	ctx.Header("ETag", etag(ad.Version))
+	ctx.JSON(http.StatusOK, domain.SuccessResponse{Message: "Ad updated successfully"})
+}
+
+// GetAdVersion godoc
+// @Summary     Admin API
+// @Description Get the configuration of an ad at a version, without its status
+// @Tags        ad
+// @Produce     json
+// @Param       id path int true "Ad id"
+// @Param       version path int true "Version of the ad"
+// @Success     200 {object} domain.Ad
+// @Failure     400 {object} domain.ErrorResponse
+// @Failure     401 {object} domain.ErrorResponse
+// @Failure     404 {object} domain.ErrorResponse
+// @Failure     500 {object} domain.ErrorResponse
+// @Failure     504 {object} domain.ErrorResponse
+// @Security    ApiKeyAuth
+// @Router      /ad/{id}/versions/{version} [get]
+func (ac *AdController) GetAdVersion(ctx *gin.Context) {
+	id, ok := parseAdID(ctx)
+	if !ok {
+		return
+	}
+
+	version, err := strconv.ParseInt(ctx.Param("version"), 10, 64)
+	if err != nil || version <= 0 {
+		ctx.JSON(http.StatusBadRequest, domain.ErrorResponse{Message: "version should be a positive integer"})
+		return
+	}
+
+	ad, err := ac.AdUsecase.GetVersion(ctx.Request.Context(), id, version)
+	if err != nil {
+		ctx.JSON(getStatusCode(err), domain.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, ad)
+}
+
+// PostRollbackAd godoc
+// @Summary       Admin API
+// @Description   Replace an ad with the configuration of a previous version, which becomes a new version
+// @Tags          ad
+// @Produce       json
+// @Param         id path int true "Ad id"
+// @Param         version query int true "Version to restore"
+// @Param         If-Match header string true "ETags of the versions to replace, or * to replace any version"
+// @Success       200 {object} domain.Ad
+// @Header        200 {string} ETag "New version of the ad"
+// @Failure       400 {object} domain.ErrorResponse
+// @Failure       401 {object} domain.ErrorResponse
+// @Failure       404 {object} domain.ErrorResponse
+// @Failure       409 {object} domain.ErrorResponse
+// @Failure       412 {object} domain.ErrorResponse
+// @Failure       428 {object} domain.ErrorResponse
+// @Failure       500 {object} domain.ErrorResponse
+// @Failure       504 {object} domain.ErrorResponse
+// @Security      ApiKeyAuth
+// @Router        /ad/{id}/rollback [post]
+func (ac *AdController) PostRollbackAd(ctx *gin.Context) {
+	id, ok := parseAdID(ctx)
+	if !ok {
+		return
+	}
+
+	version, ok := parseIntQuery(ctx, "version")
+	if !ok {
+		return
+	}
+
+	expectedVersions, ok := parseIfMatch(ctx)
+	if !ok {
+		return
+	}
+
+	ad, err := ac.AdUsecase.Rollback(ctx.Request.Context(), id, version, expectedVersions)
+	if err != nil {
+		ctx.JSON(getStatusCode(err), domain.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	ctx.Header("ETag", etag(ad.Version))
+	ctx.JSON(http.StatusOK, ad)
+}
+
+func (ac *AdController) updateStatus(ctx *gin.Context, status domain.AdStatus, message string) {
+	id, ok := parseAdID(ctx)
+	if !ok {
+		return
+	}
+
+	if err := ac.AdUsecase.UpdateStatus(ctx.Request.Context(), id, status); err != nil {
+		ctx.JSON(getStatusCode(err), domain.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, domain.SuccessResponse{Message: message})
+}
+
+// PostPauseAd  godoc
+// @Summary     Admin API
+// @Description Stop serving an ad
+// @Tags        ad
+// @Produce     json
+// @Param       id path int true "Ad id"
+// @Success     200 {object} domain.SuccessResponse
+// @Failure     400 {object} domain.ErrorResponse
+// @Failure     401 {object} domain.ErrorResponse
+// @Failure     404 {object} domain.ErrorResponse
+// @Failure     500 {object} domain.ErrorResponse
+// @Failure     504 {object} domain.ErrorResponse
+// @Security    ApiKeyAuth
+// @Router      /ad/{id}/pause [post]
+func (ac *AdController) PostPauseAd(ctx *gin.Context) {
+	ac.updateStatus(ctx, domain.AdPaused, "Ad paused successfully")
+}
+
+// PostResumeAd godoc
+// @Summary     Admin API
+// @Description Serve a paused ad again
+// @Tags        ad
+// @Produce     json
+// @Param       id path int true "Ad id"
+// @Success     200 {object} domain.SuccessResponse
+// @Failure     400 {object} domain.ErrorResponse
+// @Failure     401 {object} domain.ErrorResponse
+// @Failure     404 {object} domain.ErrorResponse
+// @Failure     500 {object} domain.ErrorResponse
+// @Failure     504 {object} domain.ErrorResponse
+// @Security    ApiKeyAuth
+// @Router      /ad/{id}/resume [post]
+func (ac *AdController) PostResumeAd(ctx *gin.Context) {
+	ac.updateStatus(ctx, domain.AdActive, "Ad resumed successfully")
+}
+
+// DeleteAd     godoc
+// @Summary     Admin API
+// @Description Delete an ad, which can be restored until it is purged
+// @Tags        ad
+// @Produce     json
+// @Param       id path int true "Ad id"
+// @Success     200 {object} domain.SuccessResponse
+// @Failure     400 {object} domain.ErrorResponse
+// @Failure     401 {object} domain.ErrorResponse
+// @Failure     404 {object} domain.ErrorResponse
+// @Failure     500 {object} domain.ErrorResponse
+// @Failure     504 {object} domain.ErrorResponse
+// @Security    ApiKeyAuth
+// @Router      /ad/{id} [delete]
+func (ac *AdController) DeleteAd(ctx *gin.Context) {
+	id, ok := parseAdID(ctx)
+	if !ok {
+		return
+	}
+
+	if err := ac.AdUsecase.Delete(ctx.Request.Context(), id); err != nil {
+		ctx.JSON(getStatusCode(err), domain.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, domain.SuccessResponse{Message: "Ad deleted successfully"})
+}
+
+// PostRestoreAd godoc
+// @Summary      Admin API
+// @Description  Restore a deleted ad that has not been purged
+// @Tags         ad
+// @Produce      json
+// @Param        id path int true "Ad id"
+// @Success      200 {object} domain.SuccessResponse
+// @Failure      400 {object} domain.ErrorResponse
+// @Failure      401 {object} domain.ErrorResponse
+// @Failure      404 {object} domain.ErrorResponse
+// @Failure      500 {object} domain.ErrorResponse
+// @Failure      504 {object} domain.ErrorResponse
+// @Security     ApiKeyAuth
+// @Router       /ad/{id}/restore [post]
+func (ac *AdController) PostRestoreAd(ctx *gin.Context) {
+	id, ok := parseAdID(ctx)
+	if !ok {
+		return
+	}
+
+	if err := ac.AdUsecase.Restore(ctx.Request.Context(), id); err != nil {
+		ctx.JSON(getStatusCode(err), domain.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, domain.SuccessResponse{Message: "Ad restored successfully"})
+}
+
+// notAcceptable reports whether the parameters of a language range give it a quality of 0, such
+// as "q=0" or "q=0.000", which means the language is not acceptable
+func notAcceptable(params string) bool {
+	for _, param := range strings.Split(params, ";") {
+		name, value, _ := strings.Cut(param, "=")
+		if strings.TrimSpace(name) != "q" {
+			continue
+		}
+		quality, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		return err != nil || quality <= 0
+	}
+	return false
+}
+
+// languagesFromHeader returns the ISO 639-1 codes of the languages accepted in an
+// Accept-Language header, e.g. ["zh", "en"] for "zh-TW,zh;q=0.9,en;q=0.8"
+func languagesFromHeader(header string) []string {
+	var languages []string
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if notAcceptable(params) {
+			continue
+		}
+
+		language, _, _ := strings.Cut(strings.TrimSpace(tag), "-")
+		language = strings.ToLower(language)
+		if language == "" || language == "*" || slices.Contains(languages, language) {
+			continue
+		}
+		languages = append(languages, language)
+	}
+	return languages
+}
+
 // GetAdWithCondition godoc
 // @Summary           Public API
 // @Description       Get a list of ads with queries
@@ -50,15 +417,69 @@ func (ac *AdController) PostAd(ctx *gin.Context) {
 // @Param             gender   query int    false "Target gender"
 // @Param             country  query string false "Target country"
 // @Param             platform query string false "Target platform"
+// @Param             language query string false "Target language in ISO 639-1"
+// @Param             Accept-Language header string false "Target languages when the language query is not provided"
+// @Param             timezone query string false "IANA timezone of the viewer, used by schedules without a timezone" default(UTC)
+// @Param             userId   query string false "Identifier of the viewer, used by frequency caps"
+// @Param             X-User-ID header string false "Identifier of the viewer when the userId query is not provided"
+// @Param             advertiserId query int false "Only get ads of the advertiser"
+// @Param             sort     query string false "Order of the ads, by priority then weighted random, or by endAt" Enums(priority, endAt) default(priority)
+// @Param             seed     query string false "Seed of the random order, which is the user, or the tenant and day for anonymous requests, by default"
+// @Param             X-Site-Key header string true "Site key of the tenant whose ads are served"
 // @Success           200 {object} map[string][]domain.Ad "{\"items\": [ad, ...]}"
+// @Failure           400 {object} domain.ErrorResponse
+// @Failure           401 {object} domain.ErrorResponse
 // @Failure           500 {object} domain.ErrorResponse
+// @Failure           504 {object} domain.ErrorResponse
 // @Router            /ad [get]
 func (ac *AdController) GetAdWithCondition(ctx *gin.Context) {
 	condition := ctx.Request.URL.Query()
+	if _, ok := condition["language"]; !ok {
+		if languages := languagesFromHeader(ctx.GetHeader("Accept-Language")); len(languages) > 0 {
+			condition["language"] = languages
+		}
+	}
+	if _, ok := condition["userId"]; !ok {
+		if userID := ctx.GetHeader(UserIDHeader); userID != "" {
+			condition["userId"] = []string{userID}
+		}
+	}
 
 	ads, err := ac.AdUsecase.GetByCondition(ctx.Request.Context(), condition)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, domain.ErrorResponse{Message: err.Error()})
+		ctx.JSON(getStatusCode(err), domain.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"items": ads,
+	})
+}
+
+// PostOverlappingAds godoc
+// @Summary           Admin API
+// @Description       List existing ads whose time window and targeting overlap a proposed ad
+// @Tags              ad
+// @Accept            json
+// @Produce           json
+// @Param             ad body domain.Ad True "Proposed ad"
+// @Success           200 {object} map[string][]domain.Ad "{\"items\": [ad, ...]}"
+// @Failure           400 {object} domain.ErrorResponse
+// @Failure           401 {object} domain.ErrorResponse
+// @Failure           500 {object} domain.ErrorResponse
+// @Failure           504 {object} domain.ErrorResponse
+// @Security          ApiKeyAuth
+// @Router            /ad/overlaps [post]
+func (ac *AdController) PostOverlappingAds(ctx *gin.Context) {
+	var ad domain.Ad
+	if err := ctx.Bind(&ad); err != nil {
+		ctx.JSON(http.StatusBadRequest, domain.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	ads, err := ac.AdUsecase.GetOverlapping(ctx.Request.Context(), &ad)
+	if err != nil {
+		ctx.JSON(getStatusCode(err), domain.ErrorResponse{Message: err.Error()})
 		return
 	}
 
@@ -8,6 +8,7 @@ import (
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"slices"
 	"strings"
 	"testing"
 
@@ -38,7 +39,9 @@ func TestPostAd_WrongJSONProvided_ShouldReturnBadRequestError(t *testing.T) {
 
 func TestPostAd_CreateFail_ShouldReturnInternalServerError(t *testing.T) {
 	mockAd := domain.Ad{
-		Title: "Test AD",
+		Title:   "Test AD",
+		StartAt: "2024-01-01T00:00:00.000Z",
+		EndAt:   "2025-01-01T00:00:00.000Z",
 	}
 	mockAdUsecase := mocks.NewAdUsecase(t)
 	mockAdUsecase.On("Create", mock.Anything, &mockAd).Return(errors.New("Fail")).Once()
@@ -47,7 +50,7 @@ func TestPostAd_CreateFail_ShouldReturnInternalServerError(t *testing.T) {
 		AdUsecase: mockAdUsecase,
 	}
 
-	reader := strings.NewReader(`{"title": "Test AD"}`)
+	reader := strings.NewReader(`{"title": "Test AD", "startAt": "2024-01-01T00:00:00.000Z", "endAt": "2025-01-01T00:00:00.000Z"}`)
 
 	httpRecorder := httptest.NewRecorder()
 	httpRequest := httptest.NewRequest(http.MethodPost, "/api/v1/ad", reader)
@@ -62,7 +65,9 @@ func TestPostAd_CreateFail_ShouldReturnInternalServerError(t *testing.T) {
 
 func TestPostAd_CreateSucess_ShouldReturnOK(t *testing.T) {
 	mockAd := domain.Ad{
-		Title: "Test AD",
+		Title:   "Test AD",
+		StartAt: "2024-01-01T00:00:00.000Z",
+		EndAt:   "2025-01-01T00:00:00.000Z",
 	}
 	mockAdUsecase := mocks.NewAdUsecase(t)
 	mockAdUsecase.On("Create", mock.Anything, &mockAd).Return(nil).Once()
@@ -71,7 +76,7 @@ func TestPostAd_CreateSucess_ShouldReturnOK(t *testing.T) {
 		AdUsecase: mockAdUsecase,
 	}
 
-	reader := strings.NewReader(`{"title": "Test AD"}`)
+	reader := strings.NewReader(`{"title": "Test AD", "startAt": "2024-01-01T00:00:00.000Z", "endAt": "2025-01-01T00:00:00.000Z"}`)
 
 	httpRecorder := httptest.NewRecorder()
 	httpRequest := httptest.NewRequest(http.MethodPost, "/api/v1/ad", reader)
@@ -133,3 +138,416 @@ func TestGetAdWithCondition_Success_ShouldReturnAds(t *testing.T) {
 	assert.Equal(t, http.StatusOK, httpRecorder.Code)
 	assert.EqualValues(t, mockAds, responseAds["items"])
 }
+
+func TestGetAdWithCondition_AcceptLanguageProvided_ShouldUseHeaderLanguages(t *testing.T) {
+	mockCondition := map[string][]string{"offset": {"0"}, "language": {"zh", "en"}}
+
+	mockAdUsecase := mocks.NewAdUsecase(t)
+	mockAdUsecase.On("GetByCondition", mock.Anything, mockCondition).Return([]domain.Ad{}, nil).Once()
+
+	testAdController := controller.AdController{
+		AdUsecase: mockAdUsecase,
+	}
+
+	httpRecorder := httptest.NewRecorder()
+	httpRequest := httptest.NewRequest(http.MethodGet, "/api/v1/ad?offset=0", nil)
+	httpRequest.Header.Set("Accept-Language", "zh-TW,zh;q=0.9,en-US;q=0.8,fr;q=0,de;q=0.00,es; q = 0.0,*;q=0.5")
+
+	app := gin.Default()
+	app.GET("/api/v1/ad", testAdController.GetAdWithCondition)
+	app.ServeHTTP(httpRecorder, httpRequest)
+
+	assert.Equal(t, http.StatusOK, httpRecorder.Code)
+}
+
+func TestGetAdWithCondition_LanguageQueryProvided_ShouldIgnoreAcceptLanguage(t *testing.T) {
+	mockCondition := map[string][]string{"offset": {"0"}, "language": {"de"}}
+
+	mockAdUsecase := mocks.NewAdUsecase(t)
+	mockAdUsecase.On("GetByCondition", mock.Anything, mockCondition).Return([]domain.Ad{}, nil).Once()
+
+	testAdController := controller.AdController{
+		AdUsecase: mockAdUsecase,
+	}
+
+	httpRecorder := httptest.NewRecorder()
+	httpRequest := httptest.NewRequest(http.MethodGet, "/api/v1/ad?offset=0&language=de", nil)
+	httpRequest.Header.Set("Accept-Language", "en")
+
+	app := gin.Default()
+	app.GET("/api/v1/ad", testAdController.GetAdWithCondition)
+	app.ServeHTTP(httpRecorder, httpRequest)
+
+	assert.Equal(t, http.StatusOK, httpRecorder.Code)
+}
+
+func TestPostAd_CreateTimeout_ShouldReturnGatewayTimeout(t *testing.T) {
+	mockAd := domain.Ad{
+		Title:   "Test AD",
+		StartAt: "2024-01-01T00:00:00.000Z",
+		EndAt:   "2025-01-01T00:00:00.000Z",
+	}
+	mockAdUsecase := mocks.NewAdUsecase(t)
+	mockAdUsecase.On("Create", mock.Anything, &mockAd).Return(domain.ErrTimeout).Once()
+
+	testAdController := controller.AdController{
+		AdUsecase: mockAdUsecase,
+	}
+
+	reader := strings.NewReader(`{"title": "Test AD", "startAt": "2024-01-01T00:00:00.000Z", "endAt": "2025-01-01T00:00:00.000Z"}`)
+
+	httpRecorder := httptest.NewRecorder()
+	httpRequest := httptest.NewRequest(http.MethodPost, "/api/v1/ad", reader)
+	httpRequest.Header.Set("Content-Type", "application/json")
+
+	app := gin.Default()
+	app.POST("/api/v1/ad", testAdController.PostAd)
+	app.ServeHTTP(httpRecorder, httpRequest)
+
+	assert.Equal(t, http.StatusGatewayTimeout, httpRecorder.Code)
+}
+
+func TestGetAdWithCondition_Timeout_ShouldReturnGatewayTimeout(t *testing.T) {
+	mockCondition := map[string][]string{"offset": {"0"}}
+
+	mockAdUsecase := mocks.NewAdUsecase(t)
+	mockAdUsecase.On("GetByCondition", mock.Anything, mockCondition).Return(nil, domain.ErrTimeout).Once()
+
+	testAdController := controller.AdController{
+		AdUsecase: mockAdUsecase,
+	}
+
+	httpRecorder := httptest.NewRecorder()
+	httpRequest := httptest.NewRequest(http.MethodGet, "/api/v1/ad?offset=0", nil)
+
+	app := gin.Default()
+	app.GET("/api/v1/ad", testAdController.GetAdWithCondition)
+	app.ServeHTTP(httpRecorder, httpRequest)
+
+	assert.Equal(t, http.StatusGatewayTimeout, httpRecorder.Code)
+}
+
+func TestPostAd_CreateConflict_ShouldReturnConflict(t *testing.T) {
+	mockAd := domain.Ad{
+		Title:   "Test AD",
+		StartAt: "2024-01-01T00:00:00.000Z",
+		EndAt:   "2025-01-01T00:00:00.000Z",
+	}
+	mockAdUsecase := mocks.NewAdUsecase(t)
+	mockAdUsecase.On("Create", mock.Anything, &mockAd).Return(domain.ErrConflict).Once()
+
+	testAdController := controller.AdController{
+		AdUsecase: mockAdUsecase,
+	}
+
+	reader := strings.NewReader(`{"title": "Test AD", "startAt": "2024-01-01T00:00:00.000Z", "endAt": "2025-01-01T00:00:00.000Z"}`)
+
+	httpRecorder := httptest.NewRecorder()
+	httpRequest := httptest.NewRequest(http.MethodPost, "/api/v1/ad", reader)
+	httpRequest.Header.Set("Content-Type", "application/json")
+
+	app := gin.Default()
+	app.POST("/api/v1/ad", testAdController.PostAd)
+	app.ServeHTTP(httpRecorder, httpRequest)
+
+	assert.Equal(t, http.StatusConflict, httpRecorder.Code)
+}
+
+func TestPostAd_CreateBadParamInput_ShouldReturnBadRequestError(t *testing.T) {
+	mockAd := domain.Ad{
+		Title:   "Test AD",
+		StartAt: "2024-01-01T00:00:00.000Z",
+		EndAt:   "2025-01-01T00:00:00.000Z",
+		Condition: &domain.Condition{
+			Country:        []string{"TW"},
+			ExcludeCountry: []string{"TW"},
+		},
+	}
+	mockAdUsecase := mocks.NewAdUsecase(t)
+	mockAdUsecase.On("Create", mock.Anything, &mockAd).Return(domain.ErrBadParamInput).Once()
+
+	testAdController := controller.AdController{
+		AdUsecase: mockAdUsecase,
+	}
+
+	reader := strings.NewReader(`{"title": "Test AD", "startAt": "2024-01-01T00:00:00.000Z", "endAt": "2025-01-01T00:00:00.000Z", "condition": {"country": ["TW"], "excludeCountry": ["TW"]}}`)
+
+	httpRecorder := httptest.NewRecorder()
+	httpRequest := httptest.NewRequest(http.MethodPost, "/api/v1/ad", reader)
+	httpRequest.Header.Set("Content-Type", "application/json")
+
+	app := gin.Default()
+	app.POST("/api/v1/ad", testAdController.PostAd)
+	app.ServeHTTP(httpRecorder, httpRequest)
+
+	assert.Equal(t, http.StatusBadRequest, httpRecorder.Code)
+}
+
+func TestPostOverlappingAds_Success_ShouldReturnAds(t *testing.T) {
+	mockAd := domain.Ad{
+		Title:   "Test AD",
+		StartAt: "2024-01-01T00:00:00.000Z",
+		EndAt:   "2025-01-01T00:00:00.000Z",
+	}
+	mockAds := []domain.Ad{{ID: 1, Title: "Another AD"}}
+
+	mockAdUsecase := mocks.NewAdUsecase(t)
+	mockAdUsecase.On("GetOverlapping", mock.Anything, &mockAd).Return(mockAds, nil).Once()
+
+	testAdController := controller.AdController{
+		AdUsecase: mockAdUsecase,
+	}
+
+	reader := strings.NewReader(`{"title": "Test AD", "startAt": "2024-01-01T00:00:00.000Z", "endAt": "2025-01-01T00:00:00.000Z"}`)
+
+	httpRecorder := httptest.NewRecorder()
+	httpRequest := httptest.NewRequest(http.MethodPost, "/api/v1/ad/overlaps", reader)
+	httpRequest.Header.Set("Content-Type", "application/json")
+
+	app := gin.Default()
+	app.POST("/api/v1/ad/overlaps", testAdController.PostOverlappingAds)
+	app.ServeHTTP(httpRecorder, httpRequest)
+
+	var responseAds map[string][]domain.Ad
+	err := json.Unmarshal(httpRecorder.Body.Bytes(), &responseAds)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, httpRecorder.Code)
+	assert.EqualValues(t, mockAds, responseAds["items"])
+}
+
+func TestGetAdWithCondition_UserIDHeaderProvided_ShouldUseHeaderUserID(t *testing.T) {
+	mockCondition := map[string][]string{"offset": {"0"}, "userId": {"user-1"}}
+
+	mockAdUsecase := mocks.NewAdUsecase(t)
+	mockAdUsecase.On("GetByCondition", mock.Anything, mockCondition).Return([]domain.Ad{}, nil).Once()
+
+	testAdController := controller.AdController{
+		AdUsecase: mockAdUsecase,
+	}
+
+	httpRecorder := httptest.NewRecorder()
+	httpRequest := httptest.NewRequest(http.MethodGet, "/api/v1/ad?offset=0", nil)
+	httpRequest.Header.Set(controller.UserIDHeader, "user-1")
+
+	app := gin.Default()
+	app.GET("/api/v1/ad", testAdController.GetAdWithCondition)
+	app.ServeHTTP(httpRecorder, httpRequest)
+
+	assert.Equal(t, http.StatusOK, httpRecorder.Code)
+}
+
+func TestGetAd_Success_ShouldReturnAd(t *testing.T) {
+	mockAdUsecase := mocks.NewAdUsecase(t)
+	mockAdUsecase.On("GetByID", mock.Anything, int64(3)).Return(domain.Ad{ID: 3, Title: "AD 3", Status: domain.AdPaused, Version: 4}, nil).Once()
+
+	testAdController := controller.AdController{
+		AdUsecase: mockAdUsecase,
+	}
+
+	httpRecorder := httptest.NewRecorder()
+	httpRequest := httptest.NewRequest(http.MethodGet, "/api/v1/ad/3", nil)
+
+	app := gin.Default()
+	app.GET("/api/v1/ad/:id", testAdController.GetAd)
+	app.ServeHTTP(httpRecorder, httpRequest)
+
+	var responseAd domain.Ad
+	err := json.Unmarshal(httpRecorder.Body.Bytes(), &responseAd)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, httpRecorder.Code)
+	assert.Equal(t, domain.AdPaused, responseAd.Status)
+	assert.Equal(t, `"4"`, httpRecorder.Header().Get("ETag"))
+}
+
+func TestPutAd_Success_ShouldUpdateAdOfPath(t *testing.T) {
+	mockAdUsecase := mocks.NewAdUsecase(t)
+	mockAdUsecase.On("Update", mock.Anything, mock.MatchedBy(func(ad *domain.Ad) bool {
+		return ad.ID == 3 && ad.Title == "AD 3" && ad.IfMatch == nil
+	})).Run(func(args mock.Arguments) {
+		args.Get(1).(*domain.Ad).Version = 2
+	}).Return(nil).Once()
+
+	testAdController := controller.AdController{
+		AdUsecase: mockAdUsecase,
+	}
+
+	httpRecorder := httptest.NewRecorder()
+	httpRequest := httptest.NewRequest(http.MethodPut, "/api/v1/ad/3", strings.NewReader(`{"id":5,"title":"AD 3","endAt":"2025-01-01T00:00:00Z","version":7}`))
+	httpRequest.Header.Set("Content-Type", "application/json")
+	httpRequest.Header.Set("If-Match", "*")
+
+	app := gin.Default()
+	app.PUT("/api/v1/ad/:id", testAdController.PutAd)
+	app.ServeHTTP(httpRecorder, httpRequest)
+
+	assert.Equal(t, http.StatusOK, httpRecorder.Code)
+	assert.Equal(t, `"2"`, httpRecorder.Header().Get("ETag"))
+}
+
+func TestPutAd_IfMatch_ShouldUpdateAdAtListedVersions(t *testing.T) {
+	invalid := []int64{-1}
+	for ifMatch, versions := range map[string][]int64{
+		`"4"`: {4}, `"3", "4"`: {3, 4}, `"3","4"`: {3, 4}, `*`: nil,
+		`W/"4"`: invalid, `4`: invalid, `"a"`: invalid, `"3", W/"4"`: invalid, `"3",`: invalid,
+	} {
+		valid := !slices.Equal(versions, invalid)
+		mockAdUsecase := mocks.NewAdUsecase(t)
+		if valid {
+			mockAdUsecase.On("Update", mock.Anything, mock.MatchedBy(func(ad *domain.Ad) bool {
+				return ad.ID == 3 && slices.Equal(ad.IfMatch, versions)
+			})).Return(nil).Once()
+		}
+
+		testAdController := controller.AdController{
+			AdUsecase: mockAdUsecase,
+		}
+
+		httpRecorder := httptest.NewRecorder()
+		httpRequest := httptest.NewRequest(http.MethodPut, "/api/v1/ad/3", strings.NewReader(`{"title":"AD 3","endAt":"2025-01-01T00:00:00Z"}`))
+		httpRequest.Header.Set("Content-Type", "application/json")
+		httpRequest.Header.Set("If-Match", ifMatch)
+
+		app := gin.Default()
+		app.PUT("/api/v1/ad/:id", testAdController.PutAd)
+		app.ServeHTTP(httpRecorder, httpRequest)
+
+		if valid {
+			assert.Equal(t, http.StatusOK, httpRecorder.Code, ifMatch)
+		} else {
+			assert.Equal(t, http.StatusPreconditionFailed, httpRecorder.Code, ifMatch)
+		}
+	}
+}
+
+func TestPutAd_IfMatchMissing_ShouldReturnPreconditionRequired(t *testing.T) {
+	testAdController := controller.AdController{
+		AdUsecase: mocks.NewAdUsecase(t),
+	}
+
+	httpRecorder := httptest.NewRecorder()
+	httpRequest := httptest.NewRequest(http.MethodPut, "/api/v1/ad/3", strings.NewReader(`{"title":"AD 3","endAt":"2025-01-01T00:00:00Z"}`))
+	httpRequest.Header.Set("Content-Type", "application/json")
+
+	app := gin.Default()
+	app.PUT("/api/v1/ad/:id", testAdController.PutAd)
+	app.ServeHTTP(httpRecorder, httpRequest)
+
+	assert.Equal(t, http.StatusPreconditionRequired, httpRecorder.Code)
+}
+
+func TestPutAd_StaleVersion_ShouldReturnPreconditionFailed(t *testing.T) {
+	mockAdUsecase := mocks.NewAdUsecase(t)
+	mockAdUsecase.On("Update", mock.Anything, mock.Anything).Return(domain.ErrPreconditionFailed).Once()
+
+	testAdController := controller.AdController{
+		AdUsecase: mockAdUsecase,
+	}
+
+	httpRecorder := httptest.NewRecorder()
+	httpRequest := httptest.NewRequest(http.MethodPut, "/api/v1/ad/3", strings.NewReader(`{"title":"AD 3","endAt":"2025-01-01T00:00:00Z"}`))
+	httpRequest.Header.Set("Content-Type", "application/json")
+	httpRequest.Header.Set("If-Match", `"1"`)
+
+	app := gin.Default()
+	app.PUT("/api/v1/ad/:id", testAdController.PutAd)
+	app.ServeHTTP(httpRecorder, httpRequest)
+
+	assert.Equal(t, http.StatusPreconditionFailed, httpRecorder.Code)
+}
+
+func TestGetAdVersion_InvalidVersion_ShouldReturnBadRequest(t *testing.T) {
+	mockAdUsecase := mocks.NewAdUsecase(t)
+
+	testAdController := controller.AdController{
+		AdUsecase: mockAdUsecase,
+	}
+
+	httpRecorder := httptest.NewRecorder()
+	httpRequest := httptest.NewRequest(http.MethodGet, "/api/v1/ad/3/versions/latest", nil)
+
+	app := gin.Default()
+	app.GET("/api/v1/ad/:id/versions/:version", testAdController.GetAdVersion)
+	app.ServeHTTP(httpRecorder, httpRequest)
+
+	assert.Equal(t, http.StatusBadRequest, httpRecorder.Code)
+}
+
+func TestPostRollbackAd_Success_ShouldReturnAdAtNewVersion(t *testing.T) {
+	mockAdUsecase := mocks.NewAdUsecase(t)
+	mockAdUsecase.On("Rollback", mock.Anything, int64(3), int64(1), []int64{4}).Return(domain.Ad{ID: 3, Title: "AD 3", Version: 5}, nil).Once()
+
+	testAdController := controller.AdController{
+		AdUsecase: mockAdUsecase,
+	}
+
+	httpRecorder := httptest.NewRecorder()
+	httpRequest := httptest.NewRequest(http.MethodPost, "/api/v1/ad/3/rollback?version=1", nil)
+	httpRequest.Header.Set("If-Match", `"4"`)
+
+	app := gin.Default()
+	app.POST("/api/v1/ad/:id/rollback", testAdController.PostRollbackAd)
+	app.ServeHTTP(httpRecorder, httpRequest)
+
+	var responseAd domain.Ad
+	err := json.Unmarshal(httpRecorder.Body.Bytes(), &responseAd)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, httpRecorder.Code)
+	assert.Equal(t, int64(5), responseAd.Version)
+	assert.Equal(t, `"5"`, httpRecorder.Header().Get("ETag"))
+}
+
+func TestPostPauseAd_NotFound_ShouldReturnNotFoundError(t *testing.T) {
+	mockAdUsecase := mocks.NewAdUsecase(t)
+	mockAdUsecase.On("UpdateStatus", mock.Anything, int64(3), domain.AdPaused).Return(domain.ErrNotFound).Once()
+
+	testAdController := controller.AdController{
+		AdUsecase: mockAdUsecase,
+	}
+
+	httpRecorder := httptest.NewRecorder()
+	httpRequest := httptest.NewRequest(http.MethodPost, "/api/v1/ad/3/pause", nil)
+
+	app := gin.Default()
+	app.POST("/api/v1/ad/:id/pause", testAdController.PostPauseAd)
+	app.ServeHTTP(httpRecorder, httpRequest)
+
+	assert.Equal(t, http.StatusNotFound, httpRecorder.Code)
+}
+
+func TestDeleteAd_InvalidID_ShouldReturnBadRequest(t *testing.T) {
+	mockAdUsecase := mocks.NewAdUsecase(t)
+
+	testAdController := controller.AdController{
+		AdUsecase: mockAdUsecase,
+	}
+
+	httpRecorder := httptest.NewRecorder()
+	httpRequest := httptest.NewRequest(http.MethodDelete, "/api/v1/ad/three", nil)
+
+	app := gin.Default()
+	app.DELETE("/api/v1/ad/:id", testAdController.DeleteAd)
+	app.ServeHTTP(httpRecorder, httpRequest)
+
+	assert.Equal(t, http.StatusBadRequest, httpRecorder.Code)
+}
+
+func TestPostRestoreAd_NotDeleted_ShouldReturnNotFoundError(t *testing.T) {
+	mockAdUsecase := mocks.NewAdUsecase(t)
+	mockAdUsecase.On("Restore", mock.Anything, int64(3)).Return(domain.ErrNotFound).Once()
+
+	testAdController := controller.AdController{
+		AdUsecase: mockAdUsecase,
+	}
+
+	httpRecorder := httptest.NewRecorder()
+	httpRequest := httptest.NewRequest(http.MethodPost, "/api/v1/ad/3/restore", nil)
+
+	app := gin.Default()
+	app.POST("/api/v1/ad/:id/restore", testAdController.PostRestoreAd)
+	app.ServeHTTP(httpRecorder, httpRequest)
+
+	assert.Equal(t, http.StatusNotFound, httpRecorder.Code)
+}
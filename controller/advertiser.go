@@ -0,0 +1,160 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"dcard-backend/domain"
+)
+
+type AdvertiserController struct {
+	AdvertiserUsecase domain.AdvertiserUsecase
+}
+
+// PostAdvertiser godoc
+// @Summary       Admin API
+// @Description   Create an advertiser
+// @Tags          advertiser
+// @Accept        json
+// @Produce       json
+// @Param         advertiser body domain.Advertiser True "Add an advertiser"
+// @Success       200 {object} domain.Advertiser
+// @Failure       400 {object} domain.ErrorResponse
+// @Failure       401 {object} domain.ErrorResponse
+// @Failure       409 {object} domain.ErrorResponse
+// @Failure       500 {object} domain.ErrorResponse
+// @Failure       504 {object} domain.ErrorResponse
+// @Security      ApiKeyAuth
+// @Router        /advertiser [post]
+func (ac *AdvertiserController) PostAdvertiser(ctx *gin.Context) {
+	var advertiser domain.Advertiser
+	if err := ctx.Bind(&advertiser); err != nil {
+		ctx.JSON(http.StatusBadRequest, domain.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	if err := ac.AdvertiserUsecase.Create(ctx.Request.Context(), &advertiser); err != nil {
+		ctx.JSON(getStatusCode(err), domain.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, advertiser)
+}
+
+// GetAdvertisers godoc
+// @Summary       Admin API
+// @Description   Get every advertiser
+// @Tags          advertiser
+// @Produce       json
+// @Success       200 {array}  domain.Advertiser
+// @Failure       401 {object} domain.ErrorResponse
+// @Failure       500 {object} domain.ErrorResponse
+// @Failure       504 {object} domain.ErrorResponse
+// @Security      ApiKeyAuth
+// @Router        /advertiser [get]
+func (ac *AdvertiserController) GetAdvertisers(ctx *gin.Context) {
+	advertisers, err := ac.AdvertiserUsecase.Fetch(ctx.Request.Context())
+	if err != nil {
+		ctx.JSON(getStatusCode(err), domain.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, advertisers)
+}
+
+// GetAdvertiser godoc
+// @Summary      Admin API
+// @Description  Get an advertiser
+// @Tags         advertiser
+// @Produce      json
+// @Param        id path int true "Advertiser id"
+// @Success      200 {object} domain.Advertiser
+// @Failure      400 {object} domain.ErrorResponse
+// @Failure      401 {object} domain.ErrorResponse
+// @Failure      404 {object} domain.ErrorResponse
+// @Failure      500 {object} domain.ErrorResponse
+// @Failure      504 {object} domain.ErrorResponse
+// @Security     ApiKeyAuth
+// @Router       /advertiser/{id} [get]
+func (ac *AdvertiserController) GetAdvertiser(ctx *gin.Context) {
+	id, ok := parseID(ctx, "advertiser")
+	if !ok {
+		return
+	}
+
+	advertiser, err := ac.AdvertiserUsecase.GetByID(ctx.Request.Context(), id)
+	if err != nil {
+		ctx.JSON(getStatusCode(err), domain.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, advertiser)
+}
+
+// PutAdvertiser godoc
+// @Summary      Admin API
+// @Description  Rename an advertiser
+// @Tags         advertiser
+// @Accept       json
+// @Produce      json
+// @Param        id path int true "Advertiser id"
+// @Param        advertiser body domain.Advertiser True "New values of the advertiser"
+// @Success      200 {object} domain.Advertiser
+// @Failure      400 {object} domain.ErrorResponse
+// @Failure      401 {object} domain.ErrorResponse
+// @Failure      404 {object} domain.ErrorResponse
+// @Failure      409 {object} domain.ErrorResponse
+// @Failure      500 {object} domain.ErrorResponse
+// @Failure      504 {object} domain.ErrorResponse
+// @Security     ApiKeyAuth
+// @Router       /advertiser/{id} [put]
+func (ac *AdvertiserController) PutAdvertiser(ctx *gin.Context) {
+	id, ok := parseID(ctx, "advertiser")
+	if !ok {
+		return
+	}
+
+	var advertiser domain.Advertiser
+	if err := ctx.Bind(&advertiser); err != nil {
+		ctx.JSON(http.StatusBadRequest, domain.ErrorResponse{Message: err.Error()})
+		return
+	}
+	advertiser.ID = id
+
+	if err := ac.AdvertiserUsecase.Update(ctx.Request.Context(), &advertiser); err != nil {
+		ctx.JSON(getStatusCode(err), domain.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, advertiser)
+}
+
+// DeleteAdvertiser godoc
+// @Summary         Admin API
+// @Description     Delete an advertiser without campaigns
+// @Tags            advertiser
+// @Produce         json
+// @Param           id path int true "Advertiser id"
+// @Success         200 {object} domain.SuccessResponse
+// @Failure         400 {object} domain.ErrorResponse
+// @Failure         401 {object} domain.ErrorResponse
+// @Failure         404 {object} domain.ErrorResponse
+// @Failure         409 {object} domain.ErrorResponse
+// @Failure         500 {object} domain.ErrorResponse
+// @Failure         504 {object} domain.ErrorResponse
+// @Security        ApiKeyAuth
+// @Router          /advertiser/{id} [delete]
+func (ac *AdvertiserController) DeleteAdvertiser(ctx *gin.Context) {
+	id, ok := parseID(ctx, "advertiser")
+	if !ok {
+		return
+	}
+
+	if err := ac.AdvertiserUsecase.Delete(ctx.Request.Context(), id); err != nil {
+		ctx.JSON(getStatusCode(err), domain.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, domain.SuccessResponse{Message: "Advertiser deleted successfully"})
+}
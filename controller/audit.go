@@ -0,0 +1,118 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"dcard-backend/domain"
+)
+
+type AuditController struct {
+	AuditUsecase domain.AuditUsecase
+}
+
+// GetAdHistory godoc
+// @Summary     Admin API
+// @Description Get the changes of an ad from the newest, including the changes of deleted ads
+// @Tags        audit
+// @Produce     json
+// @Param       id     path  int true  "Ad id"
+// @Param       limit  query int false "Number of changes, at most 500" default(50)
+// @Param       offset query int false "Number of changes to skip" default(0)
+// @Success     200 {array}  domain.AuditEntry
+// @Failure     400 {object} domain.ErrorResponse
+// @Failure     401 {object} domain.ErrorResponse
+// @Failure     404 {object} domain.ErrorResponse
+// @Failure     500 {object} domain.ErrorResponse
+// @Failure     504 {object} domain.ErrorResponse
+// @Security    ApiKeyAuth
+// @Router      /ad/{id}/history [get]
+func (ac *AuditController) GetAdHistory(ctx *gin.Context) {
+	id, ok := parseAdID(ctx)
+	if !ok {
+		return
+	}
+
+	limit, ok := parseIntQuery(ctx, "limit")
+	if !ok {
+		return
+	}
+	offset, ok := parseIntQuery(ctx, "offset")
+	if !ok {
+		return
+	}
+
+	entries, err := ac.AuditUsecase.GetHistory(ctx.Request.Context(), id, int(limit), int(offset))
+	if err != nil {
+		ctx.JSON(getStatusCode(err), domain.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, entries)
+}
+
+// parseIntQuery returns the integer of a query, which is 0 when it is not provided
+func parseIntQuery(ctx *gin.Context, key string) (int64, bool) {
+	value := ctx.Query(key)
+	if value == "" {
+		return 0, true
+	}
+	number, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, domain.ErrorResponse{Message: key + " should be an integer"})
+		return 0, false
+	}
+	return number, true
+}
+
+// GetAuditLog  godoc
+// @Summary     Admin API
+// @Description Get the changes of the ads of the tenant from the newest
+// @Tags        audit
+// @Produce     json
+// @Param       adId   query int    false "Only get the changes of the ad"
+// @Param       actor  query string false "Only get the changes made by the actor"
+// @Param       action query string false "Only get the changes of the action" Enums(create, update, delete, pause, resume, rollback, restore)
+// @Param       since  query string false "Only get the changes made at or after the time, in RFC 3339"
+// @Param       until  query string false "Only get the changes made before the time, in RFC 3339"
+// @Param       limit  query int    false "Number of changes, at most 500" default(50)
+// @Param       offset query int    false "Number of changes to skip" default(0)
+// @Success     200 {array}  domain.AuditEntry
+// @Failure     400 {object} domain.ErrorResponse
+// @Failure     401 {object} domain.ErrorResponse
+// @Failure     500 {object} domain.ErrorResponse
+// @Failure     504 {object} domain.ErrorResponse
+// @Security    ApiKeyAuth
+// @Router      /audit [get]
+func (ac *AuditController) GetAuditLog(ctx *gin.Context) {
+	filter := domain.AuditFilter{
+		Actor:  ctx.Query("actor"),
+		Action: domain.AuditAction(ctx.Query("action")),
+		Since:  ctx.Query("since"),
+		Until:  ctx.Query("until"),
+	}
+
+	adID, ok := parseIntQuery(ctx, "adId")
+	if !ok {
+		return
+	}
+	limit, ok := parseIntQuery(ctx, "limit")
+	if !ok {
+		return
+	}
+	offset, ok := parseIntQuery(ctx, "offset")
+	if !ok {
+		return
+	}
+	filter.AdID, filter.Limit, filter.Offset = adID, int(limit), int(offset)
+
+	entries, err := ac.AuditUsecase.Fetch(ctx.Request.Context(), filter)
+	if err != nil {
+		ctx.JSON(getStatusCode(err), domain.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, entries)
+}
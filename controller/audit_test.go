@@ -0,0 +1,84 @@
+package controller_test
+
+import (
+	"dcard-backend/controller"
+	"dcard-backend/domain"
+	"dcard-backend/domain/mocks"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestGetAdHistory_Success_ShouldReturnEntries(t *testing.T) {
+	entries := []domain.AuditEntry{{ID: 1, AdID: 3, Action: domain.AuditCreate, Actor: "cli:admin", After: &domain.Ad{Title: "AD 3"}}}
+	mockAuditUsecase := mocks.NewAuditUsecase(t)
+	mockAuditUsecase.On("GetHistory", mock.Anything, int64(3), 10, 20).Return(entries, nil).Once()
+
+	testAuditController := controller.AuditController{
+		AuditUsecase: mockAuditUsecase,
+	}
+
+	httpRecorder := httptest.NewRecorder()
+	httpRequest := httptest.NewRequest(http.MethodGet, "/api/v1/ad/3/history?limit=10&offset=20", nil)
+
+	app := gin.Default()
+	app.GET("/api/v1/ad/:id/history", testAuditController.GetAdHistory)
+	app.ServeHTTP(httpRecorder, httpRequest)
+
+	var responseEntries []domain.AuditEntry
+	err := json.Unmarshal(httpRecorder.Body.Bytes(), &responseEntries)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, httpRecorder.Code)
+	assert.Equal(t, entries, responseEntries)
+}
+
+func TestGetAuditLog_QueryProvided_ShouldFetchWithFilter(t *testing.T) {
+	expectedFilter := domain.AuditFilter{
+		AdID:   3,
+		Actor:  "cli:admin",
+		Action: domain.AuditUpdate,
+		Since:  "2024-01-01T00:00:00Z",
+		Limit:  10,
+		Offset: 20,
+	}
+	mockAuditUsecase := mocks.NewAuditUsecase(t)
+	mockAuditUsecase.On("Fetch", mock.Anything, expectedFilter).Return([]domain.AuditEntry{}, nil).Once()
+
+	testAuditController := controller.AuditController{
+		AuditUsecase: mockAuditUsecase,
+	}
+
+	httpRecorder := httptest.NewRecorder()
+	httpRequest := httptest.NewRequest(http.MethodGet,
+		"/api/v1/audit?adId=3&actor=cli:admin&action=update&since=2024-01-01T00:00:00Z&limit=10&offset=20", nil)
+
+	app := gin.Default()
+	app.GET("/api/v1/audit", testAuditController.GetAuditLog)
+	app.ServeHTTP(httpRecorder, httpRequest)
+
+	assert.Equal(t, http.StatusOK, httpRecorder.Code)
+	assert.Equal(t, "[]", httpRecorder.Body.String())
+}
+
+func TestGetAuditLog_InvalidLimit_ShouldReturnBadRequest(t *testing.T) {
+	mockAuditUsecase := mocks.NewAuditUsecase(t)
+
+	testAuditController := controller.AuditController{
+		AuditUsecase: mockAuditUsecase,
+	}
+
+	httpRecorder := httptest.NewRecorder()
+	httpRequest := httptest.NewRequest(http.MethodGet, "/api/v1/audit?limit=ten", nil)
+
+	app := gin.Default()
+	app.GET("/api/v1/audit", testAuditController.GetAuditLog)
+	app.ServeHTTP(httpRecorder, httpRequest)
+
+	assert.Equal(t, http.StatusBadRequest, httpRecorder.Code)
+}
@@ -0,0 +1,217 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"dcard-backend/domain"
+)
+
+type CampaignController struct {
+	CampaignUsecase domain.CampaignUsecase
+}
+
+// PostCampaign godoc
+// @Summary     Admin API
+// @Description Create a campaign of an advertiser
+// @Tags        campaign
+// @Accept      json
+// @Produce     json
+// @Param       campaign body domain.Campaign True "Add a campaign"
+// @Success     200 {object} domain.Campaign
+// @Failure     400 {object} domain.ErrorResponse
+// @Failure     401 {object} domain.ErrorResponse
+// @Failure     409 {object} domain.ErrorResponse
+// @Failure     500 {object} domain.ErrorResponse
+// @Failure     504 {object} domain.ErrorResponse
+// @Security    ApiKeyAuth
+// @Router      /campaign [post]
+func (cc *CampaignController) PostCampaign(ctx *gin.Context) {
+	var campaign domain.Campaign
+	if err := ctx.Bind(&campaign); err != nil {
+		ctx.JSON(http.StatusBadRequest, domain.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	if err := cc.CampaignUsecase.Create(ctx.Request.Context(), &campaign); err != nil {
+		ctx.JSON(getStatusCode(err), domain.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, campaign)
+}
+
+// GetCampaign godoc
+// @Summary     Admin API
+// @Description Get a campaign
+// @Tags        campaign
+// @Produce     json
+// @Param       id path int true "Campaign id"
+// @Success     200 {object} domain.Campaign
+// @Failure     400 {object} domain.ErrorResponse
+// @Failure     401 {object} domain.ErrorResponse
+// @Failure     404 {object} domain.ErrorResponse
+// @Failure     500 {object} domain.ErrorResponse
+// @Failure     504 {object} domain.ErrorResponse
+// @Security    ApiKeyAuth
+// @Router      /campaign/{id} [get]
+func (cc *CampaignController) GetCampaign(ctx *gin.Context) {
+	id, ok := parseID(ctx, "campaign")
+	if !ok {
+		return
+	}
+
+	campaign, err := cc.CampaignUsecase.GetByID(ctx.Request.Context(), id)
+	if err != nil {
+		ctx.JSON(getStatusCode(err), domain.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, campaign)
+}
+
+// GetAdvertiserCampaigns godoc
+// @Summary               Admin API
+// @Description           Get the campaigns of an advertiser
+// @Tags                  campaign
+// @Produce               json
+// @Param                 id path int true "Advertiser id"
+// @Success               200 {array}  domain.Campaign
+// @Failure               400 {object} domain.ErrorResponse
+// @Failure               401 {object} domain.ErrorResponse
+// @Failure               404 {object} domain.ErrorResponse
+// @Failure               500 {object} domain.ErrorResponse
+// @Failure               504 {object} domain.ErrorResponse
+// @Security              ApiKeyAuth
+// @Router                /advertiser/{id}/campaign [get]
+func (cc *CampaignController) GetAdvertiserCampaigns(ctx *gin.Context) {
+	advertiserID, ok := parseID(ctx, "advertiser")
+	if !ok {
+		return
+	}
+
+	campaigns, err := cc.CampaignUsecase.FetchByAdvertiser(ctx.Request.Context(), advertiserID)
+	if err != nil {
+		ctx.JSON(getStatusCode(err), domain.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, campaigns)
+}
+
+// PutCampaign godoc
+// @Summary     Admin API
+// @Description Replace a campaign, which stays with its advertiser. Existing ads keep the defaults they inherited.
+// @Tags        campaign
+// @Accept      json
+// @Produce     json
+// @Param       id path int true "Campaign id"
+// @Param       campaign body domain.Campaign True "New values of the campaign"
+// @Success     200 {object} domain.Campaign
+// @Failure     400 {object} domain.ErrorResponse
+// @Failure     401 {object} domain.ErrorResponse
+// @Failure     404 {object} domain.ErrorResponse
+// @Failure     500 {object} domain.ErrorResponse
+// @Failure     504 {object} domain.ErrorResponse
+// @Security    ApiKeyAuth
+// @Router      /campaign/{id} [put]
+func (cc *CampaignController) PutCampaign(ctx *gin.Context) {
+	id, ok := parseID(ctx, "campaign")
+	if !ok {
+		return
+	}
+
+	var campaign domain.Campaign
+	if err := ctx.Bind(&campaign); err != nil {
+		ctx.JSON(http.StatusBadRequest, domain.ErrorResponse{Message: err.Error()})
+		return
+	}
+	campaign.ID = id
+
+	if err := cc.CampaignUsecase.Update(ctx.Request.Context(), &campaign); err != nil {
+		ctx.JSON(getStatusCode(err), domain.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, campaign)
+}
+
+func (cc *CampaignController) updateStatus(ctx *gin.Context, status domain.CampaignStatus, message string) {
+	id, ok := parseID(ctx, "campaign")
+	if !ok {
+		return
+	}
+
+	if err := cc.CampaignUsecase.UpdateStatus(ctx.Request.Context(), id, status); err != nil {
+		ctx.JSON(getStatusCode(err), domain.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, domain.SuccessResponse{Message: message})
+}
+
+// PostPauseCampaign godoc
+// @Summary          Admin API
+// @Description      Stop serving every ad of a campaign
+// @Tags             campaign
+// @Produce          json
+// @Param            id path int true "Campaign id"
+// @Success          200 {object} domain.SuccessResponse
+// @Failure          400 {object} domain.ErrorResponse
+// @Failure          401 {object} domain.ErrorResponse
+// @Failure          404 {object} domain.ErrorResponse
+// @Failure          500 {object} domain.ErrorResponse
+// @Failure          504 {object} domain.ErrorResponse
+// @Security         ApiKeyAuth
+// @Router           /campaign/{id}/pause [post]
+func (cc *CampaignController) PostPauseCampaign(ctx *gin.Context) {
+	cc.updateStatus(ctx, domain.CampaignPaused, "Campaign paused successfully")
+}
+
+// PostResumeCampaign godoc
+// @Summary           Admin API
+// @Description       Serve the ads of a paused campaign again
+// @Tags              campaign
+// @Produce           json
+// @Param             id path int true "Campaign id"
+// @Success           200 {object} domain.SuccessResponse
+// @Failure           400 {object} domain.ErrorResponse
+// @Failure           401 {object} domain.ErrorResponse
+// @Failure           404 {object} domain.ErrorResponse
+// @Failure           500 {object} domain.ErrorResponse
+// @Failure           504 {object} domain.ErrorResponse
+// @Security          ApiKeyAuth
+// @Router            /campaign/{id}/resume [post]
+func (cc *CampaignController) PostResumeCampaign(ctx *gin.Context) {
+	cc.updateStatus(ctx, domain.CampaignActive, "Campaign resumed successfully")
+}
+
+// DeleteCampaign godoc
+// @Summary       Admin API
+// @Description   Delete a campaign without ads
+// @Tags          campaign
+// @Produce       json
+// @Param         id path int true "Campaign id"
+// @Success       200 {object} domain.SuccessResponse
+// @Failure       400 {object} domain.ErrorResponse
+// @Failure       401 {object} domain.ErrorResponse
+// @Failure       404 {object} domain.ErrorResponse
+// @Failure       409 {object} domain.ErrorResponse
+// @Failure       500 {object} domain.ErrorResponse
+// @Failure       504 {object} domain.ErrorResponse
+// @Security      ApiKeyAuth
+// @Router        /campaign/{id} [delete]
+func (cc *CampaignController) DeleteCampaign(ctx *gin.Context) {
+	id, ok := parseID(ctx, "campaign")
+	if !ok {
+		return
+	}
+
+	if err := cc.CampaignUsecase.Delete(ctx.Request.Context(), id); err != nil {
+		ctx.JSON(getStatusCode(err), domain.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, domain.SuccessResponse{Message: "Campaign deleted successfully"})
+}
@@ -0,0 +1,140 @@
+package controller_test
+
+import (
+	"dcard-backend/controller"
+	"dcard-backend/domain"
+	"dcard-backend/domain/mocks"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestPostCampaign_Success_ShouldReturnCampaignWithID(t *testing.T) {
+	mockCampaignUsecase := mocks.NewCampaignUsecase(t)
+	mockCampaignUsecase.On("Create", mock.Anything, mock.MatchedBy(func(campaign *domain.Campaign) bool {
+		return campaign.AdvertiserID == 2 && campaign.Name == "Spring Sale"
+	})).Run(func(args mock.Arguments) {
+		args.Get(1).(*domain.Campaign).ID = 3
+	}).Return(nil).Once()
+
+	testCampaignController := controller.CampaignController{
+		CampaignUsecase: mockCampaignUsecase,
+	}
+
+	httpRecorder := httptest.NewRecorder()
+	httpRequest := httptest.NewRequest(http.MethodPost, "/api/v1/campaign", strings.NewReader(`{"advertiserId":2,"name":"Spring Sale"}`))
+	httpRequest.Header.Set("Content-Type", "application/json")
+
+	app := gin.Default()
+	app.POST("/api/v1/campaign", testCampaignController.PostCampaign)
+	app.ServeHTTP(httpRecorder, httpRequest)
+
+	var responseCampaign domain.Campaign
+	err := json.Unmarshal(httpRecorder.Body.Bytes(), &responseCampaign)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, httpRecorder.Code)
+	assert.Equal(t, int64(3), responseCampaign.ID)
+}
+
+func TestGetCampaign_NotFound_ShouldReturnNotFoundError(t *testing.T) {
+	mockCampaignUsecase := mocks.NewCampaignUsecase(t)
+	mockCampaignUsecase.On("GetByID", mock.Anything, int64(3)).Return(domain.Campaign{}, domain.ErrNotFound).Once()
+
+	testCampaignController := controller.CampaignController{
+		CampaignUsecase: mockCampaignUsecase,
+	}
+
+	httpRecorder := httptest.NewRecorder()
+	httpRequest := httptest.NewRequest(http.MethodGet, "/api/v1/campaign/3", nil)
+
+	app := gin.Default()
+	app.GET("/api/v1/campaign/:id", testCampaignController.GetCampaign)
+	app.ServeHTTP(httpRecorder, httpRequest)
+
+	assert.Equal(t, http.StatusNotFound, httpRecorder.Code)
+}
+
+func TestPostPauseCampaign_Success_ShouldPauseCampaign(t *testing.T) {
+	mockCampaignUsecase := mocks.NewCampaignUsecase(t)
+	mockCampaignUsecase.On("UpdateStatus", mock.Anything, int64(3), domain.CampaignPaused).Return(nil).Once()
+
+	testCampaignController := controller.CampaignController{
+		CampaignUsecase: mockCampaignUsecase,
+	}
+
+	httpRecorder := httptest.NewRecorder()
+	httpRequest := httptest.NewRequest(http.MethodPost, "/api/v1/campaign/3/pause", nil)
+
+	app := gin.Default()
+	app.POST("/api/v1/campaign/:id/pause", testCampaignController.PostPauseCampaign)
+	app.ServeHTTP(httpRecorder, httpRequest)
+
+	assert.Equal(t, http.StatusOK, httpRecorder.Code)
+}
+
+func TestPostResumeCampaign_InvalidID_ShouldReturnBadRequestError(t *testing.T) {
+	testCampaignController := controller.CampaignController{
+		CampaignUsecase: mocks.NewCampaignUsecase(t),
+	}
+
+	httpRecorder := httptest.NewRecorder()
+	httpRequest := httptest.NewRequest(http.MethodPost, "/api/v1/campaign/abc/resume", nil)
+
+	app := gin.Default()
+	app.POST("/api/v1/campaign/:id/resume", testCampaignController.PostResumeCampaign)
+	app.ServeHTTP(httpRecorder, httpRequest)
+
+	assert.Equal(t, http.StatusBadRequest, httpRecorder.Code)
+}
+
+func TestDeleteAdvertiser_HasCampaigns_ShouldReturnConflictError(t *testing.T) {
+	mockAdvertiserUsecase := mocks.NewAdvertiserUsecase(t)
+	mockAdvertiserUsecase.On("Delete", mock.Anything, int64(2)).
+		Return(fmt.Errorf("%w: advertiser has campaigns", domain.ErrConflict)).Once()
+
+	testAdvertiserController := controller.AdvertiserController{
+		AdvertiserUsecase: mockAdvertiserUsecase,
+	}
+
+	httpRecorder := httptest.NewRecorder()
+	httpRequest := httptest.NewRequest(http.MethodDelete, "/api/v1/advertiser/2", nil)
+
+	app := gin.Default()
+	app.DELETE("/api/v1/advertiser/:id", testAdvertiserController.DeleteAdvertiser)
+	app.ServeHTTP(httpRecorder, httpRequest)
+
+	assert.Equal(t, http.StatusConflict, httpRecorder.Code)
+}
+
+func TestGetAdvertiserCampaigns_Success_ShouldReturnCampaigns(t *testing.T) {
+	mockCampaigns := []domain.Campaign{{ID: 3, AdvertiserID: 2, Name: "Spring Sale", Status: domain.CampaignActive}}
+
+	mockCampaignUsecase := mocks.NewCampaignUsecase(t)
+	mockCampaignUsecase.On("FetchByAdvertiser", mock.Anything, int64(2)).Return(mockCampaigns, nil).Once()
+
+	testCampaignController := controller.CampaignController{
+		CampaignUsecase: mockCampaignUsecase,
+	}
+
+	httpRecorder := httptest.NewRecorder()
+	httpRequest := httptest.NewRequest(http.MethodGet, "/api/v1/advertiser/2/campaign", nil)
+
+	app := gin.Default()
+	app.GET("/api/v1/advertiser/:id/campaign", testCampaignController.GetAdvertiserCampaigns)
+	app.ServeHTTP(httpRecorder, httpRequest)
+
+	var responseCampaigns []domain.Campaign
+	err := json.Unmarshal(httpRecorder.Body.Bytes(), &responseCampaigns)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, httpRecorder.Code)
+	assert.Equal(t, mockCampaigns, responseCampaigns)
+}
@@ -0,0 +1,88 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"dcard-backend/domain"
+)
+
+type AdStreamController struct {
+	AdStreamUsecase domain.AdStreamUsecase
+	// HeartbeatInterval is how often a comment is sent while there is no event, so that proxies
+	// keep the connection open
+	HeartbeatInterval time.Duration
+}
+
+// GetAdStream  godoc
+// @Summary     Admin API
+// @Description Stream the changes of the ads of the tenant as Server-Sent Events. The event is the type of the change, the data is the change with the ad, and the id is sent back in the Last-Event-ID header to resume the stream. The stream ends when the client falls too far behind, and the client resumes it from the last event.
+// @Tags        ad
+// @Produce     text/event-stream
+// @Param       Last-Event-ID header int false "Id of the last event received, to replay the events after it"
+// @Success     200 {object} domain.AdStreamEvent
+// @Failure     400 {object} domain.ErrorResponse
+// @Failure     401 {object} domain.ErrorResponse
+// @Failure     500 {object} domain.ErrorResponse
+// @Security    ApiKeyAuth
+// @Router      /ads/stream [get]
+func (sc *AdStreamController) GetAdStream(ctx *gin.Context) {
+	lastEventID := int64(0)
+	if header := ctx.GetHeader("Last-Event-ID"); header != "" {
+		id, err := strconv.ParseInt(header, 10, 64)
+		if err != nil || id < 0 {
+			ctx.JSON(http.StatusBadRequest, domain.ErrorResponse{Message: "Last-Event-ID should be a non-negative integer"})
+			return
+		}
+		lastEventID = id
+	}
+
+	events, err := sc.AdStreamUsecase.Subscribe(ctx.Request.Context(), lastEventID)
+	if err != nil {
+		ctx.JSON(getStatusCode(err), domain.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	ctx.Header("Content-Type", "text/event-stream")
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("Connection", "keep-alive")
+	// Proxies such as nginx should send events as they are written
+	ctx.Header("X-Accel-Buffering", "no")
+	ctx.Status(http.StatusOK)
+	ctx.Writer.Flush()
+
+	heartbeatInterval := sc.HeartbeatInterval
+	if heartbeatInterval <= 0 {
+		heartbeatInterval = 15 * time.Second
+	}
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				return
+			}
+			if _, err := fmt.Fprintf(ctx.Writer, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(ctx.Writer, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		case <-ctx.Request.Context().Done():
+			return
+		}
+		ctx.Writer.Flush()
+	}
+}
@@ -0,0 +1,62 @@
+package controller_test
+
+import (
+	"dcard-backend/controller"
+	"dcard-backend/domain"
+	"dcard-backend/domain/mocks"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestGetAdStream_LastEventID_ShouldResumeAndWriteEvents(t *testing.T) {
+	events := make(chan domain.AdStreamEvent, 2)
+	events <- domain.AdStreamEvent{ID: 43, Type: domain.AdEventUpdated, AdID: 1, At: "2024-01-01T00:00:00Z", Ad: &domain.Ad{ID: 1, Title: "AD 1"}}
+	events <- domain.AdStreamEvent{ID: 44, Type: domain.AdEventDeleted, AdID: 1, At: "2024-01-01T00:00:01Z"}
+	close(events)
+	mockAdStreamUsecase := mocks.NewAdStreamUsecase(t)
+	mockAdStreamUsecase.On("Subscribe", mock.Anything, int64(42)).Return((<-chan domain.AdStreamEvent)(events), nil).Once()
+
+	testAdStreamController := controller.AdStreamController{
+		AdStreamUsecase:   mockAdStreamUsecase,
+		HeartbeatInterval: time.Hour,
+	}
+
+	httpRecorder := httptest.NewRecorder()
+	httpRequest := httptest.NewRequest(http.MethodGet, "/api/v1/ads/stream", nil)
+	httpRequest.Header.Set("Last-Event-ID", "42")
+
+	app := gin.Default()
+	app.GET("/api/v1/ads/stream", testAdStreamController.GetAdStream)
+	app.ServeHTTP(httpRecorder, httpRequest)
+
+	assert.Equal(t, http.StatusOK, httpRecorder.Code)
+	assert.Equal(t, "text/event-stream", httpRecorder.Header().Get("Content-Type"))
+	assert.Equal(t, "id: 43\nevent: updated\n"+
+		`data: {"id":43,"type":"updated","adId":1,"at":"2024-01-01T00:00:00Z","ad":{"id":1,"title":"AD 1","endAt":""}}`+"\n\n"+
+		"id: 44\nevent: deleted\n"+
+		`data: {"id":44,"type":"deleted","adId":1,"at":"2024-01-01T00:00:01Z"}`+"\n\n", httpRecorder.Body.String())
+}
+
+func TestGetAdStream_InvalidLastEventID_ShouldReturnBadRequest(t *testing.T) {
+	mockAdStreamUsecase := mocks.NewAdStreamUsecase(t)
+
+	testAdStreamController := controller.AdStreamController{
+		AdStreamUsecase: mockAdStreamUsecase,
+	}
+
+	httpRecorder := httptest.NewRecorder()
+	httpRequest := httptest.NewRequest(http.MethodGet, "/api/v1/ads/stream", nil)
+	httpRequest.Header.Set("Last-Event-ID", "latest")
+
+	app := gin.Default()
+	app.GET("/api/v1/ads/stream", testAdStreamController.GetAdStream)
+	app.ServeHTTP(httpRecorder, httpRequest)
+
+	assert.Equal(t, http.StatusBadRequest, httpRecorder.Code)
+}
@@ -0,0 +1,105 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"dcard-backend/domain"
+)
+
+type TrackingController struct {
+	TrackingUsecase domain.TrackingUsecase
+}
+
+// parseID returns the id path parameter of an item, or false after responding 400 if it is
+// not a positive integer
+func parseID(ctx *gin.Context, item string) (int64, bool) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		ctx.JSON(http.StatusBadRequest, domain.ErrorResponse{Message: item + " id should be a positive integer"})
+		return 0, false
+	}
+	return id, true
+}
+
+func parseAdID(ctx *gin.Context) (int64, bool) {
+	return parseID(ctx, "ad")
+}
+
+func (tc *TrackingController) record(ctx *gin.Context, eventType domain.EventType) {
+	adID, ok := parseAdID(ctx)
+	if !ok {
+		return
+	}
+
+	if err := tc.TrackingUsecase.Record(ctx.Request.Context(), adID, eventType); err != nil {
+		ctx.JSON(getStatusCode(err), domain.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, domain.SuccessResponse{Message: "Event recorded"})
+}
+
+// PostImpression godoc
+// @Summary       Public API
+// @Description   Record that an ad was shown
+// @Tags          tracking
+// @Produce       json
+// @Param         id path int true "Ad id"
+// @Param         X-Site-Key header string true "Site key of the tenant whose ad was shown"
+// @Success       202 {object} domain.SuccessResponse
+// @Failure       400 {object} domain.ErrorResponse
+// @Failure       401 {object} domain.ErrorResponse
+// @Failure       404 {object} domain.ErrorResponse
+// @Failure       504 {object} domain.ErrorResponse
+// @Router        /ad/{id}/impression [post]
+func (tc *TrackingController) PostImpression(ctx *gin.Context) {
+	tc.record(ctx, domain.EventImpression)
+}
+
+// PostClick   godoc
+// @Summary     Public API
+// @Description Record that an ad was clicked
+// @Tags        tracking
+// @Produce     json
+// @Param       id path int true "Ad id"
+// @Param       X-Site-Key header string true "Site key of the tenant whose ad was clicked"
+// @Success     202 {object} domain.SuccessResponse
+// @Failure     400 {object} domain.ErrorResponse
+// @Failure     401 {object} domain.ErrorResponse
+// @Failure     404 {object} domain.ErrorResponse
+// @Failure     504 {object} domain.ErrorResponse
+// @Router      /ad/{id}/click [post]
+func (tc *TrackingController) PostClick(ctx *gin.Context) {
+	tc.record(ctx, domain.EventClick)
+}
+
+// GetStats    godoc
+// @Summary     Admin API
+// @Description Get the total and hourly impressions and clicks of an ad
+// @Tags        tracking
+// @Produce     json
+// @Param       id path int true "Ad id"
+// @Success     200 {object} domain.AdStats
+// @Failure     400 {object} domain.ErrorResponse
+// @Failure     401 {object} domain.ErrorResponse
+// @Failure     500 {object} domain.ErrorResponse
+// @Failure     504 {object} domain.ErrorResponse
+// @Security    ApiKeyAuth
+// @Router      /ad/{id}/stats [get]
+func (tc *TrackingController) GetStats(ctx *gin.Context) {
+	adID, ok := parseAdID(ctx)
+	if !ok {
+		return
+	}
+
+	stats, err := tc.TrackingUsecase.GetStats(ctx.Request.Context(), adID)
+	if err != nil {
+		ctx.JSON(getStatusCode(err), domain.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, stats)
+}
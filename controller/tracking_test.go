@@ -0,0 +1,80 @@
+package controller_test
+
+import (
+	"dcard-backend/controller"
+	"dcard-backend/domain"
+	"dcard-backend/domain/mocks"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newTrackingApp(trackingUsecase domain.TrackingUsecase) *gin.Engine {
+	testTrackingController := controller.TrackingController{
+		TrackingUsecase: trackingUsecase,
+	}
+
+	app := gin.Default()
+	app.POST("/api/v1/ad/:id/impression", testTrackingController.PostImpression)
+	app.POST("/api/v1/ad/:id/click", testTrackingController.PostClick)
+	app.GET("/api/v1/ad/:id/stats", testTrackingController.GetStats)
+	return app
+}
+
+func TestPostClick_EventRecorded_ShouldReturnAccepted(t *testing.T) {
+	mockTrackingUsecase := mocks.NewTrackingUsecase(t)
+	mockTrackingUsecase.On("Record", mock.Anything, int64(3), domain.EventClick).Return(nil).Once()
+
+	httpRecorder := httptest.NewRecorder()
+	httpRequest := httptest.NewRequest(http.MethodPost, "/api/v1/ad/3/click", nil)
+	newTrackingApp(mockTrackingUsecase).ServeHTTP(httpRecorder, httpRequest)
+
+	assert.Equal(t, http.StatusAccepted, httpRecorder.Code)
+}
+
+func TestPostImpression_InvalidID_ShouldReturnBadRequest(t *testing.T) {
+	mockTrackingUsecase := mocks.NewTrackingUsecase(t)
+
+	httpRecorder := httptest.NewRecorder()
+	httpRequest := httptest.NewRequest(http.MethodPost, "/api/v1/ad/abc/impression", nil)
+	newTrackingApp(mockTrackingUsecase).ServeHTTP(httpRecorder, httpRequest)
+
+	assert.Equal(t, http.StatusBadRequest, httpRecorder.Code)
+}
+
+func TestPostImpression_AdNotOfTenant_ShouldReturnNotFound(t *testing.T) {
+	mockTrackingUsecase := mocks.NewTrackingUsecase(t)
+	mockTrackingUsecase.On("Record", mock.Anything, int64(3), domain.EventImpression).Return(domain.ErrNotFound).Once()
+
+	httpRecorder := httptest.NewRecorder()
+	httpRequest := httptest.NewRequest(http.MethodPost, "/api/v1/ad/3/impression", nil)
+	newTrackingApp(mockTrackingUsecase).ServeHTTP(httpRecorder, httpRequest)
+
+	assert.Equal(t, http.StatusNotFound, httpRecorder.Code)
+}
+
+func TestGetStats_Success_ShouldReturnStats(t *testing.T) {
+	mockStats := domain.AdStats{
+		AdID:        3,
+		Impressions: 4,
+		Clicks:      1,
+		Hourly:      []domain.HourlyStats{{Hour: "2024-03-01T08:00:00Z", Impressions: 4, Clicks: 1}},
+	}
+	mockTrackingUsecase := mocks.NewTrackingUsecase(t)
+	mockTrackingUsecase.On("GetStats", mock.Anything, int64(3)).Return(mockStats, nil).Once()
+
+	httpRecorder := httptest.NewRecorder()
+	httpRequest := httptest.NewRequest(http.MethodGet, "/api/v1/ad/3/stats", nil)
+	newTrackingApp(mockTrackingUsecase).ServeHTTP(httpRecorder, httpRequest)
+
+	var stats domain.AdStats
+	json.Unmarshal(httpRecorder.Body.Bytes(), &stats)
+
+	assert.Equal(t, http.StatusOK, httpRecorder.Code)
+	assert.Equal(t, mockStats, stats)
+}
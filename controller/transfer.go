@@ -0,0 +1,112 @@
+package controller
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"dcard-backend/domain"
+	"dcard-backend/usecase"
+)
+
+var formatToContentType = map[string]string{
+	domain.FormatCSV:    "text/csv",
+	domain.FormatNDJSON: "application/x-ndjson",
+}
+
+type AdTransferController struct {
+	AdTransferUsecase domain.AdTransferUsecase
+}
+
+func formatFromContentType(contentType string) string {
+	for format, formatContentType := range formatToContentType {
+		if strings.HasPrefix(contentType, formatContentType) {
+			return format
+		}
+	}
+	return ""
+}
+
+// PostImport   godoc
+// @Summary     Admin API
+// @Description Import ads from a CSV or NDJSON body, or from a multipart upload named file
+// @Tags        ad
+// @Accept      text/csv,application/x-ndjson,multipart/form-data
+// @Produce     json
+// @Param       format query    string false "Format of the file" Enums(csv, ndjson)
+// @Param       file   formData file   false "File to import"
+// @Success     200 {object} domain.ImportResult
+// @Failure     400 {object} domain.ErrorResponse
+// @Failure     401 {object} domain.ErrorResponse
+// @Security    ApiKeyAuth
+// @Router      /ad/import [post]
+func (atc *AdTransferController) PostImport(ctx *gin.Context) {
+	format := ctx.Query("format")
+	var reader io.Reader = ctx.Request.Body
+
+	if file, header, err := ctx.Request.FormFile("file"); err == nil {
+		defer file.Close()
+		reader = file
+		if format == "" {
+			format = usecase.FormatFromFilename(header.Filename)
+		}
+	}
+
+	if format == "" {
+		format = formatFromContentType(ctx.ContentType())
+	}
+
+	result, err := atc.AdTransferUsecase.Import(ctx.Request.Context(), reader, format)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, domain.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+// GetExport    godoc
+// @Summary     Admin API
+// @Description Export all ads, or the ads of an advertiser
+// @Tags        ad
+// @Produce     text/csv,application/x-ndjson
+// @Param       format query string false "Format of the file" Enums(csv, ndjson) default(csv)
+// @Param       advertiserId query int false "Only export ads of the advertiser"
+// @Success     200 {file} file
+// @Failure     400 {object} domain.ErrorResponse
+// @Failure     401 {object} domain.ErrorResponse
+// @Failure     500 {object} domain.ErrorResponse
+// @Failure     504 {object} domain.ErrorResponse
+// @Security    ApiKeyAuth
+// @Router      /ad/export [get]
+func (atc *AdTransferController) GetExport(ctx *gin.Context) {
+	format := ctx.DefaultQuery("format", domain.FormatCSV)
+	contentType, ok := formatToContentType[format]
+	if !ok {
+		ctx.JSON(http.StatusBadRequest, domain.ErrorResponse{Message: "unsupported format " + format})
+		return
+	}
+
+	var advertiserID int64
+	if value, ok := ctx.GetQuery("advertiserId"); ok {
+		id, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || id <= 0 {
+			ctx.JSON(http.StatusBadRequest, domain.ErrorResponse{Message: "advertiserId should be a positive integer"})
+			return
+		}
+		advertiserID = id
+	}
+
+	var buffer bytes.Buffer
+	if err := atc.AdTransferUsecase.Export(ctx.Request.Context(), &buffer, format, advertiserID); err != nil {
+		ctx.JSON(getStatusCode(err), domain.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	ctx.Header("Content-Disposition", "attachment; filename=ads."+format)
+	ctx.Data(http.StatusOK, contentType, buffer.Bytes())
+}
@@ -0,0 +1,154 @@
+package controller_test
+
+import (
+	"bytes"
+	"dcard-backend/controller"
+	"dcard-backend/domain"
+	"dcard-backend/domain/mocks"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestPostImport_CSVBodyProvided_ShouldReturnResult(t *testing.T) {
+	mockResult := domain.ImportResult{
+		Imported: 1,
+		Errors:   []domain.ImportError{{Line: 3, Message: "Fail"}},
+	}
+
+	mockAdTransferUsecase := mocks.NewAdTransferUsecase(t)
+	mockAdTransferUsecase.On("Import", mock.Anything, mock.Anything, domain.FormatCSV).Return(mockResult, nil).Once()
+
+	testAdTransferController := controller.AdTransferController{
+		AdTransferUsecase: mockAdTransferUsecase,
+	}
+
+	httpRecorder := httptest.NewRecorder()
+	httpRequest := httptest.NewRequest(http.MethodPost, "/api/v1/ad/import", strings.NewReader("title,startAt,endAt\n"))
+	httpRequest.Header.Set("Content-Type", "text/csv")
+
+	app := gin.Default()
+	app.POST("/api/v1/ad/import", testAdTransferController.PostImport)
+	app.ServeHTTP(httpRecorder, httpRequest)
+
+	var responseResult domain.ImportResult
+	err := json.Unmarshal(httpRecorder.Body.Bytes(), &responseResult)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, httpRecorder.Code)
+	assert.Equal(t, mockResult, responseResult)
+}
+
+func TestPostImport_MultipartFileProvided_ShouldUseFileExtension(t *testing.T) {
+	mockAdTransferUsecase := mocks.NewAdTransferUsecase(t)
+	mockAdTransferUsecase.On("Import", mock.Anything, mock.MatchedBy(func(r io.Reader) bool {
+		content, _ := io.ReadAll(r)
+		return string(content) == "{}\n"
+	}), domain.FormatNDJSON).Return(domain.ImportResult{}, nil).Once()
+
+	testAdTransferController := controller.AdTransferController{
+		AdTransferUsecase: mockAdTransferUsecase,
+	}
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, _ := writer.CreateFormFile("file", "ads.ndjson")
+	part.Write([]byte("{}\n"))
+	writer.Close()
+
+	httpRecorder := httptest.NewRecorder()
+	httpRequest := httptest.NewRequest(http.MethodPost, "/api/v1/ad/import", &body)
+	httpRequest.Header.Set("Content-Type", writer.FormDataContentType())
+
+	app := gin.Default()
+	app.POST("/api/v1/ad/import", testAdTransferController.PostImport)
+	app.ServeHTTP(httpRecorder, httpRequest)
+
+	assert.Equal(t, http.StatusOK, httpRecorder.Code)
+}
+
+func TestPostImport_ImportFail_ShouldReturnBadRequestError(t *testing.T) {
+	mockAdTransferUsecase := mocks.NewAdTransferUsecase(t)
+	mockAdTransferUsecase.On("Import", mock.Anything, mock.Anything, "").Return(domain.ImportResult{}, errors.New("Fail")).Once()
+
+	testAdTransferController := controller.AdTransferController{
+		AdTransferUsecase: mockAdTransferUsecase,
+	}
+
+	httpRecorder := httptest.NewRecorder()
+	httpRequest := httptest.NewRequest(http.MethodPost, "/api/v1/ad/import", strings.NewReader(""))
+
+	app := gin.Default()
+	app.POST("/api/v1/ad/import", testAdTransferController.PostImport)
+	app.ServeHTTP(httpRecorder, httpRequest)
+
+	assert.Equal(t, http.StatusBadRequest, httpRecorder.Code)
+}
+
+func TestGetExport_NDJSONRequested_ShouldReturnFile(t *testing.T) {
+	mockAdTransferUsecase := mocks.NewAdTransferUsecase(t)
+	mockAdTransferUsecase.On("Export", mock.Anything, mock.Anything, domain.FormatNDJSON, int64(0)).
+		Run(func(args mock.Arguments) {
+			args.Get(1).(io.Writer).Write([]byte("{}\n"))
+		}).
+		Return(nil).Once()
+
+	testAdTransferController := controller.AdTransferController{
+		AdTransferUsecase: mockAdTransferUsecase,
+	}
+
+	httpRecorder := httptest.NewRecorder()
+	httpRequest := httptest.NewRequest(http.MethodGet, "/api/v1/ad/export?format=ndjson", nil)
+
+	app := gin.Default()
+	app.GET("/api/v1/ad/export", testAdTransferController.GetExport)
+	app.ServeHTTP(httpRecorder, httpRequest)
+
+	assert.Equal(t, http.StatusOK, httpRecorder.Code)
+	assert.Equal(t, "application/x-ndjson", httpRecorder.Header().Get("Content-Type"))
+	assert.Equal(t, "{}\n", httpRecorder.Body.String())
+}
+
+func TestGetExport_UnsupportedFormat_ShouldReturnBadRequestError(t *testing.T) {
+	mockAdTransferUsecase := mocks.NewAdTransferUsecase(t)
+
+	testAdTransferController := controller.AdTransferController{
+		AdTransferUsecase: mockAdTransferUsecase,
+	}
+
+	httpRecorder := httptest.NewRecorder()
+	httpRequest := httptest.NewRequest(http.MethodGet, "/api/v1/ad/export?format=xml", nil)
+
+	app := gin.Default()
+	app.GET("/api/v1/ad/export", testAdTransferController.GetExport)
+	app.ServeHTTP(httpRecorder, httpRequest)
+
+	assert.Equal(t, http.StatusBadRequest, httpRecorder.Code)
+}
+
+func TestGetExport_ExportFail_ShouldReturnInternalServerError(t *testing.T) {
+	mockAdTransferUsecase := mocks.NewAdTransferUsecase(t)
+	mockAdTransferUsecase.On("Export", mock.Anything, mock.Anything, domain.FormatCSV, int64(0)).Return(errors.New("Fail")).Once()
+
+	testAdTransferController := controller.AdTransferController{
+		AdTransferUsecase: mockAdTransferUsecase,
+	}
+
+	httpRecorder := httptest.NewRecorder()
+	httpRequest := httptest.NewRequest(http.MethodGet, "/api/v1/ad/export", nil)
+
+	app := gin.Default()
+	app.GET("/api/v1/ad/export", testAdTransferController.GetExport)
+	app.ServeHTTP(httpRecorder, httpRequest)
+
+	assert.Equal(t, http.StatusInternalServerError, httpRecorder.Code)
+}
@@ -0,0 +1,152 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"dcard-backend/domain"
+)
+
+type WebhookController struct {
+	WebhookUsecase domain.WebhookUsecase
+}
+
+// PostWebhook  godoc
+// @Summary     Admin API
+// @Description Subscribe a URL to events of the ads of the tenant. Deliveries are signed with the secret in the X-Webhook-Signature header, as sha256= and the hex HMAC-SHA256 of the X-Webhook-Timestamp header, a dot and the body.
+// @Tags        webhook
+// @Accept      json
+// @Produce     json
+// @Param       webhook body domain.Webhook True "Add a webhook"
+// @Success     200 {object} domain.Webhook
+// @Failure     400 {object} domain.ErrorResponse
+// @Failure     401 {object} domain.ErrorResponse
+// @Failure     500 {object} domain.ErrorResponse
+// @Failure     504 {object} domain.ErrorResponse
+// @Security    ApiKeyAuth
+// @Router      /webhook [post]
+func (wc *WebhookController) PostWebhook(ctx *gin.Context) {
+	var webhook domain.Webhook
+	if err := ctx.Bind(&webhook); err != nil {
+		ctx.JSON(http.StatusBadRequest, domain.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	if err := wc.WebhookUsecase.Create(ctx.Request.Context(), &webhook); err != nil {
+		ctx.JSON(getStatusCode(err), domain.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, webhook)
+}
+
+// GetWebhooks  godoc
+// @Summary     Admin API
+// @Description Get every webhook of the tenant, without their secrets
+// @Tags        webhook
+// @Produce     json
+// @Success     200 {array}  domain.Webhook
+// @Failure     401 {object} domain.ErrorResponse
+// @Failure     500 {object} domain.ErrorResponse
+// @Failure     504 {object} domain.ErrorResponse
+// @Security    ApiKeyAuth
+// @Router      /webhook [get]
+func (wc *WebhookController) GetWebhooks(ctx *gin.Context) {
+	webhooks, err := wc.WebhookUsecase.Fetch(ctx.Request.Context())
+	if err != nil {
+		ctx.JSON(getStatusCode(err), domain.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, webhooks)
+}
+
+// DeleteWebhook godoc
+// @Summary      Admin API
+// @Description  Delete a webhook with its deliveries
+// @Tags         webhook
+// @Produce      json
+// @Param        id path int true "Webhook id"
+// @Success      200 {object} domain.SuccessResponse
+// @Failure      400 {object} domain.ErrorResponse
+// @Failure      401 {object} domain.ErrorResponse
+// @Failure      404 {object} domain.ErrorResponse
+// @Failure      500 {object} domain.ErrorResponse
+// @Failure      504 {object} domain.ErrorResponse
+// @Security     ApiKeyAuth
+// @Router       /webhook/{id} [delete]
+func (wc *WebhookController) DeleteWebhook(ctx *gin.Context) {
+	id, ok := parseID(ctx, "webhook")
+	if !ok {
+		return
+	}
+
+	if err := wc.WebhookUsecase.Delete(ctx.Request.Context(), id); err != nil {
+		ctx.JSON(getStatusCode(err), domain.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, domain.SuccessResponse{Message: "Webhook deleted successfully"})
+}
+
+// GetDeadLetters godoc
+// @Summary       Admin API
+// @Description   Get the deliveries of the tenant which ran out of attempts, from the newest
+// @Tags          webhook
+// @Produce       json
+// @Param         limit  query int false "Number of deliveries, at most 500" default(50)
+// @Param         offset query int false "Number of deliveries to skip" default(0)
+// @Success       200 {array}  domain.WebhookDelivery
+// @Failure       400 {object} domain.ErrorResponse
+// @Failure       401 {object} domain.ErrorResponse
+// @Failure       500 {object} domain.ErrorResponse
+// @Failure       504 {object} domain.ErrorResponse
+// @Security      ApiKeyAuth
+// @Router        /webhook/dead-letter [get]
+func (wc *WebhookController) GetDeadLetters(ctx *gin.Context) {
+	limit, ok := parseIntQuery(ctx, "limit")
+	if !ok {
+		return
+	}
+	offset, ok := parseIntQuery(ctx, "offset")
+	if !ok {
+		return
+	}
+
+	deliveries, err := wc.WebhookUsecase.FetchDeadLetters(ctx.Request.Context(), int(limit), int(offset))
+	if err != nil {
+		ctx.JSON(getStatusCode(err), domain.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, deliveries)
+}
+
+// PostRedeliver godoc
+// @Summary      Admin API
+// @Description  Send a dead delivery again, with as many attempts as a new delivery
+// @Tags         webhook
+// @Produce      json
+// @Param        id path int true "Delivery id"
+// @Success      200 {object} domain.SuccessResponse
+// @Failure      400 {object} domain.ErrorResponse
+// @Failure      401 {object} domain.ErrorResponse
+// @Failure      404 {object} domain.ErrorResponse
+// @Failure      500 {object} domain.ErrorResponse
+// @Failure      504 {object} domain.ErrorResponse
+// @Security     ApiKeyAuth
+// @Router       /webhook/dead-letter/{id}/redeliver [post]
+func (wc *WebhookController) PostRedeliver(ctx *gin.Context) {
+	id, ok := parseID(ctx, "delivery")
+	if !ok {
+		return
+	}
+
+	if err := wc.WebhookUsecase.Redeliver(ctx.Request.Context(), id); err != nil {
+		ctx.JSON(getStatusCode(err), domain.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, domain.SuccessResponse{Message: "Delivery redelivered successfully"})
+}
@@ -0,0 +1,96 @@
+package controller_test
+
+import (
+	"bytes"
+	"dcard-backend/controller"
+	"dcard-backend/domain"
+	"dcard-backend/domain/mocks"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestPostWebhook_Success_ShouldReturnWebhookWithoutSecret(t *testing.T) {
+	mockWebhookUsecase := mocks.NewWebhookUsecase(t)
+	mockWebhookUsecase.On("Create", mock.Anything, mock.AnythingOfType("*domain.Webhook")).Return(nil).Once().
+		Run(func(args mock.Arguments) {
+			webhook := args.Get(1).(*domain.Webhook)
+			webhook.ID, webhook.Secret = 7, ""
+		})
+
+	testWebhookController := controller.WebhookController{
+		WebhookUsecase: mockWebhookUsecase,
+	}
+
+	httpRecorder := httptest.NewRecorder()
+	httpRequest := httptest.NewRequest(http.MethodPost, "/api/v1/webhook",
+		bytes.NewBufferString(`{"url":"https://example.com/webhook","eventTypes":["started"],"secret":"s3cret"}`))
+	httpRequest.Header.Set("Content-Type", "application/json")
+
+	app := gin.Default()
+	app.POST("/api/v1/webhook", testWebhookController.PostWebhook)
+	app.ServeHTTP(httpRecorder, httpRequest)
+
+	assert.Equal(t, http.StatusOK, httpRecorder.Code)
+	assert.JSONEq(t, `{"id":7,"url":"https://example.com/webhook","eventTypes":["started"]}`, httpRecorder.Body.String())
+}
+
+func TestPostWebhook_MissingSecret_ShouldReturnBadRequest(t *testing.T) {
+	mockWebhookUsecase := mocks.NewWebhookUsecase(t)
+
+	testWebhookController := controller.WebhookController{
+		WebhookUsecase: mockWebhookUsecase,
+	}
+
+	httpRecorder := httptest.NewRecorder()
+	httpRequest := httptest.NewRequest(http.MethodPost, "/api/v1/webhook",
+		bytes.NewBufferString(`{"url":"https://example.com/webhook","eventTypes":["started"]}`))
+	httpRequest.Header.Set("Content-Type", "application/json")
+
+	app := gin.Default()
+	app.POST("/api/v1/webhook", testWebhookController.PostWebhook)
+	app.ServeHTTP(httpRecorder, httpRequest)
+
+	assert.Equal(t, http.StatusBadRequest, httpRecorder.Code)
+}
+
+func TestGetDeadLetters_QueryProvided_ShouldFetchPage(t *testing.T) {
+	mockWebhookUsecase := mocks.NewWebhookUsecase(t)
+	mockWebhookUsecase.On("FetchDeadLetters", mock.Anything, 10, 20).Return([]domain.WebhookDelivery{}, nil).Once()
+
+	testWebhookController := controller.WebhookController{
+		WebhookUsecase: mockWebhookUsecase,
+	}
+
+	httpRecorder := httptest.NewRecorder()
+	httpRequest := httptest.NewRequest(http.MethodGet, "/api/v1/webhook/dead-letter?limit=10&offset=20", nil)
+
+	app := gin.Default()
+	app.GET("/api/v1/webhook/dead-letter", testWebhookController.GetDeadLetters)
+	app.ServeHTTP(httpRecorder, httpRequest)
+
+	assert.Equal(t, http.StatusOK, httpRecorder.Code)
+	assert.Equal(t, "[]", httpRecorder.Body.String())
+}
+
+func TestPostRedeliver_NotDead_ShouldReturnNotFound(t *testing.T) {
+	mockWebhookUsecase := mocks.NewWebhookUsecase(t)
+	mockWebhookUsecase.On("Redeliver", mock.Anything, int64(3)).Return(domain.ErrNotFound).Once()
+
+	testWebhookController := controller.WebhookController{
+		WebhookUsecase: mockWebhookUsecase,
+	}
+
+	httpRecorder := httptest.NewRecorder()
+	httpRequest := httptest.NewRequest(http.MethodPost, "/api/v1/webhook/dead-letter/3/redeliver", nil)
+
+	app := gin.Default()
+	app.POST("/api/v1/webhook/dead-letter/:id/redeliver", testWebhookController.PostRedeliver)
+	app.ServeHTTP(httpRecorder, httpRequest)
+
+	assert.Equal(t, http.StatusNotFound, httpRecorder.Code)
+}
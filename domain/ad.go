@@ -1,12 +1,83 @@
 package domain
 
-import "context"
+import (
+	"context"
+	"io"
+	"strings"
+	"time"
+)
 
 type Ad struct {
-	Title     string     `json:"title" binding:"required"`
-	StartAt   string     `json:"startAt,omitempty" binding:"required"`
-	EndAt     string     `json:"endAt" binding:"required"`
-	Condition *Condition `json:"condition,omitempty"`
+	ID    int64  `json:"id,omitempty"`
+	Title string `json:"title" binding:"required"`
+	// The flight dates and the condition are inherited from the campaign when they are empty
+	CampaignID int64      `json:"campaignId,omitempty"`
+	StartAt    string     `json:"startAt,omitempty"`
+	EndAt      string     `json:"endAt"`
+	Condition  *Condition `json:"condition,omitempty"`
+	// The default creative of the ad
+	Description  string `json:"description,omitempty"`
+	ImageURL     string `json:"imageUrl,omitempty" example:"https://example.com/ad.png"`
+	ClickURL     string `json:"clickUrl,omitempty" example:"https://example.com"`
+	CallToAction string `json:"callToAction,omitempty" example:"Learn more"`
+	// Creatives are variants of the default creative for platforms
+	Creatives    []Creative    `json:"creatives,omitempty"`
+	FrequencyCap *FrequencyCap `json:"frequencyCap,omitempty"`
+	Budget       *Budget       `json:"budget,omitempty"`
+	// Ads of a higher priority are served first, and ads of the same priority are shuffled in
+	// proportion to their weight, which is 1 when it is 0
+	Priority int `json:"priority,omitempty" example:"1"`
+	Weight   int `json:"weight,omitempty" example:"3"`
+	// Status is changed by pausing and resuming the ad, and is ignored when it is created or updated
+	Status AdStatus `json:"status,omitempty" enums:"active,paused"`
+	// Version starts at 1 and is increased by every update and rollback of the ad. It is the
+	// ETag of the ad, and updates take the versions they are based on from If-Match instead.
+	Version int64 `json:"version,omitempty" example:"1"`
+	// IfMatch lists the versions an update or rollback may replace, and any version is replaced
+	// when it is empty
+	IfMatch []int64 `json:"-"`
+	// Lifecycle is changed by the server when the ad starts and ends, and is ignored when it is
+	// created or updated
+	Lifecycle AdLifecycle `json:"lifecycle,omitempty" enums:"scheduled,live,expired"`
+	// Inherited lists the fields the ad takes from its campaign, which follow later changes of
+	// the campaign. Fields left empty are inherited, and the fields listed are inherited even if
+	// they are set, so that an ad read and written back keeps following its campaign.
+	Inherited []string `json:"inherited,omitempty" example:"startAt,endAt,gender"`
+}
+
+type AdStatus string
+
+const (
+	AdActive AdStatus = "active"
+	// Paused ads are not served
+	AdPaused AdStatus = "paused"
+)
+
+type AdLifecycle string
+
+const (
+	AdScheduled AdLifecycle = "scheduled"
+	AdLive      AdLifecycle = "live"
+	AdExpired   AdLifecycle = "expired"
+)
+
+// AdLifecycleState is the lifecycle of an ad of a tenant along with the times it changes
+type AdLifecycleState struct {
+	TenantID  string
+	ID        int64
+	StartAt   string
+	EndAt     string
+	Lifecycle AdLifecycle
+}
+
+// Creative is the variant of an ad shown on a platform. Its empty fields fall back to the
+// default creative of the ad.
+type Creative struct {
+	Platform     string `json:"platform" example:"ios"`
+	Description  string `json:"description,omitempty"`
+	ImageURL     string `json:"imageUrl,omitempty"`
+	ClickURL     string `json:"clickUrl,omitempty" example:"https://apps.apple.com/app/id1"`
+	CallToAction string `json:"callToAction,omitempty" example:"Install"`
 }
 
 type Condition struct {
@@ -15,14 +86,157 @@ type Condition struct {
 	Gender   []string `json:"gender"`
 	Country  []string `json:"country"`
 	Platform []string `json:"platform"`
+	Language []string `json:"language,omitempty"`
+	// Excluded values are never targeted, even if the ad targets any value
+	ExcludeGender   []string `json:"excludeGender,omitempty"`
+	ExcludeCountry  []string `json:"excludeCountry,omitempty"`
+	ExcludePlatform []string `json:"excludePlatform,omitempty"`
+	ExcludeLanguage []string `json:"excludeLanguage,omitempty"`
+	// Schedule restricts the hours in which the ad is served, which is unrestricted without it
+	Schedule *Schedule `json:"schedule,omitempty"`
+	// Targeting keeps the values of the dimensions registered without a field of their own,
+	// keyed by the name of the dimension
+	Targeting map[string]*Targeting `json:"targeting,omitempty"`
 }
 
+// Targeting has the included and excluded values of a registered dimension
+type Targeting struct {
+	Values  []string `json:"values"`
+	Exclude []string `json:"exclude,omitempty"`
+}
+
+// Schedule lists the windows in which an ad is served. Windows are in Timezone, an IANA
+// name such as Asia/Taipei, or in the timezone of the viewer when Timezone is empty.
+type Schedule struct {
+	Timezone string           `json:"timezone,omitempty" example:"Asia/Taipei"`
+	Windows  []ScheduleWindow `json:"windows"`
+}
+
+// ScheduleWindow covers the hours from StartHour to EndHour, exclusive, of a weekday. A window
+// whose EndHour is not after StartHour crosses midnight and ends on the next day.
+type ScheduleWindow struct {
+	Weekday   string `json:"weekday" example:"mon"`
+	StartHour int    `json:"startHour" example:"18"`
+	EndHour   int    `json:"endHour" example:"22"`
+}
+
+// AdRepository stores ads, where every change of an ad is recorded in the audit log in the
+// transaction of the change. Every configuration of an ad is also kept as a version. Update
+// and Rollback only replace an ad at one of the versions in ad.IfMatch, or at any version when
+// it is empty, and otherwise return ErrPreconditionFailed. They then set ad.Version to the new
+// version.
 type AdRepository interface {
-	Create(c context.Context, ad *Ad) error
+	// Create, Update and Rollback run the conflict check in the transaction writing the ad.
+	// Create sets the id, status, version and lifecycle of the ad it inserted.
+	Create(c context.Context, ad *Ad, check ConflictCheck) error
+	GetByID(c context.Context, id int64) (Ad, error)
+	GetVersion(c context.Context, id int64, version int64) (Ad, error)
+	Update(c context.Context, ad *Ad, check ConflictCheck) error
+	Rollback(c context.Context, ad *Ad, version int64, check ConflictCheck) error
+	UpdateStatus(c context.Context, id int64, status AdStatus) error
+	// Delete soft deletes an ad, which is left out of every other method until it is restored
+	Delete(c context.Context, id int64) error
+	Restore(c context.Context, id int64) error
+	// Purge hard deletes at most batchSize ads of every tenant soft deleted before
+	// deletedBefore, and returns how many it deleted
+	Purge(c context.Context, deletedBefore string, batchSize int) (int, error)
 	GetByCondition(c context.Context, condition map[string][]string) ([]Ad, error)
+	// Fetch and GetByWindow only return ads of the advertiser, or every ad when it is 0
+	Fetch(c context.Context, advertiserID int64) ([]Ad, error)
+	FetchByCampaign(c context.Context, campaignID int64) ([]Ad, error)
+	GetByWindow(c context.Context, startAt string, endAt string, advertiserID int64) ([]Ad, error)
+	GetCreativesByPlatform(c context.Context, adIDs []int64, platform string) (map[int64]Creative, error)
+	// FetchLifecycles returns a page of the ads of every tenant after afterID which are not
+	// deleted, leaving out the expired ads which are not extended
+	FetchLifecycles(c context.Context, afterID int64, limit int) ([]AdLifecycleState, error)
+	GetLifecycle(c context.Context, id int64) (AdLifecycleState, error)
+	// UpdateLifecycle changes the lifecycle of an ad from the given one and records the start
+	// or the end of the ad in the outbox, and returns false when the ad is not in it anymore
+	UpdateLifecycle(c context.Context, id int64, from AdLifecycle, to AdLifecycle) (bool, error)
 }
 
 type AdUsecase interface {
 	Create(c context.Context, ad *Ad) error
+	GetByID(c context.Context, id int64) (Ad, error)
+	GetVersion(c context.Context, id int64, version int64) (Ad, error)
+	Update(c context.Context, ad *Ad) error
+	// Rollback replaces an ad at one of expectedVersions, or at any version when it is empty,
+	// with a previous version, and returns the ad at its new version
+	Rollback(c context.Context, id int64, version int64, expectedVersions []int64) (Ad, error)
+	UpdateStatus(c context.Context, id int64, status AdStatus) error
+	Delete(c context.Context, id int64) error
+	Restore(c context.Context, id int64) error
 	GetByCondition(c context.Context, condition map[string][]string) ([]Ad, error)
+	Fetch(c context.Context, advertiserID int64) ([]Ad, error)
+	GetOverlapping(c context.Context, ad *Ad) ([]Ad, error)
+	// ApplyCampaign replaces the ads of a campaign whose inherited fields changed with the
+	// campaign
+	ApplyCampaign(c context.Context, campaignID int64) error
+}
+
+// AdPurgeUsecase hard deletes the ads that have been soft deleted for longer than the retention
+type AdPurgeUsecase interface {
+	Purge(c context.Context) (int, error)
+	Run(c context.Context, interval time.Duration)
+}
+
+// AdCacheInvalidator drops what a server keeps of an ad, so that the ad is read again the next
+// time it is needed
+type AdCacheInvalidator interface {
+	Invalidate(tenantID string, adID int64)
+}
+
+// AdLifecycleUsecase keeps the lifecycle of ads in step with their start and end, which
+// records AdEventStarted and AdEventExpired at the moment it changes. Every server invalidates
+// its caches of an ad at its start and end, whichever server records the change.
+type AdLifecycleUsecase interface {
+	// Run follows the changes of ads until c is done, and reloads every ad each resyncInterval
+	// to pick up ads changed outside the server
+	Run(c context.Context, resyncInterval time.Duration)
+}
+
+// ConflictPolicy decides what happens when a new ad has the same title as an existing ad
+// whose time window overlaps
+type ConflictPolicy string
+
+const (
+	ConflictPolicyReject ConflictPolicy = "reject"
+	ConflictPolicyWarn   ConflictPolicy = "warn"
+	ConflictPolicyAllow  ConflictPolicy = "allow"
+)
+
+// ConflictCheck checks an ad against the other ads with the same title whose time window
+// overlaps. The repository finds them in the transaction writing the ad while holding a lock
+// of the title, so that concurrent writes of the same title are checked one after another.
+type ConflictCheck struct {
+	// AdvertiserID limits the ads to those of the advertiser, or to every ad when it is 0
+	AdvertiserID int64
+	// Resolve returns an error to leave the ad unwritten, and skips the check when it is nil
+	Resolve func(conflicting []Ad) error
+}
+
+// NormalizeTitle makes titles comparable regardless of case and whitespace
+func NormalizeTitle(title string) string {
+	return strings.ToLower(strings.Join(strings.Fields(title), " "))
+}
+
+// Supported formats of ad import and export
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+)
+
+type ImportError struct {
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+type ImportResult struct {
+	Imported int           `json:"imported"`
+	Errors   []ImportError `json:"errors"`
+}
+
+type AdTransferUsecase interface {
+	Import(c context.Context, r io.Reader, format string) (ImportResult, error)
+	Export(c context.Context, w io.Writer, format string, advertiserID int64) error
 }
@@ -0,0 +1,25 @@
+package domain
+
+import "context"
+
+// Advertiser owns campaigns, which group its ads
+type Advertiser struct {
+	ID   int64  `json:"id,omitempty"`
+	Name string `json:"name" binding:"required" example:"Dcard"`
+}
+
+type AdvertiserRepository interface {
+	Create(c context.Context, advertiser *Advertiser) error
+	GetByID(c context.Context, id int64) (Advertiser, error)
+	Fetch(c context.Context) ([]Advertiser, error)
+	Update(c context.Context, advertiser *Advertiser) error
+	Delete(c context.Context, id int64) error
+}
+
+type AdvertiserUsecase interface {
+	Create(c context.Context, advertiser *Advertiser) error
+	GetByID(c context.Context, id int64) (Advertiser, error)
+	Fetch(c context.Context) ([]Advertiser, error)
+	Update(c context.Context, advertiser *Advertiser) error
+	Delete(c context.Context, id int64) error
+}
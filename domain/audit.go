@@ -0,0 +1,68 @@
+package domain
+
+import "context"
+
+// AuditAction is the change of an ad recorded in the audit log
+type AuditAction string
+
+const (
+	AuditCreate AuditAction = "create"
+	AuditUpdate AuditAction = "update"
+	AuditDelete AuditAction = "delete"
+	AuditPause  AuditAction = "pause"
+	AuditResume AuditAction = "resume"
+	// AuditRollback replaces an ad with one of its versions
+	AuditRollback AuditAction = "rollback"
+	// AuditRestore undoes the deletion of an ad
+	AuditRestore AuditAction = "restore"
+)
+
+// AuditEntry records a change of an ad along with the ad before and after the change, where
+// Before is empty for created ads and After is empty for deleted ads
+type AuditEntry struct {
+	ID        int64       `json:"id"`
+	AdID      int64       `json:"adId"`
+	Action    AuditAction `json:"action" enums:"create,update,delete,pause,resume,rollback,restore"`
+	Actor     string      `json:"actor" example:"api-key:3f2a9c1b04de"`
+	RequestID string      `json:"requestId,omitempty"`
+	CreatedAt string      `json:"createdAt"`
+	Before    *Ad         `json:"before,omitempty"`
+	After     *Ad         `json:"after,omitempty"`
+}
+
+// AuditFilter selects a page of entries of the audit log, where empty fields match every entry
+// and Limit is the size of the page
+type AuditFilter struct {
+	AdID   int64
+	Actor  string
+	Action AuditAction
+	Since  string
+	Until  string
+	Limit  int
+	Offset int
+}
+
+// requestIDKey is the context key of the id of a request, which links audit entries to logs
+type requestIDKey struct{}
+
+func WithRequestID(c context.Context, requestID string) context.Context {
+	return context.WithValue(c, requestIDKey{}, requestID)
+}
+
+// RequestIDFromContext returns the id of the request, which is empty outside of requests
+func RequestIDFromContext(c context.Context) string {
+	requestID, _ := c.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+// AuditRepository reads the audit log, whose entries are written by AdRepository in the
+// transaction of each change
+type AuditRepository interface {
+	// Fetch returns the matching entries of the tenant from the newest
+	Fetch(c context.Context, filter AuditFilter) ([]AuditEntry, error)
+}
+
+type AuditUsecase interface {
+	GetHistory(c context.Context, adID int64, limit int, offset int) ([]AuditEntry, error)
+	Fetch(c context.Context, filter AuditFilter) ([]AuditEntry, error)
+}
@@ -0,0 +1,8 @@
+package domain
+
+// Budget limits the impressions of an ad in total and per day, where 0 means unlimited.
+// Delivery is paced evenly across the flight of the ad and across each day.
+type Budget struct {
+	TotalImpressions int64 `json:"totalImpressions,omitempty" example:"100000"`
+	DailyImpressions int64 `json:"dailyImpressions,omitempty" example:"5000"`
+}
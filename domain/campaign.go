@@ -0,0 +1,41 @@
+package domain
+
+import "context"
+
+type CampaignStatus string
+
+const (
+	CampaignActive CampaignStatus = "active"
+	// Ads of a paused campaign are not served
+	CampaignPaused CampaignStatus = "paused"
+)
+
+// Campaign groups ads of an advertiser. Its flight dates and targeting are the defaults of
+// the ads created in it, which inherit every field they leave empty.
+type Campaign struct {
+	ID           int64          `json:"id,omitempty"`
+	AdvertiserID int64          `json:"advertiserId"`
+	Name         string         `json:"name" binding:"required" example:"Spring sale"`
+	Status       CampaignStatus `json:"status,omitempty" enums:"active,paused"`
+	StartAt      string         `json:"startAt,omitempty"`
+	EndAt        string         `json:"endAt,omitempty"`
+	Condition    *Condition     `json:"condition,omitempty"`
+}
+
+type CampaignRepository interface {
+	Create(c context.Context, campaign *Campaign) error
+	GetByID(c context.Context, id int64) (Campaign, error)
+	FetchByAdvertiser(c context.Context, advertiserID int64) ([]Campaign, error)
+	Update(c context.Context, campaign *Campaign) error
+	UpdateStatus(c context.Context, id int64, status CampaignStatus) error
+	Delete(c context.Context, id int64) error
+}
+
+type CampaignUsecase interface {
+	Create(c context.Context, campaign *Campaign) error
+	GetByID(c context.Context, id int64) (Campaign, error)
+	FetchByAdvertiser(c context.Context, advertiserID int64) ([]Campaign, error)
+	Update(c context.Context, campaign *Campaign) error
+	UpdateStatus(c context.Context, id int64, status CampaignStatus) error
+	Delete(c context.Context, id int64) error
+}
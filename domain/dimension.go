@@ -0,0 +1,144 @@
+package domain
+
+import "strings"
+
+// Dimension is a targeting dimension of ads, such as gender or country. The values of a
+// dimension are kept in a reference table, and ads are linked to the values they include
+// or exclude through a link table.
+type Dimension struct {
+	// Name is the key of the dimension in the query of the public API and in the CSV header
+	Name string
+	// AnyValue targets every value of the dimension
+	AnyValue string
+	// Valid reports whether a value can be used for the dimension
+	Valid func(value string) bool
+
+	// ValueTable and ValueColumn are the reference table and its column holding the values
+	ValueTable  string
+	ValueColumn string
+	// LinkTable links ads to the values, and LinkColumn references the id of ValueTable
+	LinkTable  string
+	LinkColumn string
+
+	// Values and ExcludedValues return the included and excluded values of a condition. They
+	// may be left out, and the values are then kept in Condition.Targeting.
+	Values         func(condition *Condition) *[]string
+	ExcludedValues func(condition *Condition) *[]string
+}
+
+// ExcludeName is the key of the excluded values of the dimension, such as excludeGender
+func (d Dimension) ExcludeName() string {
+	return "exclude" + strings.ToUpper(d.Name[:1]) + d.Name[1:]
+}
+
+// OneOf returns a validation accepting only the given values
+func OneOf(values ...string) func(string) bool {
+	return func(value string) bool {
+		for _, v := range values {
+			if v == value {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// IsCountryCode reports whether the value is an ISO 3166-1 alpha-2 code in upper case, such as TW
+func IsCountryCode(value string) bool {
+	if len(value) != 2 {
+		return false
+	}
+	for _, r := range value {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+var dimensions = []Dimension{
+	{
+		Name:           "gender",
+		AnyValue:       "A",
+		Valid:          OneOf("M", "F", "A"),
+		ValueTable:     "genders",
+		ValueColumn:    "gender",
+		LinkTable:      "ad_gender",
+		LinkColumn:     "gender_id",
+		Values:         func(condition *Condition) *[]string { return &condition.Gender },
+		ExcludedValues: func(condition *Condition) *[]string { return &condition.ExcludeGender },
+	},
+	{
+		Name:           "country",
+		AnyValue:       "AY",
+		Valid:          IsCountryCode,
+		ValueTable:     "countries",
+		ValueColumn:    "country",
+		LinkTable:      "ad_country",
+		LinkColumn:     "country_id",
+		Values:         func(condition *Condition) *[]string { return &condition.Country },
+		ExcludedValues: func(condition *Condition) *[]string { return &condition.ExcludeCountry },
+	},
+	{
+		Name:           "platform",
+		AnyValue:       "any",
+		Valid:          OneOf("android", "ios", "web", "any"),
+		ValueTable:     "platforms",
+		ValueColumn:    "platform",
+		LinkTable:      "ad_platform",
+		LinkColumn:     "platform_id",
+		Values:         func(condition *Condition) *[]string { return &condition.Platform },
+		ExcludedValues: func(condition *Condition) *[]string { return &condition.ExcludePlatform },
+	},
+	{
+		// ISO 639-1 codes of the languages in which the countries table has localized names
+		Name:           "language",
+		AnyValue:       "any",
+		Valid:          OneOf("cs", "de", "en", "es", "fr", "it", "nl", "any"),
+		ValueTable:     "languages",
+		ValueColumn:    "language",
+		LinkTable:      "ad_language",
+		LinkColumn:     "language_id",
+		Values:         func(condition *Condition) *[]string { return &condition.Language },
+		ExcludedValues: func(condition *Condition) *[]string { return &condition.ExcludeLanguage },
+	},
+}
+
+// targetingOf returns the values of a dimension kept in condition.Targeting, adding them when
+// they are absent
+func targetingOf(condition *Condition, name string) *Targeting {
+	if condition.Targeting == nil {
+		condition.Targeting = map[string]*Targeting{}
+	}
+	if condition.Targeting[name] == nil {
+		condition.Targeting[name] = &Targeting{}
+	}
+	return condition.Targeting[name]
+}
+
+// RegisterDimension adds a targeting dimension, which is then stored, queried and validated
+// like the built-in ones. It must be called before the server starts handling requests.
+func RegisterDimension(dimension Dimension) {
+	if dimension.Values == nil {
+		dimension.Values = func(condition *Condition) *[]string { return &targetingOf(condition, dimension.Name).Values }
+	}
+	if dimension.ExcludedValues == nil {
+		dimension.ExcludedValues = func(condition *Condition) *[]string { return &targetingOf(condition, dimension.Name).Exclude }
+	}
+	dimensions = append(dimensions, dimension)
+}
+
+// Dimensions returns the registered targeting dimensions in registration order
+func Dimensions() []Dimension {
+	return dimensions
+}
+
+// DimensionByName returns the registered targeting dimension with the name
+func DimensionByName(name string) (Dimension, bool) {
+	for _, dimension := range dimensions {
+		if dimension.Name == name {
+			return dimension, true
+		}
+	}
+	return Dimension{}, false
+}
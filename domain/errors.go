@@ -0,0 +1,18 @@
+package domain
+
+import "errors"
+
+var (
+	// ErrTimeout is returned when a request does not finish within the context timeout
+	ErrTimeout = errors.New("request timed out")
+	// ErrConflict is returned when the request conflicts with existing data
+	ErrConflict = errors.New("conflict with existing data")
+	// ErrBadParamInput is returned when the input of the request is invalid
+	ErrBadParamInput = errors.New("given param is not valid")
+	// ErrNotFound is returned when the requested item does not exist
+	ErrNotFound = errors.New("requested item is not found")
+	// ErrUnauthorized is returned when the request is not made on behalf of a tenant
+	ErrUnauthorized = errors.New("request is not authorized")
+	// ErrPreconditionFailed is returned when the item was changed since the version the request is based on
+	ErrPreconditionFailed = errors.New("item has been changed since the given version")
+)
@@ -0,0 +1,46 @@
+package domain
+
+type AdEventType string
+
+const (
+	AdEventCreated  AdEventType = "created"
+	AdEventUpdated  AdEventType = "updated"
+	AdEventPaused   AdEventType = "paused"
+	AdEventResumed  AdEventType = "resumed"
+	AdEventDeleted  AdEventType = "deleted"
+	AdEventRestored AdEventType = "restored"
+	// Started and expired are published when the lifecycle of an ad changes at its start and end
+	AdEventStarted AdEventType = "started"
+	AdEventExpired AdEventType = "expired"
+	// BudgetExhausted is recorded once the flushed impressions of an ad reach its total budget
+	AdEventBudgetExhausted AdEventType = "budget_exhausted"
+)
+
+// AdEventTypes returns every type of event
+func AdEventTypes() []AdEventType {
+	return []AdEventType{AdEventCreated, AdEventUpdated, AdEventPaused, AdEventResumed, AdEventDeleted,
+		AdEventRestored, AdEventStarted, AdEventExpired, AdEventBudgetExhausted}
+}
+
+// AdEvent tells that an ad of a tenant changed at a time in RFC 3339. ID is the id of the event
+// in the outbox, and Ad is the ad as the change left it, which is nil when the ad is deleted or
+// the event does not change it.
+type AdEvent struct {
+	ID       int64       `json:"-"`
+	Type     AdEventType `json:"type" enums:"created,updated,paused,resumed,deleted,restored,started,expired,budget_exhausted"`
+	TenantID string      `json:"-"`
+	AdID     int64       `json:"adId"`
+	At       string      `json:"at"`
+	Ad       *Ad         `json:"-"`
+}
+
+type AdEventHandler func(event AdEvent)
+
+// AdEventBus delivers the events of ads to the subscribers in the server, which are published
+// by the dispatcher of the outbox. Handlers are called in the goroutine publishing the event,
+// so they should hand slow work over to their own.
+type AdEventBus interface {
+	Publish(event AdEvent)
+	// Subscribe calls handler with every event published until the returned function is called
+	Subscribe(handler AdEventHandler) func()
+}
@@ -0,0 +1,20 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// FrequencyCap limits how many times an ad is served to a user within a window, e.g.
+// {"count": 3, "window": "24h"}. The window starts when the ad is first served to the user.
+type FrequencyCap struct {
+	Count  int    `json:"count" example:"3"`
+	Window string `json:"window" example:"24h"`
+}
+
+// FrequencyRepository counts how many times ads were served to users. Counts expire with
+// the window they were incremented with.
+type FrequencyRepository interface {
+	GetCounts(c context.Context, userID string, adIDs []int64) (map[int64]int, error)
+	Increment(c context.Context, userID string, adID int64, window time.Duration) error
+}
@@ -14,17 +14,17 @@ type AdRepository struct {
 	mock.Mock
 }
 
-// Create provides a mock function with given fields: c, ad
-func (_m *AdRepository) Create(c context.Context, ad *domain.Ad) error {
-	ret := _m.Called(c, ad)
+// Create provides a mock function with given fields: c, ad, check
+func (_m *AdRepository) Create(c context.Context, ad *domain.Ad, check domain.ConflictCheck) error {
+	ret := _m.Called(c, ad, check)
 
 	if len(ret) == 0 {
 		panic("no return value specified for Create")
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, *domain.Ad) error); ok {
-		r0 = rf(c, ad)
+	if rf, ok := ret.Get(0).(func(context.Context, *domain.Ad, domain.ConflictCheck) error); ok {
+		r0 = rf(c, ad, check)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -32,6 +32,114 @@ func (_m *AdRepository) Create(c context.Context, ad *domain.Ad) error {
 	return r0
 }
 
+// Delete provides a mock function with given fields: c, id
+func (_m *AdRepository) Delete(c context.Context, id int64) error {
+	ret := _m.Called(c, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Delete")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) error); ok {
+		r0 = rf(c, id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Fetch provides a mock function with given fields: c, advertiserID
+func (_m *AdRepository) Fetch(c context.Context, advertiserID int64) ([]domain.Ad, error) {
+	ret := _m.Called(c, advertiserID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Fetch")
+	}
+
+	var r0 []domain.Ad
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) ([]domain.Ad, error)); ok {
+		return rf(c, advertiserID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) []domain.Ad); ok {
+		r0 = rf(c, advertiserID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]domain.Ad)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(c, advertiserID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// FetchByCampaign provides a mock function with given fields: c, campaignID
+func (_m *AdRepository) FetchByCampaign(c context.Context, campaignID int64) ([]domain.Ad, error) {
+	ret := _m.Called(c, campaignID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for FetchByCampaign")
+	}
+
+	var r0 []domain.Ad
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) ([]domain.Ad, error)); ok {
+		return rf(c, campaignID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) []domain.Ad); ok {
+		r0 = rf(c, campaignID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]domain.Ad)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(c, campaignID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// FetchLifecycles provides a mock function with given fields: c, afterID, limit
+func (_m *AdRepository) FetchLifecycles(c context.Context, afterID int64, limit int) ([]domain.AdLifecycleState, error) {
+	ret := _m.Called(c, afterID, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for FetchLifecycles")
+	}
+
+	var r0 []domain.AdLifecycleState
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int) ([]domain.AdLifecycleState, error)); ok {
+		return rf(c, afterID, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int) []domain.AdLifecycleState); ok {
+		r0 = rf(c, afterID, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]domain.AdLifecycleState)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, int) error); ok {
+		r1 = rf(c, afterID, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetByCondition provides a mock function with given fields: c, condition
 func (_m *AdRepository) GetByCondition(c context.Context, condition map[string][]string) ([]domain.Ad, error) {
 	ret := _m.Called(c, condition)
@@ -62,6 +170,278 @@ func (_m *AdRepository) GetByCondition(c context.Context, condition map[string][
 	return r0, r1
 }
 
+// GetByID provides a mock function with given fields: c, id
+func (_m *AdRepository) GetByID(c context.Context, id int64) (domain.Ad, error) {
+	ret := _m.Called(c, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetByID")
+	}
+
+	var r0 domain.Ad
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) (domain.Ad, error)); ok {
+		return rf(c, id)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) domain.Ad); ok {
+		r0 = rf(c, id)
+	} else {
+		r0 = ret.Get(0).(domain.Ad)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(c, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetByWindow provides a mock function with given fields: c, startAt, endAt, advertiserID
+func (_m *AdRepository) GetByWindow(c context.Context, startAt string, endAt string, advertiserID int64) ([]domain.Ad, error) {
+	ret := _m.Called(c, startAt, endAt, advertiserID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetByWindow")
+	}
+
+	var r0 []domain.Ad
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, int64) ([]domain.Ad, error)); ok {
+		return rf(c, startAt, endAt, advertiserID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, int64) []domain.Ad); ok {
+		r0 = rf(c, startAt, endAt, advertiserID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]domain.Ad)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, int64) error); ok {
+		r1 = rf(c, startAt, endAt, advertiserID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetCreativesByPlatform provides a mock function with given fields: c, adIDs, platform
+func (_m *AdRepository) GetCreativesByPlatform(c context.Context, adIDs []int64, platform string) (map[int64]domain.Creative, error) {
+	ret := _m.Called(c, adIDs, platform)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetCreativesByPlatform")
+	}
+
+	var r0 map[int64]domain.Creative
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []int64, string) (map[int64]domain.Creative, error)); ok {
+		return rf(c, adIDs, platform)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []int64, string) map[int64]domain.Creative); ok {
+		r0 = rf(c, adIDs, platform)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[int64]domain.Creative)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []int64, string) error); ok {
+		r1 = rf(c, adIDs, platform)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetLifecycle provides a mock function with given fields: c, id
+func (_m *AdRepository) GetLifecycle(c context.Context, id int64) (domain.AdLifecycleState, error) {
+	ret := _m.Called(c, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetLifecycle")
+	}
+
+	var r0 domain.AdLifecycleState
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) (domain.AdLifecycleState, error)); ok {
+		return rf(c, id)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) domain.AdLifecycleState); ok {
+		r0 = rf(c, id)
+	} else {
+		r0 = ret.Get(0).(domain.AdLifecycleState)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(c, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetVersion provides a mock function with given fields: c, id, version
+func (_m *AdRepository) GetVersion(c context.Context, id int64, version int64) (domain.Ad, error) {
+	ret := _m.Called(c, id, version)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetVersion")
+	}
+
+	var r0 domain.Ad
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64) (domain.Ad, error)); ok {
+		return rf(c, id, version)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64) domain.Ad); ok {
+		r0 = rf(c, id, version)
+	} else {
+		r0 = ret.Get(0).(domain.Ad)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, int64) error); ok {
+		r1 = rf(c, id, version)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Purge provides a mock function with given fields: c, deletedBefore, batchSize
+func (_m *AdRepository) Purge(c context.Context, deletedBefore string, batchSize int) (int, error) {
+	ret := _m.Called(c, deletedBefore, batchSize)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Purge")
+	}
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, int) (int, error)); ok {
+		return rf(c, deletedBefore, batchSize)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, int) int); ok {
+		r0 = rf(c, deletedBefore, batchSize)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, int) error); ok {
+		r1 = rf(c, deletedBefore, batchSize)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Restore provides a mock function with given fields: c, id
+func (_m *AdRepository) Restore(c context.Context, id int64) error {
+	ret := _m.Called(c, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Restore")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) error); ok {
+		r0 = rf(c, id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Rollback provides a mock function with given fields: c, ad, version, check
+func (_m *AdRepository) Rollback(c context.Context, ad *domain.Ad, version int64, check domain.ConflictCheck) error {
+	ret := _m.Called(c, ad, version, check)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Rollback")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *domain.Ad, int64, domain.ConflictCheck) error); ok {
+		r0 = rf(c, ad, version, check)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Update provides a mock function with given fields: c, ad, check
+func (_m *AdRepository) Update(c context.Context, ad *domain.Ad, check domain.ConflictCheck) error {
+	ret := _m.Called(c, ad, check)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Update")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *domain.Ad, domain.ConflictCheck) error); ok {
+		r0 = rf(c, ad, check)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UpdateLifecycle provides a mock function with given fields: c, id, from, to
+func (_m *AdRepository) UpdateLifecycle(c context.Context, id int64, from domain.AdLifecycle, to domain.AdLifecycle) (bool, error) {
+	ret := _m.Called(c, id, from, to)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateLifecycle")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, domain.AdLifecycle, domain.AdLifecycle) (bool, error)); ok {
+		return rf(c, id, from, to)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, domain.AdLifecycle, domain.AdLifecycle) bool); ok {
+		r0 = rf(c, id, from, to)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, domain.AdLifecycle, domain.AdLifecycle) error); ok {
+		r1 = rf(c, id, from, to)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UpdateStatus provides a mock function with given fields: c, id, status
+func (_m *AdRepository) UpdateStatus(c context.Context, id int64, status domain.AdStatus) error {
+	ret := _m.Called(c, id, status)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateStatus")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, domain.AdStatus) error); ok {
+		r0 = rf(c, id, status)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // NewAdRepository creates a new instance of AdRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewAdRepository(t interface {
@@ -14,6 +14,24 @@ type AdUsecase struct {
 	mock.Mock
 }
 
+// ApplyCampaign provides a mock function with given fields: c, campaignID
+func (_m *AdUsecase) ApplyCampaign(c context.Context, campaignID int64) error {
+	ret := _m.Called(c, campaignID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ApplyCampaign")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) error); ok {
+		r0 = rf(c, campaignID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // Create provides a mock function with given fields: c, ad
 func (_m *AdUsecase) Create(c context.Context, ad *domain.Ad) error {
 	ret := _m.Called(c, ad)
@@ -32,6 +50,54 @@ func (_m *AdUsecase) Create(c context.Context, ad *domain.Ad) error {
 	return r0
 }
 
+// Delete provides a mock function with given fields: c, id
+func (_m *AdUsecase) Delete(c context.Context, id int64) error {
+	ret := _m.Called(c, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Delete")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) error); ok {
+		r0 = rf(c, id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Fetch provides a mock function with given fields: c, advertiserID
+func (_m *AdUsecase) Fetch(c context.Context, advertiserID int64) ([]domain.Ad, error) {
+	ret := _m.Called(c, advertiserID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Fetch")
+	}
+
+	var r0 []domain.Ad
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) ([]domain.Ad, error)); ok {
+		return rf(c, advertiserID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) []domain.Ad); ok {
+		r0 = rf(c, advertiserID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]domain.Ad)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(c, advertiserID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetByCondition provides a mock function with given fields: c, condition
 func (_m *AdUsecase) GetByCondition(c context.Context, condition map[string][]string) ([]domain.Ad, error) {
 	ret := _m.Called(c, condition)
@@ -62,6 +128,174 @@ func (_m *AdUsecase) GetByCondition(c context.Context, condition map[string][]st
 	return r0, r1
 }
 
+// GetByID provides a mock function with given fields: c, id
+func (_m *AdUsecase) GetByID(c context.Context, id int64) (domain.Ad, error) {
+	ret := _m.Called(c, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetByID")
+	}
+
+	var r0 domain.Ad
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) (domain.Ad, error)); ok {
+		return rf(c, id)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) domain.Ad); ok {
+		r0 = rf(c, id)
+	} else {
+		r0 = ret.Get(0).(domain.Ad)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(c, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetOverlapping provides a mock function with given fields: c, ad
+func (_m *AdUsecase) GetOverlapping(c context.Context, ad *domain.Ad) ([]domain.Ad, error) {
+	ret := _m.Called(c, ad)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetOverlapping")
+	}
+
+	var r0 []domain.Ad
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *domain.Ad) ([]domain.Ad, error)); ok {
+		return rf(c, ad)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *domain.Ad) []domain.Ad); ok {
+		r0 = rf(c, ad)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]domain.Ad)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *domain.Ad) error); ok {
+		r1 = rf(c, ad)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetVersion provides a mock function with given fields: c, id, version
+func (_m *AdUsecase) GetVersion(c context.Context, id int64, version int64) (domain.Ad, error) {
+	ret := _m.Called(c, id, version)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetVersion")
+	}
+
+	var r0 domain.Ad
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64) (domain.Ad, error)); ok {
+		return rf(c, id, version)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64) domain.Ad); ok {
+		r0 = rf(c, id, version)
+	} else {
+		r0 = ret.Get(0).(domain.Ad)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, int64) error); ok {
+		r1 = rf(c, id, version)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Restore provides a mock function with given fields: c, id
+func (_m *AdUsecase) Restore(c context.Context, id int64) error {
+	ret := _m.Called(c, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Restore")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) error); ok {
+		r0 = rf(c, id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Rollback provides a mock function with given fields: c, id, version, expectedVersions
+func (_m *AdUsecase) Rollback(c context.Context, id int64, version int64, expectedVersions []int64) (domain.Ad, error) {
+	ret := _m.Called(c, id, version, expectedVersions)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Rollback")
+	}
+
+	var r0 domain.Ad
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64, []int64) (domain.Ad, error)); ok {
+		return rf(c, id, version, expectedVersions)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64, []int64) domain.Ad); ok {
+		r0 = rf(c, id, version, expectedVersions)
+	} else {
+		r0 = ret.Get(0).(domain.Ad)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, int64, []int64) error); ok {
+		r1 = rf(c, id, version, expectedVersions)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Update provides a mock function with given fields: c, ad
+func (_m *AdUsecase) Update(c context.Context, ad *domain.Ad) error {
+	ret := _m.Called(c, ad)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Update")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *domain.Ad) error); ok {
+		r0 = rf(c, ad)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UpdateStatus provides a mock function with given fields: c, id, status
+func (_m *AdUsecase) UpdateStatus(c context.Context, id int64, status domain.AdStatus) error {
+	ret := _m.Called(c, id, status)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateStatus")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, domain.AdStatus) error); ok {
+		r0 = rf(c, id, status)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // NewAdUsecase creates a new instance of AdUsecase. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewAdUsecase(t interface {
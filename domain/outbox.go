@@ -0,0 +1,72 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// OutboxCheckpoint is how far a subscription handled the outbox. Ids are taken when events are
+// recorded but seen when their transactions commit, so Gaps keeps the ids before LastID which
+// were missing, by the time they were found missing, until they commit or their transactions
+// can no longer be open.
+type OutboxCheckpoint struct {
+	LastID  int64
+	Gaps    map[int64]time.Time
+	Failure *OutboxFailure
+}
+
+// OutboxFailure is an event whose handler failed, which is handled again at RetryAt
+type OutboxFailure struct {
+	EventID  int64     `json:"eventId"`
+	Attempts int       `json:"attempts"`
+	RetryAt  time.Time `json:"retryAt"`
+}
+
+// EventPublisher records events of ads in the outbox, from which the dispatcher relays them.
+// Changes made through the repositories publish their events in the transaction of the change,
+// so an event is recorded if and only if its change commits.
+type EventPublisher interface {
+	Publish(c context.Context, event AdEvent) error
+}
+
+type OutboxRepository interface {
+	// Publish records an event of the tenant of c on its own, for events which do not change
+	// an ad
+	EventPublisher
+	// FetchAfter returns at most limit events of every tenant after the id, from the oldest,
+	// where At is the time recorded in the database
+	FetchAfter(c context.Context, afterID int64, limit int) ([]AdEvent, error)
+	// FetchIDs returns the events of the ids which are committed, from the oldest
+	FetchIDs(c context.Context, ids []int64) ([]AdEvent, error)
+	// LastIDBefore returns the id of the newest event recorded longer than age ago, which is 0
+	// when there is none
+	LastIDBefore(c context.Context, age time.Duration) (int64, error)
+	// Acquire leases the checkpoint of a subscription to owner and returns it, or returns false
+	// when the lease of another owner has not expired. A new subscription starts after the
+	// newest event.
+	Acquire(c context.Context, subscription string, owner string, lease time.Duration) (OutboxCheckpoint, bool, error)
+	// Advance saves the checkpoint of a subscription and renews the lease, and returns
+	// ErrConflict when another owner took the lease over
+	Advance(c context.Context, subscription string, owner string, checkpoint OutboxCheckpoint, lease time.Duration) error
+	// DeadLetter keeps an event which a subscription gave up handling, with the last error
+	DeadLetter(c context.Context, subscription string, event AdEvent, lastError string) error
+	// Trim deletes the events recorded longer than retention ago which every subscription has
+	// handled. The checkpoints of subscriptions no server leased for longer than retention are
+	// deleted first, so that a subscription which was removed stops holding events back.
+	Trim(c context.Context, retention time.Duration) error
+}
+
+// EventHandler handles an event of a subscription, which is handled again when it fails, up to
+// a number of attempts after which it is dead-lettered
+type EventHandler func(c context.Context, event AdEvent) error
+
+// EventDispatcher relays the events of the outbox. Each subscription handles every event once
+// at least, on one server at a time, in the order they commit, which keeps the events of an ad
+// in order since they are recorded under the lock of the ad. The event bus of every server also
+// receives every event once it starts.
+type EventDispatcher interface {
+	// Subscribe adds a subscription, which should be done before Run
+	Subscribe(subscription string, handler EventHandler)
+	// Run relays the events every interval until c is done
+	Run(c context.Context, interval time.Duration)
+}
@@ -0,0 +1,14 @@
+package domain
+
+// Ranker orders the ads served for a request in place. Random orders are derived from the
+// seed, so that the pages of a request or the requests of a user see the same order.
+type Ranker interface {
+	Rank(ads []Ad, seed string)
+}
+
+const (
+	// SortPriority orders ads by priority, then by weighted random within a priority
+	SortPriority = "priority"
+	// SortEndAt orders ads by the time they end, the soonest first
+	SortEndAt = "endAt"
+)
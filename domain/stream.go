@@ -0,0 +1,50 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// AdStreamEvent is an event of the stream of ad changes, with the ad as it was when the event
+// was logged. Ad is empty when the ad was deleted. ID is the id of the event in the log, which
+// the client sends back as Last-Event-ID to resume the stream.
+type AdStreamEvent struct {
+	ID       int64       `json:"id,omitempty"`
+	TenantID string      `json:"-"`
+	Type     AdEventType `json:"type" enums:"created,updated,started,expired,deleted"`
+	AdID     int64       `json:"adId"`
+	At       string      `json:"at"`
+	Ad       *Ad         `json:"ad,omitempty"`
+}
+
+// AdEventLogRepository keeps the events of the streams. Tail and FetchAfter read the log in the
+// order of the ids, so an event must never commit after an event with a greater id: Append is
+// only called by the stream subscription of the outbox, which runs on one server at a time and
+// appends one event after the other, each in its own statement.
+type AdEventLogRepository interface {
+	// Append adds an event of the tenant to the log, and sets its id
+	Append(c context.Context, event *AdStreamEvent) error
+	// FetchAfter returns at most limit events of the tenant after the id, from the oldest
+	FetchAfter(c context.Context, afterID int64, limit int) ([]AdStreamEvent, error)
+	// Tail returns at most limit events of every tenant after the id, from the oldest
+	Tail(c context.Context, afterID int64, limit int) ([]AdStreamEvent, error)
+	// LastID returns the id of the newest event, which is 0 when the log is empty
+	LastID(c context.Context) (int64, error)
+	// Trim deletes the events logged longer than retention ago
+	Trim(c context.Context, retention time.Duration) error
+}
+
+type AdStreamUsecase interface {
+	// Subscribe streams the events of the tenant of c, replaying those after lastEventID from
+	// the log first unless it is 0. The channel is closed when c is done, when the subscriber
+	// falls too far behind, or when the streams are closed, and the client resumes from the
+	// last event it received.
+	Subscribe(c context.Context, lastEventID int64) (<-chan AdStreamEvent, error)
+	// Append logs an event of the tenant of c for the streams
+	Append(c context.Context, event AdEvent) error
+	// Run sends the events logged by every server to the subscribers, polling the log every
+	// interval until c is done
+	Run(c context.Context, interval time.Duration)
+	// Close ends every stream, and streams subscribed afterwards end at once
+	Close()
+}
@@ -0,0 +1,36 @@
+package domain
+
+import "context"
+
+// tenantKey is the context key of the tenant a request is made on behalf of
+type tenantKey struct{}
+
+// WithTenant returns a context of requests made on behalf of the tenant. Repositories read
+// the tenant from the context, so that every query is scoped to it.
+func WithTenant(c context.Context, tenantID string) context.Context {
+	return context.WithValue(c, tenantKey{}, tenantID)
+}
+
+// TenantFromContext returns the tenant of a request, and ErrUnauthorized when the request
+// has none, so that queries without a tenant fail instead of reaching every tenant
+func TenantFromContext(c context.Context) (string, error) {
+	tenantID, _ := c.Value(tenantKey{}).(string)
+	if tenantID == "" {
+		return "", ErrUnauthorized
+	}
+	return tenantID, nil
+}
+
+// actorKey is the context key of the principal a request is made by
+type actorKey struct{}
+
+// WithActor returns a context of requests made by the actor, who is recorded in the audit log
+func WithActor(c context.Context, actor string) context.Context {
+	return context.WithValue(c, actorKey{}, actor)
+}
+
+// ActorFromContext returns the actor of a request, which is empty when it is unknown
+func ActorFromContext(c context.Context) string {
+	actor, _ := c.Value(actorKey{}).(string)
+	return actor
+}
@@ -0,0 +1,62 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+type EventType string
+
+const (
+	EventImpression EventType = "impression"
+	EventClick      EventType = "click"
+)
+
+// EventCount is the number of impressions and clicks of an ad of a tenant in an hour
+type EventCount struct {
+	TenantID    string
+	AdID        int64
+	Hour        time.Time
+	Impressions int64
+	Clicks      int64
+}
+
+type HourlyStats struct {
+	Hour        string `json:"hour" example:"2024-03-01T08:00:00Z"`
+	Impressions int64  `json:"impressions"`
+	Clicks      int64  `json:"clicks"`
+}
+
+type AdStats struct {
+	AdID        int64         `json:"adId"`
+	Impressions int64         `json:"impressions"`
+	Clicks      int64         `json:"clicks"`
+	Hourly      []HourlyStats `json:"hourly"`
+}
+
+// Delivery is the number of tracked impressions of an ad in total and since the start of the day
+type Delivery struct {
+	Impressions      int64
+	TodayImpressions int64
+}
+
+// DeliveryCounter returns the delivery of ads, where ads without impressions are absent
+type DeliveryCounter interface {
+	GetDelivery(c context.Context, adIDs []int64, dayStart time.Time) (map[int64]Delivery, error)
+}
+
+type TrackingRepository interface {
+	CheckAd(c context.Context, adID int64) error
+	AddCounts(c context.Context, counts []EventCount) error
+	GetCounts(c context.Context, adID int64) ([]EventCount, error)
+	GetDelivery(c context.Context, adIDs []int64, dayStart time.Time) (map[int64]Delivery, error)
+}
+
+type TrackingUsecase interface {
+	DeliveryCounter
+	AdCacheInvalidator
+	Record(c context.Context, adID int64, eventType EventType) error
+	Flush(c context.Context) error
+	Run(c context.Context, interval time.Duration)
+	GetStats(c context.Context, adID int64) (AdStats, error)
+}
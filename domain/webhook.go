@@ -0,0 +1,76 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// Webhook subscribes a URL to events of the ads of a tenant. The secret signs the deliveries,
+// and is never returned once the webhook is created.
+type Webhook struct {
+	ID         int64         `json:"id,omitempty"`
+	URL        string        `json:"url" binding:"required" example:"https://example.com/webhook"`
+	EventTypes []AdEventType `json:"eventTypes" binding:"required" enums:"created,updated,paused,resumed,deleted,restored,started,expired,budget_exhausted"`
+	Secret     string        `json:"secret,omitempty" binding:"required"`
+	CreatedAt  string        `json:"createdAt,omitempty"`
+}
+
+type DeliveryStatus string
+
+const (
+	DeliveryPending DeliveryStatus = "pending"
+	// Dead deliveries ran out of attempts, and are only sent again when they are redelivered
+	DeliveryDead DeliveryStatus = "dead"
+)
+
+// WebhookDelivery is an event waiting in the outbox to be sent to a webhook, where Payload is
+// the body of the request. Deliveries leave the outbox once they are delivered.
+type WebhookDelivery struct {
+	ID        int64          `json:"id"`
+	WebhookID int64          `json:"webhookId"`
+	TenantID  string         `json:"-"`
+	EventType AdEventType    `json:"eventType"`
+	Payload   string         `json:"payload"`
+	Status    DeliveryStatus `json:"status" enums:"pending,dead"`
+	Attempts  int            `json:"attempts"`
+	LastError string         `json:"lastError,omitempty"`
+	CreatedAt string         `json:"createdAt"`
+	// The URL and the secret of the webhook, which are only read to send the delivery
+	URL    string `json:"-"`
+	Secret string `json:"-"`
+}
+
+type WebhookRepository interface {
+	Create(c context.Context, webhook *Webhook) error
+	Fetch(c context.Context) ([]Webhook, error)
+	Delete(c context.Context, id int64) error
+	// Enqueue adds a delivery of the event to the outbox for every webhook of the tenant
+	// subscribed to its type, and returns how many it added
+	Enqueue(c context.Context, event AdEvent, payload string) (int, error)
+	// Claim returns at most batchSize pending deliveries of every tenant which are due, and
+	// counts an attempt for each. They are not claimed again until the lease is over, so a
+	// delivery is retried when the server stops before finishing it.
+	Claim(c context.Context, batchSize int, lease time.Duration) ([]WebhookDelivery, error)
+	Complete(c context.Context, id int64) error
+	// Fail records the error of the last attempt and retries after retryAfter, or moves the
+	// delivery to the dead letters when it is 0
+	Fail(c context.Context, id int64, lastError string, retryAfter time.Duration) error
+	FetchDeadLetters(c context.Context, limit int, offset int) ([]WebhookDelivery, error)
+	// Redeliver sends a dead delivery again with new attempts, and returns ErrNotFound when
+	// the tenant has no such dead delivery
+	Redeliver(c context.Context, id int64) error
+}
+
+type WebhookUsecase interface {
+	Create(c context.Context, webhook *Webhook) error
+	Fetch(c context.Context) ([]Webhook, error)
+	Delete(c context.Context, id int64) error
+	FetchDeadLetters(c context.Context, limit int, offset int) ([]WebhookDelivery, error)
+	Redeliver(c context.Context, id int64) error
+	// Deliver sends the due deliveries of a batch, and returns how many were claimed
+	Deliver(c context.Context) (int, error)
+	// Enqueue adds a delivery of the event of the tenant of c for every webhook subscribed to it
+	Enqueue(c context.Context, event AdEvent) error
+	// Run delivers the outbox when an event is enqueued and every interval until c is done
+	Run(c context.Context, interval time.Duration)
+}
@@ -1,18 +1,29 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
+	"net"
+	"net/http"
 	"os"
+	"os/signal"
 	"strconv"
+	"syscall"
 	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	_ "github.com/go-sql-driver/mysql"
+	"google.golang.org/grpc"
 
+	"dcard-backend/cli"
 	"dcard-backend/config"
 	_ "dcard-backend/docs"
+	"dcard-backend/middleware"
+	"dcard-backend/repository"
 	"dcard-backend/router"
+	"dcard-backend/usecase"
 )
 
 // @title  Dcard AD API
@@ -21,6 +32,11 @@ import (
 
 // @host 127.0.0.1:3000
 // @BasePath /api/v1
+
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name Authorization
+// @description Admin API key of the tenant as "Bearer <key>"
 func main() {
 	err := config.LoadEnv()
 	if err != nil {
@@ -36,11 +52,88 @@ func main() {
 	t, _ := strconv.Atoi(os.Getenv("CONTEXT_TIMEOUT"))
 	timeout := time.Duration(t) * time.Second
 
+	if len(os.Args) > 1 && os.Args[1] == "ads" {
+		au := router.NewAdUsecase(repository.NewAdRepository(db), db, timeout)
+		if err := cli.RunAds(os.Args[2:], usecase.NewAdTransferUsecase(au), os.Stdout); err != nil {
+			log.Println(err)
+			config.CloseMySQLDatabase(db)
+			os.Exit(1)
+		}
+		return
+	}
+
 	app := gin.Default()
+	// The client IP is only taken from X-Forwarded-For when the request comes from a trusted
+	// proxy, since it decides the country of the viewer
+	if err := app.SetTrustedProxies(config.GetEnvList("TRUSTED_PROXIES")); err != nil {
+		log.Fatal(err)
+	}
 	app.Use(cors.Default())
 
-	router.SetUpRoutes(app, db, timeout)
+	// Internal services call the gRPC API on GRPC_PORT, next to the HTTP API on APP_PORT.
+	// An unset port would listen on a random one, so it is refused before anything starts
+	appPort, grpcPort := os.Getenv("APP_PORT"), os.Getenv("GRPC_PORT")
+	if appPort == "" || grpcPort == "" {
+		log.Fatal("APP_PORT and GRPC_PORT must be set")
+	}
+	rpcOptions := middleware.RPCInterceptors(config.GetEnvTenantKeys("ADMIN_API_KEYS"))
+	creds, err := config.GetRPCCredentials()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if creds != nil {
+		rpcOptions = append(rpcOptions, grpc.Creds(creds))
+	} else {
+		log.Println("GRPC_TLS_CERT and GRPC_TLS_KEY are not set, serving gRPC in plaintext for private networks only")
+	}
+	rpcServer := grpc.NewServer(rpcOptions...)
+
+	closeStreams, shutdown := router.SetUpRoutes(app, rpcServer, db, timeout)
 
-	port := os.Getenv("APP_PORT")
-	app.Run(":" + port)
+	server := &http.Server{
+		Addr:    ":" + appPort,
+		Handler: app,
+	}
+	server.RegisterOnShutdown(closeStreams)
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(err)
+		}
+	}()
+
+	listener, err := net.Listen("tcp", ":"+grpcPort)
+	if err != nil {
+		log.Fatal(err)
+	}
+	go func() {
+		if err := rpcServer.Serve(listener); err != nil {
+			log.Fatal(err)
+		}
+	}()
+
+	// Stop accepting requests on SIGINT or SIGTERM, and let the background workers flush
+	// before the database is closed
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+
+	ctx, cancel := context.WithTimeout(context.Background(), config.GetEnvSeconds("SHUTDOWN_TIMEOUT", 10*time.Second))
+	defer cancel()
+
+	if err := server.Shutdown(ctx); err != nil {
+		log.Println("Error created when shutting down the server:", err.Error())
+	}
+	// The streams of the gRPC server were ended along with those of the HTTP server
+	rpcStopped := make(chan struct{})
+	go func() {
+		defer close(rpcStopped)
+		rpcServer.GracefulStop()
+	}()
+	select {
+	case <-rpcStopped:
+	case <-ctx.Done():
+		log.Println("Error created when shutting down the gRPC server:", ctx.Err().Error())
+		rpcServer.Stop()
+	}
+	shutdown(ctx)
 }
@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"net/netip"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// AttributeSourcesKey is the key of the gin context holding the source of each resolved
+	// attribute, as a map[string]string
+	AttributeSourcesKey = "attributeSources"
+	// AttributeSourcesHeader lists the sources of the attributes in the response for debugging
+	AttributeSourcesHeader = "X-Attribute-Sources"
+
+	SourceQuery     = "query"
+	SourceUserAgent = "user-agent"
+	SourceGeoIP     = "geoip"
+)
+
+// targetingAttributes are the query parameters of the viewer whose sources are recorded
+var targetingAttributes = []string{"age", "gender", "country", "platform"}
+
+// PlatformFromUserAgent returns android, ios or web for a User-Agent header, or an empty
+// string if the header is empty
+func PlatformFromUserAgent(userAgent string) string {
+	switch {
+	case userAgent == "":
+		return ""
+	case strings.Contains(userAgent, "Android"):
+		return "android"
+	case strings.Contains(userAgent, "iPhone"), strings.Contains(userAgent, "iPad"),
+		strings.Contains(userAgent, "iPod"), strings.Contains(userAgent, "CFNetwork"):
+		return "ios"
+	default:
+		return "web"
+	}
+}
+
+// ResolveAttributes fills the platform and country query parameters the caller did not set,
+// from the User-Agent header and from the client IP looked up in countries, which may be nil.
+// The source of each attribute is kept in the context and in the response header.
+func ResolveAttributes(countries *CountryDatabase) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		query := ctx.Request.URL.Query()
+		sources := map[string]string{}
+		for _, attribute := range targetingAttributes {
+			if query.Has(attribute) {
+				sources[attribute] = SourceQuery
+			}
+		}
+
+		if !query.Has("platform") {
+			if platform := PlatformFromUserAgent(ctx.GetHeader("User-Agent")); platform != "" {
+				query.Set("platform", platform)
+				sources["platform"] = SourceUserAgent
+			}
+		}
+
+		if !query.Has("country") && countries != nil {
+			if ip, err := netip.ParseAddr(ctx.ClientIP()); err == nil {
+				if country, ok := countries.Lookup(ip); ok {
+					query.Set("country", country)
+					sources["country"] = SourceGeoIP
+				}
+			}
+		}
+
+		ctx.Request.URL.RawQuery = query.Encode()
+		ctx.Set(AttributeSourcesKey, sources)
+
+		values := make([]string, 0, len(sources))
+		for attribute, source := range sources {
+			values = append(values, attribute+"="+source)
+		}
+		sort.Strings(values)
+		ctx.Header(AttributeSourcesHeader, strings.Join(values, ", "))
+
+		ctx.Next()
+	}
+}
@@ -0,0 +1,162 @@
+package middleware_test
+
+import (
+	"dcard-backend/middleware"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+const testCountryDatabase = `# network,country
+1.2.0.0/16,JP
+1.2.3.0/24,TW
+2001:db8::/32,DE
+`
+
+func writeCountryDatabase(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "countries.csv")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestPlatformFromUserAgent_ShouldDetectPlatform(t *testing.T) {
+	tests := map[string]string{
+		"Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 Chrome/120.0 Mobile Safari/537.36": "android",
+		"Mozilla/5.0 (iPhone; CPU iPhone OS 17_2 like Mac OS X) AppleWebKit/605.1.15 Mobile/15E148":     "ios",
+		"Dcard/5.0 CFNetwork/1490.0.4 Darwin/23.2.0":                                                    "ios",
+		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 Chrome/120.0 Safari/537.36":       "web",
+		"": "",
+	}
+	for userAgent, platform := range tests {
+		assert.Equal(t, platform, middleware.PlatformFromUserAgent(userAgent), userAgent)
+	}
+}
+
+func TestCountryDatabase_Lookup_ShouldReturnMostSpecificNetwork(t *testing.T) {
+	countries, err := middleware.LoadCountryDatabase(writeCountryDatabase(t, testCountryDatabase))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	tests := map[string]string{
+		"1.2.3.4":         "TW",
+		"1.2.4.4":         "JP",
+		"::ffff:1.2.3.4":  "TW",
+		"2001:db8::1":     "DE",
+		"8.8.8.8":         "",
+		"2001:4860::8888": "",
+	}
+	for ip, expected := range tests {
+		country, _ := countries.Lookup(netip.MustParseAddr(ip))
+		assert.Equal(t, expected, country, ip)
+	}
+}
+
+func TestCountryDatabase_InvalidLine_ShouldReturnErrorAndKeepNetworks(t *testing.T) {
+	path := writeCountryDatabase(t, testCountryDatabase)
+	countries, err := middleware.LoadCountryDatabase(path)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	os.WriteFile(path, []byte("1.2.3.0/24,Taiwan\n"), 0o644)
+
+	assert.Error(t, countries.Reload())
+	country, _ := countries.Lookup(netip.MustParseAddr("1.2.3.4"))
+	assert.Equal(t, "TW", country)
+}
+
+func TestCountryDatabase_RefreshEvery_ShouldReloadFile(t *testing.T) {
+	path := writeCountryDatabase(t, testCountryDatabase)
+	countries, err := middleware.LoadCountryDatabase(path)
+	if !assert.NoError(t, err) {
+		return
+	}
+	stop := countries.RefreshEvery(5 * time.Millisecond)
+	defer stop()
+
+	os.WriteFile(path, []byte("1.2.3.0/24,KR\n"), 0o644)
+
+	assert.Eventually(t, func() bool {
+		country, _ := countries.Lookup(netip.MustParseAddr("1.2.3.4"))
+		return country == "KR"
+	}, time.Second, 5*time.Millisecond)
+}
+
+// resolvedAttributes is what the handler after ResolveAttributes sees
+type resolvedAttributes struct {
+	query   url.Values
+	sources interface{}
+}
+
+func newAttributesApp(countries *middleware.CountryDatabase) (*gin.Engine, *resolvedAttributes) {
+	app := gin.New()
+	app.RemoteIPHeaders = []string{"X-Forwarded-For"}
+	app.SetTrustedProxies([]string{"0.0.0.0/0"})
+
+	resolved := &resolvedAttributes{}
+	app.GET("/api/v1/ad", middleware.ResolveAttributes(countries), func(ctx *gin.Context) {
+		resolved.query = ctx.Request.URL.Query()
+		resolved.sources, _ = ctx.Get(middleware.AttributeSourcesKey)
+		ctx.Status(http.StatusOK)
+	})
+	return app, resolved
+}
+
+func TestResolveAttributes_ParamsMissing_ShouldFillFromHeaders(t *testing.T) {
+	countries, _ := middleware.LoadCountryDatabase(writeCountryDatabase(t, testCountryDatabase))
+	app, resolved := newAttributesApp(countries)
+
+	httpRecorder := httptest.NewRecorder()
+	httpRequest := httptest.NewRequest(http.MethodGet, "/api/v1/ad?offset=0&age=20", nil)
+	httpRequest.Header.Set("User-Agent", "Mozilla/5.0 (iPhone; CPU iPhone OS 17_2 like Mac OS X)")
+	httpRequest.Header.Set("X-Forwarded-For", "1.2.3.4")
+	app.ServeHTTP(httpRecorder, httpRequest)
+
+	query := resolved.query
+	assert.Equal(t, "ios", query.Get("platform"))
+	assert.Equal(t, "TW", query.Get("country"))
+	assert.Equal(t, "20", query.Get("age"))
+
+	assert.Equal(t, map[string]string{"age": "query", "country": "geoip", "platform": "user-agent"}, resolved.sources)
+	assert.Equal(t, "age=query, country=geoip, platform=user-agent", httpRecorder.Header().Get(middleware.AttributeSourcesHeader))
+}
+
+func TestResolveAttributes_ParamsProvided_ShouldKeepParams(t *testing.T) {
+	countries, _ := middleware.LoadCountryDatabase(writeCountryDatabase(t, testCountryDatabase))
+	app, resolved := newAttributesApp(countries)
+
+	httpRecorder := httptest.NewRecorder()
+	httpRequest := httptest.NewRequest(http.MethodGet, "/api/v1/ad?offset=0&platform=web&country=JP", nil)
+	httpRequest.Header.Set("User-Agent", "Mozilla/5.0 (Linux; Android 14)")
+	httpRequest.Header.Set("X-Forwarded-For", "1.2.3.4")
+	app.ServeHTTP(httpRecorder, httpRequest)
+
+	query := resolved.query
+	assert.Equal(t, "web", query.Get("platform"))
+	assert.Equal(t, "JP", query.Get("country"))
+	assert.Equal(t, "country=query, platform=query", httpRecorder.Header().Get(middleware.AttributeSourcesHeader))
+}
+
+func TestResolveAttributes_NoCountryDatabase_ShouldOnlyResolvePlatform(t *testing.T) {
+	app, resolved := newAttributesApp(nil)
+
+	httpRecorder := httptest.NewRecorder()
+	httpRequest := httptest.NewRequest(http.MethodGet, "/api/v1/ad?offset=0", nil)
+	httpRequest.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64)")
+	app.ServeHTTP(httpRecorder, httpRequest)
+
+	query := resolved.query
+	assert.Equal(t, "web", query.Get("platform"))
+	assert.False(t, query.Has("country"))
+}
@@ -0,0 +1,8 @@
+package middleware
+
+// StoredKeys returns the number of keys kept by the store, including those still running
+func StoredKeys(is *IdempotencyStore) int {
+	is.mutex.Lock()
+	defer is.mutex.Unlock()
+	return len(is.records)
+}
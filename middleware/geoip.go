@@ -0,0 +1,133 @@
+package middleware
+
+import (
+	"bufio"
+	"dcard-backend/domain"
+	"fmt"
+	"log"
+	"net/netip"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CountryDatabase maps client IPs to countries with a local CIDR file, where each line is
+// a network and its country code, e.g. "203.69.0.0/16,TW". Empty lines and lines starting
+// with # are skipped.
+type CountryDatabase struct {
+	path  string
+	mutex sync.RWMutex
+	// networks maps the prefix length to the networks of that length, and lengths is sorted
+	// from the longest, so that the most specific network of an IP is found first
+	networks map[int]map[netip.Prefix]string
+	lengths  []int
+}
+
+// LoadCountryDatabase reads the CIDR file at the path
+func LoadCountryDatabase(path string) (*CountryDatabase, error) {
+	database := &CountryDatabase{path: path}
+	if err := database.Reload(); err != nil {
+		return nil, err
+	}
+	return database, nil
+}
+
+// Reload reads the CIDR file again. The networks loaded before are kept if the file can
+// not be read or has an invalid line.
+func (cd *CountryDatabase) Reload() error {
+	file, err := os.Open(cd.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	networks := map[int]map[netip.Prefix]string{}
+	scanner := bufio.NewScanner(file)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		network, country, ok := strings.Cut(text, ",")
+		if !ok {
+			return fmt.Errorf("%s:%d: expected a network and a country separated by a comma", cd.path, line)
+		}
+		prefix, err := netip.ParsePrefix(strings.TrimSpace(network))
+		if err != nil {
+			return fmt.Errorf("%s:%d: %w", cd.path, line, err)
+		}
+		country = strings.ToUpper(strings.TrimSpace(country))
+		if !domain.IsCountryCode(country) {
+			return fmt.Errorf("%s:%d: invalid country %q", cd.path, line, country)
+		}
+
+		prefix = prefix.Masked()
+		if networks[prefix.Bits()] == nil {
+			networks[prefix.Bits()] = map[netip.Prefix]string{}
+		}
+		networks[prefix.Bits()][prefix] = country
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	lengths := make([]int, 0, len(networks))
+	for length := range networks {
+		lengths = append(lengths, length)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(lengths)))
+
+	cd.mutex.Lock()
+	defer cd.mutex.Unlock()
+	cd.networks, cd.lengths = networks, lengths
+	return nil
+}
+
+// Lookup returns the country of the most specific network containing the IP
+func (cd *CountryDatabase) Lookup(ip netip.Addr) (string, bool) {
+	ip = ip.Unmap()
+
+	cd.mutex.RLock()
+	defer cd.mutex.RUnlock()
+
+	for _, length := range cd.lengths {
+		if length > ip.BitLen() {
+			continue
+		}
+		prefix, err := ip.Prefix(length)
+		if err != nil {
+			continue
+		}
+		if country, ok := cd.networks[length][prefix]; ok {
+			return country, true
+		}
+	}
+	return "", false
+}
+
+// RefreshEvery reloads the CIDR file periodically until the returned function is called
+func (cd *CountryDatabase) RefreshEvery(interval time.Duration) (stop func()) {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				if err := cd.Reload(); err != nil {
+					log.Println("Error created when reloading the country database:", err.Error())
+				}
+			case <-done:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() { once.Do(func() { close(done) }) }
+}
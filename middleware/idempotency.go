@@ -0,0 +1,182 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"dcard-backend/domain"
+)
+
+const (
+	IdempotencyKeyHeader      = "Idempotency-Key"
+	IdempotentReplayedHeader  = "Idempotent-Replayed"
+	idempotencyMismatchReason = "Idempotency-Key is already used with a different request body"
+)
+
+type idempotencyRecord struct {
+	bodyHash    string
+	status      int
+	contentType string
+	body        []byte
+	expiresAt   time.Time
+	// done is closed once the first request of the key has finished
+	done chan struct{}
+}
+
+// storedResponse is a key whose response is stored, in the order the responses expire
+type storedResponse struct {
+	key    string
+	record *idempotencyRecord
+}
+
+// IdempotencyStore keeps the responses of requests sent with an Idempotency-Key for a TTL
+type IdempotencyStore struct {
+	ttl     time.Duration
+	now     func() time.Time
+	mutex   sync.Mutex
+	records map[string]*idempotencyRecord
+	// expiries holds the stored responses from the one expiring first. Every response is kept
+	// for the same TTL from when it is stored, so they are appended in the order they expire.
+	expiries []storedResponse
+}
+
+func NewIdempotencyStore(ttl time.Duration) *IdempotencyStore {
+	return &IdempotencyStore{
+		ttl:     ttl,
+		now:     time.Now,
+		records: map[string]*idempotencyRecord{},
+	}
+}
+
+// acquire returns the record of the key, and whether the caller owns it and has to run the request
+func (is *IdempotencyStore) acquire(key string, bodyHash string) (*idempotencyRecord, bool) {
+	is.mutex.Lock()
+	defer is.mutex.Unlock()
+
+	now := is.now()
+	is.expire(now)
+	if record, ok := is.records[key]; ok && (record.expiresAt.IsZero() || record.expiresAt.After(now)) {
+		return record, false
+	}
+
+	record := &idempotencyRecord{bodyHash: bodyHash, done: make(chan struct{})}
+	is.records[key] = record
+	return record, true
+}
+
+// expire drops the responses which expired by now, only looking at those that did
+func (is *IdempotencyStore) expire(now time.Time) {
+	for len(is.expiries) > 0 && !is.expiries[0].record.expiresAt.After(now) {
+		first := is.expiries[0]
+		// The key may have been used again since, which is kept
+		if is.records[first.key] == first.record {
+			delete(is.records, first.key)
+		}
+		is.expiries[0] = storedResponse{}
+		is.expiries = is.expiries[1:]
+	}
+}
+
+// release stores the response of the record, or forgets the key if the request failed
+// on the server side so that the client can retry it
+func (is *IdempotencyStore) release(key string, record *idempotencyRecord, status int, contentType string, body []byte) {
+	is.mutex.Lock()
+	defer is.mutex.Unlock()
+
+	if status >= http.StatusInternalServerError {
+		delete(is.records, key)
+	} else {
+		record.status = status
+		record.contentType = contentType
+		record.body = body
+		record.expiresAt = is.now().Add(is.ttl)
+		is.expiries = append(is.expiries, storedResponse{key: key, record: record})
+	}
+	close(record.done)
+}
+
+type bodyRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (br *bodyRecorder) Write(data []byte) (int, error) {
+	br.body.Write(data)
+	return br.ResponseWriter.Write(data)
+}
+
+func (br *bodyRecorder) WriteString(s string) (int, error) {
+	br.body.WriteString(s)
+	return br.ResponseWriter.WriteString(s)
+}
+
+// Idempotency replays the stored response of a request whose Idempotency-Key was seen before.
+// Concurrent requests with the same key wait for the first one, so the handler runs once.
+func Idempotency(store *IdempotencyStore) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		idempotencyKey := ctx.GetHeader(IdempotencyKeyHeader)
+		if idempotencyKey == "" {
+			ctx.Next()
+			return
+		}
+
+		body, err := io.ReadAll(ctx.Request.Body)
+		if err != nil {
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, domain.ErrorResponse{Message: err.Error()})
+			return
+		}
+		ctx.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		hash := sha256.Sum256(body)
+		bodyHash := hex.EncodeToString(hash[:])
+		// Keys are chosen by clients, so the same key of two tenants must not share a response
+		tenantID, _ := domain.TenantFromContext(ctx.Request.Context())
+		key := tenantID + " " + ctx.Request.Method + " " + ctx.FullPath() + " " + idempotencyKey
+
+		for {
+			record, owner := store.acquire(key, bodyHash)
+			if record.bodyHash != bodyHash {
+				ctx.AbortWithStatusJSON(http.StatusUnprocessableEntity, domain.ErrorResponse{Message: idempotencyMismatchReason})
+				return
+			}
+
+			if owner {
+				recorder := &bodyRecorder{ResponseWriter: ctx.Writer}
+				ctx.Writer = recorder
+
+				// A panicking handler is released as a server error, so the key can be retried
+				status := http.StatusInternalServerError
+				defer func() {
+					store.release(key, record, status, recorder.Header().Get("Content-Type"), recorder.body.Bytes())
+				}()
+				ctx.Next()
+				status = recorder.Status()
+				return
+			}
+
+			select {
+			case <-record.done:
+			case <-ctx.Request.Context().Done():
+				ctx.AbortWithStatusJSON(http.StatusServiceUnavailable, domain.ErrorResponse{Message: ctx.Request.Context().Err().Error()})
+				return
+			}
+
+			// The first request failed and its key was released, so this one runs it again
+			if record.status == 0 {
+				continue
+			}
+
+			ctx.Header(IdempotentReplayedHeader, "true")
+			ctx.Data(record.status, record.contentType, record.body)
+			ctx.Abort()
+			return
+		}
+	}
+}
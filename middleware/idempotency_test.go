@@ -0,0 +1,183 @@
+package middleware_test
+
+import (
+	"dcard-backend/middleware"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func newIdempotentApp(ttl time.Duration, handler gin.HandlerFunc) *gin.Engine {
+	app := gin.New()
+	app.POST("/api/v1/ad", middleware.Idempotency(middleware.NewIdempotencyStore(ttl)), handler)
+	return app
+}
+
+func postWithKey(app *gin.Engine, key string, body string) *httptest.ResponseRecorder {
+	httpRecorder := httptest.NewRecorder()
+	httpRequest := httptest.NewRequest(http.MethodPost, "/api/v1/ad", strings.NewReader(body))
+	httpRequest.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		httpRequest.Header.Set(middleware.IdempotencyKeyHeader, key)
+	}
+	app.ServeHTTP(httpRecorder, httpRequest)
+	return httpRecorder
+}
+
+func TestIdempotency_KeyNotProvided_ShouldRunEveryRequest(t *testing.T) {
+	var calls int32
+	app := newIdempotentApp(time.Hour, func(ctx *gin.Context) {
+		atomic.AddInt32(&calls, 1)
+		ctx.JSON(http.StatusOK, gin.H{"message": "ok"})
+	})
+
+	postWithKey(app, "", `{"title": "AD"}`)
+	postWithKey(app, "", `{"title": "AD"}`)
+
+	assert.Equal(t, int32(2), calls)
+}
+
+func TestIdempotency_SameKeyAndBody_ShouldReplayStoredResponse(t *testing.T) {
+	var calls int32
+	app := newIdempotentApp(time.Hour, func(ctx *gin.Context) {
+		atomic.AddInt32(&calls, 1)
+		ctx.JSON(http.StatusCreated, gin.H{"call": atomic.LoadInt32(&calls)})
+	})
+
+	first := postWithKey(app, "key", `{"title": "AD"}`)
+	second := postWithKey(app, "key", `{"title": "AD"}`)
+
+	assert.Equal(t, int32(1), calls)
+	assert.Equal(t, http.StatusCreated, second.Code)
+	assert.Equal(t, first.Body.String(), second.Body.String())
+	assert.Equal(t, first.Header().Get("Content-Type"), second.Header().Get("Content-Type"))
+	assert.Equal(t, "true", second.Header().Get(middleware.IdempotentReplayedHeader))
+}
+
+func TestIdempotency_SameKeyDifferentBody_ShouldReturnUnprocessableEntity(t *testing.T) {
+	app := newIdempotentApp(time.Hour, func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, gin.H{"message": "ok"})
+	})
+
+	postWithKey(app, "key", `{"title": "AD"}`)
+	httpRecorder := postWithKey(app, "key", `{"title": "Another AD"}`)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, httpRecorder.Code)
+}
+
+func TestIdempotency_ConcurrentDuplicates_ShouldRunHandlerOnce(t *testing.T) {
+	var calls int32
+	started := make(chan struct{})
+	unblock := make(chan struct{})
+	app := newIdempotentApp(time.Hour, func(ctx *gin.Context) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			close(started)
+		}
+		<-unblock
+		ctx.JSON(http.StatusOK, gin.H{"message": "ok"})
+	})
+
+	var wg sync.WaitGroup
+	responses := make([]*httptest.ResponseRecorder, 10)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		responses[0] = postWithKey(app, "key", `{"title": "AD"}`)
+	}()
+	<-started
+	for i := 1; i < len(responses); i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			responses[i] = postWithKey(app, "key", `{"title": "AD"}`)
+		}(i)
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(unblock)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), calls)
+	for _, response := range responses {
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Equal(t, responses[0].Body.String(), response.Body.String())
+	}
+}
+
+func TestIdempotency_ServerError_ShouldNotBeStored(t *testing.T) {
+	var calls int32
+	app := newIdempotentApp(time.Hour, func(ctx *gin.Context) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"message": "fail"})
+			return
+		}
+		ctx.JSON(http.StatusOK, gin.H{"message": "ok"})
+	})
+
+	postWithKey(app, "key", `{"title": "AD"}`)
+	httpRecorder := postWithKey(app, "key", `{"title": "AD"}`)
+
+	assert.Equal(t, int32(2), calls)
+	assert.Equal(t, http.StatusOK, httpRecorder.Code)
+	assert.Empty(t, httpRecorder.Header().Get(middleware.IdempotentReplayedHeader))
+}
+
+func TestIdempotency_TTLExpired_ShouldRunRequestAgain(t *testing.T) {
+	var calls int32
+	app := newIdempotentApp(10*time.Millisecond, func(ctx *gin.Context) {
+		atomic.AddInt32(&calls, 1)
+		ctx.JSON(http.StatusOK, gin.H{"message": "ok"})
+	})
+
+	postWithKey(app, "key", `{"title": "AD"}`)
+	time.Sleep(20 * time.Millisecond)
+	httpRecorder := postWithKey(app, "key", `{"title": "Another AD"}`)
+
+	assert.Equal(t, int32(2), calls)
+	assert.Equal(t, http.StatusOK, httpRecorder.Code)
+}
+
+func TestIdempotency_TTLExpired_ShouldDropExpiredKeysOnly(t *testing.T) {
+	store := middleware.NewIdempotencyStore(50 * time.Millisecond)
+	app := gin.New()
+	app.POST("/api/v1/ad", middleware.Idempotency(store), func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, gin.H{"message": "ok"})
+	})
+
+	postWithKey(app, "key-1", `{"title": "AD"}`)
+	postWithKey(app, "key-2", `{"title": "AD"}`)
+	time.Sleep(60 * time.Millisecond)
+	// key-1 is used again after it expired, and is kept when its first response is dropped
+	postWithKey(app, "key-1", `{"title": "AD"}`)
+	postWithKey(app, "key-3", `{"title": "AD"}`)
+
+	assert.Equal(t, 2, middleware.StoredKeys(store))
+	assert.Equal(t, "true", postWithKey(app, "key-1", `{"title": "AD"}`).Header().Get(middleware.IdempotentReplayedHeader))
+}
+
+func TestIdempotency_SameKeyOfAnotherTenant_ShouldNotReplayResponse(t *testing.T) {
+	var calls int32
+	app := gin.New()
+	app.POST("/api/v1/ad", middleware.Authenticate(map[string]string{"key-a": "team-a", "key-b": "team-b"}),
+		middleware.Idempotency(middleware.NewIdempotencyStore(time.Hour)), func(ctx *gin.Context) {
+			atomic.AddInt32(&calls, 1)
+			ctx.JSON(http.StatusOK, gin.H{"message": "ok"})
+		})
+
+	for _, apiKey := range []string{"key-a", "key-b"} {
+		httpRecorder := httptest.NewRecorder()
+		httpRequest := httptest.NewRequest(http.MethodPost, "/api/v1/ad", strings.NewReader(`{"title": "AD"}`))
+		httpRequest.Header.Set("Authorization", "Bearer "+apiKey)
+		httpRequest.Header.Set(middleware.IdempotencyKeyHeader, "key")
+		app.ServeHTTP(httpRecorder, httpRequest)
+
+		assert.Empty(t, httpRecorder.Header().Get(middleware.IdempotentReplayedHeader))
+	}
+	assert.Equal(t, int32(2), calls)
+}
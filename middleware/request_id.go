@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+
+	"dcard-backend/domain"
+)
+
+// RequestIDHeader carries the id of a request, which is returned in the response and recorded
+// in the audit log
+const RequestIDHeader = "X-Request-ID"
+
+// validRequestID limits the ids given by clients, since they end up in logs and the audit log
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,64}$`)
+
+func newRequestID() string {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(id)
+}
+
+// keepOrNewRequestID returns the id given by the client, or a new id when it is not valid
+func keepOrNewRequestID(requestID string) string {
+	if !validRequestID.MatchString(requestID) {
+		return newRequestID()
+	}
+	return requestID
+}
+
+// RequestID keeps the X-Request-ID header of a request, such as one set by a proxy, or gives
+// the request a new id when the header is missing or not valid
+func RequestID() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		requestID := keepOrNewRequestID(ctx.GetHeader(RequestIDHeader))
+		ctx.Header(RequestIDHeader, requestID)
+		ctx.Request = ctx.Request.WithContext(domain.WithRequestID(ctx.Request.Context(), requestID))
+		ctx.Next()
+	}
+}
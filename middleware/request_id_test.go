@@ -0,0 +1,48 @@
+package middleware_test
+
+import (
+	"dcard-backend/domain"
+	"dcard-backend/middleware"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// serveRequestID returns the response of a handler writing the request id of the request
+func serveRequestID(requestID string) *httptest.ResponseRecorder {
+	app := gin.New()
+	app.Use(middleware.RequestID())
+	app.GET("/api/v1/ad", func(ctx *gin.Context) {
+		ctx.String(http.StatusOK, domain.RequestIDFromContext(ctx.Request.Context()))
+	})
+
+	httpRecorder := httptest.NewRecorder()
+	httpRequest := httptest.NewRequest(http.MethodGet, "/api/v1/ad", nil)
+	if requestID != "" {
+		httpRequest.Header.Set(middleware.RequestIDHeader, requestID)
+	}
+	app.ServeHTTP(httpRecorder, httpRequest)
+	return httpRecorder
+}
+
+func TestRequestID_HeaderProvided_ShouldKeepRequestID(t *testing.T) {
+	httpRecorder := serveRequestID("edge-1234:abc")
+
+	assert.Equal(t, "edge-1234:abc", httpRecorder.Body.String())
+	assert.Equal(t, "edge-1234:abc", httpRecorder.Header().Get(middleware.RequestIDHeader))
+}
+
+func TestRequestID_HeaderMissingOrInvalid_ShouldGenerateRequestID(t *testing.T) {
+	for _, requestID := range []string{"", "id with spaces", "id\nwith\nnewlines", string(make([]byte, 65))} {
+		httpRecorder := serveRequestID(requestID)
+
+		generated := httpRecorder.Body.String()
+		assert.Regexp(t, `^[0-9a-f]{32}$`, generated)
+		assert.Equal(t, generated, httpRecorder.Header().Get(middleware.RequestIDHeader))
+	}
+
+	assert.NotEqual(t, serveRequestID("").Body.String(), serveRequestID("").Body.String())
+}
@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"dcard-backend/domain"
+)
+
+// prepareRPC sets up the context of a call, or rejects the call with an error
+type prepareRPC func(c context.Context) (context.Context, error)
+
+// contextStream is a stream whose context was set up by an interceptor
+type contextStream struct {
+	grpc.ServerStream
+	c context.Context
+}
+
+func (cs *contextStream) Context() context.Context {
+	return cs.c
+}
+
+func unaryInterceptor(prepare prepareRPC) grpc.UnaryServerInterceptor {
+	return func(c context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		c, err := prepare(c)
+		if err != nil {
+			return nil, err
+		}
+		return handler(c, req)
+	}
+}
+
+func streamInterceptor(prepare prepareRPC) grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		c, err := prepare(stream.Context())
+		if err != nil {
+			return err
+		}
+		return handler(srv, &contextStream{ServerStream: stream, c: c})
+	}
+}
+
+// firstMetadata returns the first value of a key of the metadata of a call
+func firstMetadata(c context.Context, key string) string {
+	if values := metadata.ValueFromIncomingContext(c, key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// rpcRequestID is RequestID for calls, where the id is in the x-request-id metadata and is sent
+// back in the header of the response
+func rpcRequestID(c context.Context) (context.Context, error) {
+	requestID := keepOrNewRequestID(firstMetadata(c, strings.ToLower(RequestIDHeader)))
+	if err := grpc.SetHeader(c, metadata.Pairs(strings.ToLower(RequestIDHeader), requestID)); err != nil {
+		return nil, err
+	}
+	return domain.WithRequestID(c, requestID), nil
+}
+
+// rpcAuthenticate is Authenticate for calls, where the API key is in the authorization metadata
+func rpcAuthenticate(apiKeys map[string]string) prepareRPC {
+	return func(c context.Context) (context.Context, error) {
+		tenantID, actor, reason := authenticate(apiKeys, firstMetadata(c, "authorization"))
+		if reason != "" {
+			return nil, status.Error(codes.Unauthenticated, reason)
+		}
+		return domain.WithTenant(domain.WithActor(c, actor), tenantID), nil
+	}
+}
+
+// logRPC logs a call once it ends, with its code, latency and request id
+func logRPC(c context.Context, method string, start time.Time, err error) {
+	log.Printf("[GRPC] %v | %-16s | %13v | %s | %s", start.Format("2006/01/02 - 15:04:05"), status.Code(err),
+		time.Since(start), domain.RequestIDFromContext(c), method)
+}
+
+// RPCInterceptors gives every call of the gRPC server a request id, logs it, and authenticates
+// it with the API keys as the admin API does, so the calls are made on behalf of the tenant of
+// the key
+func RPCInterceptors(apiKeys map[string]string) []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(
+			unaryInterceptor(rpcRequestID),
+			func(c context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+				start := time.Now()
+				resp, err := handler(c, req)
+				logRPC(c, info.FullMethod, start, err)
+				return resp, err
+			},
+			unaryInterceptor(rpcAuthenticate(apiKeys)),
+		),
+		grpc.ChainStreamInterceptor(
+			streamInterceptor(rpcRequestID),
+			func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+				start := time.Now()
+				err := handler(srv, stream)
+				logRPC(stream.Context(), info.FullMethod, start, err)
+				return err
+			},
+			streamInterceptor(rpcAuthenticate(apiKeys)),
+		),
+	}
+}
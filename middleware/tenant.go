@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"dcard-backend/domain"
+)
+
+const (
+	// SiteKeyHeader identifies the site of the tenant whose ads are served
+	SiteKeyHeader = "X-Site-Key"
+
+	missingAPIKeyReason  = "Authorization header should be Bearer followed by an API key"
+	invalidAPIKeyReason  = "API key is not valid"
+	invalidSiteKeyReason = SiteKeyHeader + " header should be the key of a site"
+)
+
+// withTenant runs the rest of the chain on behalf of the tenant, whose id is only ever
+// taken from a configured key, never from the request itself
+func withTenant(ctx *gin.Context, tenantID string) {
+	ctx.Request = ctx.Request.WithContext(domain.WithTenant(ctx.Request.Context(), tenantID))
+	ctx.Next()
+}
+
+// apiKeyActor identifies the holder of an API key in the audit log by a fingerprint of the key,
+// which never reveals the key itself
+func apiKeyActor(apiKey string) string {
+	fingerprint := sha256.Sum256([]byte(apiKey))
+	return "api-key:" + hex.EncodeToString(fingerprint[:6])
+}
+
+// authenticate returns the tenant and the actor of an Authorization header of Bearer and one of
+// the API keys, or the reason the header is not valid
+func authenticate(apiKeys map[string]string, authorization string) (tenantID string, actor string, reason string) {
+	apiKey, ok := strings.CutPrefix(authorization, "Bearer ")
+	if !ok || apiKey == "" {
+		return "", "", missingAPIKeyReason
+	}
+	tenantID, ok = apiKeys[apiKey]
+	if !ok {
+		return "", "", invalidAPIKeyReason
+	}
+	return tenantID, apiKeyActor(apiKey), ""
+}
+
+// Authenticate allows requests with an Authorization header of Bearer and one of the API keys,
+// which map to the tenant the request is made on behalf of. The holder of the key is the actor
+// of the request.
+func Authenticate(apiKeys map[string]string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		tenantID, actor, reason := authenticate(apiKeys, ctx.GetHeader("Authorization"))
+		if reason != "" {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, domain.ErrorResponse{Message: reason})
+			return
+		}
+		ctx.Request = ctx.Request.WithContext(domain.WithActor(ctx.Request.Context(), actor))
+		withTenant(ctx, tenantID)
+	}
+}
+
+// SiteTenant serves public requests on behalf of the tenant of the site key in the request.
+// Site keys are public, unlike API keys, so they only select the ads to serve.
+func SiteTenant(siteKeys map[string]string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		tenantID, ok := siteKeys[ctx.GetHeader(SiteKeyHeader)]
+		if !ok {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, domain.ErrorResponse{Message: invalidSiteKeyReason})
+			return
+		}
+		withTenant(ctx, tenantID)
+	}
+}
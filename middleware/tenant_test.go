@@ -0,0 +1,86 @@
+package middleware_test
+
+import (
+	"dcard-backend/domain"
+	"dcard-backend/middleware"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+var testAPIKeys = map[string]string{"secret-a": "team-a", "secret-b": "team-b"}
+
+// serveTenant returns the response of a handler writing the tenant of the request
+func serveTenant(handler gin.HandlerFunc, header string, value string) *httptest.ResponseRecorder {
+	app := gin.New()
+	app.GET("/api/v1/ad", handler, func(ctx *gin.Context) {
+		tenantID, err := domain.TenantFromContext(ctx.Request.Context())
+		if err != nil {
+			ctx.String(http.StatusInternalServerError, err.Error())
+			return
+		}
+		ctx.String(http.StatusOK, tenantID)
+	})
+
+	httpRecorder := httptest.NewRecorder()
+	httpRequest := httptest.NewRequest(http.MethodGet, "/api/v1/ad", nil)
+	if value != "" {
+		httpRequest.Header.Set(header, value)
+	}
+	app.ServeHTTP(httpRecorder, httpRequest)
+	return httpRecorder
+}
+
+func TestAuthenticate_ValidAPIKey_ShouldSetTenantOfKey(t *testing.T) {
+	httpRecorder := serveTenant(middleware.Authenticate(testAPIKeys), "Authorization", "Bearer secret-b")
+
+	assert.Equal(t, http.StatusOK, httpRecorder.Code)
+	assert.Equal(t, "team-b", httpRecorder.Body.String())
+}
+
+func TestAuthenticate_MissingOrInvalidAPIKey_ShouldReturnUnauthorized(t *testing.T) {
+	for _, authorization := range []string{"", "secret-a", "Bearer ", "Bearer team-a", "Basic secret-a"} {
+		httpRecorder := serveTenant(middleware.Authenticate(testAPIKeys), "Authorization", authorization)
+
+		assert.Equal(t, http.StatusUnauthorized, httpRecorder.Code, authorization)
+	}
+}
+
+func TestSiteTenant_ValidSiteKey_ShouldSetTenantOfSite(t *testing.T) {
+	httpRecorder := serveTenant(middleware.SiteTenant(map[string]string{"site-a": "team-a"}), middleware.SiteKeyHeader, "site-a")
+
+	assert.Equal(t, http.StatusOK, httpRecorder.Code)
+	assert.Equal(t, "team-a", httpRecorder.Body.String())
+}
+
+func TestSiteTenant_MissingOrUnknownSiteKey_ShouldReturnUnauthorized(t *testing.T) {
+	for _, siteKey := range []string{"", "team-a"} {
+		httpRecorder := serveTenant(middleware.SiteTenant(map[string]string{"site-a": "team-a"}), middleware.SiteKeyHeader, siteKey)
+
+		assert.Equal(t, http.StatusUnauthorized, httpRecorder.Code, siteKey)
+	}
+}
+
+func TestAuthenticate_ValidAPIKey_ShouldSetActorWithoutRevealingKey(t *testing.T) {
+	actors := map[string]string{}
+	for _, apiKey := range []string{"secret-a", "secret-b"} {
+		app := gin.New()
+		app.GET("/api/v1/ad", middleware.Authenticate(testAPIKeys), func(ctx *gin.Context) {
+			ctx.String(http.StatusOK, domain.ActorFromContext(ctx.Request.Context()))
+		})
+
+		httpRecorder := httptest.NewRecorder()
+		httpRequest := httptest.NewRequest(http.MethodGet, "/api/v1/ad", nil)
+		httpRequest.Header.Set("Authorization", "Bearer "+apiKey)
+		app.ServeHTTP(httpRecorder, httpRequest)
+
+		actor := httpRecorder.Body.String()
+		assert.Regexp(t, `^api-key:[0-9a-f]{12}$`, actor)
+		assert.NotContains(t, actor, apiKey)
+		actors[apiKey] = actor
+	}
+	assert.NotEqual(t, actors["secret-a"], actors["secret-b"])
+}
@@ -4,82 +4,471 @@ import (
 	"context"
 	"database/sql"
 	"dcard-backend/domain"
+	"encoding/json"
 	"fmt"
+	"slices"
 	"strings"
 )
 
+const (
+	insertAdCommand        = "INSERT INTO ads (tenant_id, campaign_id, title, title_key, start_at, end_at, age_start, age_end, schedule, description, image_url, click_url, call_to_action, frequency_cap, budget, priority, weight, inherited) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+	updateAdCommand        = "UPDATE ads SET campaign_id = ?, title = ?, title_key = ?, start_at = ?, end_at = ?, age_start = ?, age_end = ?, schedule = ?, description = ?, image_url = ?, click_url = ?, call_to_action = ?, frequency_cap = ?, budget = ?, priority = ?, weight = ?, inherited = ?, version = ?, exhausted_at = NULL WHERE id = ? AND tenant_id = ?"
+	updateAdStatusCommand  = "UPDATE ads SET status = ? WHERE id = ? AND tenant_id = ?"
+	softDeleteAdCommand    = "UPDATE ads SET deleted_at = CURRENT_TIMESTAMP WHERE id = ? AND tenant_id = ?"
+	restoreAdCommand       = "UPDATE ads SET deleted_at = NULL WHERE id = ? AND tenant_id = ?"
+	deleteCreativesCommand = "DELETE FROM ad_creatives WHERE ad_id = ?"
+	insertCreativeCommand  = "INSERT INTO ad_creatives (ad_id, platform_id, description, image_url, click_url, call_to_action) VALUES (?, (SELECT id FROM platforms WHERE platform = ?), ?, ?, ?, ?)"
+	advertiserWhereCommand = "ads.campaign_id IN (SELECT id FROM campaigns WHERE advertiser_id = ?)"
+	// adsOfTenantWhereCommand scopes ads to the tenant, leaving out soft deleted ads
+	adsOfTenantWhereCommand = "WHERE ads.tenant_id = ? AND ads.deleted_at IS NULL "
+	// lockTitleCommand locks the title of the tenant until the transaction ends
+	lockTitleCommand       = "INSERT INTO ad_title_locks (tenant_id, title_key) VALUES (?, ?) ON DUPLICATE KEY UPDATE title_key = title_key"
+	selectConflictsCommand = "SELECT ads.id, ads.title FROM ads " + adsOfTenantWhereCommand + "AND ads.title_key = ? AND ads.start_at <= ? AND ads.end_at >= ? AND ads.id <> ?"
+	selectCreativesCommand = "SELECT ad_creatives.ad_id, platforms.platform, ad_creatives.description, ad_creatives.image_url, ad_creatives.click_url, ad_creatives.call_to_action " +
+		"FROM ad_creatives INNER JOIN platforms ON platforms.id = ad_creatives.platform_id INNER JOIN ads ON ads.id = ad_creatives.ad_id "
+)
+
 type adRepository struct {
-	database *sql.DB
+	database   *sql.DB
+	statements *statementCache
+}
+
+type AdRepositoryOption func(*adRepository)
+
+// WithMaxCachedStatements sets the number of prepared statements kept on the server, which
+// are prepared on every connection using them and count towards max_prepared_stmt_count of
+// MySQL. Without it, DefaultMaxCachedStatements are kept.
+func WithMaxCachedStatements(maxStatements int) AdRepositoryOption {
+	return func(ar *adRepository) {
+		ar.statements = newStatementCache(ar.database, maxStatements)
+	}
+}
+
+func NewAdRepository(db *sql.DB, options ...AdRepositoryOption) domain.AdRepository {
+	ar := &adRepository{
+		database:   db,
+		statements: newStatementCache(db, DefaultMaxCachedStatements),
+	}
+	for _, option := range options {
+		option(ar)
+	}
+	return ar
+}
+
+func bindAndExec(c context.Context, tx *sql.Tx, stmt *sql.Stmt, args ...interface{}) (sql.Result, error) {
+	txStmt := tx.StmtContext(c, stmt)
+	defer txStmt.Close()
+
+	return txStmt.ExecContext(c, args...)
+}
+
+// insertLinkRows links an ad to the included values and then to the excluded values of a targeting
+func insertLinkRows(c context.Context, tx *sql.Tx, stmt *sql.Stmt, adId int64, values []string, excludedValues []string) error {
+	for _, value := range values {
+		if _, err := bindAndExec(c, tx, stmt, adId, value, false); err != nil {
+			return err
+		}
+	}
+	for _, value := range excludedValues {
+		if _, err := bindAndExec(c, tx, stmt, adId, value, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// targetingStatements insert the targeting and the creatives of an ad. They are prepared
+// before beginning the transaction, so that tx.Stmt can reuse them on the connection of the
+// transaction instead of holding a second connection.
+type targetingStatements struct {
+	links    []*sql.Stmt
+	creative *sql.Stmt
+	releases []func()
+}
+
+func (ar *adRepository) prepareTargeting(c context.Context, ad *domain.Ad) (*targetingStatements, error) {
+	statements := &targetingStatements{}
+	for _, dimension := range domain.Dimensions() {
+		stmt, release, err := ar.statements.prepare(c, insertLinkCommand(dimension))
+		if err != nil {
+			statements.release()
+			return nil, err
+		}
+		statements.releases = append(statements.releases, release)
+		statements.links = append(statements.links, stmt)
+	}
+
+	// Most ads only have the default creative, so the statement of the variants is prepared on demand
+	if len(ad.Creatives) > 0 {
+		stmt, release, err := ar.statements.prepare(c, insertCreativeCommand)
+		if err != nil {
+			statements.release()
+			return nil, err
+		}
+		statements.releases = append(statements.releases, release)
+		statements.creative = stmt
+	}
+	return statements, nil
+}
+
+func (ts *targetingStatements) release() {
+	for _, release := range ts.releases {
+		release()
+	}
+}
+
+func (ts *targetingStatements) insert(c context.Context, tx *sql.Tx, adId int64, ad *domain.Ad) error {
+	for i, dimension := range domain.Dimensions() {
+		err := insertLinkRows(c, tx, ts.links[i], adId, *dimension.Values(ad.Condition), *dimension.ExcludedValues(ad.Condition))
+		if err != nil {
+			fmt.Printf("Error created when inserting into %s: %s\n", dimension.LinkTable, err.Error())
+			return err
+		}
+	}
+
+	for _, creative := range ad.Creatives {
+		_, err := bindAndExec(c, tx, ts.creative, adId, creative.Platform,
+			creative.Description, creative.ImageURL, creative.ClickURL, creative.CallToAction)
+		if err != nil {
+			fmt.Println("Error created when inserting into ad_creatives:", err.Error())
+			return err
+		}
+	}
+	return nil
 }
 
-func NewAdRepository(db *sql.DB) domain.AdRepository {
-	return &adRepository{
-		database: db,
+// deleteTargeting deletes the targeting and the creatives of an ad
+func deleteTargeting(c context.Context, tx *sql.Tx, adId int64) error {
+	for _, dimension := range domain.Dimensions() {
+		if _, err := tx.ExecContext(c, deleteLinkCommand(dimension), adId); err != nil {
+			return err
+		}
 	}
+	_, err := tx.ExecContext(c, deleteCreativesCommand, adId)
+	return err
 }
 
-func prepareAndCreate(c context.Context, tx *sql.Tx, command string, args ...interface{}) (sql.Result, error) {
-	stmt, err := tx.Prepare(command)
+// adValues returns the values of the columns of ads from campaign_id to inherited
+func adValues(ad *domain.Ad) ([]interface{}, error) {
+	schedule, err := marshalJSONColumn(ad.Condition.Schedule)
+	if err != nil {
+		return nil, err
+	}
+	frequencyCap, err := marshalJSONColumn(ad.FrequencyCap)
+	if err != nil {
+		return nil, err
+	}
+	budget, err := marshalJSONColumn(ad.Budget)
+	if err != nil {
+		return nil, err
+	}
+	// Ads which inherit nothing keep NULL, like the other optional JSON columns
+	var inheritedFields *[]string
+	if len(ad.Inherited) > 0 {
+		inheritedFields = &ad.Inherited
+	}
+	inherited, err := marshalJSONColumn(inheritedFields)
 	if err != nil {
 		return nil, err
 	}
+	return []interface{}{nullableID(ad.CampaignID), ad.Title, domain.NormalizeTitle(ad.Title), ad.StartAt, ad.EndAt, ad.Condition.AgeStart, ad.Condition.AgeEnd, schedule,
+		ad.Description, ad.ImageURL, ad.ClickURL, ad.CallToAction, frequencyCap, budget, ad.Priority, ad.Weight, inherited}, nil
+}
 
-	result, err := stmt.Exec(args...)
-	return result, err
+func (ar *adRepository) inTransaction(c context.Context, fn func(tx *sql.Tx) error) error {
+	return inTransaction(c, ar.database, fn)
 }
 
-func (ar *adRepository) Create(c context.Context, ad *domain.Ad) error {
-	tx, err := ar.database.Begin()
+// inTransaction runs fn in a transaction, which is committed when fn succeeds and rolled back otherwise
+func inTransaction(c context.Context, db *sql.DB, fn func(tx *sql.Tx) error) (err error) {
+	tx, err := db.BeginTx(c, nil)
 	if err != nil {
 		return err
 	}
 
 	defer func() {
-		switch err {
-		case nil:
-			err = tx.Commit()
-		default:
+		if err != nil {
 			tx.Rollback()
+			return
 		}
+		err = tx.Commit()
 	}()
 
-	command := "INSERT INTO ads (title, start_at, end_at, age_start, age_end) VALUES (?, ?, ?, ?, ?)"
-	result, err := prepareAndCreate(c, tx, command, ad.Title, ad.StartAt, ad.EndAt, ad.Condition.AgeStart, ad.Condition.AgeEnd)
+	return fn(tx)
+}
+
+// checkConflict runs the conflict check on the other ads of the tenant with the title of the ad
+// whose time window overlaps, once no other transaction writes an ad with the title
+func checkConflict(c context.Context, tx *sql.Tx, tenantID string, ad *domain.Ad, check domain.ConflictCheck) error {
+	if check.Resolve == nil {
+		return nil
+	}
+
+	titleKey := domain.NormalizeTitle(ad.Title)
+	if _, err := tx.ExecContext(c, lockTitleCommand, tenantID, titleKey); err != nil {
+		return err
+	}
+
+	command, args := selectConflictsCommand, []interface{}{tenantID, titleKey, ad.EndAt, ad.StartAt, ad.ID}
+	if check.AdvertiserID != 0 {
+		command += " AND " + advertiserWhereCommand
+		args = append(args, check.AdvertiserID)
+	}
+	rows, err := tx.QueryContext(c, command+" ORDER BY ads.id ASC", args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	conflicting := []domain.Ad{}
+	for rows.Next() {
+		var candidate domain.Ad
+		if err := rows.Scan(&candidate.ID, &candidate.Title); err != nil {
+			return err
+		}
+		conflicting = append(conflicting, candidate)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	return check.Resolve(conflicting)
+}
+
+func (ar *adRepository) Create(c context.Context, ad *domain.Ad, check domain.ConflictCheck) error {
+	tenantID, err := domain.TenantFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	adStmt, release, err := ar.statements.prepare(c, insertAdCommand)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	statements, err := ar.prepareTargeting(c, ad)
+	if err != nil {
+		return err
+	}
+	defer statements.release()
+
+	var after domain.Ad
+	err = ar.inTransaction(c, func(tx *sql.Tx) error {
+		if err := checkConflict(c, tx, tenantID, ad, check); err != nil {
+			return err
+		}
+
+		values, err := adValues(ad)
+		if err != nil {
+			return err
+		}
+
+		result, err := bindAndExec(c, tx, adStmt, append([]interface{}{tenantID}, values...)...)
+		if err != nil {
+			fmt.Println("Error created when inserting into ads:", err.Error())
+			return err
+		}
+
+		adId, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+
+		if err := statements.insert(c, tx, adId, ad); err != nil {
+			return err
+		}
+
+		after = *ad
+		after.ID, after.Status, after.Version, after.Lifecycle = adId, domain.AdActive, 1, domain.AdScheduled
+		if err := insertVersion(c, tx, tenantID, after, 0); err != nil {
+			return err
+		}
+		return recordChange(c, tx, tenantID, adId, domain.AuditCreate, nil, &after)
+	})
+	if err != nil {
+		return err
+	}
+
+	// The caller gets the id, status, version and lifecycle of the created ad
+	ad.ID, ad.Status, ad.Version, ad.Lifecycle = after.ID, after.Status, after.Version, after.Lifecycle
+	return nil
+}
+
+// lockAd reads an ad of the tenant in the transaction, and locks it until the transaction ends
+func lockAd(c context.Context, tx *sql.Tx, tenantID string, id int64) (domain.Ad, error) {
+	return lockAdWhere(c, tx, adsOfTenantWhereCommand+"AND ads.id = ? FOR UPDATE", tenantID, id)
+}
+
+// lockDeletedAd is lockAd for a soft deleted ad
+func lockDeletedAd(c context.Context, tx *sql.Tx, tenantID string, id int64) (domain.Ad, error) {
+	return lockAdWhere(c, tx, "WHERE ads.tenant_id = ? AND ads.deleted_at IS NOT NULL AND ads.id = ? FOR UPDATE", tenantID, id)
+}
+
+func lockAdWhere(c context.Context, tx *sql.Tx, whereCommand string, tenantID string, id int64) (domain.Ad, error) {
+	ads, err := queryAdsWithTargeting(c, tx, tenantID, selectAdsWithTargetingCommand()+whereCommand, tenantID, id)
+	if err != nil {
+		return domain.Ad{}, err
+	}
+	if len(ads) == 0 {
+		return domain.Ad{}, domain.ErrNotFound
+	}
+	return ads[0], nil
+}
+
+func (ar *adRepository) GetByID(c context.Context, id int64) (domain.Ad, error) {
+	tenantID, err := domain.TenantFromContext(c)
+	if err != nil {
+		return domain.Ad{}, err
+	}
+
+	ads, err := queryAdsWithTargeting(c, ar.database, tenantID, selectAdsWithTargetingCommand()+adsOfTenantWhereCommand+"AND ads.id = ?", tenantID, id)
+	if err != nil {
+		return domain.Ad{}, err
+	}
+	if len(ads) == 0 {
+		return domain.Ad{}, domain.ErrNotFound
+	}
+	return ads[0], nil
+}
+
+// Update replaces every field of an ad except its status, along with its targeting and creatives
+func (ar *adRepository) Update(c context.Context, ad *domain.Ad, check domain.ConflictCheck) error {
+	return ar.replace(c, ad, domain.AuditUpdate, 0, check)
+}
+
+// Rollback replaces an ad with the configuration of one of its versions, which is kept as a
+// new version restored from it
+func (ar *adRepository) Rollback(c context.Context, ad *domain.Ad, version int64, check domain.ConflictCheck) error {
+	return ar.replace(c, ad, domain.AuditRollback, version, check)
+}
+
+// replace replaces an ad at one of the versions of ad.IfMatch, or at any version when it is
+// empty, and keeps it as the next version
+func (ar *adRepository) replace(c context.Context, ad *domain.Ad, action domain.AuditAction, restoredFrom int64, check domain.ConflictCheck) error {
+	tenantID, err := domain.TenantFromContext(c)
 	if err != nil {
-		fmt.Println("Error created when inserting into ads:", err.Error())
 		return err
 	}
 
-	adId, err := result.LastInsertId()
+	statements, err := ar.prepareTargeting(c, ad)
 	if err != nil {
 		return err
 	}
+	defer statements.release()
+
+	return ar.inTransaction(c, func(tx *sql.Tx) error {
+		before, err := lockAd(c, tx, tenantID, ad.ID)
+		if err != nil {
+			return err
+		}
+		if len(ad.IfMatch) > 0 && !slices.Contains(ad.IfMatch, before.Version) {
+			return fmt.Errorf("%w: ad %d is at version %d", domain.ErrPreconditionFailed, ad.ID, before.Version)
+		}
+		version := before.Version + 1
 
-	command = "INSERT INTO ad_gender (ad_id, gender_id) VALUES (?, (SELECT id FROM genders WHERE gender = ?))"
-	for _, gender := range ad.Condition.Gender {
-		if _, err = prepareAndCreate(c, tx, command, adId, gender); err != nil {
-			fmt.Println("Error created when inserting into ad_gender:", err.Error())
+		if err := checkConflict(c, tx, tenantID, ad, check); err != nil {
 			return err
 		}
+
+		values, err := adValues(ad)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(c, updateAdCommand, append(values, version, ad.ID, tenantID)...); err != nil {
+			return toConstraintError(err)
+		}
+
+		if err := deleteTargeting(c, tx, ad.ID); err != nil {
+			return err
+		}
+		if err := statements.insert(c, tx, ad.ID, ad); err != nil {
+			return err
+		}
+
+		ad.Version, ad.Status, ad.Lifecycle = version, before.Status, before.Lifecycle
+		if err := insertVersion(c, tx, tenantID, *ad, restoredFrom); err != nil {
+			return err
+		}
+		after := *ad
+		return recordChange(c, tx, tenantID, ad.ID, action, &before, &after)
+	})
+}
+
+// UpdateStatus pauses or resumes an ad, which is not recorded when the ad already has the status
+func (ar *adRepository) UpdateStatus(c context.Context, id int64, status domain.AdStatus) error {
+	tenantID, err := domain.TenantFromContext(c)
+	if err != nil {
+		return err
 	}
 
-	command = "INSERT INTO ad_country (ad_id, country_id) VALUES (?, (SELECT id FROM countries WHERE country = ?))"
-	for _, country := range ad.Condition.Country {
-		if _, err = prepareAndCreate(c, tx, command, adId, country); err != nil {
-			fmt.Println("Error created when inserting into ad_country:", err.Error())
+	return ar.inTransaction(c, func(tx *sql.Tx) error {
+		before, err := lockAd(c, tx, tenantID, id)
+		if err != nil || before.Status == status {
+			return err
+		}
+
+		if _, err := tx.ExecContext(c, updateAdStatusCommand, status, id, tenantID); err != nil {
 			return err
 		}
+
+		action := domain.AuditResume
+		if status == domain.AdPaused {
+			action = domain.AuditPause
+		}
+		after := before
+		after.Status = status
+		return recordChange(c, tx, tenantID, id, action, &before, &after)
+	})
+}
+
+// Delete soft deletes an ad, which keeps its rows until it is purged
+func (ar *adRepository) Delete(c context.Context, id int64) error {
+	tenantID, err := domain.TenantFromContext(c)
+	if err != nil {
+		return err
 	}
 
-	command = "INSERT INTO ad_platform (ad_id, platform_id) VALUES (?, (SELECT id FROM platforms WHERE platform = ?))"
-	for _, platform := range ad.Condition.Platform {
-		if _, err = prepareAndCreate(c, tx, command, adId, platform); err != nil {
-			fmt.Println("Error created when inserting into ad_platform:", err.Error())
+	return ar.inTransaction(c, func(tx *sql.Tx) error {
+		before, err := lockAd(c, tx, tenantID, id)
+		if err != nil {
 			return err
 		}
+
+		if _, err := tx.ExecContext(c, softDeleteAdCommand, id, tenantID); err != nil {
+			return err
+		}
+
+		return recordChange(c, tx, tenantID, id, domain.AuditDelete, &before, nil)
+	})
+}
+
+// Restore undoes the soft delete of an ad, and returns domain.ErrNotFound when the ad is not deleted
+func (ar *adRepository) Restore(c context.Context, id int64) error {
+	tenantID, err := domain.TenantFromContext(c)
+	if err != nil {
+		return err
 	}
 
-	return err
+	return ar.inTransaction(c, func(tx *sql.Tx) error {
+		after, err := lockDeletedAd(c, tx, tenantID, id)
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(c, restoreAdCommand, id, tenantID); err != nil {
+			return err
+		}
+
+		return recordChange(c, tx, tenantID, id, domain.AuditRestore, nil, &after)
+	})
+}
+
+// nullableID returns the value of a nullable foreign key, which is NULL when id is 0
+func nullableID(id int64) interface{} {
+	if id == 0 {
+		return nil
+	}
+	return id
 }
 
 func repeatQuestionMarks(length int) string {
@@ -95,31 +484,31 @@ func stringSliceToGenericSlice(slice []string) []interface{} {
 }
 
 func (ar *adRepository) GetByCondition(c context.Context, condition map[string][]string) ([]domain.Ad, error) {
+	tenantID, err := domain.TenantFromContext(c)
+	if err != nil {
+		return nil, err
+	}
+
 	var args []string
 	innerJoinCommands, whereCommands := []string{}, []string{}
 
-	// Gender condition
-	if values, ok := condition["gender"]; ok {
-		innerJoinCommands = append(innerJoinCommands,
-			"INNER JOIN ad_gender ON ads.id = ad_gender.ad_id INNER JOIN genders ON genders.id = ad_gender.gender_id")
-		whereCommands = append(whereCommands, "genders.gender IN ("+repeatQuestionMarks(len(values))+")")
-		args = append(args, values...)
-	}
-
-	// Country condition
-	if values, ok := condition["country"]; ok {
-		innerJoinCommands = append(innerJoinCommands,
-			"INNER JOIN ad_country ON ads.id = ad_country.ad_id INNER JOIN countries ON countries.id = ad_country.country_id")
-		whereCommands = append(whereCommands, "countries.country IN ("+repeatQuestionMarks(len(values))+")")
-		args = append(args, values...)
+	// Targeting conditions, such as gender, country and platform
+	for _, dimension := range domain.Dimensions() {
+		if values, ok := condition[dimension.Name]; ok {
+			values = padValues(values)
+			innerJoinCommands = append(innerJoinCommands, includeJoinCommand(dimension))
+			whereCommands = append(whereCommands, includeWhereCommand(dimension, len(values)))
+			args = append(args, values...)
+		}
 	}
 
-	// Platform condition
-	if values, ok := condition["platform"]; ok {
-		innerJoinCommands = append(innerJoinCommands,
-			"INNER JOIN ad_platform ON ads.id = ad_platform.ad_id INNER JOIN platforms ON platforms.id = ad_platform.platform_id")
-		whereCommands = append(whereCommands, "platforms.platform IN ("+repeatQuestionMarks(len(values))+")")
-		args = append(args, values...)
+	// Exclusion of the targeting conditions, which overrides the included values
+	for _, dimension := range domain.Dimensions() {
+		if values, ok := condition[dimension.Name]; ok {
+			values = padValues(values)
+			whereCommands = append(whereCommands, excludeWhereCommand(dimension, len(values)))
+			args = append(args, values...)
+		}
 	}
 
 	// Age condition
@@ -131,34 +520,306 @@ func (ar *adRepository) GetByCondition(c context.Context, condition map[string][
 	// Time condition
 	whereCommands = append(whereCommands, "ads.start_at <= NOW() AND ads.end_at >= NOW()")
 
-	// Set limit and offset
-	args = append(args, condition["limit"][0], condition["offset"][0])
+	// Tenant condition, where soft deleted ads are left out
+	whereCommands = append(whereCommands, "ads.tenant_id = ? AND ads.deleted_at IS NULL")
+	args = append(args, tenantID)
+
+	// Paused ads and ads of paused campaigns are not served
+	whereCommands = append(whereCommands, "ads.status = 'active'")
+	whereCommands = append(whereCommands, "(ads.campaign_id IS NULL OR ads.campaign_id IN (SELECT id FROM campaigns WHERE status = 'active'))")
 
-	command := "SELECT ads.title, ads.end_at FROM ads "
+	// Advertiser condition
+	if values, ok := condition["advertiserId"]; ok {
+		whereCommands = append(whereCommands, advertiserWhereCommand)
+		args = append(args, values[0])
+	}
+
+	command := "SELECT ads.id, ads.title, ads.start_at, ads.end_at, ads.description, ads.image_url, ads.click_url, ads.call_to_action, " +
+		"ads.schedule, ads.frequency_cap, ads.budget, ads.priority, ads.weight FROM ads "
 	command += strings.Join(innerJoinCommands, " ") + " "
 	command += "WHERE " + strings.Join(whereCommands, " AND ") + " "
-	command += "ORDER BY ads.end_at ASC LIMIT ? OFFSET ?"
+	// Ads are read ending soonest first, or of the highest priority first when sorted by priority,
+	// and by id within them so that the pages of a query do not overlap
+	if values, ok := condition["sort"]; ok && values[0] == domain.SortPriority {
+		command += "ORDER BY ads.priority DESC, ads.end_at ASC, ads.id ASC"
+	} else {
+		command += "ORDER BY ads.end_at ASC, ads.id ASC"
+	}
+
+	// Set limit and offset, without which every matching ad is returned
+	if limit, ok := condition["limit"]; ok {
+		command += " LIMIT ? OFFSET ?"
+		offset := []string{"0"}
+		if value, ok := condition["offset"]; ok {
+			offset = value
+		}
+		args = append(args, limit[0], offset[0])
+	}
 
-	stmt, err := ar.database.PrepareContext(c, command)
+	stmt, release, err := ar.statements.prepare(c, command)
 	if err != nil {
 		return nil, err
 	}
+	defer release()
 
-	rows, err := stmt.Query(stringSliceToGenericSlice(args)...)
+	rows, err := stmt.QueryContext(c, stringSliceToGenericSlice(args)...)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
 	var ads []domain.Ad
 	for rows.Next() {
 		var ad domain.Ad
-		if err := rows.Scan(&ad.Title, &ad.EndAt); err != nil {
+		var schedule, frequencyCap, budget sql.NullString
+		err := rows.Scan(&ad.ID, &ad.Title, &ad.StartAt, &ad.EndAt, &ad.Description, &ad.ImageURL, &ad.ClickURL, &ad.CallToAction,
+			&schedule, &frequencyCap, &budget, &ad.Priority, &ad.Weight)
+		if err != nil {
+			return nil, err
+		}
+		if schedule.Valid {
+			ad.Condition = &domain.Condition{}
+			if ad.Condition.Schedule, err = unmarshalJSONColumn[domain.Schedule](schedule); err != nil {
+				return nil, err
+			}
+		}
+		if ad.FrequencyCap, err = unmarshalJSONColumn[domain.FrequencyCap](frequencyCap); err != nil {
+			return nil, err
+		}
+		if ad.Budget, err = unmarshalJSONColumn[domain.Budget](budget); err != nil {
+			return nil, err
+		}
+		ads = append(ads, ad)
+	}
+	return ads, rows.Err()
+}
+
+func scanCreatives(rows *sql.Rows) (map[int64][]domain.Creative, error) {
+	defer rows.Close()
+
+	creatives := map[int64][]domain.Creative{}
+	for rows.Next() {
+		var adId int64
+		var creative domain.Creative
+		err := rows.Scan(&adId, &creative.Platform, &creative.Description, &creative.ImageURL, &creative.ClickURL, &creative.CallToAction)
+		if err != nil {
+			return nil, err
+		}
+		creatives[adId] = append(creatives[adId], creative)
+	}
+	return creatives, rows.Err()
+}
+
+func adIDsToGenericSlice(adIDs []int64) []interface{} {
+	genericSlice := make([]interface{}, len(adIDs))
+	for i, v := range adIDs {
+		genericSlice[i] = v
+	}
+	return genericSlice
+}
+
+// GetCreativesByPlatform returns the creative of each ad for the platform, where ads without
+// a variant for the platform are absent
+func (ar *adRepository) GetCreativesByPlatform(c context.Context, adIDs []int64, platform string) (map[int64]domain.Creative, error) {
+	tenantID, err := domain.TenantFromContext(c)
+	if err != nil {
+		return nil, err
+	}
+	if len(adIDs) == 0 {
+		return map[int64]domain.Creative{}, nil
+	}
+
+	adIDs = padValues(adIDs)
+	command := selectCreativesCommand + "WHERE ads.tenant_id = ? AND platforms.platform = ? AND ad_creatives.ad_id IN (" + repeatQuestionMarks(len(adIDs)) + ")"
+	stmt, release, err := ar.statements.prepare(c, command)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	rows, err := stmt.QueryContext(c, append([]interface{}{tenantID, platform}, adIDsToGenericSlice(adIDs)...)...)
+	if err != nil {
+		return nil, err
+	}
+	creatives, err := scanCreatives(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[int64]domain.Creative, len(creatives))
+	for adId, adCreatives := range creatives {
+		result[adId] = adCreatives[0]
+	}
+	return result, nil
+}
+
+// marshalJSONColumn returns the value of a JSON column, such as the schedule, which is NULL
+// without a value
+func marshalJSONColumn[T any](value *T) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return string(encoded), nil
+}
+
+func unmarshalJSONColumn[T any](value sql.NullString) (*T, error) {
+	if !value.Valid {
+		return nil, nil
+	}
+	var decoded T
+	if err := json.Unmarshal([]byte(value.String), &decoded); err != nil {
+		return nil, err
+	}
+	return &decoded, nil
+}
+
+func splitGroupConcat(value sql.NullString) []string {
+	if !value.Valid || value.String == "" {
+		return []string{}
+	}
+	return strings.Split(value.String, ",")
+}
+
+// splitOptionalGroupConcat is splitGroupConcat for optional values, which stay nil when absent
+func splitOptionalGroupConcat(value sql.NullString) []string {
+	if !value.Valid || value.String == "" {
+		return nil
+	}
+	return strings.Split(value.String, ",")
+}
+
+// selectAdsWithTargetingCommand selects every column of ads along with its included and
+// excluded values of each targeting dimension
+func selectAdsWithTargetingCommand() string {
+	var includeCommands, excludeCommands []string
+	for _, dimension := range domain.Dimensions() {
+		includeCommands = append(includeCommands, groupConcatCommand(dimension, 0))
+		excludeCommands = append(excludeCommands, groupConcatCommand(dimension, 1))
+	}
+	return "SELECT ads.id, ads.campaign_id, ads.title, ads.start_at, ads.end_at, ads.age_start, ads.age_end, ads.schedule, " +
+		"ads.description, ads.image_url, ads.click_url, ads.call_to_action, ads.frequency_cap, ads.budget, ads.priority, ads.weight, ads.inherited, ads.status, ads.version, ads.lifecycle, " +
+		strings.Join(append(includeCommands, excludeCommands...), ", ") + " FROM ads "
+}
+
+// queryer runs queries on the database or in a transaction
+type queryer interface {
+	QueryContext(c context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// queryAdsWithTargeting runs a query of selectAdsWithTargetingCommand, and adds the creatives
+// of the returned ads, which are read within the tenant
+func queryAdsWithTargeting(c context.Context, q queryer, tenantID string, command string, args ...interface{}) ([]domain.Ad, error) {
+	rows, err := q.QueryContext(c, command, args...)
+	if err != nil {
+		return nil, err
+	}
+	ads, err := scanAdsWithTargeting(rows)
+	if err != nil || len(ads) == 0 {
+		return ads, err
+	}
+
+	// Creatives of the ads are queried after the ads, because joining them would repeat the
+	// targeting values of an ad for each of its creatives
+	adIDs := make([]int64, len(ads))
+	for i, ad := range ads {
+		adIDs[i] = ad.ID
+	}
+	command = selectCreativesCommand + "WHERE ads.tenant_id = ? AND ad_creatives.ad_id IN (" + repeatQuestionMarks(len(adIDs)) + ") " +
+		"ORDER BY ad_creatives.ad_id ASC, platforms.platform ASC"
+	rows, err = q.QueryContext(c, command, append([]interface{}{tenantID}, adIDsToGenericSlice(adIDs)...)...)
+	if err != nil {
+		return nil, err
+	}
+	creatives, err := scanCreatives(rows)
+	if err != nil {
+		return nil, err
+	}
+	for i := range ads {
+		ads[i].Creatives = creatives[ads[i].ID]
+	}
+	return ads, nil
+}
+
+func scanAdsWithTargeting(rows *sql.Rows) ([]domain.Ad, error) {
+	defer rows.Close()
+
+	dimensions := domain.Dimensions()
+	ads := []domain.Ad{}
+	for rows.Next() {
+		ad := domain.Ad{Condition: &domain.Condition{}}
+		var schedule, frequencyCap, budget, inherited sql.NullString
+		values := make([]sql.NullString, 2*len(dimensions))
+		var campaignID sql.NullInt64
+		dest := []interface{}{&ad.ID, &campaignID, &ad.Title, &ad.StartAt, &ad.EndAt, &ad.Condition.AgeStart, &ad.Condition.AgeEnd, &schedule,
+			&ad.Description, &ad.ImageURL, &ad.ClickURL, &ad.CallToAction, &frequencyCap, &budget,
+			&ad.Priority, &ad.Weight, &inherited, &ad.Status, &ad.Version, &ad.Lifecycle}
+		for i := range values {
+			dest = append(dest, &values[i])
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		for i, dimension := range dimensions {
+			*dimension.Values(ad.Condition) = splitGroupConcat(values[i])
+			*dimension.ExcludedValues(ad.Condition) = splitOptionalGroupConcat(values[len(dimensions)+i])
+		}
+		ad.CampaignID = campaignID.Int64
+		var err error
+		if ad.Condition.Schedule, err = unmarshalJSONColumn[domain.Schedule](schedule); err != nil {
+			return nil, err
+		}
+		if ad.FrequencyCap, err = unmarshalJSONColumn[domain.FrequencyCap](frequencyCap); err != nil {
+			return nil, err
+		}
+		if ad.Budget, err = unmarshalJSONColumn[domain.Budget](budget); err != nil {
 			return nil, err
 		}
+		inheritedFields, err := unmarshalJSONColumn[[]string](inherited)
+		if err != nil {
+			return nil, err
+		}
+		if inheritedFields != nil {
+			ad.Inherited = *inheritedFields
+		}
 		ads = append(ads, ad)
 	}
-	err = rows.Err()
-	fmt.Println(err)
+	return ads, rows.Err()
+}
+
+func (ar *adRepository) Fetch(c context.Context, advertiserID int64) ([]domain.Ad, error) {
+	tenantID, err := domain.TenantFromContext(c)
+	if err != nil {
+		return nil, err
+	}
+	if advertiserID == 0 {
+		return queryAdsWithTargeting(c, ar.database, tenantID, selectAdsWithTargetingCommand()+adsOfTenantWhereCommand+"ORDER BY ads.id ASC", tenantID)
+	}
+	command := selectAdsWithTargetingCommand() + adsOfTenantWhereCommand + "AND " + advertiserWhereCommand + " ORDER BY ads.id ASC"
+	return queryAdsWithTargeting(c, ar.database, tenantID, command, tenantID, advertiserID)
+}
 
-	return ads, err
+func (ar *adRepository) FetchByCampaign(c context.Context, campaignID int64) ([]domain.Ad, error) {
+	tenantID, err := domain.TenantFromContext(c)
+	if err != nil {
+		return nil, err
+	}
+	command := selectAdsWithTargetingCommand() + adsOfTenantWhereCommand + "AND ads.campaign_id = ? ORDER BY ads.id ASC"
+	return queryAdsWithTargeting(c, ar.database, tenantID, command, tenantID, campaignID)
+}
+
+func (ar *adRepository) GetByWindow(c context.Context, startAt string, endAt string, advertiserID int64) ([]domain.Ad, error) {
+	tenantID, err := domain.TenantFromContext(c)
+	if err != nil {
+		return nil, err
+	}
+	if advertiserID == 0 {
+		command := selectAdsWithTargetingCommand() + adsOfTenantWhereCommand + "AND ads.start_at <= ? AND ads.end_at >= ? ORDER BY ads.id ASC"
+		return queryAdsWithTargeting(c, ar.database, tenantID, command, tenantID, endAt, startAt)
+	}
+	command := selectAdsWithTargetingCommand() + adsOfTenantWhereCommand + "AND ads.start_at <= ? AND ads.end_at >= ? AND " + advertiserWhereCommand + " ORDER BY ads.id ASC"
+	return queryAdsWithTargeting(c, ar.database, tenantID, command, tenantID, endAt, startAt, advertiserID)
 }
@@ -6,18 +6,35 @@ import (
 	"dcard-backend/repository"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/stretchr/testify/assert"
 )
 
 const (
-	query_ads         = "INSERT INTO ads (title, start_at, end_at, age_start, age_end) VALUES (?, ?, ?, ?, ?)"
-	query_ad_gender   = "INSERT INTO ad_gender (ad_id, gender_id) VALUES (?, (SELECT id FROM genders WHERE gender = ?))"
-	query_ad_country  = "INSERT INTO ad_country (ad_id, country_id) VALUES (?, (SELECT id FROM countries WHERE country = ?))"
-	query_ad_platform = "INSERT INTO ad_platform (ad_id, platform_id) VALUES (?, (SELECT id FROM platforms WHERE platform = ?))"
+	query_ads = "INSERT INTO ads (tenant_id, campaign_id, title, title_key, start_at, end_at, age_start, age_end, schedule, description, image_url, click_url, call_to_action, frequency_cap, budget, priority, weight, inherited) " +
+		"VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+	query_ad_gender    = "INSERT INTO ad_gender (ad_id, gender_id, exclude) VALUES (?, (SELECT id FROM genders WHERE gender = ?), ?)"
+	query_ad_country   = "INSERT INTO ad_country (ad_id, country_id, exclude) VALUES (?, (SELECT id FROM countries WHERE country = ?), ?)"
+	query_ad_platform  = "INSERT INTO ad_platform (ad_id, platform_id, exclude) VALUES (?, (SELECT id FROM platforms WHERE platform = ?), ?)"
+	query_ad_language  = "INSERT INTO ad_language (ad_id, language_id, exclude) VALUES (?, (SELECT id FROM languages WHERE language = ?), ?)"
+	query_ad_creatives = "INSERT INTO ad_creatives (ad_id, platform_id, description, image_url, click_url, call_to_action) VALUES (?, (SELECT id FROM platforms WHERE platform = ?), ?, ?, ?, ?)"
+
+	query_exclude_gender   = "ads.id NOT IN (SELECT ad_gender.ad_id FROM ad_gender INNER JOIN genders ON genders.id = ad_gender.gender_id WHERE ad_gender.exclude = 1 AND genders.gender IN (?,?)) AND "
+	query_exclude_country  = "ads.id NOT IN (SELECT ad_country.ad_id FROM ad_country INNER JOIN countries ON countries.id = ad_country.country_id WHERE ad_country.exclude = 1 AND countries.country IN (?,?)) AND "
+	query_exclude_platform = "ads.id NOT IN (SELECT ad_platform.ad_id FROM ad_platform INNER JOIN platforms ON platforms.id = ad_platform.platform_id WHERE ad_platform.exclude = 1 AND platforms.platform IN (?,?)) AND "
 )
 
+const testTenant = "team-a"
+
+// tenantContext is the context of requests made on behalf of testTenant
+var tenantContext = domain.WithTenant(context.Background(), testTenant)
+
+// adColumns are the columns selected by GetByCondition
+var adColumns = []string{"id", "title", "start_at", "end_at", "description", "image_url", "click_url", "call_to_action", "schedule",
+	"frequency_cap", "budget", "priority", "weight"}
+
 var mockAd = domain.Ad{
 	Title:   "AD 0",
 	StartAt: "2024-01-01 00:00:00",
@@ -28,6 +45,7 @@ var mockAd = domain.Ad{
 		Gender:   []string{"M", "F"},
 		Country:  []string{"TW", "JP"},
 		Platform: []string{"web", "ios"},
+		Language: []string{"en"},
 	},
 }
 
@@ -38,39 +56,163 @@ func TestCreate_SuccessInsertOnAllTables_NoError(t *testing.T) {
 	}
 	defer db.Close()
 
+	prepAds := mock.ExpectPrepare(query_ads)
+	prepGender := mock.ExpectPrepare(query_ad_gender)
+	prepCountry := mock.ExpectPrepare(query_ad_country)
+	prepPlatform := mock.ExpectPrepare(query_ad_platform)
+	prepLanguage := mock.ExpectPrepare(query_ad_language)
+
 	mock.ExpectBegin()
 
-	prep := mock.ExpectPrepare(query_ads)
-	prep.ExpectExec().
-		WithArgs(mockAd.Title, mockAd.StartAt, mockAd.EndAt, mockAd.Condition.AgeStart, mockAd.Condition.AgeEnd).
+	prepAds.ExpectExec().
+		WithArgs(testTenant, nil, mockAd.Title, "ad 0", mockAd.StartAt, mockAd.EndAt, mockAd.Condition.AgeStart, mockAd.Condition.AgeEnd, nil, "", "", "", "", nil, nil, 0, 0, nil).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
 	for i, gender := range mockAd.Condition.Gender {
-		prep = mock.ExpectPrepare(query_ad_gender)
-		prep.ExpectExec().
-			WithArgs(1, gender).
+		prepGender.ExpectExec().
+			WithArgs(1, gender, false).
 			WillReturnResult(sqlmock.NewResult(int64(i+1), 1))
 	}
 
 	for i, country := range mockAd.Condition.Country {
-		prep = mock.ExpectPrepare(query_ad_country)
-		prep.ExpectExec().
-			WithArgs(1, country).
+		prepCountry.ExpectExec().
+			WithArgs(1, country, false).
 			WillReturnResult(sqlmock.NewResult(int64(i+1), 1))
 	}
 
 	for i, platform := range mockAd.Condition.Platform {
-		prep = mock.ExpectPrepare(query_ad_platform)
-		prep.ExpectExec().
-			WithArgs(1, platform).
+		prepPlatform.ExpectExec().
+			WithArgs(1, platform, false).
+			WillReturnResult(sqlmock.NewResult(int64(i+1), 1))
+	}
+
+	for i, language := range mockAd.Condition.Language {
+		prepLanguage.ExpectExec().
+			WithArgs(1, language, false).
 			WillReturnResult(sqlmock.NewResult(int64(i+1), 1))
 	}
 
+	version := `{"id":1,"title":"AD 0","startAt":"2024-01-01 00:00:00","endAt":"2025-01-01 00:00:00",` +
+		`"condition":{"ageStart":10,"ageEnd":20,"gender":["M","F"],"country":["TW","JP"],"platform":["web","ios"],"language":["en"]},"version":1}`
+	mock.ExpectExec(query_insert_version).
+		WithArgs(testTenant, 1, 1, nil, version).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	snapshot := `{"id":1,"title":"AD 0","startAt":"2024-01-01 00:00:00","endAt":"2025-01-01 00:00:00",` +
+		`"condition":{"ageStart":10,"ageEnd":20,"gender":["M","F"],"country":["TW","JP"],"platform":["web","ios"],"language":["en"]},"status":"active","version":1,"lifecycle":"scheduled"}`
+	mock.ExpectExec(query_insert_audit).
+		WithArgs(testTenant, 1, "create", testActor, testRequestID, nil, snapshot).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(query_insert_outbox).
+		WithArgs(testTenant, 1, "created", snapshot).
+		WillReturnResult(sqlmock.NewResult(1, 1))
 	mock.ExpectCommit()
 
+	ad := mockAd
 	testAr := repository.NewAdRepository(db)
-	err = testAr.Create(context.Background(), &mockAd)
+	err = testAr.Create(auditContext, &ad, domain.ConflictCheck{})
 	assert.NoError(t, err, "Create function should return with no error")
+	assert.Equal(t, int64(1), ad.ID)
+	assert.Equal(t, int64(1), ad.Version)
+	assert.Equal(t, domain.AdActive, ad.Status)
+	assert.Equal(t, domain.AdScheduled, ad.Lifecycle)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreate_SuccessWithExclusion_ShouldInsertExcludedValues(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	excludingAd := domain.Ad{
+		Title:   "AD 1",
+		StartAt: "2024-01-01 00:00:00",
+		EndAt:   "2025-01-01 00:00:00",
+		Condition: &domain.Condition{
+			AgeStart:       1,
+			AgeEnd:         100,
+			Gender:         []string{"A"},
+			Country:        []string{"AY"},
+			Platform:       []string{"any"},
+			Language:       []string{"any"},
+			ExcludeCountry: []string{"CN", "RU"},
+		},
+	}
+
+	prepAds := mock.ExpectPrepare(query_ads)
+	prepGender := mock.ExpectPrepare(query_ad_gender)
+	prepCountry := mock.ExpectPrepare(query_ad_country)
+	prepPlatform := mock.ExpectPrepare(query_ad_platform)
+	prepLanguage := mock.ExpectPrepare(query_ad_language)
+
+	mock.ExpectBegin()
+	prepAds.ExpectExec().WillReturnResult(sqlmock.NewResult(1, 1))
+	prepGender.ExpectExec().WithArgs(1, "A", false).WillReturnResult(sqlmock.NewResult(0, 1))
+	prepCountry.ExpectExec().WithArgs(1, "AY", false).WillReturnResult(sqlmock.NewResult(0, 1))
+	prepCountry.ExpectExec().WithArgs(1, "CN", true).WillReturnResult(sqlmock.NewResult(0, 1))
+	prepCountry.ExpectExec().WithArgs(1, "RU", true).WillReturnResult(sqlmock.NewResult(0, 1))
+	prepPlatform.ExpectExec().WithArgs(1, "any", false).WillReturnResult(sqlmock.NewResult(0, 1))
+	prepLanguage.ExpectExec().WithArgs(1, "any", false).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(query_insert_version).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(query_insert_audit).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(query_insert_outbox).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	testAr := repository.NewAdRepository(db)
+	err = testAr.Create(tenantContext, &excludingAd, domain.ConflictCheck{})
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreate_PrepareFail_ShouldReturnErrorWithoutTransaction(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectPrepare(query_ads).WillReturnError(fmt.Errorf("Error"))
+
+	testAr := repository.NewAdRepository(db)
+	err = testAr.Create(tenantContext, &mockAd, domain.ConflictCheck{})
+	assert.Error(t, err, "If preparing statements fail, it should return error")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreate_ConflictCheck_ShouldCheckAdsOfTitleUnderLockInTransaction(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectPrepare(query_ads)
+	mock.ExpectPrepare(query_ad_gender)
+	mock.ExpectPrepare(query_ad_country)
+	mock.ExpectPrepare(query_ad_platform)
+	mock.ExpectPrepare(query_ad_language)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO ad_title_locks (tenant_id, title_key) VALUES (?, ?) ON DUPLICATE KEY UPDATE title_key = title_key").
+		WithArgs(testTenant, "ad 0").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT ads.id, ads.title FROM ads WHERE ads.tenant_id = ? AND ads.deleted_at IS NULL AND ads.title_key = ? "+
+		"AND ads.start_at <= ? AND ads.end_at >= ? AND ads.id <> ? AND ads.campaign_id IN (SELECT id FROM campaigns WHERE advertiser_id = ?) ORDER BY ads.id ASC").
+		WithArgs(testTenant, "ad 0", mockAd.EndAt, mockAd.StartAt, 0, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title"}).AddRow(7, "Ad  0"))
+	mock.ExpectRollback()
+
+	var conflicting []domain.Ad
+	testAr := repository.NewAdRepository(db)
+	err = testAr.Create(tenantContext, &mockAd, domain.ConflictCheck{AdvertiserID: 2, Resolve: func(ads []domain.Ad) error {
+		conflicting = ads
+		return domain.ErrConflict
+	}})
+
+	assert.ErrorIs(t, err, domain.ErrConflict)
+	assert.Equal(t, []domain.Ad{{ID: 7, Title: "Ad  0"}}, conflicting)
+	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
 func TestCreate_FailOnFirstInsert_ShouldRollbackOnError(t *testing.T) {
@@ -80,16 +222,22 @@ func TestCreate_FailOnFirstInsert_ShouldRollbackOnError(t *testing.T) {
 	}
 	defer db.Close()
 
+	prepAds := mock.ExpectPrepare(query_ads)
+	mock.ExpectPrepare(query_ad_gender)
+	mock.ExpectPrepare(query_ad_country)
+	mock.ExpectPrepare(query_ad_platform)
+	mock.ExpectPrepare(query_ad_language)
+
 	mock.ExpectBegin()
-	prep := mock.ExpectPrepare(query_ads)
-	prep.ExpectExec().
-		WithArgs(mockAd.Title, mockAd.StartAt, mockAd.EndAt, mockAd.Condition.AgeStart, mockAd.Condition.AgeEnd).
+	prepAds.ExpectExec().
+		WithArgs(testTenant, nil, mockAd.Title, "ad 0", mockAd.StartAt, mockAd.EndAt, mockAd.Condition.AgeStart, mockAd.Condition.AgeEnd, nil, "", "", "", "", nil, nil, 0, 0, nil).
 		WillReturnError(fmt.Errorf("Error"))
 	mock.ExpectRollback()
 
 	testAr := repository.NewAdRepository(db)
-	err = testAr.Create(context.Background(), &mockAd)
+	err = testAr.Create(tenantContext, &mockAd, domain.ConflictCheck{})
 	assert.Error(t, err, "If inserting ads fail, it should return error")
+	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
 func TestCreate_FailOnSecondInsert_ShouldRollbackOnError(t *testing.T) {
@@ -99,23 +247,28 @@ func TestCreate_FailOnSecondInsert_ShouldRollbackOnError(t *testing.T) {
 	}
 	defer db.Close()
 
+	prepAds := mock.ExpectPrepare(query_ads)
+	prepGender := mock.ExpectPrepare(query_ad_gender)
+	mock.ExpectPrepare(query_ad_country)
+	mock.ExpectPrepare(query_ad_platform)
+	mock.ExpectPrepare(query_ad_language)
+
 	mock.ExpectBegin()
 
-	prep := mock.ExpectPrepare(query_ads)
-	prep.ExpectExec().
-		WithArgs(mockAd.Title, mockAd.StartAt, mockAd.EndAt, mockAd.Condition.AgeStart, mockAd.Condition.AgeEnd).
+	prepAds.ExpectExec().
+		WithArgs(testTenant, nil, mockAd.Title, "ad 0", mockAd.StartAt, mockAd.EndAt, mockAd.Condition.AgeStart, mockAd.Condition.AgeEnd, nil, "", "", "", "", nil, nil, 0, 0, nil).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
-	prep = mock.ExpectPrepare(query_ad_gender)
-	prep.ExpectExec().
-		WithArgs(1, mockAd.Condition.Gender[0]).
+	prepGender.ExpectExec().
+		WithArgs(1, mockAd.Condition.Gender[0], false).
 		WillReturnError(fmt.Errorf("Error"))
 
 	mock.ExpectRollback()
 
 	testAr := repository.NewAdRepository(db)
-	err = testAr.Create(context.Background(), &mockAd)
+	err = testAr.Create(tenantContext, &mockAd, domain.ConflictCheck{})
 	assert.Error(t, err, "If inserting ad_gender fail, it should return error")
+	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
 func TestCreate_FailOnThirdInsert_ShouldRollbackOnError(t *testing.T) {
@@ -125,28 +278,32 @@ func TestCreate_FailOnThirdInsert_ShouldRollbackOnError(t *testing.T) {
 	}
 	defer db.Close()
 
+	prepAds := mock.ExpectPrepare(query_ads)
+	prepGender := mock.ExpectPrepare(query_ad_gender)
+	prepCountry := mock.ExpectPrepare(query_ad_country)
+	mock.ExpectPrepare(query_ad_platform)
+	mock.ExpectPrepare(query_ad_language)
+
 	mock.ExpectBegin()
 
-	prep := mock.ExpectPrepare(query_ads)
-	prep.ExpectExec().
-		WithArgs(mockAd.Title, mockAd.StartAt, mockAd.EndAt, mockAd.Condition.AgeStart, mockAd.Condition.AgeEnd).
+	prepAds.ExpectExec().
+		WithArgs(testTenant, nil, mockAd.Title, "ad 0", mockAd.StartAt, mockAd.EndAt, mockAd.Condition.AgeStart, mockAd.Condition.AgeEnd, nil, "", "", "", "", nil, nil, 0, 0, nil).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
 	for i, gender := range mockAd.Condition.Gender {
-		prep = mock.ExpectPrepare(query_ad_gender)
-		prep.ExpectExec().WithArgs(1, gender).WillReturnResult(sqlmock.NewResult(int64(i+1), 1))
+		prepGender.ExpectExec().WithArgs(1, gender, false).WillReturnResult(sqlmock.NewResult(int64(i+1), 1))
 	}
 
-	prep = mock.ExpectPrepare(query_ad_country)
-	prep.ExpectExec().
-		WithArgs(1, mockAd.Condition.Country[0]).
+	prepCountry.ExpectExec().
+		WithArgs(1, mockAd.Condition.Country[0], false).
 		WillReturnError(fmt.Errorf("Error"))
 
 	mock.ExpectRollback()
 
 	testAr := repository.NewAdRepository(db)
-	err = testAr.Create(context.Background(), &mockAd)
+	err = testAr.Create(tenantContext, &mockAd, domain.ConflictCheck{})
 	assert.Error(t, err, "If inserting ad_country fail, it should return error")
+	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
 func TestCreate_FailOnFourthInsert_ShouldRollbackOnError(t *testing.T) {
@@ -156,37 +313,94 @@ func TestCreate_FailOnFourthInsert_ShouldRollbackOnError(t *testing.T) {
 	}
 	defer db.Close()
 
+	prepAds := mock.ExpectPrepare(query_ads)
+	prepGender := mock.ExpectPrepare(query_ad_gender)
+	prepCountry := mock.ExpectPrepare(query_ad_country)
+	prepPlatform := mock.ExpectPrepare(query_ad_platform)
+	mock.ExpectPrepare(query_ad_language)
+
 	mock.ExpectBegin()
 
-	prep := mock.ExpectPrepare(query_ads)
-	prep.ExpectExec().
-		WithArgs(mockAd.Title, mockAd.StartAt, mockAd.EndAt, mockAd.Condition.AgeStart, mockAd.Condition.AgeEnd).
+	prepAds.ExpectExec().
+		WithArgs(testTenant, nil, mockAd.Title, "ad 0", mockAd.StartAt, mockAd.EndAt, mockAd.Condition.AgeStart, mockAd.Condition.AgeEnd, nil, "", "", "", "", nil, nil, 0, 0, nil).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
 	for i, gender := range mockAd.Condition.Gender {
-		prep = mock.ExpectPrepare(query_ad_gender)
-		prep.ExpectExec().
-			WithArgs(1, gender).
+		prepGender.ExpectExec().
+			WithArgs(1, gender, false).
 			WillReturnResult(sqlmock.NewResult(int64(i+1), 1))
 	}
 
 	for i, country := range mockAd.Condition.Country {
-		prep = mock.ExpectPrepare(query_ad_country)
-		prep.ExpectExec().
-			WithArgs(1, country).
+		prepCountry.ExpectExec().
+			WithArgs(1, country, false).
 			WillReturnResult(sqlmock.NewResult(int64(i+1), 1))
 	}
 
-	prep = mock.ExpectPrepare(query_ad_platform)
-	prep.ExpectExec().
-		WithArgs(1, mockAd.Condition.Platform[0]).
+	prepPlatform.ExpectExec().
+		WithArgs(1, mockAd.Condition.Platform[0], false).
 		WillReturnError(fmt.Errorf("Error"))
 
 	mock.ExpectRollback()
 
 	testAr := repository.NewAdRepository(db)
-	err = testAr.Create(context.Background(), &mockAd)
+	err = testAr.Create(tenantContext, &mockAd, domain.ConflictCheck{})
 	assert.Error(t, err, "If inserting ad_platform fail, it should return error")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreate_CommitFail_ShouldReturnError(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	prepAds := mock.ExpectPrepare(query_ads)
+	prepGender := mock.ExpectPrepare(query_ad_gender)
+	prepCountry := mock.ExpectPrepare(query_ad_country)
+	prepPlatform := mock.ExpectPrepare(query_ad_platform)
+	prepLanguage := mock.ExpectPrepare(query_ad_language)
+
+	mock.ExpectBegin()
+	prepAds.ExpectExec().WillReturnResult(sqlmock.NewResult(1, 1))
+	for range mockAd.Condition.Gender {
+		prepGender.ExpectExec().WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	for range mockAd.Condition.Country {
+		prepCountry.ExpectExec().WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	for range mockAd.Condition.Platform {
+		prepPlatform.ExpectExec().WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	for range mockAd.Condition.Language {
+		prepLanguage.ExpectExec().WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	mock.ExpectExec(query_insert_version).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(query_insert_audit).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(query_insert_outbox).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit().WillReturnError(fmt.Errorf("Error"))
+
+	testAr := repository.NewAdRepository(db)
+	err = testAr.Create(tenantContext, &mockAd, domain.ConflictCheck{})
+	assert.Error(t, err, "If committing fails, it should return error")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreate_DeadlineExceeded_ShouldReturnDeadlineExceeded(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(tenantContext, -time.Second)
+	defer cancel()
+
+	testAr := repository.NewAdRepository(db)
+	err = testAr.Create(ctx, &mockAd, domain.ConflictCheck{})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
 func TestGetByCondition_SuccessWithAllConditionsProvided_AdsReturnWithNoError(t *testing.T) {
@@ -196,19 +410,24 @@ func TestGetByCondition_SuccessWithAllConditionsProvided_AdsReturnWithNoError(t
 	}
 	defer db.Close()
 
-	query := "SELECT ads.title, ads.end_at FROM ads "
-	query += "INNER JOIN ad_gender ON ads.id = ad_gender.ad_id INNER JOIN genders ON genders.id = ad_gender.gender_id "
-	query += "INNER JOIN ad_country ON ads.id = ad_country.ad_id INNER JOIN countries ON countries.id = ad_country.country_id "
-	query += "INNER JOIN ad_platform ON ads.id = ad_platform.ad_id INNER JOIN platforms ON platforms.id = ad_platform.platform_id "
+	query := "SELECT ads.id, ads.title, ads.start_at, ads.end_at, ads.description, ads.image_url, ads.click_url, ads.call_to_action, " +
+		"ads.schedule, ads.frequency_cap, ads.budget, ads.priority, ads.weight FROM ads "
+	query += "INNER JOIN ad_gender ON ads.id = ad_gender.ad_id AND ad_gender.exclude = 0 INNER JOIN genders ON genders.id = ad_gender.gender_id "
+	query += "INNER JOIN ad_country ON ads.id = ad_country.ad_id AND ad_country.exclude = 0 INNER JOIN countries ON countries.id = ad_country.country_id "
+	query += "INNER JOIN ad_platform ON ads.id = ad_platform.ad_id AND ad_platform.exclude = 0 INNER JOIN platforms ON platforms.id = ad_platform.platform_id "
 	query += "WHERE genders.gender IN (?,?) AND countries.country IN (?,?) AND platforms.platform IN (?,?) AND "
-	query += "ads.age_start <= ? AND ads.age_end >= ? AND ads.start_at <= NOW() AND ads.end_at >= NOW() "
-	query += "ORDER BY ads.end_at ASC LIMIT ? OFFSET ?"
+	query += query_exclude_gender
+	query += query_exclude_country
+	query += query_exclude_platform
+	query += "ads.age_start <= ? AND ads.age_end >= ? AND ads.start_at <= NOW() AND ads.end_at >= NOW() AND ads.tenant_id = ? " +
+		"AND ads.deleted_at IS NULL AND ads.status = 'active' AND (ads.campaign_id IS NULL OR ads.campaign_id IN (SELECT id FROM campaigns WHERE status = 'active')) "
+	query += "ORDER BY ads.end_at ASC, ads.id ASC LIMIT ? OFFSET ?"
 
-	mockRows := sqlmock.NewRows([]string{"title", "end_at"}).AddRow(mockAd.Title, mockAd.EndAt)
+	mockRows := sqlmock.NewRows(adColumns).AddRow(1, mockAd.Title, mockAd.StartAt, mockAd.EndAt, "", "", "", "", nil, nil, nil, 0, 1)
 
 	prep := mock.ExpectPrepare(query)
 	prep.ExpectQuery().
-		WithArgs("M", "A", "TW", "AY", "web", "any", "14", "14", "10", "0").
+		WithArgs("M", "A", "TW", "AY", "web", "any", "M", "A", "TW", "AY", "web", "any", "14", "14", testTenant, "10", "0").
 		WillReturnRows(mockRows)
 
 	testAr := repository.NewAdRepository(db)
@@ -220,7 +439,7 @@ func TestGetByCondition_SuccessWithAllConditionsProvided_AdsReturnWithNoError(t
 		"limit":    {"10"},
 		"offset":   {"0"},
 	}
-	ads, err := testAr.GetByCondition(context.Background(), condition)
+	ads, err := testAr.GetByCondition(tenantContext, condition)
 
 	assert.NoError(t, err)
 	if assert.NotNil(t, ads) {
@@ -237,19 +456,24 @@ func TestGetByCondition_SuccessWithNoAgeCondition_AdsReturnWithNoError(t *testin
 	}
 	defer db.Close()
 
-	query := "SELECT ads.title, ads.end_at FROM ads "
-	query += "INNER JOIN ad_gender ON ads.id = ad_gender.ad_id INNER JOIN genders ON genders.id = ad_gender.gender_id "
-	query += "INNER JOIN ad_country ON ads.id = ad_country.ad_id INNER JOIN countries ON countries.id = ad_country.country_id "
-	query += "INNER JOIN ad_platform ON ads.id = ad_platform.ad_id INNER JOIN platforms ON platforms.id = ad_platform.platform_id "
+	query := "SELECT ads.id, ads.title, ads.start_at, ads.end_at, ads.description, ads.image_url, ads.click_url, ads.call_to_action, " +
+		"ads.schedule, ads.frequency_cap, ads.budget, ads.priority, ads.weight FROM ads "
+	query += "INNER JOIN ad_gender ON ads.id = ad_gender.ad_id AND ad_gender.exclude = 0 INNER JOIN genders ON genders.id = ad_gender.gender_id "
+	query += "INNER JOIN ad_country ON ads.id = ad_country.ad_id AND ad_country.exclude = 0 INNER JOIN countries ON countries.id = ad_country.country_id "
+	query += "INNER JOIN ad_platform ON ads.id = ad_platform.ad_id AND ad_platform.exclude = 0 INNER JOIN platforms ON platforms.id = ad_platform.platform_id "
 	query += "WHERE genders.gender IN (?,?) AND countries.country IN (?,?) AND platforms.platform IN (?,?) AND "
-	query += "ads.start_at <= NOW() AND ads.end_at >= NOW() "
-	query += "ORDER BY ads.end_at ASC LIMIT ? OFFSET ?"
+	query += query_exclude_gender
+	query += query_exclude_country
+	query += query_exclude_platform
+	query += "ads.start_at <= NOW() AND ads.end_at >= NOW() AND ads.tenant_id = ? " +
+		"AND ads.deleted_at IS NULL AND ads.status = 'active' AND (ads.campaign_id IS NULL OR ads.campaign_id IN (SELECT id FROM campaigns WHERE status = 'active')) "
+	query += "ORDER BY ads.end_at ASC, ads.id ASC LIMIT ? OFFSET ?"
 
-	mockRows := sqlmock.NewRows([]string{"title", "end_at"}).AddRow(mockAd.Title, mockAd.EndAt)
+	mockRows := sqlmock.NewRows(adColumns).AddRow(1, mockAd.Title, mockAd.StartAt, mockAd.EndAt, "", "", "", "", nil, nil, nil, 0, 1)
 
 	prep := mock.ExpectPrepare(query)
 	prep.ExpectQuery().
-		WithArgs("M", "A", "TW", "AY", "web", "any", "10", "0").
+		WithArgs("M", "A", "TW", "AY", "web", "any", "M", "A", "TW", "AY", "web", "any", testTenant, "10", "0").
 		WillReturnRows(mockRows)
 
 	testAr := repository.NewAdRepository(db)
@@ -260,7 +484,7 @@ func TestGetByCondition_SuccessWithNoAgeCondition_AdsReturnWithNoError(t *testin
 		"limit":    {"10"},
 		"offset":   {"0"},
 	}
-	ads, err := testAr.GetByCondition(context.Background(), condition)
+	ads, err := testAr.GetByCondition(tenantContext, condition)
 
 	assert.NoError(t, err)
 	if assert.NotNil(t, ads) {
@@ -277,18 +501,22 @@ func TestGetByCondition_SuccessWithNoGenderCondition_AdsReturnWithNoError(t *tes
 	}
 	defer db.Close()
 
-	query := "SELECT ads.title, ads.end_at FROM ads "
-	query += "INNER JOIN ad_country ON ads.id = ad_country.ad_id INNER JOIN countries ON countries.id = ad_country.country_id "
-	query += "INNER JOIN ad_platform ON ads.id = ad_platform.ad_id INNER JOIN platforms ON platforms.id = ad_platform.platform_id "
+	query := "SELECT ads.id, ads.title, ads.start_at, ads.end_at, ads.description, ads.image_url, ads.click_url, ads.call_to_action, " +
+		"ads.schedule, ads.frequency_cap, ads.budget, ads.priority, ads.weight FROM ads "
+	query += "INNER JOIN ad_country ON ads.id = ad_country.ad_id AND ad_country.exclude = 0 INNER JOIN countries ON countries.id = ad_country.country_id "
+	query += "INNER JOIN ad_platform ON ads.id = ad_platform.ad_id AND ad_platform.exclude = 0 INNER JOIN platforms ON platforms.id = ad_platform.platform_id "
 	query += "WHERE countries.country IN (?,?) AND platforms.platform IN (?,?) AND "
-	query += "ads.age_start <= ? AND ads.age_end >= ? AND ads.start_at <= NOW() AND ads.end_at >= NOW() "
-	query += "ORDER BY ads.end_at ASC LIMIT ? OFFSET ?"
+	query += query_exclude_country
+	query += query_exclude_platform
+	query += "ads.age_start <= ? AND ads.age_end >= ? AND ads.start_at <= NOW() AND ads.end_at >= NOW() AND ads.tenant_id = ? " +
+		"AND ads.deleted_at IS NULL AND ads.status = 'active' AND (ads.campaign_id IS NULL OR ads.campaign_id IN (SELECT id FROM campaigns WHERE status = 'active')) "
+	query += "ORDER BY ads.end_at ASC, ads.id ASC LIMIT ? OFFSET ?"
 
-	mockRows := sqlmock.NewRows([]string{"title", "end_at"}).AddRow(mockAd.Title, mockAd.EndAt)
+	mockRows := sqlmock.NewRows(adColumns).AddRow(1, mockAd.Title, mockAd.StartAt, mockAd.EndAt, "", "", "", "", nil, nil, nil, 0, 1)
 
 	prep := mock.ExpectPrepare(query)
 	prep.ExpectQuery().
-		WithArgs("TW", "AY", "web", "any", "14", "14", "10", "0").
+		WithArgs("TW", "AY", "web", "any", "TW", "AY", "web", "any", "14", "14", testTenant, "10", "0").
 		WillReturnRows(mockRows)
 
 	testAr := repository.NewAdRepository(db)
@@ -299,7 +527,7 @@ func TestGetByCondition_SuccessWithNoGenderCondition_AdsReturnWithNoError(t *tes
 		"limit":    {"10"},
 		"offset":   {"0"},
 	}
-	ads, err := testAr.GetByCondition(context.Background(), condition)
+	ads, err := testAr.GetByCondition(tenantContext, condition)
 
 	assert.NoError(t, err)
 	if assert.NotNil(t, ads) {
@@ -316,18 +544,22 @@ func TestGetByCondition_SuccessWithNoCountryCondition_AdsReturnWithNoError(t *te
 	}
 	defer db.Close()
 
-	query := "SELECT ads.title, ads.end_at FROM ads "
-	query += "INNER JOIN ad_gender ON ads.id = ad_gender.ad_id INNER JOIN genders ON genders.id = ad_gender.gender_id "
-	query += "INNER JOIN ad_platform ON ads.id = ad_platform.ad_id INNER JOIN platforms ON platforms.id = ad_platform.platform_id "
+	query := "SELECT ads.id, ads.title, ads.start_at, ads.end_at, ads.description, ads.image_url, ads.click_url, ads.call_to_action, " +
+		"ads.schedule, ads.frequency_cap, ads.budget, ads.priority, ads.weight FROM ads "
+	query += "INNER JOIN ad_gender ON ads.id = ad_gender.ad_id AND ad_gender.exclude = 0 INNER JOIN genders ON genders.id = ad_gender.gender_id "
+	query += "INNER JOIN ad_platform ON ads.id = ad_platform.ad_id AND ad_platform.exclude = 0 INNER JOIN platforms ON platforms.id = ad_platform.platform_id "
 	query += "WHERE genders.gender IN (?,?) AND platforms.platform IN (?,?) AND "
-	query += "ads.age_start <= ? AND ads.age_end >= ? AND ads.start_at <= NOW() AND ads.end_at >= NOW() "
-	query += "ORDER BY ads.end_at ASC LIMIT ? OFFSET ?"
+	query += query_exclude_gender
+	query += query_exclude_platform
+	query += "ads.age_start <= ? AND ads.age_end >= ? AND ads.start_at <= NOW() AND ads.end_at >= NOW() AND ads.tenant_id = ? " +
+		"AND ads.deleted_at IS NULL AND ads.status = 'active' AND (ads.campaign_id IS NULL OR ads.campaign_id IN (SELECT id FROM campaigns WHERE status = 'active')) "
+	query += "ORDER BY ads.end_at ASC, ads.id ASC LIMIT ? OFFSET ?"
 
-	mockRows := sqlmock.NewRows([]string{"title", "end_at"}).AddRow(mockAd.Title, mockAd.EndAt)
+	mockRows := sqlmock.NewRows(adColumns).AddRow(1, mockAd.Title, mockAd.StartAt, mockAd.EndAt, "", "", "", "", nil, nil, nil, 0, 1)
 
 	prep := mock.ExpectPrepare(query)
 	prep.ExpectQuery().
-		WithArgs("M", "A", "web", "any", "14", "14", "10", "0").
+		WithArgs("M", "A", "web", "any", "M", "A", "web", "any", "14", "14", testTenant, "10", "0").
 		WillReturnRows(mockRows)
 
 	testAr := repository.NewAdRepository(db)
@@ -338,7 +570,7 @@ func TestGetByCondition_SuccessWithNoCountryCondition_AdsReturnWithNoError(t *te
 		"limit":    {"10"},
 		"offset":   {"0"},
 	}
-	ads, err := testAr.GetByCondition(context.Background(), condition)
+	ads, err := testAr.GetByCondition(tenantContext, condition)
 
 	assert.NoError(t, err)
 	if assert.NotNil(t, ads) {
@@ -355,18 +587,22 @@ func TestGetByCondition_SuccessWithNoPlatformCondition_AdsReturnWithNoError(t *t
 	}
 	defer db.Close()
 
-	query := "SELECT ads.title, ads.end_at FROM ads "
-	query += "INNER JOIN ad_gender ON ads.id = ad_gender.ad_id INNER JOIN genders ON genders.id = ad_gender.gender_id "
-	query += "INNER JOIN ad_country ON ads.id = ad_country.ad_id INNER JOIN countries ON countries.id = ad_country.country_id "
+	query := "SELECT ads.id, ads.title, ads.start_at, ads.end_at, ads.description, ads.image_url, ads.click_url, ads.call_to_action, " +
+		"ads.schedule, ads.frequency_cap, ads.budget, ads.priority, ads.weight FROM ads "
+	query += "INNER JOIN ad_gender ON ads.id = ad_gender.ad_id AND ad_gender.exclude = 0 INNER JOIN genders ON genders.id = ad_gender.gender_id "
+	query += "INNER JOIN ad_country ON ads.id = ad_country.ad_id AND ad_country.exclude = 0 INNER JOIN countries ON countries.id = ad_country.country_id "
 	query += "WHERE genders.gender IN (?,?) AND countries.country IN (?,?) AND "
-	query += "ads.age_start <= ? AND ads.age_end >= ? AND ads.start_at <= NOW() AND ads.end_at >= NOW() "
-	query += "ORDER BY ads.end_at ASC LIMIT ? OFFSET ?"
+	query += query_exclude_gender
+	query += query_exclude_country
+	query += "ads.age_start <= ? AND ads.age_end >= ? AND ads.start_at <= NOW() AND ads.end_at >= NOW() AND ads.tenant_id = ? " +
+		"AND ads.deleted_at IS NULL AND ads.status = 'active' AND (ads.campaign_id IS NULL OR ads.campaign_id IN (SELECT id FROM campaigns WHERE status = 'active')) "
+	query += "ORDER BY ads.end_at ASC, ads.id ASC LIMIT ? OFFSET ?"
 
-	mockRows := sqlmock.NewRows([]string{"title", "end_at"}).AddRow(mockAd.Title, mockAd.EndAt)
+	mockRows := sqlmock.NewRows(adColumns).AddRow(1, mockAd.Title, mockAd.StartAt, mockAd.EndAt, "", "", "", "", nil, nil, nil, 0, 1)
 
 	prep := mock.ExpectPrepare(query)
 	prep.ExpectQuery().
-		WithArgs("M", "A", "TW", "AY", "14", "14", "10", "0").
+		WithArgs("M", "A", "TW", "AY", "M", "A", "TW", "AY", "14", "14", testTenant, "10", "0").
 		WillReturnRows(mockRows)
 
 	testAr := repository.NewAdRepository(db)
@@ -377,7 +613,7 @@ func TestGetByCondition_SuccessWithNoPlatformCondition_AdsReturnWithNoError(t *t
 		"limit":   {"10"},
 		"offset":  {"0"},
 	}
-	ads, err := testAr.GetByCondition(context.Background(), condition)
+	ads, err := testAr.GetByCondition(tenantContext, condition)
 
 	assert.NoError(t, err)
 	if assert.NotNil(t, ads) {
@@ -386,3 +622,686 @@ func TestGetByCondition_SuccessWithNoPlatformCondition_AdsReturnWithNoError(t *t
 		assert.Equal(t, ads[0].EndAt, mockAd.EndAt)
 	}
 }
+
+func TestGetByCondition_SuccessWithLanguageCondition_AdsReturnWithNoError(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	query := "SELECT ads.id, ads.title, ads.start_at, ads.end_at, ads.description, ads.image_url, ads.click_url, ads.call_to_action, " +
+		"ads.schedule, ads.frequency_cap, ads.budget, ads.priority, ads.weight FROM ads "
+	query += "INNER JOIN ad_language ON ads.id = ad_language.ad_id AND ad_language.exclude = 0 INNER JOIN languages ON languages.id = ad_language.language_id "
+	query += "WHERE languages.language IN (?,?) AND "
+	query += "ads.id NOT IN (SELECT ad_language.ad_id FROM ad_language INNER JOIN languages ON languages.id = ad_language.language_id WHERE ad_language.exclude = 1 AND languages.language IN (?,?)) AND "
+	query += "ads.start_at <= NOW() AND ads.end_at >= NOW() AND ads.tenant_id = ? " +
+		"AND ads.deleted_at IS NULL AND ads.status = 'active' AND (ads.campaign_id IS NULL OR ads.campaign_id IN (SELECT id FROM campaigns WHERE status = 'active')) "
+	query += "ORDER BY ads.end_at ASC, ads.id ASC LIMIT ? OFFSET ?"
+
+	mockRows := sqlmock.NewRows(adColumns).AddRow(1, mockAd.Title, mockAd.StartAt, mockAd.EndAt, "", "", "", "", nil, nil, nil, 0, 1)
+
+	prep := mock.ExpectPrepare(query)
+	prep.ExpectQuery().
+		WithArgs("en", "any", "en", "any", testTenant, "10", "0").
+		WillReturnRows(mockRows)
+
+	testAr := repository.NewAdRepository(db)
+	condition := map[string][]string{
+		"language": {"en", "any"},
+		"limit":    {"10"},
+		"offset":   {"0"},
+	}
+	ads, err := testAr.GetByCondition(tenantContext, condition)
+
+	assert.NoError(t, err)
+	assert.Len(t, ads, 1)
+}
+
+func TestGetByCondition_LimitNotProvided_ShouldReturnAllAdsWithDeliverySettings(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	query := "SELECT ads.id, ads.title, ads.start_at, ads.end_at, ads.description, ads.image_url, ads.click_url, ads.call_to_action, " +
+		"ads.schedule, ads.frequency_cap, ads.budget, ads.priority, ads.weight FROM ads "
+	query += "WHERE ads.start_at <= NOW() AND ads.end_at >= NOW() AND ads.tenant_id = ? " +
+		"AND ads.deleted_at IS NULL AND ads.status = 'active' AND (ads.campaign_id IS NULL OR ads.campaign_id IN (SELECT id FROM campaigns WHERE status = 'active')) "
+	query += "ORDER BY ads.end_at ASC, ads.id ASC"
+
+	mockRows := sqlmock.NewRows(adColumns).
+		AddRow(1, "AD 0", mockAd.StartAt, mockAd.EndAt, "", "", "", "", nil, `{"count":3,"window":"24h"}`, `{"totalImpressions":1000}`, 2, 3).
+		AddRow(2, "AD 1", mockAd.StartAt, mockAd.EndAt, "", "", "", "", `{"timezone":"Asia/Taipei","windows":[{"weekday":"mon","startHour":18,"endHour":22}]}`, nil, nil, 0, 1)
+
+	prep := mock.ExpectPrepare(query)
+	prep.ExpectQuery().WithArgs(testTenant).WillReturnRows(mockRows)
+
+	testAr := repository.NewAdRepository(db)
+	ads, err := testAr.GetByCondition(tenantContext, map[string][]string{})
+
+	assert.NoError(t, err)
+	if assert.Len(t, ads, 2) {
+		assert.Nil(t, ads[0].Condition)
+		assert.Equal(t, &domain.FrequencyCap{Count: 3, Window: "24h"}, ads[0].FrequencyCap)
+		assert.Nil(t, ads[1].FrequencyCap)
+		assert.Equal(t, &domain.Budget{TotalImpressions: 1000}, ads[0].Budget)
+		assert.Nil(t, ads[1].Budget)
+		assert.Equal(t, 2, ads[0].Priority)
+		assert.Equal(t, 3, ads[0].Weight)
+		assert.Equal(t, &domain.Schedule{
+			Timezone: "Asia/Taipei",
+			Windows:  []domain.ScheduleWindow{{Weekday: "mon", StartHour: 18, EndHour: 22}},
+		}, ads[1].Condition.Schedule)
+	}
+}
+
+func TestCreate_ScheduleProvided_ShouldInsertScheduleAsJSON(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	scheduledAd := domain.Ad{
+		Title:   "AD 1",
+		StartAt: "2024-01-01 00:00:00",
+		EndAt:   "2025-01-01 00:00:00",
+		Condition: &domain.Condition{
+			AgeStart: 1,
+			AgeEnd:   100,
+			Schedule: &domain.Schedule{
+				Windows: []domain.ScheduleWindow{{Weekday: "fri", StartHour: 22, EndHour: 2}},
+			},
+		},
+	}
+
+	prepAds := mock.ExpectPrepare(query_ads)
+	mock.ExpectPrepare(query_ad_gender)
+	mock.ExpectPrepare(query_ad_country)
+	mock.ExpectPrepare(query_ad_platform)
+	mock.ExpectPrepare(query_ad_language)
+
+	mock.ExpectBegin()
+	prepAds.ExpectExec().
+		WithArgs(testTenant, nil, "AD 1", "ad 1", scheduledAd.StartAt, scheduledAd.EndAt, 1, 100, `{"windows":[{"weekday":"fri","startHour":22,"endHour":2}]}`, "", "", "", "", nil, nil, 0, 0, nil).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(query_insert_version).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(query_insert_audit).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(query_insert_outbox).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	testAr := repository.NewAdRepository(db)
+	err = testAr.Create(tenantContext, &scheduledAd, domain.ConflictCheck{})
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+const query_ads_with_targeting = "SELECT ads.id, ads.campaign_id, ads.title, ads.start_at, ads.end_at, ads.age_start, ads.age_end, ads.schedule, " +
+	"ads.description, ads.image_url, ads.click_url, ads.call_to_action, ads.frequency_cap, ads.budget, ads.priority, ads.weight, ads.inherited, ads.status, ads.version, ads.lifecycle, " +
+	"(SELECT GROUP_CONCAT(genders.gender) FROM ad_gender INNER JOIN genders ON genders.id = ad_gender.gender_id WHERE ad_gender.ad_id = ads.id AND ad_gender.exclude = 0), " +
+	"(SELECT GROUP_CONCAT(countries.country) FROM ad_country INNER JOIN countries ON countries.id = ad_country.country_id WHERE ad_country.ad_id = ads.id AND ad_country.exclude = 0), " +
+	"(SELECT GROUP_CONCAT(platforms.platform) FROM ad_platform INNER JOIN platforms ON platforms.id = ad_platform.platform_id WHERE ad_platform.ad_id = ads.id AND ad_platform.exclude = 0), " +
+	"(SELECT GROUP_CONCAT(languages.language) FROM ad_language INNER JOIN languages ON languages.id = ad_language.language_id WHERE ad_language.ad_id = ads.id AND ad_language.exclude = 0), " +
+	"(SELECT GROUP_CONCAT(genders.gender) FROM ad_gender INNER JOIN genders ON genders.id = ad_gender.gender_id WHERE ad_gender.ad_id = ads.id AND ad_gender.exclude = 1), " +
+	"(SELECT GROUP_CONCAT(countries.country) FROM ad_country INNER JOIN countries ON countries.id = ad_country.country_id WHERE ad_country.ad_id = ads.id AND ad_country.exclude = 1), " +
+	"(SELECT GROUP_CONCAT(platforms.platform) FROM ad_platform INNER JOIN platforms ON platforms.id = ad_platform.platform_id WHERE ad_platform.ad_id = ads.id AND ad_platform.exclude = 1), " +
+	"(SELECT GROUP_CONCAT(languages.language) FROM ad_language INNER JOIN languages ON languages.id = ad_language.language_id WHERE ad_language.ad_id = ads.id AND ad_language.exclude = 1) " +
+	"FROM ads "
+
+const query_creatives = "SELECT ad_creatives.ad_id, platforms.platform, ad_creatives.description, ad_creatives.image_url, ad_creatives.click_url, ad_creatives.call_to_action " +
+	"FROM ad_creatives INNER JOIN platforms ON platforms.id = ad_creatives.platform_id INNER JOIN ads ON ads.id = ad_creatives.ad_id "
+
+var adsWithTargetingColumns = []string{"id", "campaign_id", "title", "start_at", "end_at", "age_start", "age_end", "schedule",
+	"description", "image_url", "click_url", "call_to_action", "frequency_cap", "budget", "priority", "weight", "inherited", "status", "version", "lifecycle", "genders", "countries", "platforms", "languages",
+	"excluded_genders", "excluded_countries", "excluded_platforms", "excluded_languages"}
+
+var creativeColumns = []string{"ad_id", "platform", "description", "image_url", "click_url", "call_to_action"}
+
+func TestFetch_Success_AdsReturnWithCondition(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mockRows := sqlmock.NewRows(adsWithTargetingColumns).
+		AddRow(1, nil, mockAd.Title, mockAd.StartAt, mockAd.EndAt, 10, 20, nil, "Sale", "https://example.com/ad.png", "https://example.com", "Shop now", nil, nil, 0, 1, nil, "active", 1, "live",
+			"M,F", "TW,JP", "web,ios", "en", nil, nil, nil, nil)
+	mock.ExpectQuery(query_ads_with_targeting + "WHERE ads.tenant_id = ? AND ads.deleted_at IS NULL ORDER BY ads.id ASC").WithArgs(testTenant).WillReturnRows(mockRows)
+	mock.ExpectQuery(query_creatives+"WHERE ads.tenant_id = ? AND ad_creatives.ad_id IN (?) ORDER BY ad_creatives.ad_id ASC, platforms.platform ASC").
+		WithArgs(testTenant, 1).
+		WillReturnRows(sqlmock.NewRows(creativeColumns).AddRow(1, "ios", "", "", "https://apps.apple.com/app/id1", "Install"))
+
+	testAr := repository.NewAdRepository(db)
+	ads, err := testAr.Fetch(tenantContext, 0)
+
+	expectedAd := mockAd
+	expectedAd.ID = 1
+	expectedAd.Description = "Sale"
+	expectedAd.ImageURL = "https://example.com/ad.png"
+	expectedAd.ClickURL = "https://example.com"
+	expectedAd.CallToAction = "Shop now"
+	expectedAd.Creatives = []domain.Creative{{Platform: "ios", ClickURL: "https://apps.apple.com/app/id1", CallToAction: "Install"}}
+	expectedAd.Weight = 1
+	expectedAd.Status = domain.AdActive
+	expectedAd.Version = 1
+	expectedAd.Lifecycle = domain.AdLive
+
+	assert.NoError(t, err)
+	if assert.Len(t, ads, 1) {
+		assert.Equal(t, expectedAd, ads[0])
+	}
+}
+
+func TestFetchByCampaign_Success_AdsReturnWithInheritedFields(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mockRows := sqlmock.NewRows(adsWithTargetingColumns).
+		AddRow(1, 3, mockAd.Title, mockAd.StartAt, mockAd.EndAt, 10, 20, nil, "", "", "", "", nil, nil, 0, 1, `["startAt","gender"]`, "active", 2, "live",
+			"M,F", "TW,JP", "web,ios", "en", nil, nil, nil, nil)
+	mock.ExpectQuery(query_ads_with_targeting+"WHERE ads.tenant_id = ? AND ads.deleted_at IS NULL AND ads.campaign_id = ? ORDER BY ads.id ASC").
+		WithArgs(testTenant, 3).
+		WillReturnRows(mockRows)
+	mock.ExpectQuery(query_creatives+"WHERE ads.tenant_id = ? AND ad_creatives.ad_id IN (?) ORDER BY ad_creatives.ad_id ASC, platforms.platform ASC").
+		WithArgs(testTenant, 1).
+		WillReturnRows(sqlmock.NewRows(creativeColumns))
+
+	testAr := repository.NewAdRepository(db)
+	ads, err := testAr.FetchByCampaign(tenantContext, 3)
+
+	assert.NoError(t, err)
+	if assert.Len(t, ads, 1) {
+		assert.Equal(t, int64(3), ads[0].CampaignID)
+		assert.Equal(t, int64(2), ads[0].Version)
+		assert.Equal(t, []string{"startAt", "gender"}, ads[0].Inherited)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetByWindow_Success_AdsReturnWithCondition(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mockRows := sqlmock.NewRows(adsWithTargetingColumns).
+		AddRow(1, nil, mockAd.Title, mockAd.StartAt, mockAd.EndAt, 10, 20, nil, "", "", "", "", nil, nil, 0, 1, nil, "active", 1, "live", "M,F", "AY", "web,ios", "any", nil, "CN,RU", nil, "de")
+	mock.ExpectQuery(query_ads_with_targeting+"WHERE ads.tenant_id = ? AND ads.deleted_at IS NULL AND ads.start_at <= ? AND ads.end_at >= ? ORDER BY ads.id ASC").
+		WithArgs(testTenant, "2024-06-01 00:00:00", "2024-03-01 00:00:00").
+		WillReturnRows(mockRows)
+	mock.ExpectQuery(query_creatives+"WHERE ads.tenant_id = ? AND ad_creatives.ad_id IN (?) ORDER BY ad_creatives.ad_id ASC, platforms.platform ASC").
+		WithArgs(testTenant, 1).
+		WillReturnRows(sqlmock.NewRows(creativeColumns))
+
+	testAr := repository.NewAdRepository(db)
+	ads, err := testAr.GetByWindow(tenantContext, "2024-03-01 00:00:00", "2024-06-01 00:00:00", 0)
+
+	assert.NoError(t, err)
+	if assert.Len(t, ads, 1) {
+		assert.Equal(t, int64(1), ads[0].ID)
+		assert.Equal(t, []string{"AY"}, ads[0].Condition.Country)
+		assert.Equal(t, []string{"CN", "RU"}, ads[0].Condition.ExcludeCountry)
+		assert.Equal(t, []string{"de"}, ads[0].Condition.ExcludeLanguage)
+		assert.Nil(t, ads[0].Condition.ExcludeGender)
+		assert.Nil(t, ads[0].Creatives)
+	}
+}
+
+func TestCreate_CreativesProvided_ShouldInsertCreatives(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	creativeAd := domain.Ad{
+		Title:        "AD 1",
+		StartAt:      "2024-01-01 00:00:00",
+		EndAt:        "2025-01-01 00:00:00",
+		Description:  "Sale",
+		ImageURL:     "https://example.com/ad.png",
+		ClickURL:     "https://example.com",
+		CallToAction: "Shop now",
+		Creatives: []domain.Creative{
+			{Platform: "android", ClickURL: "https://play.google.com/store/apps/details?id=com.example"},
+			{Platform: "ios", ClickURL: "https://apps.apple.com/app/id1", CallToAction: "Install"},
+		},
+		Condition: &domain.Condition{
+			AgeStart: 1, AgeEnd: 100,
+			Gender: []string{"A"}, Country: []string{"AY"}, Platform: []string{"any"}, Language: []string{"any"},
+		},
+	}
+
+	prepAds := mock.ExpectPrepare(query_ads)
+	prepGender := mock.ExpectPrepare(query_ad_gender)
+	prepCountry := mock.ExpectPrepare(query_ad_country)
+	prepPlatform := mock.ExpectPrepare(query_ad_platform)
+	prepLanguage := mock.ExpectPrepare(query_ad_language)
+	prepCreative := mock.ExpectPrepare(query_ad_creatives)
+	mock.ExpectBegin()
+
+	prepAds.ExpectExec().
+		WithArgs(testTenant, nil, "AD 1", "ad 1", creativeAd.StartAt, creativeAd.EndAt, 1, 100, nil, "Sale", "https://example.com/ad.png", "https://example.com", "Shop now", nil, nil, 0, 0, nil).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	prepGender.ExpectExec().WithArgs(1, "A", false).WillReturnResult(sqlmock.NewResult(0, 1))
+	prepCountry.ExpectExec().WithArgs(1, "AY", false).WillReturnResult(sqlmock.NewResult(0, 1))
+	prepPlatform.ExpectExec().WithArgs(1, "any", false).WillReturnResult(sqlmock.NewResult(0, 1))
+	prepLanguage.ExpectExec().WithArgs(1, "any", false).WillReturnResult(sqlmock.NewResult(0, 1))
+	prepCreative.ExpectExec().
+		WithArgs(1, "android", "", "", "https://play.google.com/store/apps/details?id=com.example", "").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	prepCreative.ExpectExec().
+		WithArgs(1, "ios", "", "", "https://apps.apple.com/app/id1", "Install").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(query_insert_version).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(query_insert_audit).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(query_insert_outbox).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	testAr := repository.NewAdRepository(db)
+	err = testAr.Create(tenantContext, &creativeAd, domain.ConflictCheck{})
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetCreativesByPlatform_Success_CreativesReturnByAd(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	prep := mock.ExpectPrepare(query_creatives + "WHERE ads.tenant_id = ? AND platforms.platform = ? AND ad_creatives.ad_id IN (?,?)")
+	prep.ExpectQuery().
+		WithArgs(testTenant, "ios", 1, 2).
+		WillReturnRows(sqlmock.NewRows(creativeColumns).AddRow(2, "ios", "", "", "https://apps.apple.com/app/id1", "Install"))
+
+	testAr := repository.NewAdRepository(db)
+	creatives, err := testAr.GetCreativesByPlatform(tenantContext, []int64{1, 2}, "ios")
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[int64]domain.Creative{
+		2: {Platform: "ios", ClickURL: "https://apps.apple.com/app/id1", CallToAction: "Install"},
+	}, creatives)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetByCondition_DeadlineExceeded_ShouldNotRunQuery(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	query := "SELECT ads.id, ads.title, ads.start_at, ads.end_at, ads.description, ads.image_url, ads.click_url, ads.call_to_action, " +
+		"ads.schedule, ads.frequency_cap, ads.budget, ads.priority, ads.weight FROM ads "
+	query += "WHERE ads.start_at <= NOW() AND ads.end_at >= NOW() AND ads.tenant_id = ? " +
+		"AND ads.deleted_at IS NULL AND ads.status = 'active' AND (ads.campaign_id IS NULL OR ads.campaign_id IN (SELECT id FROM campaigns WHERE status = 'active')) "
+	query += "ORDER BY ads.end_at ASC, ads.id ASC LIMIT ? OFFSET ?"
+
+	prep := mock.ExpectPrepare(query)
+	prep.ExpectQuery().
+		WithArgs(testTenant, "10", "0").
+		WillReturnRows(sqlmock.NewRows(adColumns))
+
+	testAr := repository.NewAdRepository(db)
+	_, err = testAr.GetByCondition(tenantContext, map[string][]string{"limit": {"10"}, "offset": {"0"}})
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(tenantContext, -time.Second)
+	defer cancel()
+
+	_, err = testAr.GetByCondition(ctx, map[string][]string{"limit": {"10"}, "offset": {"0"}})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetByCondition_SortByPriority_ShouldReadAdsOfHighestPriorityFirst(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	query := "SELECT ads.id, ads.title, ads.start_at, ads.end_at, ads.description, ads.image_url, ads.click_url, ads.call_to_action, " +
+		"ads.schedule, ads.frequency_cap, ads.budget, ads.priority, ads.weight FROM ads "
+	query += "WHERE ads.start_at <= NOW() AND ads.end_at >= NOW() AND ads.tenant_id = ? " +
+		"AND ads.deleted_at IS NULL AND ads.status = 'active' AND (ads.campaign_id IS NULL OR ads.campaign_id IN (SELECT id FROM campaigns WHERE status = 'active')) "
+	query += "ORDER BY ads.priority DESC, ads.end_at ASC, ads.id ASC LIMIT ? OFFSET ?"
+
+	mock.ExpectPrepare(query).ExpectQuery().
+		WithArgs(testTenant, "100", "200").
+		WillReturnRows(sqlmock.NewRows(adColumns))
+
+	testAr := repository.NewAdRepository(db)
+	_, err = testAr.GetByCondition(tenantContext, map[string][]string{"limit": {"100"}, "offset": {"200"}, "sort": {"priority"}})
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetByCondition_AdvertiserProvided_ShouldScopeAdsToAdvertiser(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	query := "SELECT ads.id, ads.title, ads.start_at, ads.end_at, ads.description, ads.image_url, ads.click_url, ads.call_to_action, " +
+		"ads.schedule, ads.frequency_cap, ads.budget, ads.priority, ads.weight FROM ads "
+	query += "WHERE ads.start_at <= NOW() AND ads.end_at >= NOW() AND ads.tenant_id = ? " +
+		"AND ads.deleted_at IS NULL AND ads.status = 'active' AND (ads.campaign_id IS NULL OR ads.campaign_id IN (SELECT id FROM campaigns WHERE status = 'active')) " +
+		"AND ads.campaign_id IN (SELECT id FROM campaigns WHERE advertiser_id = ?) "
+	query += "ORDER BY ads.end_at ASC, ads.id ASC"
+
+	prep := mock.ExpectPrepare(query)
+	prep.ExpectQuery().WithArgs(testTenant, "7").
+		WillReturnRows(sqlmock.NewRows(adColumns).AddRow(1, "AD 0", mockAd.StartAt, mockAd.EndAt, "", "", "", "", nil, nil, nil, 0, 1))
+
+	testAr := repository.NewAdRepository(db)
+	ads, err := testAr.GetByCondition(tenantContext, map[string][]string{"advertiserId": {"7"}})
+
+	assert.NoError(t, err)
+	assert.Len(t, ads, 1)
+}
+
+func TestFetch_AdvertiserProvided_ShouldReturnAdsOfItsCampaigns(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mockRows := sqlmock.NewRows(adsWithTargetingColumns).
+		AddRow(1, 3, mockAd.Title, mockAd.StartAt, mockAd.EndAt, 10, 20, nil, "", "", "", "", nil, nil, 0, 1, nil, "active", 1, "live",
+			"M,F", "TW,JP", "web,ios", "en", nil, nil, nil, nil)
+	mock.ExpectQuery(query_ads_with_targeting+"WHERE ads.tenant_id = ? AND ads.deleted_at IS NULL AND ads.campaign_id IN (SELECT id FROM campaigns WHERE advertiser_id = ?) ORDER BY ads.id ASC").
+		WithArgs(testTenant, 7).
+		WillReturnRows(mockRows)
+	mock.ExpectQuery(query_creatives+"WHERE ads.tenant_id = ? AND ad_creatives.ad_id IN (?) ORDER BY ad_creatives.ad_id ASC, platforms.platform ASC").
+		WithArgs(testTenant, 1).
+		WillReturnRows(sqlmock.NewRows(creativeColumns))
+
+	testAr := repository.NewAdRepository(db)
+	ads, err := testAr.Fetch(tenantContext, 7)
+
+	assert.NoError(t, err)
+	if assert.Len(t, ads, 1) {
+		assert.Equal(t, int64(3), ads[0].CampaignID)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+const (
+	query_update_ad        = "UPDATE ads SET campaign_id = ?, title = ?, title_key = ?, start_at = ?, end_at = ?, age_start = ?, age_end = ?, schedule = ?, description = ?, image_url = ?, click_url = ?, call_to_action = ?, frequency_cap = ?, budget = ?, priority = ?, weight = ?, inherited = ?, version = ?, exhausted_at = NULL WHERE id = ? AND tenant_id = ?"
+	query_update_ad_status = "UPDATE ads SET status = ? WHERE id = ? AND tenant_id = ?"
+	query_soft_delete_ad   = "UPDATE ads SET deleted_at = CURRENT_TIMESTAMP WHERE id = ? AND tenant_id = ?"
+	query_lock_ad          = query_ads_with_targeting + "WHERE ads.tenant_id = ? AND ads.deleted_at IS NULL AND ads.id = ? FOR UPDATE"
+	query_ad_creatives_of  = query_creatives + "WHERE ads.tenant_id = ? AND ad_creatives.ad_id IN (?) ORDER BY ad_creatives.ad_id ASC, platforms.platform ASC"
+)
+
+// expectLockAd expects the ad 1 of testTenant at version 2 to be read and locked with the status
+func expectLockAd(mock sqlmock.Sqlmock, status string) {
+	mock.ExpectQuery(query_lock_ad).
+		WithArgs(testTenant, 1).
+		WillReturnRows(sqlmock.NewRows(adsWithTargetingColumns).
+			AddRow(1, nil, mockAd.Title, mockAd.StartAt, mockAd.EndAt, 10, 20, nil, "", "", "", "", nil, nil, 0, 1, nil, status, 2, "live",
+				"M,F", "TW,JP", "web,ios", "en", nil, nil, nil, nil))
+	mock.ExpectQuery(query_ad_creatives_of).WithArgs(testTenant, 1).WillReturnRows(sqlmock.NewRows(creativeColumns))
+}
+
+// expectDeleteTargeting expects the targeting and the creatives of the ad 1 to be deleted
+func expectDeleteTargeting(mock sqlmock.Sqlmock) {
+	for _, table := range []string{"ad_gender", "ad_country", "ad_platform", "ad_language", "ad_creatives"} {
+		mock.ExpectExec("DELETE FROM " + table + " WHERE ad_id = ?").WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+}
+
+func TestGetByID_Success_AdReturnWithStatus(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(query_ads_with_targeting+"WHERE ads.tenant_id = ? AND ads.deleted_at IS NULL AND ads.id = ?").
+		WithArgs(testTenant, 1).
+		WillReturnRows(sqlmock.NewRows(adsWithTargetingColumns).
+			AddRow(1, nil, mockAd.Title, mockAd.StartAt, mockAd.EndAt, 10, 20, nil, "", "", "", "", nil, nil, 0, 1, nil, "paused", 2, "scheduled",
+				"M,F", "TW,JP", "web,ios", "en", nil, nil, nil, nil))
+	mock.ExpectQuery(query_ad_creatives_of).WithArgs(testTenant, 1).WillReturnRows(sqlmock.NewRows(creativeColumns))
+
+	testAr := repository.NewAdRepository(db)
+	ad, err := testAr.GetByID(tenantContext, 1)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), ad.ID)
+	assert.Equal(t, domain.AdPaused, ad.Status)
+	assert.Equal(t, domain.AdScheduled, ad.Lifecycle)
+	assert.Equal(t, []string{"TW", "JP"}, ad.Condition.Country)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetByID_AdOfAnotherTenant_ShouldReturnErrNotFound(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(query_ads_with_targeting+"WHERE ads.tenant_id = ? AND ads.deleted_at IS NULL AND ads.id = ?").
+		WithArgs(testTenant, 1).
+		WillReturnRows(sqlmock.NewRows(adsWithTargetingColumns))
+
+	testAr := repository.NewAdRepository(db)
+	_, err = testAr.GetByID(tenantContext, 1)
+
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdate_Success_ShouldReplaceTargetingAndRecordBothSnapshots(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	updatedAd := domain.Ad{
+		ID:        1,
+		Title:     "AD 1",
+		StartAt:   mockAd.StartAt,
+		EndAt:     mockAd.EndAt,
+		Condition: &domain.Condition{AgeStart: 1, AgeEnd: 100, Gender: []string{"A"}, Country: []string{"TW"}, Platform: []string{"any"}, Language: []string{"any"}},
+	}
+
+	prepGender := mock.ExpectPrepare(query_ad_gender)
+	prepCountry := mock.ExpectPrepare(query_ad_country)
+	prepPlatform := mock.ExpectPrepare(query_ad_platform)
+	prepLanguage := mock.ExpectPrepare(query_ad_language)
+
+	mock.ExpectBegin()
+	expectLockAd(mock, "paused")
+	mock.ExpectExec(query_update_ad).
+		WithArgs(nil, "AD 1", "ad 1", mockAd.StartAt, mockAd.EndAt, 1, 100, nil, "", "", "", "", nil, nil, 0, 0, nil, 3, 1, testTenant).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectDeleteTargeting(mock)
+	prepGender.ExpectExec().WithArgs(1, "A", false).WillReturnResult(sqlmock.NewResult(0, 1))
+	prepCountry.ExpectExec().WithArgs(1, "TW", false).WillReturnResult(sqlmock.NewResult(0, 1))
+	prepPlatform.ExpectExec().WithArgs(1, "any", false).WillReturnResult(sqlmock.NewResult(0, 1))
+	prepLanguage.ExpectExec().WithArgs(1, "any", false).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	version := `{"id":1,"title":"AD 1","startAt":"2024-01-01 00:00:00","endAt":"2025-01-01 00:00:00",` +
+		`"condition":{"ageStart":1,"ageEnd":100,"gender":["A"],"country":["TW"],"platform":["any"],"language":["any"]},"version":3}`
+	mock.ExpectExec(query_insert_version).
+		WithArgs(testTenant, 1, 3, nil, version).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	before := `{"id":1,"title":"AD 0","startAt":"2024-01-01 00:00:00","endAt":"2025-01-01 00:00:00",` +
+		`"condition":{"ageStart":10,"ageEnd":20,"gender":["M","F"],"country":["TW","JP"],"platform":["web","ios"],"language":["en"]},"weight":1,"status":"paused","version":2,"lifecycle":"live"}`
+	after := `{"id":1,"title":"AD 1","startAt":"2024-01-01 00:00:00","endAt":"2025-01-01 00:00:00",` +
+		`"condition":{"ageStart":1,"ageEnd":100,"gender":["A"],"country":["TW"],"platform":["any"],"language":["any"]},"status":"paused","version":3,"lifecycle":"live"}`
+	mock.ExpectExec(query_insert_audit).
+		WithArgs(testTenant, 1, "update", testActor, testRequestID, before, after).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(query_insert_outbox).
+		WithArgs(testTenant, 1, "updated", after).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	testAr := repository.NewAdRepository(db)
+	err = testAr.Update(auditContext, &updatedAd, domain.ConflictCheck{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), updatedAd.Version)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdate_AdOfAnotherTenant_ShouldRollbackWithErrNotFound(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	updatedAd := mockAd
+	updatedAd.ID = 1
+
+	mock.ExpectPrepare(query_ad_gender)
+	mock.ExpectPrepare(query_ad_country)
+	mock.ExpectPrepare(query_ad_platform)
+	mock.ExpectPrepare(query_ad_language)
+	mock.ExpectBegin()
+	mock.ExpectQuery(query_lock_ad).WithArgs(testTenant, 1).WillReturnRows(sqlmock.NewRows(adsWithTargetingColumns))
+	mock.ExpectRollback()
+
+	testAr := repository.NewAdRepository(db)
+	err = testAr.Update(tenantContext, &updatedAd, domain.ConflictCheck{})
+
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdate_AuditFail_ShouldRollbackChange(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	updatedAd := mockAd
+	updatedAd.ID = 1
+
+	prepGender := mock.ExpectPrepare(query_ad_gender)
+	prepCountry := mock.ExpectPrepare(query_ad_country)
+	prepPlatform := mock.ExpectPrepare(query_ad_platform)
+	prepLanguage := mock.ExpectPrepare(query_ad_language)
+	mock.ExpectBegin()
+	expectLockAd(mock, "active")
+	mock.ExpectExec(query_update_ad).WillReturnResult(sqlmock.NewResult(0, 1))
+	expectDeleteTargeting(mock)
+	for _, prep := range []*sqlmock.ExpectedPrepare{prepGender, prepGender, prepCountry, prepCountry, prepPlatform, prepPlatform, prepLanguage} {
+		prep.ExpectExec().WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	mock.ExpectExec(query_insert_version).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(query_insert_audit).WillReturnError(fmt.Errorf("Error"))
+	mock.ExpectRollback()
+
+	testAr := repository.NewAdRepository(db)
+	err = testAr.Update(auditContext, &updatedAd, domain.ConflictCheck{})
+
+	assert.Error(t, err, "A change should not be committed without its audit entry")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateStatus_Pause_ShouldRecordPause(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	expectLockAd(mock, "active")
+	mock.ExpectExec(query_update_ad_status).WithArgs("paused", 1, testTenant).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(query_insert_audit).
+		WithArgs(testTenant, 1, "pause", testActor, testRequestID, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(query_insert_outbox).
+		WithArgs(testTenant, 1, "paused", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	testAr := repository.NewAdRepository(db)
+	err = testAr.UpdateStatus(auditContext, 1, domain.AdPaused)
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateStatus_SameStatus_ShouldNotRecordChange(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	expectLockAd(mock, "paused")
+	mock.ExpectCommit()
+
+	testAr := repository.NewAdRepository(db)
+	err = testAr.UpdateStatus(auditContext, 1, domain.AdPaused)
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDelete_Success_ShouldSoftDeleteAndRecordBefore(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	expectLockAd(mock, "active")
+	mock.ExpectExec(query_soft_delete_ad).WithArgs(1, testTenant).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(query_insert_audit).
+		WithArgs(testTenant, 1, "delete", testActor, testRequestID, sqlmock.AnyArg(), nil).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(query_insert_outbox).
+		WithArgs(testTenant, 1, "deleted", nil).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	testAr := repository.NewAdRepository(db)
+	err = testAr.Delete(auditContext, 1)
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDelete_AdOfAnotherTenant_ShouldRollbackWithErrNotFound(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(query_lock_ad).WithArgs(testTenant, 1).WillReturnRows(sqlmock.NewRows(adsWithTargetingColumns))
+	mock.ExpectRollback()
+
+	testAr := repository.NewAdRepository(db)
+	err = testAr.Delete(tenantContext, 1)
+
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"dcard-backend/domain"
+	"errors"
+)
+
+type advertiserRepository struct {
+	database *sql.DB
+}
+
+func NewAdvertiserRepository(db *sql.DB) domain.AdvertiserRepository {
+	return &advertiserRepository{
+		database: db,
+	}
+}
+
+func (ar *advertiserRepository) Create(c context.Context, advertiser *domain.Advertiser) error {
+	tenantID, err := domain.TenantFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	result, err := ar.database.ExecContext(c, "INSERT INTO advertisers (tenant_id, name) VALUES (?, ?)", tenantID, advertiser.Name)
+	if err != nil {
+		return toConstraintError(err)
+	}
+	advertiser.ID, err = result.LastInsertId()
+	return err
+}
+
+func (ar *advertiserRepository) GetByID(c context.Context, id int64) (domain.Advertiser, error) {
+	tenantID, err := domain.TenantFromContext(c)
+	if err != nil {
+		return domain.Advertiser{}, err
+	}
+
+	var advertiser domain.Advertiser
+	row := ar.database.QueryRowContext(c, "SELECT id, name FROM advertisers WHERE id = ? AND tenant_id = ?", id, tenantID)
+	if err := row.Scan(&advertiser.ID, &advertiser.Name); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.Advertiser{}, domain.ErrNotFound
+		}
+		return domain.Advertiser{}, err
+	}
+	return advertiser, nil
+}
+
+func (ar *advertiserRepository) Fetch(c context.Context) ([]domain.Advertiser, error) {
+	tenantID, err := domain.TenantFromContext(c)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := ar.database.QueryContext(c, "SELECT id, name FROM advertisers WHERE tenant_id = ? ORDER BY id ASC", tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	advertisers := []domain.Advertiser{}
+	for rows.Next() {
+		var advertiser domain.Advertiser
+		if err := rows.Scan(&advertiser.ID, &advertiser.Name); err != nil {
+			return nil, err
+		}
+		advertisers = append(advertisers, advertiser)
+	}
+	return advertisers, rows.Err()
+}
+
+func (ar *advertiserRepository) Update(c context.Context, advertiser *domain.Advertiser) error {
+	tenantID, err := domain.TenantFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	_, err = ar.database.ExecContext(c, "UPDATE advertisers SET name = ? WHERE id = ? AND tenant_id = ?", advertiser.Name, advertiser.ID, tenantID)
+	return toConstraintError(err)
+}
+
+// Delete removes an advertiser without campaigns, and returns domain.ErrConflict otherwise
+func (ar *advertiserRepository) Delete(c context.Context, id int64) error {
+	tenantID, err := domain.TenantFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	result, err := ar.database.ExecContext(c, "DELETE FROM advertisers WHERE id = ? AND tenant_id = ?", id, tenantID)
+	if err != nil {
+		return toConstraintError(err)
+	}
+	return checkAffected(result)
+}
@@ -0,0 +1,108 @@
+package repository_test
+
+import (
+	"dcard-backend/domain"
+	"dcard-backend/repository"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdvertiserCreate_Success_ShouldSetID(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec("INSERT INTO advertisers (tenant_id, name) VALUES (?, ?)").WithArgs(testTenant, "Dcard").WillReturnResult(sqlmock.NewResult(5, 1))
+
+	testAr := repository.NewAdvertiserRepository(db)
+	advertiser := domain.Advertiser{Name: "Dcard"}
+	err = testAr.Create(tenantContext, &advertiser)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), advertiser.ID)
+}
+
+func TestAdvertiserCreate_DuplicateName_ShouldReturnErrConflict(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec("INSERT INTO advertisers (tenant_id, name) VALUES (?, ?)").WithArgs(testTenant, "Dcard").
+		WillReturnError(&mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'Dcard' for key 'name'"})
+
+	testAr := repository.NewAdvertiserRepository(db)
+	err = testAr.Create(tenantContext, &domain.Advertiser{Name: "Dcard"})
+
+	assert.ErrorIs(t, err, domain.ErrConflict)
+}
+
+func TestAdvertiserGetByID_NoRows_ShouldReturnErrNotFound(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT id, name FROM advertisers WHERE id = ? AND tenant_id = ?").WithArgs(5, testTenant).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}))
+
+	testAr := repository.NewAdvertiserRepository(db)
+	_, err = testAr.GetByID(tenantContext, 5)
+
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+}
+
+func TestAdvertiserFetch_Success_AdvertisersReturnByID(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT id, name FROM advertisers WHERE tenant_id = ? ORDER BY id ASC").WithArgs(testTenant).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "Dcard").AddRow(2, "Acme"))
+
+	testAr := repository.NewAdvertiserRepository(db)
+	advertisers, err := testAr.Fetch(tenantContext)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.Advertiser{{ID: 1, Name: "Dcard"}, {ID: 2, Name: "Acme"}}, advertisers)
+}
+
+func TestAdvertiserDelete_HasCampaigns_ShouldReturnErrConflict(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec("DELETE FROM advertisers WHERE id = ? AND tenant_id = ?").WithArgs(5, testTenant).
+		WillReturnError(&mysql.MySQLError{Number: 1451, Message: "Cannot delete or update a parent row"})
+
+	testAr := repository.NewAdvertiserRepository(db)
+	err = testAr.Delete(tenantContext, 5)
+
+	assert.ErrorIs(t, err, domain.ErrConflict)
+}
+
+func TestAdvertiserDelete_NoRowsAffected_ShouldReturnErrNotFound(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec("DELETE FROM advertisers WHERE id = ? AND tenant_id = ?").WithArgs(5, testTenant).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	testAr := repository.NewAdvertiserRepository(db)
+	err = testAr.Delete(tenantContext, 5)
+
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+}
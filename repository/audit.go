@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"dcard-backend/domain"
+	"strings"
+)
+
+const (
+	insertAuditCommand = "INSERT INTO ad_audit_log (tenant_id, ad_id, action, actor, request_id, before_ad, after_ad) VALUES (?, ?, ?, ?, ?, ?, ?)"
+	selectAuditCommand = "SELECT id, ad_id, action, actor, request_id, created_at, before_ad, after_ad FROM ad_audit_log "
+)
+
+// insertAuditEntry records the change of an ad in the transaction of the change, so that the
+// audit log has an entry for exactly the committed changes. The actor and the request id are
+// taken from the context, and the snapshots keep the times of the ad as they were read or
+// written.
+func insertAuditEntry(c context.Context, tx *sql.Tx, tenantID string, adID int64, action domain.AuditAction, before *domain.Ad, after *domain.Ad) error {
+	beforeAd, err := marshalJSONColumn(before)
+	if err != nil {
+		return err
+	}
+	afterAd, err := marshalJSONColumn(after)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(c, insertAuditCommand, tenantID, adID, string(action),
+		domain.ActorFromContext(c), domain.RequestIDFromContext(c), beforeAd, afterAd)
+	return err
+}
+
+type auditRepository struct {
+	database *sql.DB
+}
+
+func NewAuditRepository(db *sql.DB) domain.AuditRepository {
+	return &auditRepository{
+		database: db,
+	}
+}
+
+func (ar *auditRepository) Fetch(c context.Context, filter domain.AuditFilter) ([]domain.AuditEntry, error) {
+	tenantID, err := domain.TenantFromContext(c)
+	if err != nil {
+		return nil, err
+	}
+
+	whereCommands := []string{"tenant_id = ?"}
+	args := []interface{}{tenantID}
+	if filter.AdID != 0 {
+		whereCommands = append(whereCommands, "ad_id = ?")
+		args = append(args, filter.AdID)
+	}
+	if filter.Actor != "" {
+		whereCommands = append(whereCommands, "actor = ?")
+		args = append(args, filter.Actor)
+	}
+	if filter.Action != "" {
+		whereCommands = append(whereCommands, "action = ?")
+		args = append(args, string(filter.Action))
+	}
+	if filter.Since != "" {
+		whereCommands = append(whereCommands, "created_at >= ?")
+		args = append(args, filter.Since)
+	}
+	if filter.Until != "" {
+		whereCommands = append(whereCommands, "created_at < ?")
+		args = append(args, filter.Until)
+	}
+
+	command := selectAuditCommand + "WHERE " + strings.Join(whereCommands, " AND ") + " ORDER BY id DESC LIMIT ? OFFSET ?"
+	args = append(args, filter.Limit, filter.Offset)
+
+	rows, err := ar.database.QueryContext(c, command, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []domain.AuditEntry{}
+	for rows.Next() {
+		var entry domain.AuditEntry
+		var beforeAd, afterAd sql.NullString
+		err := rows.Scan(&entry.ID, &entry.AdID, &entry.Action, &entry.Actor, &entry.RequestID, &entry.CreatedAt, &beforeAd, &afterAd)
+		if err != nil {
+			return nil, err
+		}
+		if entry.Before, err = unmarshalJSONColumn[domain.Ad](beforeAd); err != nil {
+			return nil, err
+		}
+		if entry.After, err = unmarshalJSONColumn[domain.Ad](afterAd); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
@@ -0,0 +1,82 @@
+package repository_test
+
+import (
+	"dcard-backend/domain"
+	"dcard-backend/repository"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	query_insert_audit = "INSERT INTO ad_audit_log (tenant_id, ad_id, action, actor, request_id, before_ad, after_ad) VALUES (?, ?, ?, ?, ?, ?, ?)"
+	query_select_audit = "SELECT id, ad_id, action, actor, request_id, created_at, before_ad, after_ad FROM ad_audit_log "
+)
+
+const (
+	testActor     = "api-key:3f2a9c1b04de"
+	testRequestID = "req-1"
+)
+
+// auditContext is a request of testTenant made by testActor
+var auditContext = domain.WithRequestID(domain.WithActor(tenantContext, testActor), testRequestID)
+
+var auditColumns = []string{"id", "ad_id", "action", "actor", "request_id", "created_at", "before_ad", "after_ad"}
+
+func TestAuditFetch_NoFilter_ShouldReturnEntriesOfTenantFromNewest(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mockRows := sqlmock.NewRows(auditColumns).
+		AddRow(2, 1, "pause", testActor, testRequestID, "2024-01-02 08:00:00", `{"title":"AD 0","endAt":"","status":"active"}`, `{"title":"AD 0","endAt":"","status":"paused"}`).
+		AddRow(1, 1, "create", "cli:admin", "", "2024-01-01 08:00:00", nil, `{"title":"AD 0","endAt":"","status":"active"}`)
+	mock.ExpectQuery(query_select_audit+"WHERE tenant_id = ? ORDER BY id DESC LIMIT ? OFFSET ?").WithArgs(testTenant, 50, 0).WillReturnRows(mockRows)
+
+	testAr := repository.NewAuditRepository(db)
+	entries, err := testAr.Fetch(tenantContext, domain.AuditFilter{Limit: 50})
+
+	assert.NoError(t, err)
+	if assert.Len(t, entries, 2) {
+		assert.Equal(t, domain.AuditEntry{
+			ID: 2, AdID: 1, Action: domain.AuditPause, Actor: testActor, RequestID: testRequestID, CreatedAt: "2024-01-02 08:00:00",
+			Before: &domain.Ad{Title: "AD 0", Status: domain.AdActive},
+			After:  &domain.Ad{Title: "AD 0", Status: domain.AdPaused},
+		}, entries[0])
+		assert.Nil(t, entries[1].Before)
+		assert.Equal(t, domain.AuditCreate, entries[1].Action)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAuditFetch_FilterProvided_ShouldQueryMatchingEntries(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	query := query_select_audit + "WHERE tenant_id = ? AND ad_id = ? AND actor = ? AND action = ? AND created_at >= ? AND created_at < ? " +
+		"ORDER BY id DESC LIMIT ? OFFSET ?"
+	mock.ExpectQuery(query).
+		WithArgs(testTenant, 1, testActor, "update", "2024-01-01T08:00:00+08:00", "2024-02-01T08:00:00+08:00", 10, 20).
+		WillReturnRows(sqlmock.NewRows(auditColumns))
+
+	testAr := repository.NewAuditRepository(db)
+	entries, err := testAr.Fetch(tenantContext, domain.AuditFilter{
+		AdID:   1,
+		Actor:  testActor,
+		Action: domain.AuditUpdate,
+		Since:  "2024-01-01T08:00:00+08:00",
+		Until:  "2024-02-01T08:00:00+08:00",
+		Limit:  10,
+		Offset: 20,
+	})
+
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
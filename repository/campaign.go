@@ -0,0 +1,155 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"dcard-backend/domain"
+	"errors"
+	"fmt"
+)
+
+const (
+	selectCampaignsCommand = "SELECT id, advertiser_id, name, status, start_at, end_at, targeting FROM campaigns "
+	// campaignTenantWhereCommand scopes campaigns to the tenant through their advertiser
+	campaignTenantWhereCommand = "advertiser_id IN (SELECT id FROM advertisers WHERE tenant_id = ?)"
+)
+
+type campaignRepository struct {
+	database *sql.DB
+}
+
+func NewCampaignRepository(db *sql.DB) domain.CampaignRepository {
+	return &campaignRepository{
+		database: db,
+	}
+}
+
+// nullableString returns the value of a nullable column, which is NULL when value is empty
+func nullableString(value string) interface{} {
+	if value == "" {
+		return nil
+	}
+	return value
+}
+
+// Create inserts a campaign, and returns domain.ErrBadParamInput when its advertiser is not
+// of the tenant
+func (cr *campaignRepository) Create(c context.Context, campaign *domain.Campaign) error {
+	tenantID, err := domain.TenantFromContext(c)
+	if err != nil {
+		return err
+	}
+	targeting, err := marshalJSONColumn(campaign.Condition)
+	if err != nil {
+		return err
+	}
+
+	// The values are selected from the advertiser, so that nothing is inserted for the
+	// advertiser of another tenant
+	command := "INSERT INTO campaigns (advertiser_id, name, status, start_at, end_at, targeting) " +
+		"SELECT id, ?, ?, ?, ?, ? FROM advertisers WHERE id = ? AND tenant_id = ?"
+	result, err := cr.database.ExecContext(c, command, campaign.Name, campaign.Status,
+		nullableString(campaign.StartAt), nullableString(campaign.EndAt), targeting, campaign.AdvertiserID, tenantID)
+	if err != nil {
+		return toConstraintError(err)
+	}
+	if err := checkAffected(result); err != nil {
+		return fmt.Errorf("%w: advertiser %d does not exist", domain.ErrBadParamInput, campaign.AdvertiserID)
+	}
+	campaign.ID, err = result.LastInsertId()
+	return err
+}
+
+func scanCampaign(scan func(dest ...interface{}) error) (domain.Campaign, error) {
+	var campaign domain.Campaign
+	var startAt, endAt, targeting sql.NullString
+	if err := scan(&campaign.ID, &campaign.AdvertiserID, &campaign.Name, &campaign.Status, &startAt, &endAt, &targeting); err != nil {
+		return domain.Campaign{}, err
+	}
+	campaign.StartAt, campaign.EndAt = startAt.String, endAt.String
+
+	var err error
+	if campaign.Condition, err = unmarshalJSONColumn[domain.Condition](targeting); err != nil {
+		return domain.Campaign{}, err
+	}
+	return campaign, nil
+}
+
+func (cr *campaignRepository) GetByID(c context.Context, id int64) (domain.Campaign, error) {
+	tenantID, err := domain.TenantFromContext(c)
+	if err != nil {
+		return domain.Campaign{}, err
+	}
+
+	row := cr.database.QueryRowContext(c, selectCampaignsCommand+"WHERE id = ? AND "+campaignTenantWhereCommand, id, tenantID)
+	campaign, err := scanCampaign(row.Scan)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Campaign{}, domain.ErrNotFound
+	}
+	return campaign, err
+}
+
+func (cr *campaignRepository) FetchByAdvertiser(c context.Context, advertiserID int64) ([]domain.Campaign, error) {
+	tenantID, err := domain.TenantFromContext(c)
+	if err != nil {
+		return nil, err
+	}
+
+	command := selectCampaignsCommand + "WHERE advertiser_id = ? AND " + campaignTenantWhereCommand + " ORDER BY id ASC"
+	rows, err := cr.database.QueryContext(c, command, advertiserID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	campaigns := []domain.Campaign{}
+	for rows.Next() {
+		campaign, err := scanCampaign(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		campaigns = append(campaigns, campaign)
+	}
+	return campaigns, rows.Err()
+}
+
+// Update replaces the campaign except its advertiser, which does not change
+func (cr *campaignRepository) Update(c context.Context, campaign *domain.Campaign) error {
+	tenantID, err := domain.TenantFromContext(c)
+	if err != nil {
+		return err
+	}
+	targeting, err := marshalJSONColumn(campaign.Condition)
+	if err != nil {
+		return err
+	}
+
+	command := "UPDATE campaigns SET name = ?, status = ?, start_at = ?, end_at = ?, targeting = ? WHERE id = ? AND " + campaignTenantWhereCommand
+	_, err = cr.database.ExecContext(c, command, campaign.Name, campaign.Status,
+		nullableString(campaign.StartAt), nullableString(campaign.EndAt), targeting, campaign.ID, tenantID)
+	return toConstraintError(err)
+}
+
+func (cr *campaignRepository) UpdateStatus(c context.Context, id int64, status domain.CampaignStatus) error {
+	tenantID, err := domain.TenantFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	_, err = cr.database.ExecContext(c, "UPDATE campaigns SET status = ? WHERE id = ? AND "+campaignTenantWhereCommand, status, id, tenantID)
+	return err
+}
+
+// Delete removes a campaign without ads, and returns domain.ErrConflict otherwise
+func (cr *campaignRepository) Delete(c context.Context, id int64) error {
+	tenantID, err := domain.TenantFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	result, err := cr.database.ExecContext(c, "DELETE FROM campaigns WHERE id = ? AND "+campaignTenantWhereCommand, id, tenantID)
+	if err != nil {
+		return toConstraintError(err)
+	}
+	return checkAffected(result)
+}
@@ -0,0 +1,139 @@
+package repository_test
+
+import (
+	"dcard-backend/domain"
+	"dcard-backend/repository"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	query_campaigns       = "SELECT id, advertiser_id, name, status, start_at, end_at, targeting FROM campaigns "
+	query_insert_campaign = "INSERT INTO campaigns (advertiser_id, name, status, start_at, end_at, targeting) " +
+		"SELECT id, ?, ?, ?, ?, ? FROM advertisers WHERE id = ? AND tenant_id = ?"
+	campaign_tenant_where = "advertiser_id IN (SELECT id FROM advertisers WHERE tenant_id = ?)"
+)
+
+var campaignColumns = []string{"id", "advertiser_id", "name", "status", "start_at", "end_at", "targeting"}
+
+func TestCampaignCreate_Success_ShouldInsertDefaults(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec(query_insert_campaign).
+		WithArgs("Spring Sale", "active", "2024-03-01 08:00:00", nil, `{"ageStart":0,"ageEnd":0,"gender":["F"],"country":null,"platform":null}`, 2, testTenant).
+		WillReturnResult(sqlmock.NewResult(3, 1))
+
+	testCr := repository.NewCampaignRepository(db)
+	campaign := domain.Campaign{
+		AdvertiserID: 2,
+		Name:         "Spring Sale",
+		Status:       domain.CampaignActive,
+		StartAt:      "2024-03-01 08:00:00",
+		Condition:    &domain.Condition{Gender: []string{"F"}},
+	}
+	err = testCr.Create(tenantContext, &campaign)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), campaign.ID)
+}
+
+func TestCampaignCreate_AdvertiserOfAnotherTenant_ShouldReturnErrBadParamInput(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec(query_insert_campaign).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	testCr := repository.NewCampaignRepository(db)
+	err = testCr.Create(tenantContext, &domain.Campaign{AdvertiserID: 2, Name: "Spring Sale", Status: domain.CampaignActive})
+
+	assert.ErrorIs(t, err, domain.ErrBadParamInput)
+}
+
+func TestCampaignGetByID_Success_CampaignReturnWithTargeting(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(query_campaigns+"WHERE id = ? AND "+campaign_tenant_where).WithArgs(3, testTenant).
+		WillReturnRows(sqlmock.NewRows(campaignColumns).
+			AddRow(3, 2, "Spring Sale", "paused", "2024-03-01 08:00:00", nil, `{"ageStart":18,"excludeCountry":["CN"]}`))
+
+	testCr := repository.NewCampaignRepository(db)
+	campaign, err := testCr.GetByID(tenantContext, 3)
+
+	assert.NoError(t, err)
+	assert.Equal(t, domain.Campaign{
+		ID:           3,
+		AdvertiserID: 2,
+		Name:         "Spring Sale",
+		Status:       domain.CampaignPaused,
+		StartAt:      "2024-03-01 08:00:00",
+		Condition:    &domain.Condition{AgeStart: 18, ExcludeCountry: []string{"CN"}},
+	}, campaign)
+}
+
+func TestCampaignGetByID_NoRows_ShouldReturnErrNotFound(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(query_campaigns+"WHERE id = ? AND "+campaign_tenant_where).WithArgs(3, testTenant).WillReturnRows(sqlmock.NewRows(campaignColumns))
+
+	testCr := repository.NewCampaignRepository(db)
+	_, err = testCr.GetByID(tenantContext, 3)
+
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+}
+
+func TestCampaignFetchByAdvertiser_Success_CampaignsReturnByID(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(query_campaigns+"WHERE advertiser_id = ? AND "+campaign_tenant_where+" ORDER BY id ASC").WithArgs(2, testTenant).
+		WillReturnRows(sqlmock.NewRows(campaignColumns).
+			AddRow(3, 2, "Spring Sale", "active", nil, nil, nil).
+			AddRow(4, 2, "Summer Sale", "paused", nil, nil, nil))
+
+	testCr := repository.NewCampaignRepository(db)
+	campaigns, err := testCr.FetchByAdvertiser(tenantContext, 2)
+
+	assert.NoError(t, err)
+	if assert.Len(t, campaigns, 2) {
+		assert.Equal(t, "Spring Sale", campaigns[0].Name)
+		assert.Nil(t, campaigns[0].Condition)
+		assert.Equal(t, domain.CampaignPaused, campaigns[1].Status)
+	}
+}
+
+func TestCampaignDelete_HasAds_ShouldReturnErrConflict(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec("DELETE FROM campaigns WHERE id = ? AND "+campaign_tenant_where).WithArgs(3, testTenant).
+		WillReturnError(&mysql.MySQLError{Number: 1451, Message: "Cannot delete or update a parent row"})
+
+	testCr := repository.NewCampaignRepository(db)
+	err = testCr.Delete(tenantContext, 3)
+
+	assert.ErrorIs(t, err, domain.ErrConflict)
+}
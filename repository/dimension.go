@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"dcard-backend/domain"
+	"fmt"
+)
+
+// The commands below are built from a targeting dimension, so that every registered
+// dimension is stored and matched in the same way through its reference and link tables.
+
+func insertLinkCommand(d domain.Dimension) string {
+	return fmt.Sprintf("INSERT INTO %s (ad_id, %s, exclude) VALUES (?, (SELECT id FROM %s WHERE %s = ?), ?)",
+		d.LinkTable, d.LinkColumn, d.ValueTable, d.ValueColumn)
+}
+
+func deleteLinkCommand(d domain.Dimension) string {
+	return fmt.Sprintf("DELETE FROM %s WHERE ad_id = ?", d.LinkTable)
+}
+
+// includeJoinCommand joins the included values of ads, so that includeWhereCommand can match them
+func includeJoinCommand(d domain.Dimension) string {
+	return fmt.Sprintf("INNER JOIN %[1]s ON ads.id = %[1]s.ad_id AND %[1]s.exclude = 0 INNER JOIN %[2]s ON %[2]s.id = %[1]s.%[3]s",
+		d.LinkTable, d.ValueTable, d.LinkColumn)
+}
+
+func includeWhereCommand(d domain.Dimension, length int) string {
+	return fmt.Sprintf("%s.%s IN (%s)", d.ValueTable, d.ValueColumn, repeatQuestionMarks(length))
+}
+
+// excludeWhereCommand filters out ads excluding any of the values
+func excludeWhereCommand(d domain.Dimension, length int) string {
+	return fmt.Sprintf("ads.id NOT IN (SELECT %[1]s.ad_id FROM %[1]s INNER JOIN %[2]s ON %[2]s.id = %[1]s.%[3]s "+
+		"WHERE %[1]s.exclude = 1 AND %[2]s.%[4]s IN (%[5]s))",
+		d.LinkTable, d.ValueTable, d.LinkColumn, d.ValueColumn, repeatQuestionMarks(length))
+}
+
+// groupConcatCommand selects the included or excluded values of an ad concatenated by commas
+func groupConcatCommand(d domain.Dimension, exclude int) string {
+	return fmt.Sprintf("(SELECT GROUP_CONCAT(%[2]s.%[4]s) FROM %[1]s INNER JOIN %[2]s ON %[2]s.id = %[1]s.%[3]s "+
+		"WHERE %[1]s.ad_id = ads.id AND %[1]s.exclude = %[5]d)",
+		d.LinkTable, d.ValueTable, d.LinkColumn, d.ValueColumn, exclude)
+}
@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"database/sql"
+	"dcard-backend/domain"
+	"errors"
+	"fmt"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// MySQL error numbers of violated constraints
+const (
+	errDuplicateEntry  = 1062
+	errRowIsReferenced = 1451
+	errNoReferencedRow = 1452
+)
+
+// toConstraintError maps the errors of violated unique and foreign key constraints to domain
+// errors, and returns other errors as they are
+func toConstraintError(err error) error {
+	var mysqlErr *mysql.MySQLError
+	if !errors.As(err, &mysqlErr) {
+		return err
+	}
+
+	switch mysqlErr.Number {
+	case errDuplicateEntry, errRowIsReferenced:
+		return fmt.Errorf("%w: %s", domain.ErrConflict, mysqlErr.Message)
+	case errNoReferencedRow:
+		return fmt.Errorf("%w: %s", domain.ErrBadParamInput, mysqlErr.Message)
+	}
+	return err
+}
+
+// checkAffected returns domain.ErrNotFound when a statement changed no rows. MySQL does not
+// count rows updated to their current values, so it only suits deletes.
+func checkAffected(result sql.Result) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,11 @@
+package repository
+
+import "dcard-backend/domain"
+
+// CachedStatements returns the number of statements kept by the ad repository
+func CachedStatements(ar domain.AdRepository) int {
+	sc := ar.(*adRepository).statements
+	sc.mutex.Lock()
+	defer sc.mutex.Unlock()
+	return sc.recent.Len()
+}
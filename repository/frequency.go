@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"dcard-backend/domain"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// frequencySweepInterval is how often a server deletes expired counts
+const frequencySweepInterval = time.Minute
+
+// maxExpiredCountsPerSweep bounds the rows deleted by a sweep, so that a sweep never holds the
+// table for long. Expired counts are never read, so the rest are left to the next sweeps.
+const maxExpiredCountsPerSweep = 1000
+
+// Expiry times of the counts are stored in UTC
+const expiryLayout = "2006-01-02 15:04:05"
+
+// incrementCountCommand starts a count with its window, or increments it while its window has
+// not ended. The count is assigned first, so it compares the expiry before it is reset.
+const incrementCountCommand = "INSERT INTO ad_frequency_counts (tenant_id, user_key, ad_id, count, expires_at) VALUES (?, ?, ?, 1, ?) " +
+	"ON DUPLICATE KEY UPDATE count = IF(expires_at > ?, count + 1, 1), expires_at = IF(expires_at > ?, expires_at, VALUES(expires_at))"
+
+const deleteExpiredCountsCommand = "DELETE FROM ad_frequency_counts WHERE expires_at <= ? LIMIT ?"
+
+// frequencyRepository keeps the counts in the database, so that they are shared by every
+// server and survive restarts. Counts expire with their window, which is at most 30 days, and
+// are deleted by the sweeps of the servers, so the table only holds the users served within
+// the last window.
+type frequencyRepository struct {
+	database  *sql.DB
+	now       func() time.Time
+	mutex     sync.Mutex
+	nextSweep time.Time
+}
+
+func NewFrequencyRepository(db *sql.DB, now func() time.Time) domain.FrequencyRepository {
+	return &frequencyRepository{
+		database: db,
+		now:      now,
+	}
+}
+
+// userKey returns a fixed length key of a user id, which is taken from the request, so that
+// ids of any length take the same room
+func userKey(userID string) string {
+	hash := sha256.Sum256([]byte(userID))
+	return hex.EncodeToString(hash[:16])
+}
+
+func (fr *frequencyRepository) GetCounts(c context.Context, userID string, adIDs []int64) (map[int64]int, error) {
+	tenantID, err := domain.TenantFromContext(c)
+	if err != nil {
+		return nil, err
+	}
+	counts := map[int64]int{}
+	if len(adIDs) == 0 {
+		return counts, nil
+	}
+
+	command := "SELECT ad_id, count FROM ad_frequency_counts WHERE tenant_id = ? AND user_key = ? AND ad_id IN (" +
+		repeatQuestionMarks(len(adIDs)) + ") AND expires_at > ?"
+	args := append([]interface{}{tenantID, userKey(userID)}, adIDsToGenericSlice(adIDs)...)
+	args = append(args, fr.now().UTC().Format(expiryLayout))
+
+	rows, err := fr.database.QueryContext(c, command, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var adID int64
+		var count int
+		if err := rows.Scan(&adID, &count); err != nil {
+			return nil, err
+		}
+		counts[adID] = count
+	}
+	return counts, rows.Err()
+}
+
+func (fr *frequencyRepository) Increment(c context.Context, userID string, adID int64, window time.Duration) error {
+	tenantID, err := domain.TenantFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	now := fr.now().UTC()
+	if err := fr.sweep(c, now); err != nil {
+		return err
+	}
+
+	nowValue := now.Format(expiryLayout)
+	_, err = fr.database.ExecContext(c, incrementCountCommand,
+		tenantID, userKey(userID), adID, now.Add(window).Format(expiryLayout), nowValue, nowValue)
+	return err
+}
+
+// sweep deletes a batch of expired counts of every tenant once every frequencySweepInterval
+func (fr *frequencyRepository) sweep(c context.Context, now time.Time) error {
+	fr.mutex.Lock()
+	if now.Before(fr.nextSweep) {
+		fr.mutex.Unlock()
+		return nil
+	}
+	fr.nextSweep = now.Add(frequencySweepInterval)
+	fr.mutex.Unlock()
+
+	_, err := fr.database.ExecContext(c, deleteExpiredCountsCommand, now.Format(expiryLayout), maxExpiredCountsPerSweep)
+	return err
+}
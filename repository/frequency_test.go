@@ -0,0 +1,74 @@
+package repository_test
+
+import (
+	"context"
+	"dcard-backend/domain"
+	"dcard-backend/repository"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+const query_frequency_counts = "SELECT ad_id, count FROM ad_frequency_counts WHERE tenant_id = ? AND user_key = ? AND ad_id IN (?,?) AND expires_at > ?"
+
+const query_increment_count = "INSERT INTO ad_frequency_counts (tenant_id, user_key, ad_id, count, expires_at) VALUES (?, ?, ?, 1, ?) " +
+	"ON DUPLICATE KEY UPDATE count = IF(expires_at > ?, count + 1, 1), expires_at = IF(expires_at > ?, expires_at, VALUES(expires_at))"
+
+const query_delete_expired_counts = "DELETE FROM ad_frequency_counts WHERE expires_at <= ? LIMIT ?"
+
+// userKeyOfUser1 is the first 16 bytes of the SHA-256 of "user-1" in hex
+const userKeyOfUser1 = "c6c289e49e9c05b2145860387b73bcb1"
+
+func TestFrequencyRepository_GetCounts_ShouldReadCountsOfUserInWindow(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	// Expiry times are compared in UTC
+	now := time.Date(2024, 3, 1, 16, 0, 0, 0, time.FixedZone("Asia/Taipei", 8*60*60))
+	mock.ExpectQuery(query_frequency_counts).
+		WithArgs("team-a", userKeyOfUser1, 1, 2, "2024-03-01 08:00:00").
+		WillReturnRows(sqlmock.NewRows([]string{"ad_id", "count"}).AddRow(1, 2))
+
+	testFr := repository.NewFrequencyRepository(db, func() time.Time { return now })
+	counts, err := testFr.GetCounts(domain.WithTenant(context.Background(), "team-a"), "user-1", []int64{1, 2})
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[int64]int{1: 2}, counts)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestFrequencyRepository_Increment_ShouldSweepExpiredCountsOncePerInterval(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec(query_delete_expired_counts).WithArgs("2024-03-01 08:00:00", 1000).WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectExec(query_increment_count).
+		WithArgs("team-a", userKeyOfUser1, 1, "2024-03-01 09:00:00", "2024-03-01 08:00:00", "2024-03-01 08:00:00").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(query_increment_count).
+		WithArgs("team-a", userKeyOfUser1, 1, "2024-03-01 09:00:30", "2024-03-01 08:00:30", "2024-03-01 08:00:30").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(query_delete_expired_counts).WithArgs("2024-03-01 08:01:00", 1000).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(query_increment_count).
+		WithArgs("team-a", userKeyOfUser1, 2, "2024-03-02 08:01:00", "2024-03-01 08:01:00", "2024-03-01 08:01:00").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	now := time.Date(2024, 3, 1, 8, 0, 0, 0, time.UTC)
+	testFr := repository.NewFrequencyRepository(db, func() time.Time { return now })
+	c := domain.WithTenant(context.Background(), "team-a")
+
+	assert.NoError(t, testFr.Increment(c, "user-1", 1, time.Hour))
+	now = now.Add(30 * time.Second)
+	assert.NoError(t, testFr.Increment(c, "user-1", 1, time.Hour))
+	now = now.Add(30 * time.Second)
+	assert.NoError(t, testFr.Increment(c, "user-1", 2, 24*time.Hour))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"dcard-backend/domain"
+	"errors"
+)
+
+const (
+	selectLifecycleCommand = "SELECT tenant_id, id, start_at, end_at, lifecycle FROM ads "
+	// Expired ads are only returned when their end was moved past now since they expired
+	fetchLifecyclesCommand = selectLifecycleCommand + "WHERE id > ? AND deleted_at IS NULL AND (lifecycle <> 'expired' OR end_at >= NOW()) ORDER BY id ASC LIMIT ?"
+	getLifecycleCommand    = selectLifecycleCommand + adsOfTenantWhereCommand + "AND ads.id = ?"
+	updateLifecycleCommand = "UPDATE ads SET lifecycle = ? WHERE id = ? AND tenant_id = ? AND lifecycle = ? AND deleted_at IS NULL"
+)
+
+func scanLifecycle(scanner interface{ Scan(...interface{}) error }) (domain.AdLifecycleState, error) {
+	var state domain.AdLifecycleState
+	err := scanner.Scan(&state.TenantID, &state.ID, &state.StartAt, &state.EndAt, &state.Lifecycle)
+	return state, err
+}
+
+// FetchLifecycles is run by the server for every tenant, so it is not scoped to a tenant
+func (ar *adRepository) FetchLifecycles(c context.Context, afterID int64, limit int) ([]domain.AdLifecycleState, error) {
+	rows, err := ar.database.QueryContext(c, fetchLifecyclesCommand, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	states := []domain.AdLifecycleState{}
+	for rows.Next() {
+		state, err := scanLifecycle(rows)
+		if err != nil {
+			return nil, err
+		}
+		states = append(states, state)
+	}
+	return states, rows.Err()
+}
+
+func (ar *adRepository) GetLifecycle(c context.Context, id int64) (domain.AdLifecycleState, error) {
+	tenantID, err := domain.TenantFromContext(c)
+	if err != nil {
+		return domain.AdLifecycleState{}, err
+	}
+
+	state, err := scanLifecycle(ar.database.QueryRowContext(c, getLifecycleCommand, tenantID, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.AdLifecycleState{}, domain.ErrNotFound
+	}
+	return state, err
+}
+
+// lifecycleEvents are the events recorded when an ad moves into a lifecycle. Ads moved back to
+// scheduled, since their start was postponed, were recorded as updated by the change.
+var lifecycleEvents = map[domain.AdLifecycle]domain.AdEventType{
+	domain.AdLive:    domain.AdEventStarted,
+	domain.AdExpired: domain.AdEventExpired,
+}
+
+// UpdateLifecycle is not recorded in the audit log nor kept as a version, since it follows
+// from the times of the ad instead of being a change made by an actor. Its event is only
+// recorded by the server changing the ad.
+func (ar *adRepository) UpdateLifecycle(c context.Context, id int64, from domain.AdLifecycle, to domain.AdLifecycle) (bool, error) {
+	tenantID, err := domain.TenantFromContext(c)
+	if err != nil {
+		return false, err
+	}
+
+	changed := false
+	err = ar.inTransaction(c, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(c, updateLifecycleCommand, to, id, tenantID, from)
+		if err != nil {
+			return err
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		changed = affected == 1
+		eventType, ok := lifecycleEvents[to]
+		if !changed || !ok {
+			return nil
+		}
+		after, err := lockAd(c, tx, tenantID, id)
+		if err != nil {
+			return err
+		}
+		return newOutboxPublisher(tx).Publish(c, domain.AdEvent{Type: eventType, TenantID: tenantID, AdID: id, Ad: &after})
+	})
+	return changed, err
+}
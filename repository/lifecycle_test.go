@@ -0,0 +1,107 @@
+package repository_test
+
+import (
+	"context"
+	"dcard-backend/domain"
+	"dcard-backend/repository"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	query_fetch_lifecycles = "SELECT tenant_id, id, start_at, end_at, lifecycle FROM ads " +
+		"WHERE id > ? AND deleted_at IS NULL AND (lifecycle <> 'expired' OR end_at >= NOW()) ORDER BY id ASC LIMIT ?"
+	query_get_lifecycle    = "SELECT tenant_id, id, start_at, end_at, lifecycle FROM ads WHERE ads.tenant_id = ? AND ads.deleted_at IS NULL AND ads.id = ?"
+	query_update_lifecycle = "UPDATE ads SET lifecycle = ? WHERE id = ? AND tenant_id = ? AND lifecycle = ? AND deleted_at IS NULL"
+)
+
+var lifecycleColumns = []string{"tenant_id", "id", "start_at", "end_at", "lifecycle"}
+
+// FetchLifecycles is run by the server for every tenant, so it is not scoped to a tenant
+func TestFetchLifecycles_Success_ShouldReturnAdsOfEveryTenant(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(query_fetch_lifecycles).WithArgs(0, 1000).
+		WillReturnRows(sqlmock.NewRows(lifecycleColumns).
+			AddRow(testTenant, 1, mockAd.StartAt, mockAd.EndAt, "scheduled").
+			AddRow("team-b", 2, mockAd.StartAt, mockAd.EndAt, "live"))
+
+	testAr := repository.NewAdRepository(db)
+	states, err := testAr.FetchLifecycles(context.Background(), 0, 1000)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.AdLifecycleState{
+		{TenantID: testTenant, ID: 1, StartAt: mockAd.StartAt, EndAt: mockAd.EndAt, Lifecycle: domain.AdScheduled},
+		{TenantID: "team-b", ID: 2, StartAt: mockAd.StartAt, EndAt: mockAd.EndAt, Lifecycle: domain.AdLive},
+	}, states)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetLifecycle_DeletedAd_ShouldReturnErrNotFound(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(query_get_lifecycle).WithArgs(testTenant, 1).WillReturnRows(sqlmock.NewRows(lifecycleColumns))
+
+	testAr := repository.NewAdRepository(db)
+	_, err = testAr.GetLifecycle(tenantContext, 1)
+
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateLifecycle_ChangedByAnotherServer_ShouldReturnFalse(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(query_update_lifecycle).
+		WithArgs(domain.AdLive, 1, testTenant, domain.AdScheduled).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	testAr := repository.NewAdRepository(db)
+	changed, err := testAr.UpdateLifecycle(tenantContext, 1, domain.AdScheduled, domain.AdLive)
+
+	assert.NoError(t, err)
+	assert.False(t, changed)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateLifecycle_Started_ShouldRecordStartedInOutbox(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(query_update_lifecycle).
+		WithArgs(domain.AdLive, 1, testTenant, domain.AdScheduled).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	// The event keeps the ad as it started
+	expectLockAd(mock, "active")
+	mock.ExpectExec(query_insert_outbox).
+		WithArgs(testTenant, 1, domain.AdEventStarted, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	testAr := repository.NewAdRepository(db)
+	changed, err := testAr.UpdateLifecycle(tenantContext, 1, domain.AdScheduled, domain.AdLive)
+
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,230 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"dcard-backend/domain"
+	"fmt"
+	"time"
+)
+
+const (
+	insertOutboxCommand = "INSERT INTO outbox (tenant_id, ad_id, event_type, ad) VALUES (?, ?, ?, ?)"
+	fetchOutboxCommand  = "SELECT id, tenant_id, ad_id, event_type, ad, created_at FROM outbox WHERE id > ? ORDER BY id ASC LIMIT ?"
+	lastOutboxIDCommand = "SELECT COALESCE(MAX(id), 0) FROM outbox WHERE created_at < NOW() - INTERVAL ? SECOND"
+	// A new subscription starts after the newest event instead of handling every event kept
+	insertCheckpointCommand = "INSERT IGNORE INTO outbox_checkpoints (subscription, last_id) SELECT ?, COALESCE(MAX(id), 0) FROM outbox"
+	// The checkpoint is only locked while it is leased or advanced, not while its events are handled
+	lockCheckpointCommand = "SELECT last_id, gaps, failure, owner IS NOT NULL AND owner <> ? AND lease_until > NOW() " +
+		"FROM outbox_checkpoints WHERE subscription = ? FOR UPDATE"
+	leaseCheckpointCommand = "UPDATE outbox_checkpoints SET owner = ?, lease_until = NOW() + INTERVAL ? SECOND WHERE subscription = ?"
+	lockOwnerCommand       = "SELECT owner FROM outbox_checkpoints WHERE subscription = ? FOR UPDATE"
+	advanceCommand         = "UPDATE outbox_checkpoints SET last_id = ?, gaps = ?, failure = ?, lease_until = NOW() + INTERVAL ? SECOND WHERE subscription = ?"
+	// An event dead-lettered again by a subscription which lost its lease meanwhile is kept once
+	deadLetterCommand = "INSERT IGNORE INTO outbox_dead_letters (subscription, event_id, tenant_id, ad_id, event_type, ad, last_error) VALUES (?, ?, ?, ?, ?, ?, ?)"
+	// A checkpoint no server leased for longer than the retention is of a subscription that was
+	// removed, which would otherwise keep every event from being trimmed
+	deleteStaleCheckpointsCommand = "DELETE FROM outbox_checkpoints WHERE lease_until < NOW() - INTERVAL ? SECOND"
+	trimOutboxCommand             = "DELETE FROM outbox WHERE created_at < NOW() - INTERVAL ? SECOND " +
+		"AND id <= (SELECT COALESCE(MIN(last_id), 0) FROM outbox_checkpoints)"
+)
+
+// auditEvents are the events of the changes recorded in the audit log
+var auditEvents = map[domain.AuditAction]domain.AdEventType{
+	domain.AuditCreate:   domain.AdEventCreated,
+	domain.AuditUpdate:   domain.AdEventUpdated,
+	domain.AuditRollback: domain.AdEventUpdated,
+	domain.AuditPause:    domain.AdEventPaused,
+	domain.AuditResume:   domain.AdEventResumed,
+	domain.AuditDelete:   domain.AdEventDeleted,
+	domain.AuditRestore:  domain.AdEventRestored,
+}
+
+// execer runs statements on the database or in a transaction
+type execer interface {
+	ExecContext(c context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// outboxPublisher records events in the outbox with exec, which is the transaction of the change
+// for the events of changes, so that an event is only recorded if its change commits
+type outboxPublisher struct {
+	exec execer
+}
+
+func newOutboxPublisher(exec execer) domain.EventPublisher {
+	return &outboxPublisher{
+		exec: exec,
+	}
+}
+
+// Publish records the event for its tenant, along with the ad as the change left it
+func (op *outboxPublisher) Publish(c context.Context, event domain.AdEvent) error {
+	adColumn, err := marshalJSONColumn(event.Ad)
+	if err != nil {
+		return err
+	}
+	_, err = op.exec.ExecContext(c, insertOutboxCommand, event.TenantID, event.AdID, event.Type, adColumn)
+	return err
+}
+
+// recordChange records a change of an ad in the audit log and its event in the outbox
+func recordChange(c context.Context, tx *sql.Tx, tenantID string, adID int64, action domain.AuditAction, before *domain.Ad, after *domain.Ad) error {
+	if err := insertAuditEntry(c, tx, tenantID, adID, action, before, after); err != nil {
+		return err
+	}
+	return newOutboxPublisher(tx).Publish(c, domain.AdEvent{Type: auditEvents[action], TenantID: tenantID, AdID: adID, Ad: after})
+}
+
+type outboxRepository struct {
+	database *sql.DB
+}
+
+func NewOutboxRepository(db *sql.DB) domain.OutboxRepository {
+	return &outboxRepository{
+		database: db,
+	}
+}
+
+func (or *outboxRepository) Publish(c context.Context, event domain.AdEvent) error {
+	tenantID, err := domain.TenantFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	event.TenantID = tenantID
+	return newOutboxPublisher(or.database).Publish(c, event)
+}
+
+// FetchAfter is run by the server for every tenant, so it is not scoped to a tenant
+func (or *outboxRepository) FetchAfter(c context.Context, afterID int64, limit int) ([]domain.AdEvent, error) {
+	rows, err := or.database.QueryContext(c, fetchOutboxCommand, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanOutboxEvents(rows)
+}
+
+// FetchIDs is run by the server for every tenant, so it is not scoped to a tenant
+func (or *outboxRepository) FetchIDs(c context.Context, ids []int64) ([]domain.AdEvent, error) {
+	if len(ids) == 0 {
+		return []domain.AdEvent{}, nil
+	}
+
+	command := "SELECT id, tenant_id, ad_id, event_type, ad, created_at FROM outbox WHERE id IN (" + repeatQuestionMarks(len(ids)) + ") ORDER BY id ASC"
+	rows, err := or.database.QueryContext(c, command, adIDsToGenericSlice(ids)...)
+	if err != nil {
+		return nil, err
+	}
+	return scanOutboxEvents(rows)
+}
+
+func scanOutboxEvents(rows *sql.Rows) ([]domain.AdEvent, error) {
+	defer rows.Close()
+
+	events := []domain.AdEvent{}
+	for rows.Next() {
+		var event domain.AdEvent
+		var ad sql.NullString
+		if err := rows.Scan(&event.ID, &event.TenantID, &event.AdID, &event.Type, &ad, &event.At); err != nil {
+			return nil, err
+		}
+		var err error
+		if event.Ad, err = unmarshalJSONColumn[domain.Ad](ad); err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+func (or *outboxRepository) LastIDBefore(c context.Context, age time.Duration) (int64, error) {
+	var id int64
+	err := or.database.QueryRowContext(c, lastOutboxIDCommand, int(age.Seconds())).Scan(&id)
+	return id, err
+}
+
+func (or *outboxRepository) Acquire(c context.Context, subscription string, owner string, lease time.Duration) (domain.OutboxCheckpoint, bool, error) {
+	if _, err := or.database.ExecContext(c, insertCheckpointCommand, subscription); err != nil {
+		return domain.OutboxCheckpoint{}, false, err
+	}
+
+	var checkpoint domain.OutboxCheckpoint
+	acquired := false
+	err := inTransaction(c, or.database, func(tx *sql.Tx) error {
+		var gaps, failure sql.NullString
+		var held bool
+		if err := tx.QueryRowContext(c, lockCheckpointCommand, owner, subscription).Scan(&checkpoint.LastID, &gaps, &failure, &held); err != nil {
+			return err
+		}
+		if held {
+			return nil
+		}
+
+		decodedGaps, err := unmarshalJSONColumn[map[int64]time.Time](gaps)
+		if err != nil {
+			return err
+		}
+		if decodedGaps != nil {
+			checkpoint.Gaps = *decodedGaps
+		}
+		if checkpoint.Failure, err = unmarshalJSONColumn[domain.OutboxFailure](failure); err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(c, leaseCheckpointCommand, owner, int(lease.Seconds()), subscription); err != nil {
+			return err
+		}
+		acquired = true
+		return nil
+	})
+	if err != nil {
+		return domain.OutboxCheckpoint{}, false, err
+	}
+	return checkpoint, acquired, nil
+}
+
+func (or *outboxRepository) Advance(c context.Context, subscription string, owner string, checkpoint domain.OutboxCheckpoint, lease time.Duration) error {
+	var gaps interface{}
+	if len(checkpoint.Gaps) > 0 {
+		var err error
+		if gaps, err = marshalJSONColumn(&checkpoint.Gaps); err != nil {
+			return err
+		}
+	}
+	failure, err := marshalJSONColumn(checkpoint.Failure)
+	if err != nil {
+		return err
+	}
+
+	return inTransaction(c, or.database, func(tx *sql.Tx) error {
+		var holder sql.NullString
+		if err := tx.QueryRowContext(c, lockOwnerCommand, subscription).Scan(&holder); err != nil {
+			return err
+		}
+		if holder.String != owner {
+			return fmt.Errorf("%w: the checkpoint of %s is leased by another server", domain.ErrConflict, subscription)
+		}
+
+		_, err := tx.ExecContext(c, advanceCommand, checkpoint.LastID, gaps, failure, int(lease.Seconds()), subscription)
+		return err
+	})
+}
+
+// DeadLetter copies the event, since the outbox is trimmed
+func (or *outboxRepository) DeadLetter(c context.Context, subscription string, event domain.AdEvent, lastError string) error {
+	ad, err := marshalJSONColumn(event.Ad)
+	if err != nil {
+		return err
+	}
+
+	_, err = or.database.ExecContext(c, deadLetterCommand, subscription, event.ID, event.TenantID, event.AdID, event.Type, ad, lastError)
+	return err
+}
+
+func (or *outboxRepository) Trim(c context.Context, retention time.Duration) error {
+	if _, err := or.database.ExecContext(c, deleteStaleCheckpointsCommand, int(retention.Seconds())); err != nil {
+		return err
+	}
+	_, err := or.database.ExecContext(c, trimOutboxCommand, int(retention.Seconds()))
+	return err
+}
@@ -0,0 +1,236 @@
+package repository_test
+
+import (
+	"context"
+	"dcard-backend/domain"
+	"dcard-backend/repository"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	query_insert_outbox     = "INSERT INTO outbox (tenant_id, ad_id, event_type, ad) VALUES (?, ?, ?, ?)"
+	query_fetch_outbox      = "SELECT id, tenant_id, ad_id, event_type, ad, created_at FROM outbox WHERE id > ? ORDER BY id ASC LIMIT ?"
+	query_fetch_outbox_ids  = "SELECT id, tenant_id, ad_id, event_type, ad, created_at FROM outbox WHERE id IN (?,?) ORDER BY id ASC"
+	query_last_outbox_id    = "SELECT COALESCE(MAX(id), 0) FROM outbox WHERE created_at < NOW() - INTERVAL ? SECOND"
+	query_insert_checkpoint = "INSERT IGNORE INTO outbox_checkpoints (subscription, last_id) SELECT ?, COALESCE(MAX(id), 0) FROM outbox"
+	query_lock_checkpoint   = "SELECT last_id, gaps, failure, owner IS NOT NULL AND owner <> ? AND lease_until > NOW() " +
+		"FROM outbox_checkpoints WHERE subscription = ? FOR UPDATE"
+	query_lease_checkpoint = "UPDATE outbox_checkpoints SET owner = ?, lease_until = NOW() + INTERVAL ? SECOND WHERE subscription = ?"
+	query_lock_owner       = "SELECT owner FROM outbox_checkpoints WHERE subscription = ? FOR UPDATE"
+	query_advance          = "UPDATE outbox_checkpoints SET last_id = ?, gaps = ?, failure = ?, lease_until = NOW() + INTERVAL ? SECOND WHERE subscription = ?"
+	query_dead_letter      = "INSERT IGNORE INTO outbox_dead_letters (subscription, event_id, tenant_id, ad_id, event_type, ad, last_error) VALUES (?, ?, ?, ?, ?, ?, ?)"
+	query_delete_stale     = "DELETE FROM outbox_checkpoints WHERE lease_until < NOW() - INTERVAL ? SECOND"
+	query_trim_outbox      = "DELETE FROM outbox WHERE created_at < NOW() - INTERVAL ? SECOND " +
+		"AND id <= (SELECT COALESCE(MIN(last_id), 0) FROM outbox_checkpoints)"
+)
+
+var outboxColumns = []string{"id", "tenant_id", "ad_id", "event_type", "ad", "created_at"}
+
+func TestPublishEvent_Success_ShouldRecordEventOfTenant(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec(query_insert_outbox).
+		WithArgs(testTenant, 1, domain.AdEventBudgetExhausted, nil).
+		WillReturnResult(sqlmock.NewResult(42, 1))
+
+	testOr := repository.NewOutboxRepository(db)
+	err = testOr.Publish(tenantContext, domain.AdEvent{Type: domain.AdEventBudgetExhausted, TenantID: "team-b", AdID: 1})
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// FetchAfter is run by the server for every tenant, so it is not scoped to a tenant
+func TestFetchOutboxAfter_Success_ShouldReturnEventsOfEveryTenant(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(query_fetch_outbox).WithArgs(41, 500).
+		WillReturnRows(sqlmock.NewRows(outboxColumns).
+			AddRow(42, testTenant, 1, "created", `{"id":1,"title":"AD 1"}`, "2024-01-01 08:00:00").
+			AddRow(43, "team-b", 2, "deleted", nil, "2024-01-01 08:00:01"))
+
+	testOr := repository.NewOutboxRepository(db)
+	events, err := testOr.FetchAfter(context.Background(), 41, 500)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.AdEvent{
+		{ID: 42, Type: domain.AdEventCreated, TenantID: testTenant, AdID: 1, At: "2024-01-01 08:00:00", Ad: &domain.Ad{ID: 1, Title: "AD 1"}},
+		{ID: 43, Type: domain.AdEventDeleted, TenantID: "team-b", AdID: 2, At: "2024-01-01 08:00:01"},
+	}, events)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestFetchOutboxIDs_Success_ShouldReturnCommittedEvents(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	// Event 43 is still not committed
+	mock.ExpectQuery(query_fetch_outbox_ids).WithArgs(42, 43).
+		WillReturnRows(sqlmock.NewRows(outboxColumns).AddRow(42, testTenant, 1, "paused", nil, "2024-01-01 08:00:00"))
+
+	testOr := repository.NewOutboxRepository(db)
+	events, err := testOr.FetchIDs(context.Background(), []int64{42, 43})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.AdEvent{{ID: 42, Type: domain.AdEventPaused, TenantID: testTenant, AdID: 1, At: "2024-01-01 08:00:00"}}, events)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestLastOutboxIDBefore_Success_ShouldPassAgeInSeconds(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(query_last_outbox_id).WithArgs(4).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(41))
+
+	testOr := repository.NewOutboxRepository(db)
+	id, err := testOr.LastIDBefore(context.Background(), 4*time.Second)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(41), id)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAcquireCheckpoint_Free_ShouldLeaseAndReturnCheckpoint(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec(query_insert_checkpoint).WithArgs("webhooks").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectBegin()
+	mock.ExpectQuery(query_lock_checkpoint).WithArgs("server-a", "webhooks").
+		WillReturnRows(sqlmock.NewRows([]string{"last_id", "gaps", "failure", "held"}).
+			AddRow(41, `{"40":"2024-01-01T00:00:00Z"}`, `{"eventId":42,"attempts":2,"retryAt":"2024-01-01T00:00:04Z"}`, false))
+	mock.ExpectExec(query_lease_checkpoint).WithArgs("server-a", 64, "webhooks").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	testOr := repository.NewOutboxRepository(db)
+	checkpoint, acquired, err := testOr.Acquire(context.Background(), "webhooks", "server-a", 64*time.Second)
+
+	assert.NoError(t, err)
+	assert.True(t, acquired)
+	assert.Equal(t, domain.OutboxCheckpoint{
+		LastID:  41,
+		Gaps:    map[int64]time.Time{40: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		Failure: &domain.OutboxFailure{EventID: 42, Attempts: 2, RetryAt: time.Date(2024, 1, 1, 0, 0, 4, 0, time.UTC)},
+	}, checkpoint)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAcquireCheckpoint_LeasedByAnotherServer_ShouldNotAcquire(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec(query_insert_checkpoint).WithArgs("webhooks").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectBegin()
+	mock.ExpectQuery(query_lock_checkpoint).WithArgs("server-a", "webhooks").
+		WillReturnRows(sqlmock.NewRows([]string{"last_id", "gaps", "failure", "held"}).AddRow(41, nil, nil, true))
+	mock.ExpectCommit()
+
+	testOr := repository.NewOutboxRepository(db)
+	_, acquired, err := testOr.Acquire(context.Background(), "webhooks", "server-a", 64*time.Second)
+
+	assert.NoError(t, err)
+	assert.False(t, acquired)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAdvanceCheckpoint_Owner_ShouldSaveCheckpointAndRenewLease(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(query_lock_owner).WithArgs("webhooks").WillReturnRows(sqlmock.NewRows([]string{"owner"}).AddRow("server-a"))
+	mock.ExpectExec(query_advance).WithArgs(43, `{"40":"2024-01-01T00:00:00Z"}`, nil, 64, "webhooks").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	testOr := repository.NewOutboxRepository(db)
+	err = testOr.Advance(context.Background(), "webhooks", "server-a", domain.OutboxCheckpoint{
+		LastID: 43,
+		Gaps:   map[int64]time.Time{40: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}, 64*time.Second)
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAdvanceCheckpoint_LeaseTakenOver_ShouldReturnErrConflict(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(query_lock_owner).WithArgs("webhooks").WillReturnRows(sqlmock.NewRows([]string{"owner"}).AddRow("server-b"))
+	mock.ExpectRollback()
+
+	testOr := repository.NewOutboxRepository(db)
+	err = testOr.Advance(context.Background(), "webhooks", "server-a", domain.OutboxCheckpoint{LastID: 43}, 64*time.Second)
+
+	assert.ErrorIs(t, err, domain.ErrConflict)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeadLetter_Success_ShouldCopyEvent(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec(query_dead_letter).
+		WithArgs("stream", 42, testTenant, 1, domain.AdEventUpdated, sqlmock.AnyArg(), "unavailable").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	testOr := repository.NewOutboxRepository(db)
+	err = testOr.DeadLetter(context.Background(), "stream", domain.AdEvent{
+		ID: 42, Type: domain.AdEventUpdated, TenantID: testTenant, AdID: 1, Ad: &domain.Ad{ID: 1, Title: "AD 1"},
+	}, "unavailable")
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTrimOutbox_Success_ShouldDeleteHandledEventsOlderThanRetention(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	// The checkpoint of a removed subscription is deleted before it holds the events back
+	mock.ExpectExec(query_delete_stale).WithArgs(86400).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(query_trim_outbox).WithArgs(86400).WillReturnResult(sqlmock.NewResult(0, 3))
+
+	testOr := repository.NewOutboxRepository(db)
+	err = testOr.Trim(context.Background(), 24*time.Hour)
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
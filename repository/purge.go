@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"dcard-backend/domain"
+)
+
+// selectPurgeableAdsCommand locks a batch of ads soft deleted before a time, so that they can
+// not be restored while their rows are deleted
+const selectPurgeableAdsCommand = "SELECT id FROM ads WHERE deleted_at IS NOT NULL AND deleted_at < ? ORDER BY id ASC LIMIT ? FOR UPDATE"
+
+// purgeCommands delete the rows of ads from the tables referencing them, and then the ads. The
+// event counts, the versions and the audit log of the ads are kept for reporting.
+func purgeCommands(length int) []string {
+	var commands []string
+	for _, dimension := range domain.Dimensions() {
+		commands = append(commands, "DELETE FROM "+dimension.LinkTable+" WHERE ad_id IN ("+repeatQuestionMarks(length)+")")
+	}
+	return append(commands,
+		"DELETE FROM ad_creatives WHERE ad_id IN ("+repeatQuestionMarks(length)+")",
+		"DELETE FROM ads WHERE id IN ("+repeatQuestionMarks(length)+")")
+}
+
+// Purge deletes a batch in a transaction of its own, which only locks the rows of the batch,
+// so that purging many ads does not hold the tables. It is not scoped to a tenant.
+func (ar *adRepository) Purge(c context.Context, deletedBefore string, batchSize int) (int, error) {
+	var purged int
+	err := ar.inTransaction(c, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(c, selectPurgeableAdsCommand, deletedBefore, batchSize)
+		if err != nil {
+			return err
+		}
+		var adIDs []int64
+		for rows.Next() {
+			var adID int64
+			if err := rows.Scan(&adID); err != nil {
+				rows.Close()
+				return err
+			}
+			adIDs = append(adIDs, adID)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil || len(adIDs) == 0 {
+			return err
+		}
+
+		args := adIDsToGenericSlice(adIDs)
+		for _, command := range purgeCommands(len(adIDs)) {
+			if _, err := tx.ExecContext(c, command, args...); err != nil {
+				return err
+			}
+		}
+		purged = len(adIDs)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return purged, nil
+}
@@ -0,0 +1,139 @@
+package repository_test
+
+import (
+	"context"
+	"dcard-backend/domain"
+	"dcard-backend/repository"
+	"fmt"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	query_restore_ad    = "UPDATE ads SET deleted_at = NULL WHERE id = ? AND tenant_id = ?"
+	query_lock_deleted  = query_ads_with_targeting + "WHERE ads.tenant_id = ? AND ads.deleted_at IS NOT NULL AND ads.id = ? FOR UPDATE"
+	query_purgeable_ads = "SELECT id FROM ads WHERE deleted_at IS NOT NULL AND deleted_at < ? ORDER BY id ASC LIMIT ? FOR UPDATE"
+	testDeletedBefore   = "2024-01-01T08:00:00+08:00"
+	testPurgeBatchSize  = 2
+)
+
+func TestRestore_DeletedAd_ShouldUndoDeleteAndRecordAfter(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(query_lock_deleted).
+		WithArgs(testTenant, 1).
+		WillReturnRows(sqlmock.NewRows(adsWithTargetingColumns).
+			AddRow(1, nil, mockAd.Title, mockAd.StartAt, mockAd.EndAt, 10, 20, nil, "", "", "", "", nil, nil, 0, 1, nil, "active", 2, "live",
+				"M,F", "TW,JP", "web,ios", "en", nil, nil, nil, nil))
+	mock.ExpectQuery(query_ad_creatives_of).WithArgs(testTenant, 1).WillReturnRows(sqlmock.NewRows(creativeColumns))
+	mock.ExpectExec(query_restore_ad).WithArgs(1, testTenant).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(query_insert_audit).
+		WithArgs(testTenant, 1, "restore", testActor, testRequestID, nil, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(query_insert_outbox).
+		WithArgs(testTenant, 1, "restored", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	testAr := repository.NewAdRepository(db)
+	err = testAr.Restore(auditContext, 1)
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRestore_AdNotDeleted_ShouldRollbackWithErrNotFound(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(query_lock_deleted).WithArgs(testTenant, 1).WillReturnRows(sqlmock.NewRows(adsWithTargetingColumns))
+	mock.ExpectRollback()
+
+	testAr := repository.NewAdRepository(db)
+	err = testAr.Restore(tenantContext, 1)
+
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Purge is run by the server for every tenant, so it is not scoped to a tenant
+func TestPurge_FullBatch_ShouldDeleteLinkRowsThenAds(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(query_purgeable_ads).
+		WithArgs(testDeletedBefore, testPurgeBatchSize).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3).AddRow(7))
+	for _, table := range []string{"ad_gender", "ad_country", "ad_platform", "ad_language", "ad_creatives"} {
+		mock.ExpectExec("DELETE FROM "+table+" WHERE ad_id IN (?,?)").
+			WithArgs(3, 7).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+	}
+	mock.ExpectExec("DELETE FROM ads WHERE id IN (?,?)").WithArgs(3, 7).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	testAr := repository.NewAdRepository(db)
+	purged, err := testAr.Purge(context.Background(), testDeletedBefore, testPurgeBatchSize)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, purged)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPurge_NoDeletedAds_ShouldDeleteNothing(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(query_purgeable_ads).
+		WithArgs(testDeletedBefore, testPurgeBatchSize).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectCommit()
+
+	testAr := repository.NewAdRepository(db)
+	purged, err := testAr.Purge(context.Background(), testDeletedBefore, testPurgeBatchSize)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0, purged)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPurge_DeleteFail_ShouldRollbackBatch(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(query_purgeable_ads).
+		WithArgs(testDeletedBefore, testPurgeBatchSize).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+	mock.ExpectExec("DELETE FROM ad_gender WHERE ad_id IN (?)").WithArgs(3).WillReturnError(fmt.Errorf("Error"))
+	mock.ExpectRollback()
+
+	testAr := repository.NewAdRepository(db)
+	purged, err := testAr.Purge(context.Background(), testDeletedBefore, testPurgeBatchSize)
+
+	assert.Error(t, err)
+	assert.Equal(t, 0, purged)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
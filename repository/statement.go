@@ -0,0 +1,148 @@
+package repository
+
+import (
+	"container/list"
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	// DefaultMaxCachedStatements bounds the number of statements kept on the server by default.
+	// GetByCondition builds one query per combination of the optional conditions, whose lists
+	// of values are padded by padValues. Lists of up to 8 values give 2048 combinations, so the
+	// queries used the least recently are closed beyond this number.
+	DefaultMaxCachedStatements = 2048
+	// prepareTimeout bounds the preparation of a statement, which does not end with the
+	// request that started it since other requests may wait for the same statement
+	prepareTimeout = 5 * time.Second
+)
+
+// cachedStatement is a statement of the cache, which is closed once it is evicted and no
+// request uses it anymore
+type cachedStatement struct {
+	command string
+	stmt    *sql.Stmt
+	refs    int
+	evicted bool
+}
+
+// statementCache keeps prepared statements keyed by their query, so that each query shape
+// is prepared once and reused across requests instead of once per call. Statements are
+// prepared outside the lock, once per query however many requests wait for it.
+type statementCache struct {
+	database      *sql.DB
+	maxStatements int
+	mutex         sync.Mutex
+	statements    map[string]*list.Element
+	recent        *list.List
+	inflight      singleflight.Group
+}
+
+func newStatementCache(db *sql.DB, maxStatements int) *statementCache {
+	return &statementCache{
+		database:      db,
+		maxStatements: maxStatements,
+		statements:    map[string]*list.Element{},
+		recent:        list.New(),
+	}
+}
+
+// prepare returns the cached statement of the command, preparing it if needed. The returned
+// release function must be called once the statement is no longer used. Requests waiting for
+// the same statement share its preparation, which is detached from their contexts so that the
+// cancellation of one does not fail the others, while each stops waiting when its own ends.
+func (sc *statementCache) prepare(c context.Context, command string) (*sql.Stmt, func(), error) {
+	for {
+		if stmt, release, ok := sc.acquire(command); ok {
+			return stmt, release, nil
+		}
+
+		prepared := sc.inflight.DoChan(command, func() (interface{}, error) {
+			ctx, cancel := context.WithTimeout(context.WithoutCancel(c), prepareTimeout)
+			defer cancel()
+
+			stmt, err := sc.database.PrepareContext(ctx, command)
+			if err != nil {
+				return nil, err
+			}
+			sc.add(command, stmt)
+			return nil, nil
+		})
+		select {
+		case result := <-prepared:
+			if result.Err != nil {
+				return nil, nil, result.Err
+			}
+		case <-c.Done():
+			return nil, nil, c.Err()
+		}
+	}
+}
+
+// acquire returns the statement of the command if it is cached, and marks it as the most
+// recently used
+func (sc *statementCache) acquire(command string) (*sql.Stmt, func(), bool) {
+	sc.mutex.Lock()
+	defer sc.mutex.Unlock()
+
+	element, ok := sc.statements[command]
+	if !ok {
+		return nil, nil, false
+	}
+	sc.recent.MoveToFront(element)
+	cached := element.Value.(*cachedStatement)
+	cached.refs++
+
+	return cached.stmt, func() {
+		sc.mutex.Lock()
+		defer sc.mutex.Unlock()
+		cached.refs--
+		if cached.evicted && cached.refs == 0 {
+			cached.stmt.Close()
+		}
+	}, true
+}
+
+// add caches the statement of the command, and evicts the least recently used statements
+// beyond maxStatements
+func (sc *statementCache) add(command string, stmt *sql.Stmt) {
+	sc.mutex.Lock()
+	defer sc.mutex.Unlock()
+
+	if _, ok := sc.statements[command]; ok {
+		stmt.Close()
+		return
+	}
+	sc.statements[command] = sc.recent.PushFront(&cachedStatement{command: command, stmt: stmt})
+
+	for sc.recent.Len() > sc.maxStatements {
+		cached := sc.recent.Remove(sc.recent.Back()).(*cachedStatement)
+		delete(sc.statements, cached.command)
+		cached.evicted = true
+		if cached.refs == 0 {
+			cached.stmt.Close()
+		}
+	}
+}
+
+// padValues pads a list of values of an IN list to the next power of two by repeating its
+// last value, which matches the same rows, so that lists of any length share a few queries
+func padValues[T any](values []T) []T {
+	if len(values) == 0 {
+		return values
+	}
+	length := 1
+	for length < len(values) {
+		length *= 2
+	}
+	padded := make([]T, length)
+	copy(padded, values)
+	for i := len(values); i < length; i++ {
+		padded[i] = values[len(values)-1]
+	}
+	return padded
+}
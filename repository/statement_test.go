@@ -0,0 +1,217 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql"
+	"dcard-backend/domain"
+	"dcard-backend/repository"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+const loadTestRequests = 200
+
+// newLoadTestDatabase opens a stub database for concurrent requests. sqlmock prepares a
+// statement once per connection, so the pool is held at one connection for the prepares of
+// the cache to be counted, while the requests still race for the cache.
+func newLoadTestDatabase(t *testing.T) (*sql.DB, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	db.SetMaxOpenConns(1)
+	mock.MatchExpectationsInOrder(false)
+	return db, mock
+}
+
+// runConcurrently runs the request loadTestRequests times at once, and returns the errors
+func runConcurrently(request func() error) []error {
+	var wg sync.WaitGroup
+	errs := make(chan error, loadTestRequests)
+	for i := 0; i < loadTestRequests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := request(); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	var failed []error
+	for err := range errs {
+		failed = append(failed, err)
+	}
+	return failed
+}
+
+func TestGetByCondition_UnderConcurrentLoad_ShouldPrepareOnceAndKeepConnectionsStable(t *testing.T) {
+	db, mock := newLoadTestDatabase(t)
+	defer db.Close()
+
+	query := "SELECT ads.id, ads.title, ads.start_at, ads.end_at, ads.description, ads.image_url, ads.click_url, ads.call_to_action, " +
+		"ads.schedule, ads.frequency_cap, ads.budget, ads.priority, ads.weight FROM ads "
+	query += "INNER JOIN ad_country ON ads.id = ad_country.ad_id AND ad_country.exclude = 0 INNER JOIN countries ON countries.id = ad_country.country_id "
+	query += "WHERE countries.country IN (?,?) AND "
+	query += query_exclude_country
+	query += "ads.start_at <= NOW() AND ads.end_at >= NOW() AND ads.tenant_id = ? " +
+		"AND ads.deleted_at IS NULL AND ads.status = 'active' AND (ads.campaign_id IS NULL OR ads.campaign_id IN (SELECT id FROM campaigns WHERE status = 'active')) "
+	query += "ORDER BY ads.end_at ASC, ads.id ASC LIMIT ? OFFSET ?"
+
+	// A second prepare of the query would find no expectation left and fail its request
+	prep := mock.ExpectPrepare(query)
+	for i := 0; i < loadTestRequests; i++ {
+		mockRows := sqlmock.NewRows(adColumns).AddRow(1, mockAd.Title, mockAd.StartAt, mockAd.EndAt, "", "", "", "", nil, nil, nil, 0, 1)
+		prep.ExpectQuery().
+			WithArgs("TW", "AY", "TW", "AY", testTenant, "10", "0").
+			WillReturnRows(mockRows)
+	}
+
+	testAr := repository.NewAdRepository(db)
+	errs := runConcurrently(func() error {
+		condition := map[string][]string{
+			"country": {"TW", "AY"},
+			"limit":   {"10"},
+			"offset":  {"0"},
+		}
+		_, err := testAr.GetByCondition(tenantContext, condition)
+		return err
+	})
+
+	assert.Empty(t, errs)
+	assert.Equal(t, 1, repository.CachedStatements(testAr))
+	stats := db.Stats()
+	assert.Equal(t, 1, stats.OpenConnections)
+	assert.Equal(t, 0, stats.InUse)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreate_UnderConcurrentLoad_ShouldPrepareOnceAndKeepConnectionsStable(t *testing.T) {
+	db, mock := newLoadTestDatabase(t)
+	defer db.Close()
+
+	prepAds := mock.ExpectPrepare(query_ads)
+	prepGender := mock.ExpectPrepare(query_ad_gender)
+	prepCountry := mock.ExpectPrepare(query_ad_country)
+	prepPlatform := mock.ExpectPrepare(query_ad_platform)
+	prepLanguage := mock.ExpectPrepare(query_ad_language)
+	for i := 0; i < loadTestRequests; i++ {
+		mock.ExpectBegin()
+		prepAds.ExpectExec().WillReturnResult(sqlmock.NewResult(int64(i+1), 1))
+		for range mockAd.Condition.Gender {
+			prepGender.ExpectExec().WillReturnResult(sqlmock.NewResult(0, 1))
+		}
+		for range mockAd.Condition.Country {
+			prepCountry.ExpectExec().WillReturnResult(sqlmock.NewResult(0, 1))
+		}
+		for range mockAd.Condition.Platform {
+			prepPlatform.ExpectExec().WillReturnResult(sqlmock.NewResult(0, 1))
+		}
+		for range mockAd.Condition.Language {
+			prepLanguage.ExpectExec().WillReturnResult(sqlmock.NewResult(0, 1))
+		}
+		mock.ExpectExec(query_insert_version).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(query_insert_audit).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(query_insert_outbox).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+	}
+
+	testAr := repository.NewAdRepository(db)
+	errs := runConcurrently(func() error {
+		ad := mockAd
+		return testAr.Create(tenantContext, &ad, domain.ConflictCheck{})
+	})
+
+	assert.Empty(t, errs)
+	assert.Equal(t, 5, repository.CachedStatements(testAr))
+	stats := db.Stats()
+	assert.Equal(t, 1, stats.OpenConnections)
+	assert.Equal(t, 0, stats.InUse)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetCreativesByPlatform_MoreShapesThanCached_ShouldKeepMostRecentlyUsed(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	testAr := repository.NewAdRepository(db, repository.WithMaxCachedStatements(2))
+	requests := []struct {
+		adIDs []int64
+		marks string
+	}{
+		{[]int64{1}, "?"},
+		{[]int64{1, 2}, "?,?"},
+		{[]int64{1, 2, 3}, "?,?,?,?"},
+	}
+	for i, request := range requests {
+		prep := mock.ExpectPrepare(query_creatives + "WHERE ads.tenant_id = ? AND platforms.platform = ? AND ad_creatives.ad_id IN (" + request.marks + ")")
+		prep.ExpectQuery().WillReturnRows(sqlmock.NewRows(creativeColumns))
+		// The statement used the least recently is closed once a third one is kept
+		if i == 0 {
+			prep.WillBeClosed()
+		}
+
+		_, err = testAr.GetCreativesByPlatform(tenantContext, request.adIDs, "ios")
+		assert.NoError(t, err)
+	}
+
+	assert.Equal(t, 2, repository.CachedStatements(testAr))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetCreativesByPlatform_FirstWaiterCancelled_ShouldStillPrepareForOthers(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	command := query_creatives + "WHERE ads.tenant_id = ? AND platforms.platform = ? AND ad_creatives.ad_id IN (?)"
+	prep := mock.ExpectPrepare(command).WillDelayFor(100 * time.Millisecond)
+	prep.ExpectQuery().WithArgs(testTenant, "ios", 1).WillReturnRows(sqlmock.NewRows(creativeColumns))
+
+	testAr := repository.NewAdRepository(db)
+	cancelled, cancel := context.WithTimeout(tenantContext, 10*time.Millisecond)
+	defer cancel()
+	first := make(chan error)
+	go func() {
+		_, err := testAr.GetCreativesByPlatform(cancelled, []int64{1}, "ios")
+		first <- err
+	}()
+	time.Sleep(5 * time.Millisecond)
+
+	_, err = testAr.GetCreativesByPlatform(tenantContext, []int64{1}, "ios")
+
+	assert.NoError(t, err)
+	assert.ErrorIs(t, <-first, context.DeadlineExceeded)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetCreativesByPlatform_ListsOfDifferentLengths_ShouldShareOnePaddedStatement(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	prep := mock.ExpectPrepare(query_creatives + "WHERE ads.tenant_id = ? AND platforms.platform = ? AND ad_creatives.ad_id IN (?,?,?,?)")
+	prep.ExpectQuery().WithArgs(testTenant, "ios", 1, 2, 3, 3).WillReturnRows(sqlmock.NewRows(creativeColumns))
+	prep.ExpectQuery().WithArgs(testTenant, "ios", 1, 2, 3, 4).WillReturnRows(sqlmock.NewRows(creativeColumns))
+
+	testAr := repository.NewAdRepository(db)
+	_, err = testAr.GetCreativesByPlatform(tenantContext, []int64{1, 2, 3}, "ios")
+	assert.NoError(t, err)
+	_, err = testAr.GetCreativesByPlatform(tenantContext, []int64{1, 2, 3, 4}, "ios")
+	assert.NoError(t, err)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
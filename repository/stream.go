@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"dcard-backend/domain"
+	"time"
+)
+
+const (
+	appendEventCommand = "INSERT INTO ad_event_log (tenant_id, event_type, ad_id, payload) VALUES (?, ?, ?, ?)"
+	fetchEventsCommand = "SELECT id, tenant_id, payload FROM ad_event_log WHERE tenant_id = ? AND id > ? ORDER BY id ASC LIMIT ?"
+	tailEventsCommand  = "SELECT id, tenant_id, payload FROM ad_event_log WHERE id > ? ORDER BY id ASC LIMIT ?"
+	trimEventsCommand  = "DELETE FROM ad_event_log WHERE created_at < NOW() - INTERVAL ? SECOND"
+)
+
+type adEventLogRepository struct {
+	database *sql.DB
+}
+
+func NewAdEventLogRepository(db *sql.DB) domain.AdEventLogRepository {
+	return &adEventLogRepository{
+		database: db,
+	}
+}
+
+// Append keeps the whole event as the payload, so the ad is replayed as the change left it
+func (lr *adEventLogRepository) Append(c context.Context, event *domain.AdStreamEvent) error {
+	tenantID, err := domain.TenantFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	event.ID, event.TenantID = 0, tenantID
+	payload, err := marshalJSONColumn(event)
+	if err != nil {
+		return err
+	}
+
+	result, err := lr.database.ExecContext(c, appendEventCommand, tenantID, event.Type, event.AdID, payload)
+	if err != nil {
+		return err
+	}
+	event.ID, err = result.LastInsertId()
+	return err
+}
+
+func (lr *adEventLogRepository) FetchAfter(c context.Context, afterID int64, limit int) ([]domain.AdStreamEvent, error) {
+	tenantID, err := domain.TenantFromContext(c)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := lr.database.QueryContext(c, fetchEventsCommand, tenantID, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanStreamEvents(rows)
+}
+
+// Tail is run by the server for every tenant, so it is not scoped to a tenant
+func (lr *adEventLogRepository) Tail(c context.Context, afterID int64, limit int) ([]domain.AdStreamEvent, error) {
+	rows, err := lr.database.QueryContext(c, tailEventsCommand, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanStreamEvents(rows)
+}
+
+func scanStreamEvents(rows *sql.Rows) ([]domain.AdStreamEvent, error) {
+	defer rows.Close()
+
+	events := []domain.AdStreamEvent{}
+	for rows.Next() {
+		var id int64
+		var tenantID string
+		var payload sql.NullString
+		if err := rows.Scan(&id, &tenantID, &payload); err != nil {
+			return nil, err
+		}
+		event, err := unmarshalJSONColumn[domain.AdStreamEvent](payload)
+		if err != nil {
+			return nil, err
+		}
+		if event == nil {
+			event = &domain.AdStreamEvent{}
+		}
+		event.ID, event.TenantID = id, tenantID
+		events = append(events, *event)
+	}
+	return events, rows.Err()
+}
+
+func (lr *adEventLogRepository) LastID(c context.Context) (int64, error) {
+	var id int64
+	err := lr.database.QueryRowContext(c, "SELECT COALESCE(MAX(id), 0) FROM ad_event_log").Scan(&id)
+	return id, err
+}
+
+// Trim is run by the server for every tenant, so it is not scoped to a tenant
+func (lr *adEventLogRepository) Trim(c context.Context, retention time.Duration) error {
+	_, err := lr.database.ExecContext(c, trimEventsCommand, int(retention.Seconds()))
+	return err
+}
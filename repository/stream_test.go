@@ -0,0 +1,101 @@
+package repository_test
+
+import (
+	"context"
+	"dcard-backend/domain"
+	"dcard-backend/repository"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	query_append_event = "INSERT INTO ad_event_log (tenant_id, event_type, ad_id, payload) VALUES (?, ?, ?, ?)"
+	query_fetch_events = "SELECT id, tenant_id, payload FROM ad_event_log WHERE tenant_id = ? AND id > ? ORDER BY id ASC LIMIT ?"
+	query_tail_events  = "SELECT id, tenant_id, payload FROM ad_event_log WHERE id > ? ORDER BY id ASC LIMIT ?"
+	query_trim_events  = "DELETE FROM ad_event_log WHERE created_at < NOW() - INTERVAL ? SECOND"
+)
+
+var eventLogColumns = []string{"id", "tenant_id", "payload"}
+
+func TestAppendEvent_Success_ShouldLogEventWithAd(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec(query_append_event).
+		WithArgs(testTenant, domain.AdEventUpdated, 1, `{"type":"updated","adId":1,"at":"2024-01-01T00:00:00Z","ad":{"title":"AD 1","endAt":""}}`).
+		WillReturnResult(sqlmock.NewResult(42, 1))
+
+	event := domain.AdStreamEvent{Type: domain.AdEventUpdated, AdID: 1, At: "2024-01-01T00:00:00Z", Ad: &domain.Ad{Title: "AD 1"}}
+	testLr := repository.NewAdEventLogRepository(db)
+	err = testLr.Append(tenantContext, &event)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(42), event.ID)
+	assert.Equal(t, testTenant, event.TenantID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestFetchEventsAfter_Success_ShouldReturnEventsOfTenant(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(query_fetch_events).WithArgs(testTenant, 41, 500).
+		WillReturnRows(sqlmock.NewRows(eventLogColumns).
+			AddRow(42, testTenant, `{"type":"deleted","adId":1,"at":"2024-01-01T00:00:00Z"}`))
+
+	testLr := repository.NewAdEventLogRepository(db)
+	events, err := testLr.FetchAfter(tenantContext, 41, 500)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.AdStreamEvent{{ID: 42, TenantID: testTenant, Type: domain.AdEventDeleted, AdID: 1, At: "2024-01-01T00:00:00Z"}}, events)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Tail is run by the server for every tenant, so it is not scoped to a tenant
+func TestTailEvents_Success_ShouldReturnEventsOfEveryTenant(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(query_tail_events).WithArgs(41, 500).
+		WillReturnRows(sqlmock.NewRows(eventLogColumns).
+			AddRow(42, testTenant, `{"type":"started","adId":1,"at":"2024-01-01T00:00:00Z","ad":{"title":"AD 1"}}`).
+			AddRow(43, "team-b", `{"type":"expired","adId":2,"at":"2024-01-01T00:00:00Z","ad":{"title":"AD 2"}}`))
+
+	testLr := repository.NewAdEventLogRepository(db)
+	events, err := testLr.Tail(context.Background(), 41, 500)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.AdStreamEvent{
+		{ID: 42, TenantID: testTenant, Type: domain.AdEventStarted, AdID: 1, At: "2024-01-01T00:00:00Z", Ad: &domain.Ad{Title: "AD 1"}},
+		{ID: 43, TenantID: "team-b", Type: domain.AdEventExpired, AdID: 2, At: "2024-01-01T00:00:00Z", Ad: &domain.Ad{Title: "AD 2"}},
+	}, events)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTrimEvents_Success_ShouldDeleteEventsOlderThanRetention(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec(query_trim_events).WithArgs(86400).WillReturnResult(sqlmock.NewResult(0, 3))
+
+	testLr := repository.NewAdEventLogRepository(db)
+	err = testLr.Trim(context.Background(), 24*time.Hour)
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
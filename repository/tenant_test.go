@@ -0,0 +1,154 @@
+package repository_test
+
+import (
+	"context"
+	"dcard-backend/domain"
+	"dcard-backend/repository"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepositories_NoTenant_ShouldReturnErrUnauthorizedWithoutQuery(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	testAr := repository.NewAdRepository(db)
+	testAdvr := repository.NewAdvertiserRepository(db)
+	testCr := repository.NewCampaignRepository(db)
+	testTr := repository.NewTrackingRepository(db)
+	testAur := repository.NewAuditRepository(db)
+	testWr := repository.NewWebhookRepository(db)
+	testLr := repository.NewAdEventLogRepository(db)
+	testOr := repository.NewOutboxRepository(db)
+	testFr := repository.NewFrequencyRepository(db, time.Now)
+	ad := mockAd
+
+	calls := map[string]func(c context.Context) error{
+		"AdRepository.Create": func(c context.Context) error { return testAr.Create(c, &ad, domain.ConflictCheck{}) },
+		"AdRepository.GetByID": func(c context.Context) error {
+			_, err := testAr.GetByID(c, 1)
+			return err
+		},
+		"AdRepository.GetVersion": func(c context.Context) error {
+			_, err := testAr.GetVersion(c, 1, 1)
+			return err
+		},
+		"AdRepository.Update":       func(c context.Context) error { return testAr.Update(c, &ad, domain.ConflictCheck{}) },
+		"AdRepository.Rollback":     func(c context.Context) error { return testAr.Rollback(c, &ad, 1, domain.ConflictCheck{}) },
+		"AdRepository.UpdateStatus": func(c context.Context) error { return testAr.UpdateStatus(c, 1, domain.AdPaused) },
+		"AdRepository.Delete":       func(c context.Context) error { return testAr.Delete(c, 1) },
+		"AdRepository.Restore":      func(c context.Context) error { return testAr.Restore(c, 1) },
+		"AdRepository.GetByCondition": func(c context.Context) error {
+			_, err := testAr.GetByCondition(c, map[string][]string{})
+			return err
+		},
+		"AdRepository.Fetch": func(c context.Context) error {
+			_, err := testAr.Fetch(c, 0)
+			return err
+		},
+		"AdRepository.FetchByCampaign": func(c context.Context) error {
+			_, err := testAr.FetchByCampaign(c, 3)
+			return err
+		},
+		"AdRepository.GetByWindow": func(c context.Context) error {
+			_, err := testAr.GetByWindow(c, "2024-03-01 00:00:00", "2024-06-01 00:00:00", 0)
+			return err
+		},
+		"AdRepository.GetCreativesByPlatform": func(c context.Context) error {
+			_, err := testAr.GetCreativesByPlatform(c, []int64{1}, "ios")
+			return err
+		},
+		"AdRepository.GetLifecycle": func(c context.Context) error {
+			_, err := testAr.GetLifecycle(c, 1)
+			return err
+		},
+		"AdRepository.UpdateLifecycle": func(c context.Context) error {
+			_, err := testAr.UpdateLifecycle(c, 1, domain.AdScheduled, domain.AdLive)
+			return err
+		},
+		"AdvertiserRepository.Create": func(c context.Context) error { return testAdvr.Create(c, &domain.Advertiser{Name: "Dcard"}) },
+		"AdvertiserRepository.GetByID": func(c context.Context) error {
+			_, err := testAdvr.GetByID(c, 1)
+			return err
+		},
+		"AdvertiserRepository.Fetch": func(c context.Context) error {
+			_, err := testAdvr.Fetch(c)
+			return err
+		},
+		"AdvertiserRepository.Update": func(c context.Context) error { return testAdvr.Update(c, &domain.Advertiser{ID: 1, Name: "Dcard"}) },
+		"AdvertiserRepository.Delete": func(c context.Context) error { return testAdvr.Delete(c, 1) },
+		"CampaignRepository.Create": func(c context.Context) error {
+			return testCr.Create(c, &domain.Campaign{AdvertiserID: 1, Name: "Spring Sale"})
+		},
+		"CampaignRepository.GetByID": func(c context.Context) error {
+			_, err := testCr.GetByID(c, 1)
+			return err
+		},
+		"CampaignRepository.FetchByAdvertiser": func(c context.Context) error {
+			_, err := testCr.FetchByAdvertiser(c, 1)
+			return err
+		},
+		"CampaignRepository.Update": func(c context.Context) error {
+			return testCr.Update(c, &domain.Campaign{ID: 1, Name: "Spring Sale"})
+		},
+		"CampaignRepository.UpdateStatus": func(c context.Context) error { return testCr.UpdateStatus(c, 1, domain.CampaignPaused) },
+		"CampaignRepository.Delete":       func(c context.Context) error { return testCr.Delete(c, 1) },
+		"AuditRepository.Fetch": func(c context.Context) error {
+			_, err := testAur.Fetch(c, domain.AuditFilter{})
+			return err
+		},
+		"WebhookRepository.Create": func(c context.Context) error {
+			return testWr.Create(c, &domain.Webhook{URL: "https://example.com/webhook", EventTypes: []domain.AdEventType{domain.AdEventCreated}})
+		},
+		"WebhookRepository.Fetch": func(c context.Context) error {
+			_, err := testWr.Fetch(c)
+			return err
+		},
+		"WebhookRepository.Delete": func(c context.Context) error { return testWr.Delete(c, 1) },
+		"WebhookRepository.Enqueue": func(c context.Context) error {
+			_, err := testWr.Enqueue(c, domain.AdEvent{Type: domain.AdEventCreated, AdID: 1}, "{}")
+			return err
+		},
+		"WebhookRepository.FetchDeadLetters": func(c context.Context) error {
+			_, err := testWr.FetchDeadLetters(c, 50, 0)
+			return err
+		},
+		"WebhookRepository.Redeliver": func(c context.Context) error { return testWr.Redeliver(c, 1) },
+		"AdEventLogRepository.Append": func(c context.Context) error {
+			return testLr.Append(c, &domain.AdStreamEvent{Type: domain.AdEventCreated, AdID: 1})
+		},
+		"AdEventLogRepository.FetchAfter": func(c context.Context) error {
+			_, err := testLr.FetchAfter(c, 0, 500)
+			return err
+		},
+		"OutboxRepository.Publish": func(c context.Context) error {
+			return testOr.Publish(c, domain.AdEvent{Type: domain.AdEventBudgetExhausted, AdID: 1})
+		},
+		"FrequencyRepository.GetCounts": func(c context.Context) error {
+			_, err := testFr.GetCounts(c, "user-1", []int64{1})
+			return err
+		},
+		"FrequencyRepository.Increment": func(c context.Context) error { return testFr.Increment(c, "user-1", 1, time.Hour) },
+		"TrackingRepository.CheckAd":    func(c context.Context) error { return testTr.CheckAd(c, 1) },
+		"TrackingRepository.GetCounts": func(c context.Context) error {
+			_, err := testTr.GetCounts(c, 1)
+			return err
+		},
+		"TrackingRepository.GetDelivery": func(c context.Context) error {
+			_, err := testTr.GetDelivery(c, []int64{1}, time.Now())
+			return err
+		},
+	}
+
+	for name, call := range calls {
+		err := call(context.Background())
+		assert.ErrorIs(t, err, domain.ErrUnauthorized, name)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,239 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"dcard-backend/domain"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Hours of the event counts are stored in UTC
+const hourLayout = "2006-01-02 15:04:05"
+
+// maxCountsPerInsert bounds the rows of a single INSERT, so that a large flush is split into
+// several statements
+const maxCountsPerInsert = 500
+
+type trackingRepository struct {
+	database *sql.DB
+}
+
+func NewTrackingRepository(db *sql.DB) domain.TrackingRepository {
+	return &trackingRepository{
+		database: db,
+	}
+}
+
+// addCountsCommand upserts the counts of rows ads, joined to the ads of the tenant, so that a
+// count of an ad of another tenant or of an unknown ad is never written
+func addCountsCommand(rows int) string {
+	values := "SELECT ? AS ad_id, ? AS hour, ? AS impressions, ? AS clicks" + strings.Repeat(" UNION ALL SELECT ?, ?, ?, ?", rows-1)
+	return "INSERT INTO ad_event_counts (ad_id, hour, impressions, clicks) " +
+		"SELECT counts.ad_id, counts.hour, counts.impressions, counts.clicks FROM (" + values + ") AS counts " +
+		"JOIN ads ON ads.id = counts.ad_id WHERE ads.tenant_id = ? " +
+		"ON DUPLICATE KEY UPDATE impressions = ad_event_counts.impressions + counts.impressions, clicks = ad_event_counts.clicks + counts.clicks"
+}
+
+// exhaustedAdsCommand locks the ads of adIDs whose impressions reached their total budget and
+// whose exhausted budget was not recorded yet, so that servers flushing concurrently record it
+// once
+func exhaustedAdsCommand(adIDs int) string {
+	return "SELECT ads.id FROM ads JOIN (SELECT ad_id, SUM(impressions) AS impressions FROM ad_event_counts " +
+		"WHERE ad_id IN (" + repeatQuestionMarks(adIDs) + ") GROUP BY ad_id) AS delivered ON delivered.ad_id = ads.id " +
+		"WHERE ads.tenant_id = ? AND ads.exhausted_at IS NULL " +
+		"AND delivered.impressions >= JSON_EXTRACT(ads.budget, '$.totalImpressions') FOR UPDATE"
+}
+
+func markExhaustedCommand(adIDs int) string {
+	return "UPDATE ads SET exhausted_at = CURRENT_TIMESTAMP WHERE id IN (" + repeatQuestionMarks(adIDs) + ")"
+}
+
+// CheckAd returns domain.ErrNotFound unless the ad is a live ad of the tenant, so that events
+// are only recorded for ads that can be served
+func (tr *trackingRepository) CheckAd(c context.Context, adID int64) error {
+	tenantID, err := domain.TenantFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	var id int64
+	err = tr.database.QueryRowContext(c, "SELECT id FROM ads WHERE id = ? AND tenant_id = ? AND deleted_at IS NULL", adID, tenantID).Scan(&id)
+	if err == sql.ErrNoRows {
+		return domain.ErrNotFound
+	}
+	return err
+}
+
+// AddCounts adds the counts to the hourly counters of the ads in a transaction, so that a
+// failed flush adds nothing and can be retried. AddCounts is run by the server for the counts
+// of every tenant, so each count is only written if its ad is of the tenant of the count.
+// Consecutive counts of a tenant share a statement. The ads whose impressions reach their total
+// budget are marked in the same transaction, with a budget_exhausted event in the outbox.
+func (tr *trackingRepository) AddCounts(c context.Context, counts []domain.EventCount) (err error) {
+	if len(counts) == 0 {
+		return nil
+	}
+
+	tx, err := tr.database.BeginTx(c, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	for start := 0; start < len(counts); {
+		end := start + 1
+		for end < len(counts) && end-start < maxCountsPerInsert && counts[end].TenantID == counts[start].TenantID {
+			end++
+		}
+		batch := counts[start:end]
+
+		args := make([]interface{}, 0, 4*len(batch)+1)
+		for _, count := range batch {
+			args = append(args, count.AdID, count.Hour.UTC().Format(hourLayout), count.Impressions, count.Clicks)
+		}
+		args = append(args, batch[0].TenantID)
+		if _, err = tx.ExecContext(c, addCountsCommand(len(batch)), args...); err != nil {
+			return err
+		}
+		if err = markExhausted(c, tx, batch); err != nil {
+			return err
+		}
+		start = end
+	}
+	return nil
+}
+
+// markExhausted marks the ads of a batch of counts of a tenant which exhausted their total budget
+// with these counts, and records their budget_exhausted events
+func markExhausted(c context.Context, tx *sql.Tx, batch []domain.EventCount) error {
+	adIDs := []int64{}
+	for _, count := range batch {
+		if count.Impressions > 0 && !slices.Contains(adIDs, count.AdID) {
+			adIDs = append(adIDs, count.AdID)
+		}
+	}
+	if len(adIDs) == 0 {
+		return nil
+	}
+
+	tenantID := batch[0].TenantID
+	args := append(adIDsToGenericSlice(adIDs), tenantID)
+	rows, err := tx.QueryContext(c, exhaustedAdsCommand(len(adIDs)), args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	exhausted := []int64{}
+	for rows.Next() {
+		var adID int64
+		if err := rows.Scan(&adID); err != nil {
+			return err
+		}
+		exhausted = append(exhausted, adID)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(exhausted) == 0 {
+		return nil
+	}
+
+	if _, err := tx.ExecContext(c, markExhaustedCommand(len(exhausted)), adIDsToGenericSlice(exhausted)...); err != nil {
+		return err
+	}
+	publisher := newOutboxPublisher(tx)
+	for _, adID := range exhausted {
+		if err := publisher.Publish(c, domain.AdEvent{Type: domain.AdEventBudgetExhausted, TenantID: tenantID, AdID: adID}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetCounts returns the hourly counts of an ad, and domain.ErrNotFound when the ad is not of
+// the tenant
+func (tr *trackingRepository) GetCounts(c context.Context, adID int64) ([]domain.EventCount, error) {
+	tenantID, err := domain.TenantFromContext(c)
+	if err != nil {
+		return nil, err
+	}
+
+	// The counts are joined to the ad, which gives one row of NULL counts for an ad without
+	// events and no rows for a deleted ad or an ad of another tenant
+	command := "SELECT ad_event_counts.hour, ad_event_counts.impressions, ad_event_counts.clicks FROM ads " +
+		"LEFT JOIN ad_event_counts ON ad_event_counts.ad_id = ads.id WHERE ads.id = ? AND ads.tenant_id = ? AND ads.deleted_at IS NULL ORDER BY ad_event_counts.hour ASC"
+	rows, err := tr.database.QueryContext(c, command, adID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	found := false
+	counts := []domain.EventCount{}
+	for rows.Next() {
+		found = true
+		var hour sql.NullString
+		var impressions, clicks sql.NullInt64
+		if err := rows.Scan(&hour, &impressions, &clicks); err != nil {
+			return nil, err
+		}
+		if !hour.Valid {
+			continue
+		}
+		count := domain.EventCount{TenantID: tenantID, AdID: adID, Impressions: impressions.Int64, Clicks: clicks.Int64}
+		if count.Hour, err = time.ParseInLocation(hourLayout, hour.String, time.UTC); err != nil {
+			return nil, err
+		}
+		counts = append(counts, count)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, domain.ErrNotFound
+	}
+	return counts, nil
+}
+
+// GetDelivery sums the impressions of the ads in total and from the hour of dayStart
+func (tr *trackingRepository) GetDelivery(c context.Context, adIDs []int64, dayStart time.Time) (map[int64]domain.Delivery, error) {
+	tenantID, err := domain.TenantFromContext(c)
+	if err != nil {
+		return nil, err
+	}
+	delivery := map[int64]domain.Delivery{}
+	if len(adIDs) == 0 {
+		return delivery, nil
+	}
+
+	command := "SELECT ad_id, SUM(impressions), SUM(CASE WHEN hour >= ? THEN impressions ELSE 0 END) FROM ad_event_counts " +
+		"WHERE ad_id IN (" + repeatQuestionMarks(len(adIDs)) + ") AND ad_id IN (SELECT id FROM ads WHERE tenant_id = ?) GROUP BY ad_id"
+	args := append([]interface{}{dayStart.UTC().Format(hourLayout)}, adIDsToGenericSlice(adIDs)...)
+	args = append(args, tenantID)
+
+	rows, err := tr.database.QueryContext(c, command, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var adID int64
+		var adDelivery domain.Delivery
+		if err := rows.Scan(&adID, &adDelivery.Impressions, &adDelivery.TodayImpressions); err != nil {
+			return nil, err
+		}
+		delivery[adID] = adDelivery
+	}
+	return delivery, rows.Err()
+}
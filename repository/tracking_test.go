@@ -0,0 +1,214 @@
+package repository_test
+
+import (
+	"context"
+	"dcard-backend/domain"
+	"dcard-backend/repository"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+const query_add_counts = "INSERT INTO ad_event_counts (ad_id, hour, impressions, clicks) " +
+	"SELECT counts.ad_id, counts.hour, counts.impressions, counts.clicks FROM (SELECT ? AS ad_id, ? AS hour, ? AS impressions, ? AS clicks UNION ALL SELECT ?, ?, ?, ?) AS counts " +
+	"JOIN ads ON ads.id = counts.ad_id WHERE ads.tenant_id = ? " +
+	"ON DUPLICATE KEY UPDATE impressions = ad_event_counts.impressions + counts.impressions, clicks = ad_event_counts.clicks + counts.clicks"
+
+const query_add_count = "INSERT INTO ad_event_counts (ad_id, hour, impressions, clicks) " +
+	"SELECT counts.ad_id, counts.hour, counts.impressions, counts.clicks FROM (SELECT ? AS ad_id, ? AS hour, ? AS impressions, ? AS clicks) AS counts " +
+	"JOIN ads ON ads.id = counts.ad_id WHERE ads.tenant_id = ? " +
+	"ON DUPLICATE KEY UPDATE impressions = ad_event_counts.impressions + counts.impressions, clicks = ad_event_counts.clicks + counts.clicks"
+
+const query_exhausted_ads = "SELECT ads.id FROM ads JOIN (SELECT ad_id, SUM(impressions) AS impressions FROM ad_event_counts " +
+	"WHERE ad_id IN (?,?) GROUP BY ad_id) AS delivered ON delivered.ad_id = ads.id " +
+	"WHERE ads.tenant_id = ? AND ads.exhausted_at IS NULL " +
+	"AND delivered.impressions >= JSON_EXTRACT(ads.budget, '$.totalImpressions') FOR UPDATE"
+
+const query_exhausted_ad = "SELECT ads.id FROM ads JOIN (SELECT ad_id, SUM(impressions) AS impressions FROM ad_event_counts " +
+	"WHERE ad_id IN (?) GROUP BY ad_id) AS delivered ON delivered.ad_id = ads.id " +
+	"WHERE ads.tenant_id = ? AND ads.exhausted_at IS NULL " +
+	"AND delivered.impressions >= JSON_EXTRACT(ads.budget, '$.totalImpressions') FOR UPDATE"
+
+const query_mark_exhausted = "UPDATE ads SET exhausted_at = CURRENT_TIMESTAMP WHERE id IN (?)"
+
+const query_check_ad = "SELECT id FROM ads WHERE id = ? AND tenant_id = ? AND deleted_at IS NULL"
+
+const query_counts = "SELECT ad_event_counts.hour, ad_event_counts.impressions, ad_event_counts.clicks FROM ads " +
+	"LEFT JOIN ad_event_counts ON ad_event_counts.ad_id = ads.id WHERE ads.id = ? AND ads.tenant_id = ? AND ads.deleted_at IS NULL ORDER BY ad_event_counts.hour ASC"
+
+var mockCounts = []domain.EventCount{
+	{TenantID: "team-a", AdID: 1, Hour: time.Date(2024, 3, 1, 8, 0, 0, 0, time.UTC), Impressions: 10, Clicks: 2},
+	{TenantID: "team-a", AdID: 2, Hour: time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC), Impressions: 5, Clicks: 0},
+	{TenantID: "team-b", AdID: 3, Hour: time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC), Impressions: 1, Clicks: 1},
+}
+
+func TestAddCounts_Success_ShouldUpsertCountsOfEachTenantInTransaction(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(query_add_counts).
+		WithArgs(1, "2024-03-01 08:00:00", 10, 2, 2, "2024-03-01 09:00:00", 5, 0, "team-a").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectQuery(query_exhausted_ads).WithArgs(1, 2, "team-a").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectExec(query_add_count).
+		WithArgs(3, "2024-03-01 09:00:00", 1, 1, "team-b").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(query_exhausted_ad).WithArgs(3, "team-b").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectCommit()
+
+	testTr := repository.NewTrackingRepository(db)
+	err = testTr.AddCounts(context.Background(), mockCounts)
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAddCounts_BudgetReached_ShouldMarkAdAndRecordEventOnce(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	counts := []domain.EventCount{
+		{TenantID: "team-a", AdID: 1, Hour: time.Date(2024, 3, 1, 8, 0, 0, 0, time.UTC), Impressions: 10, Clicks: 2},
+		{TenantID: "team-a", AdID: 1, Hour: time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC), Impressions: 5, Clicks: 0},
+		{TenantID: "team-a", AdID: 2, Hour: time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC), Impressions: 0, Clicks: 1},
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO ad_event_counts (ad_id, hour, impressions, clicks) " +
+		"SELECT counts.ad_id, counts.hour, counts.impressions, counts.clicks FROM (SELECT ? AS ad_id, ? AS hour, ? AS impressions, ? AS clicks UNION ALL SELECT ?, ?, ?, ? UNION ALL SELECT ?, ?, ?, ?) AS counts " +
+		"JOIN ads ON ads.id = counts.ad_id WHERE ads.tenant_id = ? " +
+		"ON DUPLICATE KEY UPDATE impressions = ad_event_counts.impressions + counts.impressions, clicks = ad_event_counts.clicks + counts.clicks").
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	// Ad 2 only got a click, so its budget is not checked
+	mock.ExpectQuery(query_exhausted_ad).WithArgs(1, "team-a").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectExec(query_mark_exhausted).WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(query_insert_outbox).WithArgs("team-a", 1, domain.AdEventBudgetExhausted, nil).WillReturnResult(sqlmock.NewResult(7, 1))
+	mock.ExpectCommit()
+
+	testTr := repository.NewTrackingRepository(db)
+	err = testTr.AddCounts(context.Background(), counts)
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAddCounts_ExecFail_ShouldRollback(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(query_add_counts).WillReturnError(fmt.Errorf("Error"))
+	mock.ExpectRollback()
+
+	testTr := repository.NewTrackingRepository(db)
+	err = testTr.AddCounts(context.Background(), mockCounts)
+
+	assert.Error(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCheckAd_AdOfAnotherTenant_ShouldReturnErrNotFound(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(query_check_ad).WithArgs(1, "team-a").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery(query_check_ad).WithArgs(2, "team-a").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+
+	testTr := repository.NewTrackingRepository(db)
+
+	assert.ErrorIs(t, testTr.CheckAd(domain.WithTenant(context.Background(), "team-a"), 1), domain.ErrNotFound)
+	assert.NoError(t, testTr.CheckAd(domain.WithTenant(context.Background(), "team-a"), 2))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetCounts_Success_CountsReturnInUTC(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mockRows := sqlmock.NewRows([]string{"hour", "impressions", "clicks"}).AddRow("2024-03-01 08:00:00", 10, 2)
+	mock.ExpectQuery(query_counts).
+		WithArgs(1, testTenant).
+		WillReturnRows(mockRows)
+
+	testTr := repository.NewTrackingRepository(db)
+	counts, err := testTr.GetCounts(tenantContext, 1)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.EventCount{mockCounts[0]}, counts)
+}
+
+func TestGetCounts_AdWithoutEvents_ShouldReturnNoCounts(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(query_counts).
+		WithArgs(1, testTenant).
+		WillReturnRows(sqlmock.NewRows([]string{"hour", "impressions", "clicks"}).AddRow(nil, nil, nil))
+
+	testTr := repository.NewTrackingRepository(db)
+	counts, err := testTr.GetCounts(tenantContext, 1)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.EventCount{}, counts)
+}
+
+func TestGetCounts_AdOfAnotherTenant_ShouldReturnErrNotFound(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(query_counts).
+		WithArgs(1, testTenant).
+		WillReturnRows(sqlmock.NewRows([]string{"hour", "impressions", "clicks"}))
+
+	testTr := repository.NewTrackingRepository(db)
+	_, err = testTr.GetCounts(tenantContext, 1)
+
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+}
+
+func TestGetDelivery_Success_ShouldSumImpressionsInTotalAndSinceDayStart(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	query := "SELECT ad_id, SUM(impressions), SUM(CASE WHEN hour >= ? THEN impressions ELSE 0 END) FROM ad_event_counts " +
+		"WHERE ad_id IN (?,?) AND ad_id IN (SELECT id FROM ads WHERE tenant_id = ?) GROUP BY ad_id"
+	mockRows := sqlmock.NewRows([]string{"ad_id", "impressions", "today_impressions"}).AddRow(1, 120, 20)
+	mock.ExpectQuery(query).
+		WithArgs("2024-02-29 16:00:00", 1, 2, testTenant).
+		WillReturnRows(mockRows)
+
+	dayStart := time.Date(2024, 3, 1, 0, 0, 0, 0, time.FixedZone("CST", 8*60*60))
+	testTr := repository.NewTrackingRepository(db)
+	delivery, err := testTr.GetDelivery(tenantContext, []int64{1, 2}, dayStart)
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[int64]domain.Delivery{1: {Impressions: 120, TodayImpressions: 20}}, delivery)
+}
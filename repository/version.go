@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"dcard-backend/domain"
+	"encoding/json"
+	"errors"
+)
+
+const (
+	insertVersionCommand = "INSERT INTO ad_versions (tenant_id, ad_id, version, restored_from, snapshot) VALUES (?, ?, ?, ?, ?)"
+	selectVersionCommand = "SELECT snapshot FROM ad_versions WHERE tenant_id = ? AND ad_id = ? AND version = ?"
+)
+
+// insertVersion keeps the configuration of an ad at its version in the transaction that wrote
+// it. The status and the lifecycle are left out, since they are changed by pausing and resuming
+// the ad and by its start and end instead.
+func insertVersion(c context.Context, tx *sql.Tx, tenantID string, ad domain.Ad, restoredFrom int64) error {
+	ad.Status, ad.Lifecycle = "", ""
+	snapshot, err := marshalJSONColumn(&ad)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(c, insertVersionCommand, tenantID, ad.ID, ad.Version, nullableID(restoredFrom), snapshot)
+	return err
+}
+
+func (ar *adRepository) GetVersion(c context.Context, id int64, version int64) (domain.Ad, error) {
+	tenantID, err := domain.TenantFromContext(c)
+	if err != nil {
+		return domain.Ad{}, err
+	}
+
+	var snapshot string
+	err = ar.database.QueryRowContext(c, selectVersionCommand, tenantID, id, version).Scan(&snapshot)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Ad{}, domain.ErrNotFound
+	}
+	if err != nil {
+		return domain.Ad{}, err
+	}
+
+	var ad domain.Ad
+	err = json.Unmarshal([]byte(snapshot), &ad)
+	return ad, err
+}
@@ -0,0 +1,130 @@
+package repository_test
+
+import (
+	"dcard-backend/domain"
+	"dcard-backend/repository"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	query_insert_version = "INSERT INTO ad_versions (tenant_id, ad_id, version, restored_from, snapshot) VALUES (?, ?, ?, ?, ?)"
+	query_select_version = "SELECT snapshot FROM ad_versions WHERE tenant_id = ? AND ad_id = ? AND version = ?"
+)
+
+func TestGetVersion_Success_ShouldReturnSnapshot(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	snapshot := `{"id":1,"title":"AD 0","startAt":"2024-01-01T08:00:00+08:00","endAt":"2025-01-01T08:00:00+08:00",` +
+		`"condition":{"ageStart":10,"ageEnd":20,"gender":["M","F"],"country":["TW","JP"],"platform":["web","ios"],"language":["en"]},"version":1}`
+	mock.ExpectQuery(query_select_version).
+		WithArgs(testTenant, 1, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"snapshot"}).AddRow(snapshot))
+
+	testAr := repository.NewAdRepository(db)
+	ad, err := testAr.GetVersion(tenantContext, 1, 1)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "AD 0", ad.Title)
+	assert.Equal(t, int64(1), ad.Version)
+	assert.Equal(t, []string{"TW", "JP"}, ad.Condition.Country)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetVersion_UnknownVersion_ShouldReturnErrNotFound(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(query_select_version).
+		WithArgs(testTenant, 1, 9).
+		WillReturnRows(sqlmock.NewRows([]string{"snapshot"}))
+
+	testAr := repository.NewAdRepository(db)
+	_, err = testAr.GetVersion(tenantContext, 1, 9)
+
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdate_StaleVersion_ShouldRollbackWithErrPreconditionFailed(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	updatedAd := mockAd
+	updatedAd.ID, updatedAd.IfMatch = 1, []int64{1, 4}
+
+	mock.ExpectPrepare(query_ad_gender)
+	mock.ExpectPrepare(query_ad_country)
+	mock.ExpectPrepare(query_ad_platform)
+	mock.ExpectPrepare(query_ad_language)
+	mock.ExpectBegin()
+	expectLockAd(mock, "active")
+	mock.ExpectRollback()
+
+	testAr := repository.NewAdRepository(db)
+	err = testAr.Update(auditContext, &updatedAd, domain.ConflictCheck{})
+
+	assert.ErrorIs(t, err, domain.ErrPreconditionFailed)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRollback_Success_ShouldKeepNewVersionRestoredFromVersion(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	restoredAd := domain.Ad{
+		ID:        1,
+		Title:     "AD 0",
+		StartAt:   mockAd.StartAt,
+		EndAt:     mockAd.EndAt,
+		Condition: &domain.Condition{AgeStart: 1, AgeEnd: 100, Gender: []string{"A"}, Country: []string{"TW"}, Platform: []string{"any"}, Language: []string{"any"}},
+		Version:   2,
+	}
+
+	prepGender := mock.ExpectPrepare(query_ad_gender)
+	prepCountry := mock.ExpectPrepare(query_ad_country)
+	prepPlatform := mock.ExpectPrepare(query_ad_platform)
+	prepLanguage := mock.ExpectPrepare(query_ad_language)
+	mock.ExpectBegin()
+	expectLockAd(mock, "active")
+	mock.ExpectExec(query_update_ad).
+		WithArgs(nil, "AD 0", "ad 0", mockAd.StartAt, mockAd.EndAt, 1, 100, nil, "", "", "", "", nil, nil, 0, 0, nil, 3, 1, testTenant).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectDeleteTargeting(mock)
+	for _, prep := range []*sqlmock.ExpectedPrepare{prepGender, prepCountry, prepPlatform, prepLanguage} {
+		prep.ExpectExec().WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	mock.ExpectExec(query_insert_version).
+		WithArgs(testTenant, 1, 3, 1, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(query_insert_audit).
+		WithArgs(testTenant, 1, "rollback", testActor, testRequestID, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(query_insert_outbox).
+		WithArgs(testTenant, 1, "updated", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	testAr := repository.NewAdRepository(db)
+	err = testAr.Rollback(auditContext, &restoredAd, 1, domain.ConflictCheck{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), restoredAd.Version)
+	assert.Equal(t, domain.AdActive, restoredAd.Status)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,214 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"dcard-backend/domain"
+	"strings"
+	"time"
+)
+
+const (
+	insertWebhookCommand = "INSERT INTO webhooks (tenant_id, url, event_types, secret) VALUES (?, ?, ?, ?)"
+	// Deliveries are added for the webhooks of the tenant whose event types include the event
+	enqueueDeliveriesCommand = "INSERT INTO webhook_outbox (tenant_id, webhook_id, event_type, payload) " +
+		"SELECT tenant_id, id, ?, ? FROM webhooks WHERE tenant_id = ? AND FIND_IN_SET(?, event_types)"
+	// claimDeliveriesCommand skips the deliveries claimed by other servers instead of waiting for them
+	claimDeliveriesCommand = "SELECT webhook_outbox.id, webhook_outbox.webhook_id, webhook_outbox.tenant_id, webhook_outbox.event_type, " +
+		"webhook_outbox.payload, webhook_outbox.status, webhook_outbox.attempts, webhook_outbox.last_error, webhook_outbox.created_at, webhooks.url, webhooks.secret " +
+		"FROM webhook_outbox INNER JOIN webhooks ON webhooks.id = webhook_outbox.webhook_id " +
+		"WHERE webhook_outbox.status = 'pending' AND webhook_outbox.next_attempt_at <= NOW() " +
+		"ORDER BY webhook_outbox.id ASC LIMIT ? FOR UPDATE OF webhook_outbox SKIP LOCKED"
+	completeDeliveryCommand = "DELETE FROM webhook_outbox WHERE id = ?"
+	retryDeliveryCommand    = "UPDATE webhook_outbox SET last_error = ?, next_attempt_at = NOW() + INTERVAL ? SECOND WHERE id = ?"
+	killDeliveryCommand     = "UPDATE webhook_outbox SET status = 'dead', last_error = ? WHERE id = ?"
+	fetchDeadLettersCommand = "SELECT id, webhook_id, tenant_id, event_type, payload, status, attempts, last_error, created_at FROM webhook_outbox " +
+		"WHERE tenant_id = ? AND status = 'dead' ORDER BY id DESC LIMIT ? OFFSET ?"
+	redeliverCommand = "UPDATE webhook_outbox SET status = 'pending', attempts = 0, next_attempt_at = NOW() WHERE id = ? AND tenant_id = ? AND status = 'dead'"
+)
+
+type webhookRepository struct {
+	database *sql.DB
+}
+
+func NewWebhookRepository(db *sql.DB) domain.WebhookRepository {
+	return &webhookRepository{
+		database: db,
+	}
+}
+
+// eventTypesToColumn joins the event types of a webhook, which are matched with FIND_IN_SET
+func eventTypesToColumn(eventTypes []domain.AdEventType) string {
+	values := make([]string, len(eventTypes))
+	for i, eventType := range eventTypes {
+		values[i] = string(eventType)
+	}
+	return strings.Join(values, ",")
+}
+
+func (wr *webhookRepository) Create(c context.Context, webhook *domain.Webhook) error {
+	tenantID, err := domain.TenantFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	result, err := wr.database.ExecContext(c, insertWebhookCommand, tenantID, webhook.URL, eventTypesToColumn(webhook.EventTypes), webhook.Secret)
+	if err != nil {
+		return err
+	}
+	webhook.ID, err = result.LastInsertId()
+	return err
+}
+
+// Fetch returns the webhooks of the tenant without their secrets
+func (wr *webhookRepository) Fetch(c context.Context) ([]domain.Webhook, error) {
+	tenantID, err := domain.TenantFromContext(c)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := wr.database.QueryContext(c, "SELECT id, url, event_types, created_at FROM webhooks WHERE tenant_id = ? ORDER BY id ASC", tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	webhooks := []domain.Webhook{}
+	for rows.Next() {
+		var webhook domain.Webhook
+		var eventTypes string
+		if err := rows.Scan(&webhook.ID, &webhook.URL, &eventTypes, &webhook.CreatedAt); err != nil {
+			return nil, err
+		}
+		for _, eventType := range strings.Split(eventTypes, ",") {
+			webhook.EventTypes = append(webhook.EventTypes, domain.AdEventType(eventType))
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	return webhooks, rows.Err()
+}
+
+// Delete removes a webhook along with its deliveries in the outbox
+func (wr *webhookRepository) Delete(c context.Context, id int64) error {
+	tenantID, err := domain.TenantFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	result, err := wr.database.ExecContext(c, "DELETE FROM webhooks WHERE id = ? AND tenant_id = ?", id, tenantID)
+	if err != nil {
+		return err
+	}
+	return checkAffected(result)
+}
+
+func (wr *webhookRepository) Enqueue(c context.Context, event domain.AdEvent, payload string) (int, error) {
+	tenantID, err := domain.TenantFromContext(c)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := wr.database.ExecContext(c, enqueueDeliveriesCommand, event.Type, payload, tenantID, event.Type)
+	if err != nil {
+		return 0, err
+	}
+	enqueued, err := result.RowsAffected()
+	return int(enqueued), err
+}
+
+func scanDelivery(rows *sql.Rows, extra ...interface{}) (domain.WebhookDelivery, error) {
+	var delivery domain.WebhookDelivery
+	dest := []interface{}{&delivery.ID, &delivery.WebhookID, &delivery.TenantID, &delivery.EventType, &delivery.Payload,
+		&delivery.Status, &delivery.Attempts, &delivery.LastError, &delivery.CreatedAt}
+	err := rows.Scan(append(dest, extra...)...)
+	return delivery, err
+}
+
+// Claim is run by the server for every tenant, so it is not scoped to a tenant
+func (wr *webhookRepository) Claim(c context.Context, batchSize int, lease time.Duration) ([]domain.WebhookDelivery, error) {
+	deliveries := []domain.WebhookDelivery{}
+	err := inTransaction(c, wr.database, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(c, claimDeliveriesCommand, batchSize)
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			var url, secret string
+			delivery, err := scanDelivery(rows, &url, &secret)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			delivery.URL, delivery.Secret = url, secret
+			delivery.Attempts++
+			deliveries = append(deliveries, delivery)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil || len(deliveries) == 0 {
+			return err
+		}
+
+		ids := make([]int64, len(deliveries))
+		for i, delivery := range deliveries {
+			ids[i] = delivery.ID
+		}
+		command := "UPDATE webhook_outbox SET attempts = attempts + 1, next_attempt_at = NOW() + INTERVAL ? SECOND WHERE id IN (" + repeatQuestionMarks(len(ids)) + ")"
+		_, err = tx.ExecContext(c, command, append([]interface{}{int(lease.Seconds())}, adIDsToGenericSlice(ids)...)...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+func (wr *webhookRepository) Complete(c context.Context, id int64) error {
+	_, err := wr.database.ExecContext(c, completeDeliveryCommand, id)
+	return err
+}
+
+func (wr *webhookRepository) Fail(c context.Context, id int64, lastError string, retryAfter time.Duration) error {
+	if retryAfter <= 0 {
+		_, err := wr.database.ExecContext(c, killDeliveryCommand, lastError, id)
+		return err
+	}
+	_, err := wr.database.ExecContext(c, retryDeliveryCommand, lastError, int(retryAfter.Seconds()), id)
+	return err
+}
+
+// FetchDeadLetters returns the dead deliveries of the tenant from the newest
+func (wr *webhookRepository) FetchDeadLetters(c context.Context, limit int, offset int) ([]domain.WebhookDelivery, error) {
+	tenantID, err := domain.TenantFromContext(c)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := wr.database.QueryContext(c, fetchDeadLettersCommand, tenantID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []domain.WebhookDelivery{}
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, rows.Err()
+}
+
+func (wr *webhookRepository) Redeliver(c context.Context, id int64) error {
+	tenantID, err := domain.TenantFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	result, err := wr.database.ExecContext(c, redeliverCommand, id, tenantID)
+	if err != nil {
+		return err
+	}
+	return checkAffected(result)
+}
@@ -0,0 +1,194 @@
+package repository_test
+
+import (
+	"context"
+	"dcard-backend/domain"
+	"dcard-backend/repository"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	query_insert_webhook     = "INSERT INTO webhooks (tenant_id, url, event_types, secret) VALUES (?, ?, ?, ?)"
+	query_fetch_webhooks     = "SELECT id, url, event_types, created_at FROM webhooks WHERE tenant_id = ? ORDER BY id ASC"
+	query_enqueue_deliveries = "INSERT INTO webhook_outbox (tenant_id, webhook_id, event_type, payload) " +
+		"SELECT tenant_id, id, ?, ? FROM webhooks WHERE tenant_id = ? AND FIND_IN_SET(?, event_types)"
+	query_claim_deliveries = "SELECT webhook_outbox.id, webhook_outbox.webhook_id, webhook_outbox.tenant_id, webhook_outbox.event_type, " +
+		"webhook_outbox.payload, webhook_outbox.status, webhook_outbox.attempts, webhook_outbox.last_error, webhook_outbox.created_at, webhooks.url, webhooks.secret " +
+		"FROM webhook_outbox INNER JOIN webhooks ON webhooks.id = webhook_outbox.webhook_id " +
+		"WHERE webhook_outbox.status = 'pending' AND webhook_outbox.next_attempt_at <= NOW() " +
+		"ORDER BY webhook_outbox.id ASC LIMIT ? FOR UPDATE OF webhook_outbox SKIP LOCKED"
+	query_lease_deliveries = "UPDATE webhook_outbox SET attempts = attempts + 1, next_attempt_at = NOW() + INTERVAL ? SECOND WHERE id IN (?,?)"
+	query_retry_delivery   = "UPDATE webhook_outbox SET last_error = ?, next_attempt_at = NOW() + INTERVAL ? SECOND WHERE id = ?"
+	query_kill_delivery    = "UPDATE webhook_outbox SET status = 'dead', last_error = ? WHERE id = ?"
+	query_redeliver        = "UPDATE webhook_outbox SET status = 'pending', attempts = 0, next_attempt_at = NOW() WHERE id = ? AND tenant_id = ? AND status = 'dead'"
+)
+
+var deliveryColumns = []string{"id", "webhook_id", "tenant_id", "event_type", "payload", "status", "attempts", "last_error", "created_at", "url", "secret"}
+
+func TestCreateWebhook_Success_ShouldJoinEventTypes(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec(query_insert_webhook).
+		WithArgs(testTenant, "https://example.com/webhook", "created,expired", "s3cret").
+		WillReturnResult(sqlmock.NewResult(7, 1))
+
+	webhook := domain.Webhook{
+		URL:        "https://example.com/webhook",
+		EventTypes: []domain.AdEventType{domain.AdEventCreated, domain.AdEventExpired},
+		Secret:     "s3cret",
+	}
+	testWr := repository.NewWebhookRepository(db)
+	err = testWr.Create(tenantContext, &webhook)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(7), webhook.ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestFetchWebhooks_Success_ShouldSplitEventTypes(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(query_fetch_webhooks).WithArgs(testTenant).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "url", "event_types", "created_at"}).
+			AddRow(7, "https://example.com/webhook", "created,expired", "2024-01-01 08:00:00"))
+
+	testWr := repository.NewWebhookRepository(db)
+	webhooks, err := testWr.Fetch(tenantContext)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.Webhook{{
+		ID:         7,
+		URL:        "https://example.com/webhook",
+		EventTypes: []domain.AdEventType{domain.AdEventCreated, domain.AdEventExpired},
+		CreatedAt:  "2024-01-01 08:00:00",
+	}}, webhooks)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestEnqueue_Success_ShouldReturnNumberOfDeliveries(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	payload := `{"type":"started","adId":1,"at":"2024-01-01T01:00:00Z"}`
+	mock.ExpectExec(query_enqueue_deliveries).
+		WithArgs(domain.AdEventStarted, payload, testTenant, domain.AdEventStarted).
+		WillReturnResult(sqlmock.NewResult(10, 2))
+
+	testWr := repository.NewWebhookRepository(db)
+	enqueued, err := testWr.Enqueue(tenantContext, domain.AdEvent{Type: domain.AdEventStarted, AdID: 1}, payload)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, enqueued)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Claim is run by the server for every tenant, so it is not scoped to a tenant
+func TestClaim_Success_ShouldLeaseDeliveriesAndCountAttempts(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(query_claim_deliveries).WithArgs(100).
+		WillReturnRows(sqlmock.NewRows(deliveryColumns).
+			AddRow(3, 7, testTenant, "created", "{}", "pending", 0, "", "2024-01-01 08:00:00", "https://example.com/webhook", "s3cret").
+			AddRow(4, 8, "team-b", "expired", "{}", "pending", 2, "timeout", "2024-01-01 08:00:00", "https://example.org/hook", "other"))
+	mock.ExpectExec(query_lease_deliveries).WithArgs(60, 3, 4).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	testWr := repository.NewWebhookRepository(db)
+	deliveries, err := testWr.Claim(context.Background(), 100, time.Minute)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.WebhookDelivery{
+		{ID: 3, WebhookID: 7, TenantID: testTenant, EventType: domain.AdEventCreated, Payload: "{}", Status: domain.DeliveryPending,
+			Attempts: 1, CreatedAt: "2024-01-01 08:00:00", URL: "https://example.com/webhook", Secret: "s3cret"},
+		{ID: 4, WebhookID: 8, TenantID: "team-b", EventType: domain.AdEventExpired, Payload: "{}", Status: domain.DeliveryPending,
+			Attempts: 3, LastError: "timeout", CreatedAt: "2024-01-01 08:00:00", URL: "https://example.org/hook", Secret: "other"},
+	}, deliveries)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestClaim_NothingDue_ShouldNotLease(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(query_claim_deliveries).WithArgs(100).WillReturnRows(sqlmock.NewRows(deliveryColumns))
+	mock.ExpectCommit()
+
+	testWr := repository.NewWebhookRepository(db)
+	deliveries, err := testWr.Claim(context.Background(), 100, time.Minute)
+
+	assert.NoError(t, err)
+	assert.Empty(t, deliveries)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestFail_RetryAfter_ShouldDelayNextAttempt(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec(query_retry_delivery).WithArgs("status 500", 120, 3).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	testWr := repository.NewWebhookRepository(db)
+	err = testWr.Fail(context.Background(), 3, "status 500", 2*time.Minute)
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestFail_NoRetry_ShouldMoveToDeadLetters(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec(query_kill_delivery).WithArgs("status 500", 3).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	testWr := repository.NewWebhookRepository(db)
+	err = testWr.Fail(context.Background(), 3, "status 500", 0)
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRedeliver_NotDead_ShouldReturnErrNotFound(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec(query_redeliver).WithArgs(3, testTenant).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	testWr := repository.NewWebhookRepository(db)
+	err = testWr.Redeliver(tenantContext, 3)
+
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
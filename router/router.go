@@ -1,26 +1,260 @@
 package router
 
 import (
+	"context"
 	"database/sql"
+	"dcard-backend/config"
 	"dcard-backend/controller"
+	"dcard-backend/domain"
+	"dcard-backend/middleware"
+	"dcard-backend/proto/adpb"
 	"dcard-backend/repository"
+	"dcard-backend/rpc"
 	"dcard-backend/usecase"
+	"log"
+	"os"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	swaggerfiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
+	"google.golang.org/grpc"
 )
 
-func SetUpRoutes(router *gin.Engine, db *sql.DB, timeout time.Duration) {
-	ar := repository.NewAdRepository(db)
-	au := usecase.NewAdUsecase(ar, timeout)
+// loadCountryDatabase loads the CIDR file at GEOIP_CIDR_FILE and refreshes it every
+// GEOIP_REFRESH_INTERVAL seconds, unless it is 0. Countries are not resolved without the file.
+func loadCountryDatabase() (countries *middleware.CountryDatabase, stop func()) {
+	path := os.Getenv("GEOIP_CIDR_FILE")
+	if path == "" {
+		return nil, func() {}
+	}
+
+	countries, err := middleware.LoadCountryDatabase(path)
+	if err != nil {
+		log.Println("Error created when loading the country database:", err.Error())
+		return nil, func() {}
+	}
+	if interval := config.GetEnvSeconds("GEOIP_REFRESH_INTERVAL", time.Hour); interval > 0 {
+		return countries, countries.RefreshEvery(interval)
+	}
+	return countries, func() {}
+}
+
+// NewAdUsecase builds the ad usecase on the ad repository with the options both the server and
+// the command line take, such as AD_CONFLICT_POLICY, followed by options of their own
+func NewAdUsecase(ar domain.AdRepository, db *sql.DB, timeout time.Duration, options ...usecase.AdUsecaseOption) domain.AdUsecase {
+	shared := []usecase.AdUsecaseOption{
+		usecase.WithConflictPolicy(config.GetConflictPolicy()),
+		usecase.WithCampaignRepository(repository.NewCampaignRepository(db)),
+	}
+	return usecase.NewAdUsecase(ar, timeout, append(shared, options...)...)
+}
+
+// SetUpRoutes registers the routes and the services of the gRPC server, and starts their
+// background workers. closeStreams ends the open streams of both, which never finish on their
+// own, so it is called when the servers start shutting down. shutdown stops the workers,
+// waiting for them to flush until ctx is done.
+func SetUpRoutes(router *gin.Engine, rpcServer *grpc.Server, db *sql.DB, timeout time.Duration) (closeStreams func(), shutdown func(ctx context.Context)) {
+	// Events of ads are recorded in the outbox, from which the dispatcher relays them to the
+	// event bus of every server and to the durable subscriptions
+	outboxRepository := repository.NewOutboxRepository(db)
+	eventBus := usecase.NewAdEventBus()
+	dispatcher := usecase.NewEventDispatcher(outboxRepository, eventBus, timeout,
+		usecase.WithOutboxRetention(config.GetEnvSeconds("OUTBOX_RETENTION", 24*time.Hour)),
+		usecase.WithTransactionTimeout(config.GetEnvSeconds("OUTBOX_TRANSACTION_TIMEOUT", 2*timeout)),
+		usecase.WithDispatcherMaxAttempts(config.GetEnvInt("OUTBOX_MAX_ATTEMPTS", 8)))
+
+	tu := usecase.NewTrackingUsecase(repository.NewTrackingRepository(db), timeout,
+		usecase.WithBatchSize(config.GetEnvInt("TRACKING_BATCH_SIZE", 1000)),
+		usecase.WithMaxBufferedCounts(config.GetEnvInt("TRACKING_MAX_BUFFERED", 100000)),
+		usecase.WithTrackingEvents(eventBus))
+	tc := controller.TrackingController{
+		TrackingUsecase: tu,
+	}
+
+	advertiserRepository := repository.NewAdvertiserRepository(db)
+	campaignRepository := repository.NewCampaignRepository(db)
+	advc := controller.AdvertiserController{
+		AdvertiserUsecase: usecase.NewAdvertiserUsecase(advertiserRepository, timeout),
+	}
+	ar := repository.NewAdRepository(db,
+		repository.WithMaxCachedStatements(config.GetEnvInt("MYSQL_MAX_CACHED_STATEMENTS", repository.DefaultMaxCachedStatements)))
+	au := NewAdUsecase(ar, db, timeout,
+		usecase.WithFrequencyRepository(repository.NewFrequencyRepository(db, time.Now)),
+		usecase.WithDeliveryCounter(tu))
 	ac := controller.AdController{
 		AdUsecase: au,
 	}
+	// Updates of campaigns reach the fields their ads inherit
+	cc := controller.CampaignController{
+		CampaignUsecase: usecase.NewCampaignUsecase(campaignRepository, advertiserRepository, timeout, usecase.WithCampaignAds(au)),
+	}
+	atc := controller.AdTransferController{
+		AdTransferUsecase: usecase.NewAdTransferUsecase(au),
+	}
+
+	idempotencyStore := middleware.NewIdempotencyStore(config.GetEnvSeconds("IDEMPOTENCY_TTL", 24*time.Hour))
+
+	getAdHandlers := []gin.HandlerFunc{ac.GetAdWithCondition}
+	stopRefreshingCountries := func() {}
+	if config.GetEnvBool("RESOLVE_ATTRIBUTES", false) {
+		var countries *middleware.CountryDatabase
+		countries, stopRefreshingCountries = loadCountryDatabase()
+		getAdHandlers = append([]gin.HandlerFunc{middleware.ResolveAttributes(countries)}, getAdHandlers...)
+	}
+
+	auc := controller.AuditController{
+		AuditUsecase: usecase.NewAuditUsecase(repository.NewAuditRepository(db), timeout),
+	}
+
+	// Events of ads are delivered to the webhooks of their tenant through the outbox
+	wu := usecase.NewWebhookUsecase(repository.NewWebhookRepository(db), timeout,
+		usecase.WithWebhookMaxAttempts(config.GetEnvInt("WEBHOOK_MAX_ATTEMPTS", 8)),
+		usecase.WithPrivateWebhookAddresses(config.GetEnvBool("WEBHOOK_ALLOW_PRIVATE_ADDRESSES", false)))
+	dispatcher.Subscribe("webhooks", wu.Enqueue)
+	wc := controller.WebhookController{
+		WebhookUsecase: wu,
+	}
+
+	// Changes of ads are streamed from the event log, which the events of every server are
+	// appended to
+	su := usecase.NewAdStreamUsecase(repository.NewAdEventLogRepository(db), timeout,
+		usecase.WithStreamRetention(config.GetEnvSeconds("STREAM_LOG_RETENTION", 24*time.Hour)))
+	dispatcher.Subscribe("stream", su.Append)
+	sc := controller.AdStreamController{
+		AdStreamUsecase:   su,
+		HeartbeatInterval: config.GetEnvSeconds("STREAM_HEARTBEAT_INTERVAL", 15*time.Second),
+	}
+
+	// Every request has an id, which is returned to the client and recorded in the audit log
+	router.Use(middleware.RequestID())
 
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerfiles.Handler))
 
-	router.POST("/api/v1/ad", ac.PostAd)
-	router.GET("/api/v1/ad", ac.GetAdWithCondition)
+	// Ads are served on behalf of the tenant of the site key, and events are only recorded for
+	// the ads of that tenant
+	siteTenant := middleware.SiteTenant(config.GetEnvTenantKeys("SITE_KEYS"))
+	router.GET("/api/v1/ad", append([]gin.HandlerFunc{siteTenant}, getAdHandlers...)...)
+	router.POST("/api/v1/ad/:id/impression", siteTenant, tc.PostImpression)
+	router.POST("/api/v1/ad/:id/click", siteTenant, tc.PostClick)
+
+	// The admin API is made on behalf of the tenant of the API key
+	admin := router.Group("/api/v1", middleware.Authenticate(config.GetEnvTenantKeys("ADMIN_API_KEYS")))
+
+	admin.POST("/ad", middleware.Idempotency(idempotencyStore), ac.PostAd)
+	admin.POST("/ad/overlaps", ac.PostOverlappingAds)
+	admin.POST("/ad/import", atc.PostImport)
+	admin.GET("/ad/export", atc.GetExport)
+	admin.GET("/ad/:id", ac.GetAd)
+	admin.PUT("/ad/:id", ac.PutAd)
+	admin.DELETE("/ad/:id", ac.DeleteAd)
+	admin.POST("/ad/:id/restore", ac.PostRestoreAd)
+	admin.POST("/ad/:id/pause", ac.PostPauseAd)
+	admin.POST("/ad/:id/resume", ac.PostResumeAd)
+	admin.GET("/ad/:id/versions/:version", ac.GetAdVersion)
+	admin.POST("/ad/:id/rollback", ac.PostRollbackAd)
+	admin.GET("/ad/:id/stats", tc.GetStats)
+	admin.GET("/ad/:id/history", auc.GetAdHistory)
+	admin.GET("/audit", auc.GetAuditLog)
+	admin.GET("/ads/stream", sc.GetAdStream)
+
+	admin.POST("/advertiser", advc.PostAdvertiser)
+	admin.GET("/advertiser", advc.GetAdvertisers)
+	admin.GET("/advertiser/:id", advc.GetAdvertiser)
+	admin.PUT("/advertiser/:id", advc.PutAdvertiser)
+	admin.DELETE("/advertiser/:id", advc.DeleteAdvertiser)
+	admin.GET("/advertiser/:id/campaign", cc.GetAdvertiserCampaigns)
+
+	admin.POST("/campaign", cc.PostCampaign)
+	admin.GET("/campaign/:id", cc.GetCampaign)
+	admin.PUT("/campaign/:id", cc.PutCampaign)
+	admin.DELETE("/campaign/:id", cc.DeleteCampaign)
+	admin.POST("/campaign/:id/pause", cc.PostPauseCampaign)
+	admin.POST("/campaign/:id/resume", cc.PostResumeCampaign)
+
+	admin.POST("/webhook", wc.PostWebhook)
+	admin.GET("/webhook", wc.GetWebhooks)
+	admin.DELETE("/webhook/:id", wc.DeleteWebhook)
+	admin.GET("/webhook/dead-letter", wc.GetDeadLetters)
+	admin.POST("/webhook/dead-letter/:id/redeliver", wc.PostRedeliver)
+
+	// The gRPC API serves the same ads, authenticated by its interceptors
+	adpb.RegisterAdServiceServer(rpcServer, &rpc.AdServer{
+		AdUsecase:       au,
+		AdStreamUsecase: su,
+	})
+
+	pu := usecase.NewAdPurgeUsecase(ar, timeout, config.GetEnvSeconds("PURGE_RETENTION", 30*24*time.Hour),
+		usecase.WithPurgeBatchSize(config.GetEnvInt("PURGE_BATCH_SIZE", 500)))
+	lu := usecase.NewAdLifecycleUsecase(ar, eventBus, timeout, usecase.WithLifecycleInvalidators(tu))
+
+	workers, stopWorkers := context.WithCancel(context.Background())
+	flushed := make(chan struct{})
+	go func() {
+		defer close(flushed)
+		tu.Run(workers, config.GetEnvSeconds("TRACKING_FLUSH_INTERVAL", 10*time.Second))
+	}()
+	purged := make(chan struct{})
+	go func() {
+		defer close(purged)
+		pu.Run(workers, config.GetEnvSeconds("PURGE_INTERVAL", time.Hour))
+	}()
+	followed := make(chan struct{})
+	go func() {
+		defer close(followed)
+		lu.Run(workers, config.GetEnvSeconds("LIFECYCLE_RESYNC_INTERVAL", 5*time.Minute))
+	}()
+
+	dispatched := make(chan struct{})
+	go func() {
+		defer close(dispatched)
+		dispatcher.Run(workers, config.GetEnvSeconds("OUTBOX_POLL_INTERVAL", time.Second))
+	}()
+
+	notified := make(chan struct{})
+	go func() {
+		defer close(notified)
+		wu.Run(workers, config.GetEnvSeconds("WEBHOOK_POLL_INTERVAL", 5*time.Second))
+	}()
+
+	streamed := make(chan struct{})
+	go func() {
+		defer close(streamed)
+		su.Run(workers, config.GetEnvSeconds("STREAM_POLL_INTERVAL", time.Second))
+	}()
+
+	return su.Close, func(ctx context.Context) {
+		stopWorkers()
+		stopRefreshingCountries()
+		select {
+		case <-flushed:
+		case <-ctx.Done():
+			log.Println("Error created when flushing ad events on shutdown:", ctx.Err().Error())
+		}
+		select {
+		case <-purged:
+		case <-ctx.Done():
+			log.Println("Error created when stopping the purge of deleted ads:", ctx.Err().Error())
+		}
+		select {
+		case <-followed:
+		case <-ctx.Done():
+			log.Println("Error created when stopping the lifecycle of ads:", ctx.Err().Error())
+		}
+		select {
+		case <-dispatched:
+		case <-ctx.Done():
+			log.Println("Error created when stopping the dispatch of the outbox:", ctx.Err().Error())
+		}
+		select {
+		case <-notified:
+		case <-ctx.Done():
+			log.Println("Error created when stopping the delivery of webhooks:", ctx.Err().Error())
+		}
+		select {
+		case <-streamed:
+		case <-ctx.Done():
+			log.Println("Error created when stopping the streams of ads:", ctx.Err().Error())
+		}
+	}
 }
@@ -0,0 +1,130 @@
+package router_test
+
+import (
+	"context"
+	"dcard-backend/router"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+)
+
+// publicRoutes are served without a tenant, since they never read the ads of a tenant
+var publicRoutes = map[string]bool{
+	"GET /swagger/*any": true,
+}
+
+func setUpTestRoutes(t *testing.T) (*gin.Engine, sqlmock.Sqlmock) {
+	t.Setenv("ADMIN_API_KEYS", "team-a:secret-a,team-b:secret-b")
+	t.Setenv("SITE_KEYS", "team-a:site-a")
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	app := gin.New()
+	_, shutdown := router.SetUpRoutes(app, grpc.NewServer(), db, time.Second)
+	t.Cleanup(func() { shutdown(context.Background()) })
+	return app, mock
+}
+
+func serve(app *gin.Engine, method string, path string, apiKey string, body string) *httptest.ResponseRecorder {
+	httpRecorder := httptest.NewRecorder()
+	httpRequest := httptest.NewRequest(method, path, strings.NewReader(body))
+	httpRequest.Header.Set("Content-Type", "application/json")
+	// Replacing an ad requires If-Match, which is ignored by other routes
+	httpRequest.Header.Set("If-Match", "*")
+	if apiKey != "" {
+		httpRequest.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+	app.ServeHTTP(httpRecorder, httpRequest)
+	return httpRecorder
+}
+
+func TestSetUpRoutes_NoCredentials_EveryTenantRouteShouldReturnUnauthorized(t *testing.T) {
+	app, mock := setUpTestRoutes(t)
+
+	for _, route := range app.Routes() {
+		if publicRoutes[route.Method+" "+route.Path] {
+			continue
+		}
+		path := strings.ReplaceAll(route.Path, ":id", "1")
+
+		httpRecorder := serve(app, route.Method, path, "", "{}")
+		assert.Equal(t, http.StatusUnauthorized, httpRecorder.Code, route.Method+" "+route.Path)
+
+		httpRecorder = serve(app, route.Method, path, "team-a", "{}")
+		assert.Equal(t, http.StatusUnauthorized, httpRecorder.Code, route.Method+" "+route.Path+" with a tenant id as the key")
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSetUpRoutes_AnotherTenant_ShouldNotReachAdsOfTenant(t *testing.T) {
+	app, mock := setUpTestRoutes(t)
+	noRows := func() *sqlmock.Rows { return sqlmock.NewRows([]string{"id"}) }
+
+	// Every query of team-b is scoped to team-b, so ids of team-a are not found
+	mock.ExpectQuery("FROM advertisers WHERE id = \\? AND tenant_id = \\?").WithArgs(1, "team-b").WillReturnRows(noRows())
+	mock.ExpectQuery("FROM advertisers WHERE id = \\? AND tenant_id = \\?").WithArgs(1, "team-b").WillReturnRows(noRows())
+	mock.ExpectExec("DELETE FROM advertisers").WithArgs(1, "team-b").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("FROM campaigns WHERE id = \\?").WithArgs(1, "team-b").WillReturnRows(noRows())
+	mock.ExpectQuery("FROM campaigns WHERE id = \\?").WithArgs(1, "team-b").WillReturnRows(noRows())
+	mock.ExpectExec("DELETE FROM campaigns").WithArgs(1, "team-b").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("FROM campaigns WHERE id = \\?").WithArgs(1, "team-b").WillReturnRows(noRows())
+	mock.ExpectQuery("FROM ads LEFT JOIN ad_event_counts").WithArgs(1, "team-b").WillReturnRows(noRows())
+	mock.ExpectQuery("FROM ads WHERE ads.tenant_id = \\? AND ads.deleted_at IS NULL ORDER BY").WithArgs("team-b").WillReturnRows(noRows())
+	mock.ExpectQuery("FROM ads WHERE ads.tenant_id = \\? AND ads.deleted_at IS NULL AND ads.id = \\?").WithArgs("team-b", 1).WillReturnRows(noRows())
+	mock.ExpectBegin()
+	mock.ExpectQuery("FROM ads WHERE ads.tenant_id = \\? AND ads.deleted_at IS NULL AND ads.id = \\? FOR UPDATE").WithArgs("team-b", 1).WillReturnRows(noRows())
+	mock.ExpectRollback()
+	mock.ExpectBegin()
+	mock.ExpectQuery("FROM ads WHERE ads.tenant_id = \\? AND ads.deleted_at IS NULL AND ads.id = \\? FOR UPDATE").WithArgs("team-b", 1).WillReturnRows(noRows())
+	mock.ExpectRollback()
+	mock.ExpectBegin()
+	mock.ExpectQuery("FROM ads WHERE ads.tenant_id = \\? AND ads.deleted_at IS NOT NULL AND ads.id = \\? FOR UPDATE").WithArgs("team-b", 1).WillReturnRows(noRows())
+	mock.ExpectRollback()
+	mock.ExpectQuery("FROM ad_audit_log WHERE tenant_id = \\? AND ad_id = \\?").WithArgs("team-b", 1, 50, 0).WillReturnRows(noRows())
+	mock.ExpectQuery("FROM ad_versions WHERE tenant_id = \\? AND ad_id = \\? AND version = \\?").WithArgs("team-b", 1, 1).WillReturnRows(noRows())
+	mock.ExpectQuery("FROM ad_versions WHERE tenant_id = \\? AND ad_id = \\? AND version = \\?").WithArgs("team-b", 1, 1).WillReturnRows(noRows())
+	mock.ExpectExec("DELETE FROM webhooks WHERE id = \\? AND tenant_id = \\?").WithArgs(1, "team-b").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("UPDATE webhook_outbox SET status = 'pending'").WithArgs(1, "team-b").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	requests := []struct {
+		method string
+		path   string
+		body   string
+		status int
+	}{
+		{http.MethodGet, "/api/v1/advertiser/1", "", http.StatusNotFound},
+		{http.MethodPut, "/api/v1/advertiser/1", `{"name": "Renamed"}`, http.StatusNotFound},
+		{http.MethodDelete, "/api/v1/advertiser/1", "", http.StatusNotFound},
+		{http.MethodGet, "/api/v1/campaign/1", "", http.StatusNotFound},
+		{http.MethodPost, "/api/v1/campaign/1/pause", "", http.StatusNotFound},
+		{http.MethodDelete, "/api/v1/campaign/1", "", http.StatusNotFound},
+		{http.MethodPost, "/api/v1/ad", `{"title": "AD", "campaignId": 1}`, http.StatusBadRequest},
+		{http.MethodGet, "/api/v1/ad/1/stats", "", http.StatusNotFound},
+		{http.MethodGet, "/api/v1/ad/export?format=csv", "", http.StatusOK},
+		{http.MethodGet, "/api/v1/ad/1", "", http.StatusNotFound},
+		{http.MethodPost, "/api/v1/ad/1/pause", "", http.StatusNotFound},
+		{http.MethodDelete, "/api/v1/ad/1", "", http.StatusNotFound},
+		{http.MethodPost, "/api/v1/ad/1/restore", "", http.StatusNotFound},
+		{http.MethodGet, "/api/v1/ad/1/history", "", http.StatusNotFound},
+		{http.MethodGet, "/api/v1/ad/1/versions/1", "", http.StatusNotFound},
+		{http.MethodPost, "/api/v1/ad/1/rollback?version=1", "", http.StatusNotFound},
+		{http.MethodDelete, "/api/v1/webhook/1", "", http.StatusNotFound},
+		{http.MethodPost, "/api/v1/webhook/dead-letter/1/redeliver", "", http.StatusNotFound},
+	}
+	for _, request := range requests {
+		httpRecorder := serve(app, request.method, request.path, "secret-b", request.body)
+		assert.Equal(t, request.status, httpRecorder.Code, request.method+" "+request.path)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
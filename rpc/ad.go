@@ -0,0 +1,104 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"dcard-backend/domain"
+	"dcard-backend/proto/adpb"
+)
+
+// getStatusCode maps the errors of the domain to the codes of gRPC, as the controllers map them
+// to the status codes of HTTP
+func getStatusCode(err error) codes.Code {
+	switch {
+	case errors.Is(err, domain.ErrTimeout):
+		return codes.DeadlineExceeded
+	case errors.Is(err, domain.ErrConflict):
+		return codes.AlreadyExists
+	case errors.Is(err, domain.ErrBadParamInput):
+		return codes.InvalidArgument
+	case errors.Is(err, domain.ErrNotFound):
+		return codes.NotFound
+	case errors.Is(err, domain.ErrUnauthorized):
+		return codes.Unauthenticated
+	case errors.Is(err, domain.ErrPreconditionFailed):
+		return codes.FailedPrecondition
+	default:
+		return codes.Internal
+	}
+}
+
+func toStatusError(err error) error {
+	return status.Error(getStatusCode(err), err.Error())
+}
+
+type AdServer struct {
+	adpb.UnimplementedAdServiceServer
+	AdUsecase       domain.AdUsecase
+	AdStreamUsecase domain.AdStreamUsecase
+}
+
+func (as *AdServer) CreateAd(c context.Context, req *adpb.CreateAdRequest) (*adpb.Ad, error) {
+	if req.GetAd().GetTitle() == "" {
+		return nil, status.Error(codes.InvalidArgument, "title is required")
+	}
+
+	ad := fromProtoAd(req.GetAd())
+	if err := as.AdUsecase.Create(c, &ad); err != nil {
+		return nil, toStatusError(err)
+	}
+	return toProtoAd(ad), nil
+}
+
+func (as *AdServer) GetAd(c context.Context, req *adpb.GetAdRequest) (*adpb.Ad, error) {
+	if req.GetId() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "ad id should be a positive integer")
+	}
+
+	ad, err := as.AdUsecase.GetByID(c, req.GetId())
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+	return toProtoAd(ad), nil
+}
+
+func (as *AdServer) ListAds(c context.Context, req *adpb.ListAdsRequest) (*adpb.ListAdsResponse, error) {
+	condition := map[string][]string{}
+	for key, values := range req.GetCondition() {
+		condition[key] = values.GetValues()
+	}
+
+	ads, err := as.AdUsecase.GetByCondition(c, condition)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+	items := make([]*adpb.Ad, 0, len(ads))
+	for _, ad := range ads {
+		items = append(items, toProtoAd(ad))
+	}
+	return &adpb.ListAdsResponse{Items: items}, nil
+}
+
+// WatchAds sends the changes of the stream until the client cancels the call, or until the
+// stream ends, and the client then resumes it from the last change
+func (as *AdServer) WatchAds(req *adpb.WatchAdsRequest, stream adpb.AdService_WatchAdsServer) error {
+	events, err := as.AdStreamUsecase.Subscribe(stream.Context(), req.GetLastEventId())
+	if err != nil {
+		return toStatusError(err)
+	}
+
+	for event := range events {
+		change := &adpb.AdChange{Id: event.ID, Type: string(event.Type), AdId: event.AdID, At: event.At}
+		if event.Ad != nil {
+			change.Ad = toProtoAd(*event.Ad)
+		}
+		if err := stream.Send(change); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,229 @@
+package rpc_test
+
+import (
+	"context"
+	"dcard-backend/domain"
+	"dcard-backend/domain/mocks"
+	"dcard-backend/middleware"
+	"dcard-backend/proto/adpb"
+	"dcard-backend/repository"
+	"dcard-backend/rpc"
+	"dcard-backend/usecase"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// newTestClient serves the ads in process until the test ends, with the interceptors of the
+// server and the API key of team-a
+func newTestClient(t *testing.T, adUsecase domain.AdUsecase, streamUsecase domain.AdStreamUsecase) adpb.AdServiceClient {
+	listener := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer(middleware.RPCInterceptors(map[string]string{"secret-a": "team-a"})...)
+	adpb.RegisterAdServiceServer(server, &rpc.AdServer{AdUsecase: adUsecase, AdStreamUsecase: streamUsecase})
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.DialContext(context.Background(), "bufnet",
+		grpc.WithContextDialer(func(c context.Context, _ string) (net.Conn, error) { return listener.DialContext(c) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when dialing the server", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return adpb.NewAdServiceClient(conn)
+}
+
+func withAPIKey(apiKey string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+apiKey)
+}
+
+// isTenant matches the contexts of calls made on behalf of the tenant
+func isTenant(tenantID string) any {
+	return mock.MatchedBy(func(c context.Context) bool {
+		tenant, err := domain.TenantFromContext(c)
+		return err == nil && tenant == tenantID
+	})
+}
+
+func TestCreateAd_ValidAPIKey_ShouldCreateAdOfTenant(t *testing.T) {
+	mockAdUsecase := mocks.NewAdUsecase(t)
+	mockAdUsecase.On("Create", isTenant("team-a"), &domain.Ad{Title: "AD 1", EndAt: "2024-12-31 16:00:00",
+		Condition: &domain.Condition{AgeStart: 18, AgeEnd: 30, Country: []string{"TW"}},
+		Budget:    &domain.Budget{TotalImpressions: 1000}}).
+		Return(nil).Once()
+	client := newTestClient(t, mockAdUsecase, mocks.NewAdStreamUsecase(t))
+
+	var header metadata.MD
+	ad, err := client.CreateAd(withAPIKey("secret-a"), &adpb.CreateAdRequest{Ad: &adpb.Ad{Title: "AD 1", EndAt: "2024-12-31 16:00:00",
+		Condition: &adpb.Condition{AgeStart: 18, AgeEnd: 30, Country: []string{"TW"}},
+		Budget:    &adpb.Budget{TotalImpressions: 1000}}}, grpc.Header(&header))
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"TW"}, ad.GetCondition().GetCountry())
+	assert.Len(t, header.Get("x-request-id"), 1)
+}
+
+func TestCreateAd_Inserted_ShouldReturnIDAndVersionOfCreatedAd(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	prepAds := mock.ExpectPrepare("INSERT INTO ads ")
+	prepLinks := []*sqlmock.ExpectedPrepare{}
+	for range domain.Dimensions() {
+		prepLinks = append(prepLinks, mock.ExpectPrepare("INSERT INTO ad_"))
+	}
+	mock.ExpectBegin()
+	prepAds.ExpectExec().WillReturnResult(sqlmock.NewResult(42, 1))
+	// Dimensions without values target their "any" value
+	for i, dimension := range domain.Dimensions() {
+		value := dimension.AnyValue
+		if dimension.Name == "country" {
+			value = "TW"
+		}
+		prepLinks[i].ExpectExec().WithArgs(42, value, false).WillReturnResult(sqlmock.NewResult(1, 1))
+	}
+	mock.ExpectExec("INSERT INTO ad_versions ").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO ad_audit_log ").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO outbox ").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	adUsecase := usecase.NewAdUsecase(repository.NewAdRepository(db), time.Second, usecase.WithConflictPolicy(domain.ConflictPolicyAllow))
+	client := newTestClient(t, adUsecase, mocks.NewAdStreamUsecase(t))
+
+	ad, err := client.CreateAd(withAPIKey("secret-a"), &adpb.CreateAdRequest{Ad: &adpb.Ad{Title: "AD 1", StartAt: "2024-01-01T00:00:00Z",
+		EndAt: "2024-12-31T16:00:00Z", Condition: &adpb.Condition{Country: []string{"TW"}}}})
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(42), ad.GetId())
+	assert.Equal(t, int64(1), ad.GetVersion())
+	assert.Equal(t, string(domain.AdActive), ad.GetStatus())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateAd_TargetingOfRegisteredDimension_ShouldKeepItByName(t *testing.T) {
+	mockAdUsecase := mocks.NewAdUsecase(t)
+	mockAdUsecase.On("Create", isTenant("team-a"), &domain.Ad{Title: "AD 1", EndAt: "2024-12-31 16:00:00",
+		Condition: &domain.Condition{Targeting: map[string]*domain.Targeting{"os": {Values: []string{"ios17"}, Exclude: []string{"ios16"}}}}}).
+		Return(nil).Once()
+	client := newTestClient(t, mockAdUsecase, mocks.NewAdStreamUsecase(t))
+
+	ad, err := client.CreateAd(withAPIKey("secret-a"), &adpb.CreateAdRequest{Ad: &adpb.Ad{Title: "AD 1", EndAt: "2024-12-31 16:00:00",
+		Condition: &adpb.Condition{Targeting: map[string]*adpb.Targeting{"os": {Values: []string{"ios17"}, Exclude: []string{"ios16"}}}}}})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"ios16"}, ad.GetCondition().GetTargeting()["os"].GetExclude())
+}
+
+func TestCreateAd_MissingTitle_ShouldReturnInvalidArgument(t *testing.T) {
+	client := newTestClient(t, mocks.NewAdUsecase(t), mocks.NewAdStreamUsecase(t))
+
+	_, err := client.CreateAd(withAPIKey("secret-a"), &adpb.CreateAdRequest{Ad: &adpb.Ad{EndAt: "2024-12-31 16:00:00"}})
+
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestAdService_MissingOrInvalidAPIKey_ShouldReturnUnauthenticated(t *testing.T) {
+	client := newTestClient(t, mocks.NewAdUsecase(t), mocks.NewAdStreamUsecase(t))
+
+	for _, c := range []context.Context{context.Background(), withAPIKey("secret-b")} {
+		_, err := client.GetAd(c, &adpb.GetAdRequest{Id: 1})
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+		stream, err := client.WatchAds(c, &adpb.WatchAdsRequest{})
+		if assert.NoError(t, err) {
+			_, err = stream.Recv()
+			assert.Equal(t, codes.Unauthenticated, status.Code(err))
+		}
+	}
+}
+
+func TestGetAd_AdOfAnotherTenant_ShouldReturnNotFound(t *testing.T) {
+	mockAdUsecase := mocks.NewAdUsecase(t)
+	mockAdUsecase.On("GetByID", isTenant("team-a"), int64(2)).Return(domain.Ad{}, domain.ErrNotFound).Once()
+	client := newTestClient(t, mockAdUsecase, mocks.NewAdStreamUsecase(t))
+
+	_, err := client.GetAd(withAPIKey("secret-a"), &adpb.GetAdRequest{Id: 2})
+
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestListAds_Condition_ShouldListAdsAsGetByCondition(t *testing.T) {
+	mockAdUsecase := mocks.NewAdUsecase(t)
+	mockAdUsecase.On("GetByCondition", isTenant("team-a"), map[string][]string{
+		"offset":   {"0"},
+		"limit":    {"2"},
+		"country":  {"TW"},
+		"platform": {"ios", "web"},
+	}).Return([]domain.Ad{{ID: 1, Title: "AD 1"}, {ID: 2, Title: "AD 2"}}, nil).Once()
+	client := newTestClient(t, mockAdUsecase, mocks.NewAdStreamUsecase(t))
+
+	resp, err := client.ListAds(withAPIKey("secret-a"), &adpb.ListAdsRequest{Condition: map[string]*adpb.ConditionValues{
+		"offset":   {Values: []string{"0"}},
+		"limit":    {Values: []string{"2"}},
+		"country":  {Values: []string{"TW"}},
+		"platform": {Values: []string{"ios", "web"}},
+	}})
+
+	assert.NoError(t, err)
+	if assert.Len(t, resp.GetItems(), 2) {
+		assert.Equal(t, "AD 2", resp.GetItems()[1].GetTitle())
+	}
+}
+
+func TestListAds_InvalidCondition_ShouldReturnInvalidArgument(t *testing.T) {
+	mockAdUsecase := mocks.NewAdUsecase(t)
+	mockAdUsecase.On("GetByCondition", mock.Anything, mock.Anything).Return(nil, domain.ErrBadParamInput).Once()
+	client := newTestClient(t, mockAdUsecase, mocks.NewAdStreamUsecase(t))
+
+	_, err := client.ListAds(withAPIKey("secret-a"), &adpb.ListAdsRequest{Condition: map[string]*adpb.ConditionValues{
+		"age": {Values: []string{"old"}},
+	}})
+
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestWatchAds_LastEventID_ShouldStreamChangesOfTenant(t *testing.T) {
+	events := make(chan domain.AdStreamEvent, 2)
+	events <- domain.AdStreamEvent{ID: 42, Type: domain.AdEventUpdated, AdID: 1, At: "2024-01-01T00:00:00Z", Ad: &domain.Ad{ID: 1, Title: "AD 1"}}
+	events <- domain.AdStreamEvent{ID: 43, Type: domain.AdEventDeleted, AdID: 1, At: "2024-01-01T00:00:01Z"}
+	close(events)
+	mockAdStreamUsecase := mocks.NewAdStreamUsecase(t)
+	mockAdStreamUsecase.On("Subscribe", isTenant("team-a"), int64(41)).Return((<-chan domain.AdStreamEvent)(events), nil).Once()
+	client := newTestClient(t, mocks.NewAdUsecase(t), mockAdStreamUsecase)
+
+	stream, err := client.WatchAds(withAPIKey("secret-a"), &adpb.WatchAdsRequest{LastEventId: 41})
+	assert.NoError(t, err)
+
+	changes := []*adpb.AdChange{}
+	for {
+		change, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if !assert.NoError(t, err) {
+			break
+		}
+		changes = append(changes, change)
+	}
+
+	if assert.Len(t, changes, 2) {
+		assert.Equal(t, "updated", changes[0].GetType())
+		assert.Equal(t, "AD 1", changes[0].GetAd().GetTitle())
+		assert.Equal(t, int64(43), changes[1].GetId())
+		assert.Nil(t, changes[1].GetAd())
+	}
+}
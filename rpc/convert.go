@@ -0,0 +1,138 @@
+package rpc
+
+import (
+	"dcard-backend/domain"
+	"dcard-backend/proto/adpb"
+)
+
+func fromProtoAd(ad *adpb.Ad) domain.Ad {
+	converted := domain.Ad{
+		ID:           ad.GetId(),
+		Title:        ad.GetTitle(),
+		CampaignID:   ad.GetCampaignId(),
+		StartAt:      ad.GetStartAt(),
+		EndAt:        ad.GetEndAt(),
+		Description:  ad.GetDescription(),
+		ImageURL:     ad.GetImageUrl(),
+		ClickURL:     ad.GetClickUrl(),
+		CallToAction: ad.GetCallToAction(),
+		Priority:     int(ad.GetPriority()),
+		Weight:       int(ad.GetWeight()),
+		Status:       domain.AdStatus(ad.GetStatus()),
+		Version:      ad.GetVersion(),
+		Lifecycle:    domain.AdLifecycle(ad.GetLifecycle()),
+		Inherited:    ad.GetInherited(),
+	}
+	if condition := ad.GetCondition(); condition != nil {
+		converted.Condition = &domain.Condition{
+			AgeStart:        int(condition.GetAgeStart()),
+			AgeEnd:          int(condition.GetAgeEnd()),
+			Gender:          condition.GetGender(),
+			Country:         condition.GetCountry(),
+			Platform:        condition.GetPlatform(),
+			Language:        condition.GetLanguage(),
+			ExcludeGender:   condition.GetExcludeGender(),
+			ExcludeCountry:  condition.GetExcludeCountry(),
+			ExcludePlatform: condition.GetExcludePlatform(),
+			ExcludeLanguage: condition.GetExcludeLanguage(),
+		}
+		if schedule := condition.GetSchedule(); schedule != nil {
+			converted.Condition.Schedule = &domain.Schedule{Timezone: schedule.GetTimezone(), Windows: []domain.ScheduleWindow{}}
+			for _, window := range schedule.GetWindows() {
+				converted.Condition.Schedule.Windows = append(converted.Condition.Schedule.Windows, domain.ScheduleWindow{
+					Weekday:   window.GetWeekday(),
+					StartHour: int(window.GetStartHour()),
+					EndHour:   int(window.GetEndHour()),
+				})
+			}
+		}
+		for name, targeting := range condition.GetTargeting() {
+			if converted.Condition.Targeting == nil {
+				converted.Condition.Targeting = map[string]*domain.Targeting{}
+			}
+			converted.Condition.Targeting[name] = &domain.Targeting{Values: targeting.GetValues(), Exclude: targeting.GetExclude()}
+		}
+	}
+	for _, creative := range ad.GetCreatives() {
+		converted.Creatives = append(converted.Creatives, domain.Creative{
+			Platform:     creative.GetPlatform(),
+			Description:  creative.GetDescription(),
+			ImageURL:     creative.GetImageUrl(),
+			ClickURL:     creative.GetClickUrl(),
+			CallToAction: creative.GetCallToAction(),
+		})
+	}
+	if frequencyCap := ad.GetFrequencyCap(); frequencyCap != nil {
+		converted.FrequencyCap = &domain.FrequencyCap{Count: int(frequencyCap.GetCount()), Window: frequencyCap.GetWindow()}
+	}
+	if budget := ad.GetBudget(); budget != nil {
+		converted.Budget = &domain.Budget{TotalImpressions: budget.GetTotalImpressions(), DailyImpressions: budget.GetDailyImpressions()}
+	}
+	return converted
+}
+
+func toProtoAd(ad domain.Ad) *adpb.Ad {
+	converted := &adpb.Ad{
+		Id:           ad.ID,
+		Title:        ad.Title,
+		CampaignId:   ad.CampaignID,
+		StartAt:      ad.StartAt,
+		EndAt:        ad.EndAt,
+		Description:  ad.Description,
+		ImageUrl:     ad.ImageURL,
+		ClickUrl:     ad.ClickURL,
+		CallToAction: ad.CallToAction,
+		Priority:     int32(ad.Priority),
+		Weight:       int32(ad.Weight),
+		Status:       string(ad.Status),
+		Version:      ad.Version,
+		Lifecycle:    string(ad.Lifecycle),
+		Inherited:    ad.Inherited,
+	}
+	if condition := ad.Condition; condition != nil {
+		converted.Condition = &adpb.Condition{
+			AgeStart:        int32(condition.AgeStart),
+			AgeEnd:          int32(condition.AgeEnd),
+			Gender:          condition.Gender,
+			Country:         condition.Country,
+			Platform:        condition.Platform,
+			Language:        condition.Language,
+			ExcludeGender:   condition.ExcludeGender,
+			ExcludeCountry:  condition.ExcludeCountry,
+			ExcludePlatform: condition.ExcludePlatform,
+			ExcludeLanguage: condition.ExcludeLanguage,
+		}
+		if schedule := condition.Schedule; schedule != nil {
+			converted.Condition.Schedule = &adpb.Schedule{Timezone: schedule.Timezone}
+			for _, window := range schedule.Windows {
+				converted.Condition.Schedule.Windows = append(converted.Condition.Schedule.Windows, &adpb.ScheduleWindow{
+					Weekday:   window.Weekday,
+					StartHour: int32(window.StartHour),
+					EndHour:   int32(window.EndHour),
+				})
+			}
+		}
+		for name, targeting := range condition.Targeting {
+			if converted.Condition.Targeting == nil {
+				converted.Condition.Targeting = map[string]*adpb.Targeting{}
+			}
+			converted.Condition.Targeting[name] = &adpb.Targeting{Values: targeting.Values, Exclude: targeting.Exclude}
+		}
+	}
+	for _, creative := range ad.Creatives {
+		converted.Creatives = append(converted.Creatives, &adpb.Creative{
+			Platform:     creative.Platform,
+			Description:  creative.Description,
+			ImageUrl:     creative.ImageURL,
+			ClickUrl:     creative.ClickURL,
+			CallToAction: creative.CallToAction,
+		})
+	}
+	if frequencyCap := ad.FrequencyCap; frequencyCap != nil {
+		converted.FrequencyCap = &adpb.FrequencyCap{Count: int32(frequencyCap.Count), Window: frequencyCap.Window}
+	}
+	if budget := ad.Budget; budget != nil {
+		converted.Budget = &adpb.Budget{TotalImpressions: budget.TotalImpressions, DailyImpressions: budget.DailyImpressions}
+	}
+	return converted
+}
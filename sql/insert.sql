@@ -9,6 +9,21 @@ insert into platforms (platform) values
 ('web'),
 ('any');
 
+insert into languages (language) values
+('cs'),
+('de'),
+('en'),
+('es'),
+('fr'),
+('it'),
+('nl'),
+('any');
+
+-- Ads created before language targeting target any language
+insert into ad_language (ad_id, language_id)
+select ads.id, (select id from languages where language = 'any') from ads
+where not exists (select 1 from ad_language where ad_language.ad_id = ads.id);
+
 
 INSERT INTO `countries` (`id`, `country`, `alpha3`, `langCS`, `langDE`, `langEN`, `langES`, `langFR`, `langIT`, `langNL`) VALUES
 (0, 'AY', 'ANY', '', '', '', '', '', '', ''),
@@ -1,11 +1,59 @@
+create table if not exists advertisers (
+    id        int unsigned auto_increment not null,
+    tenant_id varchar(64) not null default 'default',
+    name      varchar(128) not null,
+    primary key (id),
+    unique key (tenant_id, name)
+);
+
+create table if not exists campaigns (
+    id            int unsigned auto_increment not null,
+    advertiser_id int unsigned not null,
+    name          varchar(128) not null,
+    status        varchar(16) not null default 'active',
+    start_at      timestamp null,
+    end_at        timestamp null,
+    targeting     json null,
+    primary key (id),
+    constraint campaign_advertiser foreign key (advertiser_id) references advertisers(id)
+);
+
 create table if not exists ads (
     id        int unsigned auto_increment not null,
+    tenant_id varchar(64) not null default 'default',
+    campaign_id int unsigned null,
     title     varchar(128) not null,
+    title_key varchar(128) not null default '',
     start_at  timestamp not null,
     end_at    timestamp not null,
     age_start int unsigned not null,
     age_end   int unsigned not null,
-    primary key (id)
+    schedule  json null,
+    description    varchar(512) not null default '',
+    image_url      varchar(2048) not null default '',
+    click_url      varchar(2048) not null default '',
+    call_to_action varchar(32) not null default '',
+    frequency_cap  json null,
+    budget         json null,
+    priority       int unsigned not null default 0,
+    weight         int unsigned not null default 1,
+    inherited      json null,
+    status         varchar(16) not null default 'active',
+    version        int unsigned not null default 1,
+    lifecycle      varchar(16) not null default 'scheduled',
+    exhausted_at   timestamp null,
+    deleted_at     timestamp null,
+    primary key (id),
+    key (tenant_id),
+    key (tenant_id, title_key),
+    key (deleted_at),
+    constraint ad_campaign foreign key (campaign_id) references campaigns(id)
+);
+
+create table if not exists ad_title_locks (
+    tenant_id varchar(64) not null,
+    title_key varchar(128) not null,
+    primary key (tenant_id, title_key)
 );
 
 create table if not exists genders (
@@ -22,6 +70,13 @@ create table if not exists platforms (
     unique key (platform)
 );
 
+create table if not exists languages (
+    id int unsigned auto_increment not null,
+    language varchar(3) not null,
+    primary key (id),
+    unique key (language)
+);
+
 CREATE TABLE IF NOT EXISTS `countries` (
     `id` int(3) NOT NULL,
     `country` varchar(2) NOT NULL,
@@ -42,6 +97,7 @@ CREATE TABLE IF NOT EXISTS `countries` (
 create table if not exists ad_gender (
     ad_id int unsigned not null,
     gender_id int unsigned not null,
+    exclude tinyint(1) not null default 0,
     constraint ad_gender_ad foreign key (ad_id) references ads(id),
     constraint ad_gender_gender foreign key (gender_id) references genders(id)
 );
@@ -49,6 +105,7 @@ create table if not exists ad_gender (
 create table if not exists ad_platform (
     ad_id int unsigned not null,
     platform_id int unsigned not null,
+    exclude tinyint(1) not null default 0,
     constraint ad_platform_ad foreign key (ad_id) references ads(id),
     constraint ad_platform_platform foreign key (platform_id) references platforms(id)
 );
@@ -56,6 +113,143 @@ create table if not exists ad_platform (
 create table if not exists ad_country (
     ad_id int unsigned not null,
     country_id int(3) not null,
+    exclude tinyint(1) not null default 0,
     constraint ad_contry_ad foreign key (ad_id) references ads(id),
     constraint ad_contry_contry foreign key (country_id) references countries(id)
-);
\ No newline at end of file
+);
+
+create table if not exists ad_language (
+    ad_id int unsigned not null,
+    language_id int unsigned not null,
+    exclude tinyint(1) not null default 0,
+    constraint ad_language_ad foreign key (ad_id) references ads(id),
+    constraint ad_language_language foreign key (language_id) references languages(id)
+);
+
+create table if not exists ad_creatives (
+    ad_id int unsigned not null,
+    platform_id int unsigned not null,
+    description    varchar(512) not null default '',
+    image_url      varchar(2048) not null default '',
+    click_url      varchar(2048) not null default '',
+    call_to_action varchar(32) not null default '',
+    primary key (ad_id, platform_id),
+    constraint ad_creatives_ad foreign key (ad_id) references ads(id),
+    constraint ad_creatives_platform foreign key (platform_id) references platforms(id)
+);
+
+create table if not exists ad_event_counts (
+    ad_id int unsigned not null,
+    hour datetime not null,
+    impressions bigint unsigned not null default 0,
+    clicks bigint unsigned not null default 0,
+    primary key (ad_id, hour)
+);
+
+create table if not exists ad_frequency_counts (
+    tenant_id varchar(64) not null,
+    user_key char(32) not null,
+    ad_id int unsigned not null,
+    count int unsigned not null,
+    expires_at datetime not null,
+    primary key (tenant_id, user_key, ad_id),
+    key (expires_at)
+);
+
+create table if not exists ad_audit_log (
+    id         bigint unsigned auto_increment not null,
+    tenant_id  varchar(64) not null,
+    ad_id      int unsigned not null,
+    action     varchar(16) not null,
+    actor      varchar(128) not null default '',
+    request_id varchar(64) not null default '',
+    created_at timestamp not null default current_timestamp,
+    before_ad  json null,
+    after_ad   json null,
+    primary key (id),
+    key (tenant_id, ad_id),
+    key (tenant_id, created_at)
+);
+
+create table if not exists ad_versions (
+    tenant_id     varchar(64) not null,
+    ad_id         int unsigned not null,
+    version       int unsigned not null,
+    restored_from int unsigned null,
+    created_at    timestamp not null default current_timestamp,
+    snapshot      json not null,
+    primary key (ad_id, version)
+);
+
+create table if not exists webhooks (
+    id          int unsigned auto_increment not null,
+    tenant_id   varchar(64) not null,
+    url         varchar(2048) not null,
+    event_types varchar(256) not null,
+    secret      varchar(256) not null,
+    created_at  timestamp not null default current_timestamp,
+    primary key (id),
+    key (tenant_id)
+);
+
+create table if not exists webhook_outbox (
+    id              bigint unsigned auto_increment not null,
+    tenant_id       varchar(64) not null,
+    webhook_id      int unsigned not null,
+    event_type      varchar(32) not null,
+    payload         json not null,
+    status          varchar(16) not null default 'pending',
+    attempts        int unsigned not null default 0,
+    next_attempt_at timestamp not null default current_timestamp,
+    last_error      varchar(512) not null default '',
+    created_at      timestamp not null default current_timestamp,
+    primary key (id),
+    key (status, next_attempt_at),
+    key (tenant_id, status),
+    constraint webhook_outbox_webhook foreign key (webhook_id) references webhooks(id) on delete cascade
+);
+
+create table if not exists ad_event_log (
+    id         bigint unsigned auto_increment not null,
+    tenant_id  varchar(64) not null,
+    event_type varchar(16) not null,
+    ad_id      int unsigned not null,
+    payload    json not null,
+    created_at timestamp not null default current_timestamp,
+    primary key (id),
+    key (tenant_id, id),
+    key (created_at)
+);
+
+create table if not exists outbox (
+    id         bigint unsigned auto_increment not null,
+    tenant_id  varchar(64) not null,
+    ad_id      int unsigned not null,
+    event_type varchar(32) not null,
+    ad         json null,
+    created_at timestamp not null default current_timestamp,
+    primary key (id),
+    key (created_at)
+);
+
+create table if not exists outbox_checkpoints (
+    subscription varchar(64) not null,
+    last_id      bigint unsigned not null,
+    gaps         json null,
+    failure      json null,
+    owner        varchar(64) null,
+    lease_until  timestamp null,
+    primary key (subscription)
+);
+
+create table if not exists outbox_dead_letters (
+    subscription varchar(64) not null,
+    event_id     bigint unsigned not null,
+    tenant_id    varchar(64) not null,
+    ad_id        int unsigned not null,
+    event_type   varchar(32) not null,
+    ad           json null,
+    last_error   varchar(512) not null default '',
+    created_at   timestamp not null default current_timestamp,
+    primary key (subscription, event_id)
+);
@@ -3,26 +3,70 @@ package usecase
 import (
 	"context"
 	"dcard-backend/domain"
+	"errors"
 	"fmt"
+	"log"
+	"maps"
+	"math/rand"
+	"strconv"
+	"strings"
 	"time"
 )
 
-var conditionToAnyValue = map[string]string{
-	"gender":   "A",
-	"country":  "AY",
-	"platform": "any",
-}
+// maxCandidateBatchSize bounds the number of matching ads read at once to fill a page
+const maxCandidateBatchSize = 500
 
 type adUsecase struct {
 	adRepository   domain.AdRepository
 	contextTimeout time.Duration
+	conflictPolicy domain.ConflictPolicy
+	now            func() time.Time
+
+	frequencyRepository domain.FrequencyRepository
+	deliveryCounter     domain.DeliveryCounter
+	random              func() float64
+	rankers             map[string]domain.Ranker
+	campaignRepository  domain.CampaignRepository
+}
+
+type AdUsecaseOption func(*adUsecase)
+
+// WithConflictPolicy sets how Create treats a new ad whose title matches an existing ad
+// with an overlapping time window. Without it, such ads are allowed.
+func WithConflictPolicy(policy domain.ConflictPolicy) AdUsecaseOption {
+	return func(au *adUsecase) {
+		au.conflictPolicy = policy
+	}
+}
+
+// WithClock sets the clock used to check the schedules of ads, which is time.Now by default
+func WithClock(now func() time.Time) AdUsecaseOption {
+	return func(au *adUsecase) {
+		au.now = now
+	}
 }
 
-func NewAdUsecase(adRepository domain.AdRepository, timeout time.Duration) domain.AdUsecase {
-	return &adUsecase{
+func NewAdUsecase(adRepository domain.AdRepository, timeout time.Duration, options ...AdUsecaseOption) domain.AdUsecase {
+	au := &adUsecase{
 		adRepository:   adRepository,
 		contextTimeout: timeout,
+		conflictPolicy: domain.ConflictPolicyAllow,
+		now:            time.Now,
+		random:         rand.Float64,
+		rankers:        defaultRankers(),
 	}
+	for _, option := range options {
+		option(au)
+	}
+	return au
+}
+
+// toDomainError maps errors of the context timeout applied by the usecase to domain errors
+func toDomainError(err error) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return domain.ErrTimeout
+	}
+	return err
 }
 
 func changeTimeToUTF8(timeStr *string) error {
@@ -39,6 +83,20 @@ func changeTimeToUTF8(timeStr *string) error {
 	return nil
 }
 
+func changeTimeToUTC(timeStr *string) error {
+	loc, err := time.LoadLocation("Asia/Taipei")
+	if err != nil {
+		return err
+	}
+
+	t, err := time.ParseInLocation("2006-01-02 15:04:05", *timeStr, loc)
+	if err != nil {
+		return err
+	}
+	*timeStr = t.UTC().Format(time.RFC3339)
+	return nil
+}
+
 func changeAgeIfZero(age *int, defaultAge int) {
 	if *age == 0 {
 		*age = defaultAge
@@ -51,12 +109,40 @@ func changeSliceIfEmpty(inputSlice *[]string, anyValueStr string) {
 	}
 }
 
-func (au *adUsecase) Create(c context.Context, ad *domain.Ad) error {
-	ctx, cancel := context.WithTimeout(c, au.contextTimeout)
-	defer cancel()
+func contains(values []string, target string) bool {
+	for _, value := range values {
+		if value == target {
+			return true
+		}
+	}
+	return false
+}
+
+// validateTargeting checks the values of a targeting dimension, where the excluded values
+// must neither contain the any value nor contradict the included values
+func validateTargeting(dimension domain.Dimension, values []string, excludedValues []string) error {
+	for _, value := range append(append([]string{}, values...), excludedValues...) {
+		if dimension.Valid != nil && !dimension.Valid(value) {
+			return fmt.Errorf("%w: %q is not a valid %s", domain.ErrBadParamInput, value, dimension.Name)
+		}
+	}
 
+	for _, excludedValue := range excludedValues {
+		if excludedValue == dimension.AnyValue {
+			return fmt.Errorf("%w: %s %q can not be excluded", domain.ErrBadParamInput, dimension.Name, excludedValue)
+		}
+		if contains(values, excludedValue) {
+			return fmt.Errorf("%w: %s %q is both included and excluded", domain.ErrBadParamInput, dimension.Name, excludedValue)
+		}
+	}
+	return nil
+}
+
+// normalizeAd validates the required fields of an ad and fills its condition with the
+// default values of unrestricted targeting
+func normalizeAd(ad *domain.Ad) error {
 	if ad.Title == "" || ad.StartAt == "" || ad.EndAt == "" {
-		return fmt.Errorf("title, startAt, and endAt should not be empty")
+		return fmt.Errorf("%w: title, startAt, and endAt should not be empty", domain.ErrBadParamInput)
 	}
 
 	if ad.Condition == nil {
@@ -65,29 +151,300 @@ func (au *adUsecase) Create(c context.Context, ad *domain.Ad) error {
 
 	err := changeTimeToUTF8(&ad.StartAt)
 	if err != nil {
-		return err
+		return fmt.Errorf("%w: %s", domain.ErrBadParamInput, err.Error())
 	}
 
 	err = changeTimeToUTF8(&ad.EndAt)
 	if err != nil {
-		return err
+		return fmt.Errorf("%w: %s", domain.ErrBadParamInput, err.Error())
 	}
 
 	changeAgeIfZero(&ad.Condition.AgeStart, 1)
 	changeAgeIfZero(&ad.Condition.AgeEnd, 100)
 
-	changeSliceIfEmpty(&ad.Condition.Gender, conditionToAnyValue["gender"])
-	changeSliceIfEmpty(&ad.Condition.Country, conditionToAnyValue["country"])
-	changeSliceIfEmpty(&ad.Condition.Platform, conditionToAnyValue["platform"])
+	for _, dimension := range domain.Dimensions() {
+		values, excludedValues := dimension.Values(ad.Condition), dimension.ExcludedValues(ad.Condition)
+		if err := validateTargeting(dimension, *values, *excludedValues); err != nil {
+			return err
+		}
+		changeSliceIfEmpty(values, dimension.AnyValue)
+	}
 
-	err = au.adRepository.Create(ctx, ad)
-	return err
+	if err := validateCreatives(ad); err != nil {
+		return err
+	}
+	if err := validateFrequencyCap(ad.FrequencyCap); err != nil {
+		return err
+	}
+	if err := validateBudget(ad.Budget); err != nil {
+		return err
+	}
+	if err := validateRanking(ad); err != nil {
+		return err
+	}
+	return validateSchedule(ad.Condition.Schedule)
+}
+
+// conflictCheck checks the ad against the ads of the same advertiser, or against every ad when
+// the ad is not in a campaign
+func (au *adUsecase) conflictCheck(ad *domain.Ad, advertiserID int64) domain.ConflictCheck {
+	if au.conflictPolicy == domain.ConflictPolicyAllow {
+		return domain.ConflictCheck{}
+	}
+
+	return domain.ConflictCheck{AdvertiserID: advertiserID, Resolve: func(conflicting []domain.Ad) error {
+		if len(conflicting) == 0 {
+			return nil
+		}
+		ids := make([]string, 0, len(conflicting))
+		for _, candidate := range conflicting {
+			ids = append(ids, strconv.FormatInt(candidate.ID, 10))
+		}
+
+		if au.conflictPolicy == domain.ConflictPolicyReject {
+			return fmt.Errorf("%w: ads %s have the same title and an overlapping time window", domain.ErrConflict, strings.Join(ids, ", "))
+		}
+		log.Printf("Ad %q has the same title and an overlapping time window as ads %s", ad.Title, strings.Join(ids, ", "))
+		return nil
+	}}
+}
+
+func (au *adUsecase) Create(c context.Context, ad *domain.Ad) error {
+	ctx, cancel := context.WithTimeout(c, au.contextTimeout)
+	defer cancel()
+
+	advertiserID, err := au.inheritCampaign(ctx, ad)
+	if err != nil {
+		return err
+	}
+
+	if err := normalizeAd(ad); err != nil {
+		return err
+	}
+
+	err = au.adRepository.Create(ctx, ad, au.conflictCheck(ad, advertiserID))
+	return toDomainError(err)
+}
+
+func (au *adUsecase) GetByID(c context.Context, id int64) (domain.Ad, error) {
+	ctx, cancel := context.WithTimeout(c, au.contextTimeout)
+	defer cancel()
+
+	ad, err := au.adRepository.GetByID(ctx, id)
+	if err != nil {
+		return domain.Ad{}, toDomainError(err)
+	}
+
+	if err := changeTimeToUTC(&ad.StartAt); err != nil {
+		return domain.Ad{}, err
+	}
+	if err := changeTimeToUTC(&ad.EndAt); err != nil {
+		return domain.Ad{}, err
+	}
+	return ad, nil
+}
+
+// GetVersion returns the configuration of an ad at a version, whose status is not versioned
+func (au *adUsecase) GetVersion(c context.Context, id int64, version int64) (domain.Ad, error) {
+	ctx, cancel := context.WithTimeout(c, au.contextTimeout)
+	defer cancel()
+
+	if version <= 0 {
+		return domain.Ad{}, fmt.Errorf("%w: version should be a positive integer", domain.ErrBadParamInput)
+	}
+
+	ad, err := au.adRepository.GetVersion(ctx, id, version)
+	if err != nil {
+		return domain.Ad{}, toDomainError(err)
+	}
+
+	if err := changeSnapshotTimeToUTC(&ad.StartAt); err != nil {
+		return domain.Ad{}, err
+	}
+	if err := changeSnapshotTimeToUTC(&ad.EndAt); err != nil {
+		return domain.Ad{}, err
+	}
+	return ad, nil
+}
+
+// checkReplacement runs the same inheritance and validation as creating an ad on an ad
+// replacing it, and returns the conflict check of the ad
+func (au *adUsecase) checkReplacement(c context.Context, ad *domain.Ad) (domain.ConflictCheck, error) {
+	advertiserID, err := au.inheritCampaign(c, ad)
+	if err != nil {
+		return domain.ConflictCheck{}, err
+	}
+
+	if err := normalizeAd(ad); err != nil {
+		return domain.ConflictCheck{}, err
+	}
+
+	return au.conflictCheck(ad, advertiserID), nil
+}
+
+// Update replaces an ad, and sets ad.Version to its new version
+func (au *adUsecase) Update(c context.Context, ad *domain.Ad) error {
+	ctx, cancel := context.WithTimeout(c, au.contextTimeout)
+	defer cancel()
+
+	check, err := au.checkReplacement(ctx, ad)
+	if err != nil {
+		return err
+	}
+
+	return toDomainError(au.adRepository.Update(ctx, ad, check))
+}
+
+// Rollback replaces an ad with a previous version, which is checked again since other ads
+// and the campaign of the ad may have changed since then
+func (au *adUsecase) Rollback(c context.Context, id int64, version int64, expectedVersions []int64) (domain.Ad, error) {
+	ctx, cancel := context.WithTimeout(c, au.contextTimeout)
+	defer cancel()
+
+	if version <= 0 {
+		return domain.Ad{}, fmt.Errorf("%w: version should be a positive integer", domain.ErrBadParamInput)
+	}
+
+	ad, err := au.adRepository.GetVersion(ctx, id, version)
+	if err != nil {
+		return domain.Ad{}, toDomainError(err)
+	}
+	ad.ID, ad.IfMatch = id, expectedVersions
+
+	check, err := au.checkReplacement(ctx, &ad)
+	if err != nil {
+		return domain.Ad{}, err
+	}
+
+	if err := au.adRepository.Rollback(ctx, &ad, version, check); err != nil {
+		return domain.Ad{}, toDomainError(err)
+	}
+
+	if err := changeSnapshotTimeToUTC(&ad.StartAt); err != nil {
+		return domain.Ad{}, err
+	}
+	if err := changeSnapshotTimeToUTC(&ad.EndAt); err != nil {
+		return domain.Ad{}, err
+	}
+	return ad, nil
+}
+
+func (au *adUsecase) UpdateStatus(c context.Context, id int64, status domain.AdStatus) error {
+	ctx, cancel := context.WithTimeout(c, au.contextTimeout)
+	defer cancel()
+
+	if status != domain.AdActive && status != domain.AdPaused {
+		return fmt.Errorf("%w: status should be %s or %s", domain.ErrBadParamInput, domain.AdActive, domain.AdPaused)
+	}
+
+	err := au.adRepository.UpdateStatus(ctx, id, status)
+	return toDomainError(err)
+}
+
+func (au *adUsecase) Delete(c context.Context, id int64) error {
+	ctx, cancel := context.WithTimeout(c, au.contextTimeout)
+	defer cancel()
+
+	err := au.adRepository.Delete(ctx, id)
+	return toDomainError(err)
+}
+
+func (au *adUsecase) Restore(c context.Context, id int64) error {
+	ctx, cancel := context.WithTimeout(c, au.contextTimeout)
+	defer cancel()
+
+	err := au.adRepository.Restore(ctx, id)
+	return toDomainError(err)
+}
+
+func parsePagination(condition map[string][]string) (limit int, offset int, err error) {
+	if limit, err = strconv.Atoi(condition["limit"][0]); err != nil || limit < 0 {
+		return 0, 0, fmt.Errorf("%w: limit should be a non-negative integer", domain.ErrBadParamInput)
+	}
+	if offset, err = strconv.Atoi(condition["offset"][0]); err != nil || offset < 0 {
+		return 0, 0, fmt.Errorf("%w: offset should be a non-negative integer", domain.ErrBadParamInput)
+	}
+	return limit, offset, nil
+}
+
+// pageFilled reports whether the eligible ads, read in the order of the repository, fill a page
+// ending at the end-th ad. Ads are ranked by priority, so the ads of the lowest priority of the
+// page must be read to the last, unless they are ranked by their end alone.
+func pageFilled(eligible []domain.Ad, end int, byEndAt bool, last domain.Ad) bool {
+	if len(eligible) < end {
+		return false
+	}
+	return byEndAt || end == 0 || last.Priority < eligible[end-1].Priority
+}
+
+// eligibleAds reads the ads matching the query in batches until the eligible ones fill a page
+// ending at the end-th ad or every matching ad is read, leaving out those outside their
+// schedule, capped for the user or throttled by pacing, and returns them with the frequency caps
+// of the ads read. The first batch is the page and the ad after it, and each batch after it is
+// twice as large up to maxCandidateBatchSize, so a page far down is reached in a few queries.
+func (au *adUsecase) eligibleAds(c context.Context, query map[string][]string, end int, byEndAt bool, userID string, viewerLocation *time.Location) ([]domain.Ad, map[int64]domain.FrequencyCap, error) {
+	now := au.now()
+	today, err := dayStart(now)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	eligible := []domain.Ad{}
+	caps := map[int64]domain.FrequencyCap{}
+	read, size := 0, min(end+1, maxCandidateBatchSize)
+	for {
+		batch := maps.Clone(query)
+		batch["limit"] = []string{strconv.Itoa(size)}
+		batch["offset"] = []string{strconv.Itoa(read)}
+		candidates, err := au.adRepository.GetByCondition(c, batch)
+		if err != nil {
+			return nil, nil, toDomainError(err)
+		}
+
+		batchCaps := frequencyCaps(candidates)
+		maps.Copy(caps, batchCaps)
+		counts, err := au.servedCounts(c, userID, batchCaps)
+		if err != nil {
+			return nil, nil, err
+		}
+		adFlights, err := flights(candidates)
+		if err != nil {
+			return nil, nil, err
+		}
+		delivery, err := au.deliveries(c, adFlights, today)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for _, ad := range candidates {
+			if ad.Condition != nil && !scheduleActive(ad.Condition.Schedule, now, viewerLocation) {
+				continue
+			}
+			if frequencyCap, ok := caps[ad.ID]; ok && counts[ad.ID] >= frequencyCap.Count {
+				continue
+			}
+			if adFlight, ok := adFlights[ad.ID]; ok {
+				if !au.paced(adFlight, delivery[ad.ID], today, now) {
+					continue
+				}
+			}
+			eligible = append(eligible, ad)
+		}
+
+		if len(candidates) < size || pageFilled(eligible, end, byEndAt, candidates[len(candidates)-1]) {
+			return eligible, caps, nil
+		}
+		read, size = read+size, min(2*size, maxCandidateBatchSize)
+	}
 }
 
 func (au *adUsecase) GetByCondition(c context.Context, condition map[string][]string) ([]domain.Ad, error) {
 	ctx, cancel := context.WithTimeout(c, au.contextTimeout)
 	defer cancel()
 
+	// The condition is the query of the caller, which is left as it is
+	condition = maps.Clone(condition)
+
 	if _, ok := condition["offset"]; !ok {
 		return nil, fmt.Errorf("condition should have offset provided")
 	}
@@ -96,29 +453,225 @@ func (au *adUsecase) GetByCondition(c context.Context, condition map[string][]st
 		condition["limit"] = []string{"5"}
 	}
 
-	for key, anyValue := range conditionToAnyValue {
-		if _, ok := condition[key]; ok {
-			condition[key] = append(condition[key], anyValue)
+	limit, offset, err := parsePagination(condition)
+	if err != nil {
+		return nil, err
+	}
+
+	viewerLocation := time.UTC
+	if timezone, ok := condition["timezone"]; ok {
+		if viewerLocation, err = time.LoadLocation(timezone[0]); err != nil {
+			return nil, fmt.Errorf("%w: unknown timezone %q", domain.ErrBadParamInput, timezone[0])
+		}
+	}
+
+	// The creative is picked by the platform of the viewer, before the any value is added
+	platform := ""
+	if values, ok := condition["platform"]; ok {
+		platform = values[0]
+	}
+
+	userID := ""
+	if values, ok := condition["userId"]; ok {
+		userID = values[0]
+	}
+
+	if values, ok := condition["advertiserId"]; ok {
+		if advertiserID, err := strconv.ParseInt(values[0], 10, 64); err != nil || advertiserID <= 0 {
+			return nil, fmt.Errorf("%w: advertiserId should be a positive integer", domain.ErrBadParamInput)
+		}
+	}
+
+	sortBy := domain.SortPriority
+	if values, ok := condition["sort"]; ok {
+		sortBy = values[0]
+	}
+	ranker, ok := au.rankers[sortBy]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown sort %q", domain.ErrBadParamInput, sortBy)
+	}
+
+	query := map[string][]string{}
+	for key, values := range condition {
+		if key != "limit" && key != "offset" && key != "timezone" && key != "userId" && key != "sort" && key != "seed" {
+			query[key] = values
 		}
 	}
 
-	ads, err := au.adRepository.GetByCondition(ctx, condition)
+	for _, dimension := range domain.Dimensions() {
+		if values, ok := query[dimension.Name]; ok {
+			query[dimension.Name] = append(append([]string{}, values...), dimension.AnyValue)
+		}
+	}
+
+	// The repository reads the ads ending soonest first, or those of the highest priority first
+	// for the other rankers, which only reorder ads of the same priority
+	order := domain.SortPriority
+	if sortBy == domain.SortEndAt {
+		order = domain.SortEndAt
+	}
+	query["sort"] = []string{order}
+
+	eligible, caps, err := au.eligibleAds(ctx, query, offset+limit, sortBy == domain.SortEndAt, userID, viewerLocation)
 	if err != nil {
 		return nil, err
 	}
 
-	for i, ad := range ads {
-		loc, err := time.LoadLocation("Asia/Taipei")
-		if err != nil {
+	// The repository already refused requests without a tenant, which only seeds the order here
+	tenantID, _ := domain.TenantFromContext(ctx)
+	today, err := dayStart(au.now())
+	if err != nil {
+		return nil, err
+	}
+	ranker.Rank(eligible, rankingSeed(condition, userID, tenantID, today))
+
+	if offset >= len(eligible) {
+		return []domain.Ad{}, nil
+	}
+	ads := []domain.Ad{}
+	for _, ad := range eligible[offset:min(offset+limit, len(eligible))] {
+		ads = append(ads, domain.Ad{
+			ID:           ad.ID,
+			Title:        ad.Title,
+			EndAt:        ad.EndAt,
+			Description:  ad.Description,
+			ImageURL:     ad.ImageURL,
+			ClickURL:     ad.ClickURL,
+			CallToAction: ad.CallToAction,
+		})
+	}
+
+	if err := au.pickCreatives(ctx, ads, platform); err != nil {
+		return nil, err
+	}
+	au.countServed(ctx, userID, ads, caps)
+
+	for i := range ads {
+		if err := changeTimeToUTC(&ads[i].EndAt); err != nil {
 			return nil, err
 		}
+	}
+	return ads, nil
+}
 
-		endAtTime, err := time.ParseInLocation("2006-01-02 15:04:05", ad.EndAt, loc)
-		if err != nil {
+// pickCreatives replaces the default creatives of the ads with their variants for the platform
+func (au *adUsecase) pickCreatives(c context.Context, ads []domain.Ad, platform string) error {
+	if platform == "" || len(ads) == 0 {
+		return nil
+	}
+
+	adIDs := make([]int64, len(ads))
+	for i, ad := range ads {
+		adIDs[i] = ad.ID
+	}
+	creatives, err := au.adRepository.GetCreativesByPlatform(c, adIDs, platform)
+	if err != nil {
+		return toDomainError(err)
+	}
+
+	for i := range ads {
+		if creative, ok := creatives[ads[i].ID]; ok {
+			applyCreative(&ads[i], creative)
+		}
+	}
+	return nil
+}
+
+func (au *adUsecase) Fetch(c context.Context, advertiserID int64) ([]domain.Ad, error) {
+	ctx, cancel := context.WithTimeout(c, au.contextTimeout)
+	defer cancel()
+
+	ads, err := au.adRepository.Fetch(ctx, advertiserID)
+	if err != nil {
+		return nil, toDomainError(err)
+	}
+
+	for i := range ads {
+		if err := changeTimeToUTC(&ads[i].StartAt); err != nil {
 			return nil, err
 		}
+		if err := changeTimeToUTC(&ads[i].EndAt); err != nil {
+			return nil, err
+		}
+	}
+	return ads, nil
+}
+
+// targetsValue reports whether a targeting with the given included and excluded values
+// reaches users having the value
+func targetsValue(value string, values []string, excludedValues []string, anyValue string) bool {
+	return (contains(values, value) || contains(values, anyValue)) && !contains(excludedValues, value)
+}
 
-		ads[i].EndAt = endAtTime.UTC().Format(time.RFC3339)
+func valuesOverlap(values []string, excludedValues []string, otherValues []string, otherExcludedValues []string, anyValue string) bool {
+	// Ads created before a dimension was registered have no values, and target any value
+	if len(values) == 0 {
+		values = []string{anyValue}
+	}
+	if len(otherValues) == 0 {
+		otherValues = []string{anyValue}
+	}
+
+	// Both targetings reach every value except finitely many exclusions
+	if contains(values, anyValue) && contains(otherValues, anyValue) {
+		return true
+	}
+
+	for _, value := range append(append([]string{}, values...), otherValues...) {
+		if value == anyValue {
+			continue
+		}
+		if targetsValue(value, values, excludedValues, anyValue) && targetsValue(value, otherValues, otherExcludedValues, anyValue) {
+			return true
+		}
+	}
+	return false
+}
+
+// targetingOverlaps reports whether some user could be targeted by both conditions
+func targetingOverlaps(condition *domain.Condition, otherCondition *domain.Condition) bool {
+	if condition.AgeStart > otherCondition.AgeEnd || otherCondition.AgeStart > condition.AgeEnd {
+		return false
+	}
+
+	for _, d := range domain.Dimensions() {
+		if !valuesOverlap(*d.Values(condition), *d.ExcludedValues(condition), *d.Values(otherCondition), *d.ExcludedValues(otherCondition), d.AnyValue) {
+			return false
+		}
+	}
+	return true
+}
+
+func (au *adUsecase) GetOverlapping(c context.Context, ad *domain.Ad) ([]domain.Ad, error) {
+	ctx, cancel := context.WithTimeout(c, au.contextTimeout)
+	defer cancel()
+
+	advertiserID, err := au.inheritCampaign(ctx, ad)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := normalizeAd(ad); err != nil {
+		return nil, err
+	}
+
+	candidates, err := au.adRepository.GetByWindow(ctx, ad.StartAt, ad.EndAt, advertiserID)
+	if err != nil {
+		return nil, toDomainError(err)
+	}
+
+	ads := []domain.Ad{}
+	for _, candidate := range candidates {
+		if !targetingOverlaps(ad.Condition, candidate.Condition) {
+			continue
+		}
+		if err := changeTimeToUTC(&candidate.StartAt); err != nil {
+			return nil, err
+		}
+		if err := changeTimeToUTC(&candidate.EndAt); err != nil {
+			return nil, err
+		}
+		ads = append(ads, candidate)
 	}
 	return ads, nil
 }
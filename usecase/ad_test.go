@@ -6,6 +6,8 @@ import (
 	"dcard-backend/domain/mocks"
 	"dcard-backend/usecase"
 	"errors"
+	"maps"
+	"strconv"
 	"testing"
 	"time"
 
@@ -59,7 +61,7 @@ func TestCreate_ConditionNotProvided_ShouldCreateEmptyCondition(t *testing.T) {
 		EndAt:   "2025-01-01T00:00:00.000Z",
 	}
 	mockAdRepository := mocks.NewAdRepository(t)
-	mockAdRepository.On("Create", mock.Anything, &mockAd).Return(nil).Once()
+	mockAdRepository.On("Create", mock.Anything, &mockAd, mock.Anything).Return(nil).Once()
 
 	testAdUsecase := usecase.NewAdUsecase(mockAdRepository, time.Second*1)
 
@@ -76,7 +78,7 @@ func TestCreate_StartAtEndAtProvided_ShouldChangeToUTF8(t *testing.T) {
 		EndAt:   "2025-01-01T00:00:00.000Z",
 	}
 	mockAdRepository := mocks.NewAdRepository(t)
-	mockAdRepository.On("Create", mock.Anything, &mockAd).Return(nil).Once()
+	mockAdRepository.On("Create", mock.Anything, &mockAd, mock.Anything).Return(nil).Once()
 
 	testAdUsecase := usecase.NewAdUsecase(mockAdRepository, time.Second*1)
 
@@ -106,7 +108,7 @@ func TestCreate_AgeStartNotProvided_ShouldSetTo1(t *testing.T) {
 		EndAt:   "2025-01-01T00:00:00.000Z",
 	}
 	mockAdRepository := mocks.NewAdRepository(t)
-	mockAdRepository.On("Create", mock.Anything, &mockAd).Return(nil).Once()
+	mockAdRepository.On("Create", mock.Anything, &mockAd, mock.Anything).Return(nil).Once()
 
 	testAdUsecase := usecase.NewAdUsecase(mockAdRepository, time.Second*1)
 
@@ -123,7 +125,7 @@ func TestCreate_AgeEndNotProvided_ShouldSetTo100(t *testing.T) {
 		EndAt:   "2025-01-01T00:00:00.000Z",
 	}
 	mockAdRepository := mocks.NewAdRepository(t)
-	mockAdRepository.On("Create", mock.Anything, &mockAd).Return(nil).Once()
+	mockAdRepository.On("Create", mock.Anything, &mockAd, mock.Anything).Return(nil).Once()
 
 	testAdUsecase := usecase.NewAdUsecase(mockAdRepository, time.Second*1)
 
@@ -140,7 +142,7 @@ func TestCreate_GenderAndCountryAndPlatformNotProvided_ShouldAddAnyValueToSlice(
 		EndAt:   "2025-01-01T00:00:00.000Z",
 	}
 	mockAdRepository := mocks.NewAdRepository(t)
-	mockAdRepository.On("Create", mock.Anything, &mockAd).Return(nil).Once()
+	mockAdRepository.On("Create", mock.Anything, &mockAd, mock.Anything).Return(nil).Once()
 
 	testAdUsecase := usecase.NewAdUsecase(mockAdRepository, time.Second*1)
 
@@ -152,6 +154,78 @@ func TestCreate_GenderAndCountryAndPlatformNotProvided_ShouldAddAnyValueToSlice(
 	assert.Equal(t, []string{"any"}, mockAd.Condition.Platform)
 }
 
+func TestCreate_InvalidTargetingValue_ShouldReturnErrBadParamInput(t *testing.T) {
+	mockAd := domain.Ad{
+		Title:   "Test AD",
+		StartAt: "2024-01-01T00:00:00.000Z",
+		EndAt:   "2024-01-01T00:00:00.000Z",
+		Condition: &domain.Condition{
+			Platform: []string{"windows"},
+		},
+	}
+	mockAdRepository := mocks.NewAdRepository(t)
+
+	testAdUsecase := usecase.NewAdUsecase(mockAdRepository, time.Second*1)
+
+	err := testAdUsecase.Create(context.Background(), &mockAd)
+	assert.ErrorIs(t, err, domain.ErrBadParamInput)
+}
+
+func TestCreate_ValueBothIncludedAndExcluded_ShouldReturnErrBadParamInput(t *testing.T) {
+	mockAd := domain.Ad{
+		Title:   "Test AD",
+		StartAt: "2024-01-01T00:00:00.000Z",
+		EndAt:   "2024-01-01T00:00:00.000Z",
+		Condition: &domain.Condition{
+			Country:        []string{"TW", "JP"},
+			ExcludeCountry: []string{"JP"},
+		},
+	}
+	mockAdRepository := mocks.NewAdRepository(t)
+
+	testAdUsecase := usecase.NewAdUsecase(mockAdRepository, time.Second*1)
+
+	err := testAdUsecase.Create(context.Background(), &mockAd)
+	assert.ErrorIs(t, err, domain.ErrBadParamInput)
+}
+
+func TestCreate_AnyValueExcluded_ShouldReturnErrBadParamInput(t *testing.T) {
+	mockAd := domain.Ad{
+		Title:   "Test AD",
+		StartAt: "2024-01-01T00:00:00.000Z",
+		EndAt:   "2024-01-01T00:00:00.000Z",
+		Condition: &domain.Condition{
+			ExcludePlatform: []string{"any"},
+		},
+	}
+	mockAdRepository := mocks.NewAdRepository(t)
+
+	testAdUsecase := usecase.NewAdUsecase(mockAdRepository, time.Second*1)
+
+	err := testAdUsecase.Create(context.Background(), &mockAd)
+	assert.ErrorIs(t, err, domain.ErrBadParamInput)
+}
+
+func TestCreate_ExclusionWithAnyValue_ShouldCreate(t *testing.T) {
+	mockAd := domain.Ad{
+		Title:   "Test AD",
+		StartAt: "2024-01-01T00:00:00.000Z",
+		EndAt:   "2024-01-01T00:00:00.000Z",
+		Condition: &domain.Condition{
+			ExcludeCountry: []string{"CN", "RU"},
+		},
+	}
+	mockAdRepository := mocks.NewAdRepository(t)
+	mockAdRepository.On("Create", mock.Anything, mock.AnythingOfType("*domain.Ad"), mock.Anything).Return(nil).Once()
+
+	testAdUsecase := usecase.NewAdUsecase(mockAdRepository, time.Second*1)
+
+	err := testAdUsecase.Create(context.Background(), &mockAd)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"AY"}, mockAd.Condition.Country)
+	assert.Equal(t, []string{"CN", "RU"}, mockAd.Condition.ExcludeCountry)
+}
+
 func TestCreate_AdRepositoryFail_ShouldReturnError(t *testing.T) {
 	mockAd := domain.Ad{
 		Title:   "Test AD",
@@ -159,7 +233,7 @@ func TestCreate_AdRepositoryFail_ShouldReturnError(t *testing.T) {
 		EndAt:   "2025-01-01T00:00:00.000Z",
 	}
 	mockAdRepository := mocks.NewAdRepository(t)
-	mockAdRepository.On("Create", mock.Anything, &mockAd).Return(errors.New("Fail")).Once()
+	mockAdRepository.On("Create", mock.Anything, &mockAd, mock.Anything).Return(errors.New("Fail")).Once()
 
 	testAdUsecase := usecase.NewAdUsecase(mockAdRepository, time.Second*1)
 
@@ -180,20 +254,110 @@ func TestGetByCondition_IfOffsetNotProvided_ShouldReturnError(t *testing.T) {
 	assert.Error(t, err)
 }
 
-func TestGetByCondition_IfLimitNotProvided_ShouldAssignValue5(t *testing.T) {
+// candidateQuery is the query of the first batch of ads read by GetByCondition for a page
+// ending at the end-th ad, which has the conditions of the request
+func candidateQuery(sort string, end int, conditions map[string][]string) map[string][]string {
+	query := map[string][]string{"sort": {sort}, "limit": {strconv.Itoa(min(end+1, 500))}, "offset": {"0"}}
+	for key, values := range conditions {
+		query[key] = values
+	}
+	return query
+}
+
+// pageOf reads the ads as the repository does for the batches of GetByCondition, for ads which
+// match every query
+func pageOf(ads []domain.Ad) func(context.Context, map[string][]string) []domain.Ad {
+	return func(_ context.Context, query map[string][]string) []domain.Ad {
+		offset, _ := strconv.Atoi(query["offset"][0])
+		limit, _ := strconv.Atoi(query["limit"][0])
+		return ads[min(offset, len(ads)):min(offset+limit, len(ads))]
+	}
+}
+
+// nextBatch is the query of the batch read after the batch of the query
+func nextBatch(query map[string][]string) map[string][]string {
+	offset, _ := strconv.Atoi(query["offset"][0])
+	size, _ := strconv.Atoi(query["limit"][0])
+	next := maps.Clone(query)
+	next["offset"] = []string{strconv.Itoa(offset + size)}
+	next["limit"] = []string{strconv.Itoa(min(2*size, 500))}
+	return next
+}
+
+func TestGetByCondition_IfLimitNotProvided_ShouldServe5Ads(t *testing.T) {
 	condition := map[string][]string{
 		"offset": {"0"},
 	}
+	ads := []domain.Ad{}
+	for id := int64(1); id <= 6; id++ {
+		ads = append(ads, domain.Ad{ID: id, EndAt: "2025-01-01 08:00:00"})
+	}
 
 	mockAdRepository := mocks.NewAdRepository(t)
-	mockAdRepository.On("GetByCondition", mock.Anything, condition).Return([]domain.Ad{}, nil).Once()
+	mockAdRepository.On("GetByCondition", mock.Anything, candidateQuery(domain.SortPriority, 5, nil)).Return(ads, nil).Once()
+	// The ads of the same priority are shuffled among themselves, so they are read to the last
+	mockAdRepository.On("GetByCondition", mock.Anything, nextBatch(candidateQuery(domain.SortPriority, 5, nil))).Return([]domain.Ad{}, nil).Once()
+
+	testAdUsecase := usecase.NewAdUsecase(mockAdRepository, time.Second*1)
+
+	served, err := testAdUsecase.GetByCondition(context.Background(), condition)
+
+	assert.NoError(t, err)
+	assert.Len(t, served, 5)
+	assert.NotContains(t, condition, "limit")
+}
+
+func TestGetByCondition_ManyMatchingAds_ShouldReadBatchesUntilPriorityOfPageIsRead(t *testing.T) {
+	batch := func(priority int, firstID int64, size int) []domain.Ad {
+		ads := make([]domain.Ad, size)
+		for i := range ads {
+			ads[i] = domain.Ad{ID: firstID + int64(i), EndAt: "2025-01-01 08:00:00", Priority: priority, Weight: 1}
+		}
+		return ads
+	}
+	firstBatch := candidateQuery(domain.SortPriority, 95, nil)
+	secondBatch := nextBatch(firstBatch)
+	thirdBatch := nextBatch(secondBatch)
+
+	mockAdRepository := mocks.NewAdRepository(t)
+	mockAdRepository.On("GetByCondition", mock.Anything, firstBatch).Return(batch(2, 1, 96), nil).Once()
+	// The page ends within the ads of priority 2, so they are read until an ad of a lower priority
+	mockAdRepository.On("GetByCondition", mock.Anything, secondBatch).Return(batch(2, 97, 192), nil).Once()
+	mockAdRepository.On("GetByCondition", mock.Anything, thirdBatch).Return(append(batch(2, 289, 50), batch(1, 339, 334)...), nil).Once()
+
+	testAdUsecase := usecase.NewAdUsecase(mockAdRepository, time.Second*1)
+
+	served, err := testAdUsecase.GetByCondition(context.Background(), map[string][]string{"offset": {"90"}, "limit": {"5"}})
+
+	assert.NoError(t, err)
+	assert.Len(t, served, 5)
+}
+
+func TestGetByCondition_OffsetBeyond1000Ads_ShouldReadUntilPageIsFilled(t *testing.T) {
+	batch := func(firstID int64, size int) []domain.Ad {
+		ads := make([]domain.Ad, size)
+		for i := range ads {
+			ads[i] = domain.Ad{ID: firstID + int64(i), Title: "AD " + strconv.FormatInt(firstID+int64(i), 10), EndAt: "2025-01-01 08:00:00"}
+		}
+		return ads
+	}
+	mockAdRepository := mocks.NewAdRepository(t)
+	query, read := candidateQuery(domain.SortEndAt, 1210, nil), 0
+	for read < 1211 {
+		size, _ := strconv.Atoi(query["limit"][0])
+		mockAdRepository.On("GetByCondition", mock.Anything, query).Return(batch(int64(read+1), size), nil).Once()
+		read += size
+		query = nextBatch(query)
+	}
 
 	testAdUsecase := usecase.NewAdUsecase(mockAdRepository, time.Second*1)
 
-	testAdUsecase.GetByCondition(context.Background(), condition)
+	served, err := testAdUsecase.GetByCondition(context.Background(), map[string][]string{"offset": {"1200"}, "limit": {"10"}, "sort": {"endAt"}})
 
-	if assert.NotNil(t, condition["limit"]) {
-		assert.Equal(t, []string{"5"}, condition["limit"])
+	assert.NoError(t, err)
+	if assert.Len(t, served, 10) {
+		assert.Equal(t, "AD 1201", served[0].Title)
+		assert.Equal(t, "AD 1210", served[9].Title)
 	}
 }
 
@@ -205,16 +369,24 @@ func TestGetByCondition_IfConditionProvided_ShouldAppendAnyValueToSlice(t *testi
 		"platform": {"web"},
 	}
 
+	query := map[string][]string{
+		"gender":   {"M", "A"},
+		"country":  {"TW", "AY"},
+		"platform": {"web", "any"},
+	}
+
 	mockAdRepository := mocks.NewAdRepository(t)
-	mockAdRepository.On("GetByCondition", mock.Anything, condition).Return([]domain.Ad{}, nil).Once()
+	mockAdRepository.On("GetByCondition", mock.Anything, candidateQuery(domain.SortPriority, 5, query)).Return([]domain.Ad{}, nil).Once()
 
 	testAdUsecase := usecase.NewAdUsecase(mockAdRepository, time.Second*1)
 
-	testAdUsecase.GetByCondition(context.Background(), condition)
+	_, err := testAdUsecase.GetByCondition(context.Background(), condition)
 
-	assert.Equal(t, []string{"M", "A"}, condition["gender"])
-	assert.Equal(t, []string{"TW", "AY"}, condition["country"])
-	assert.Equal(t, []string{"web", "any"}, condition["platform"])
+	assert.NoError(t, err)
+	// The condition of the caller is left as it is
+	assert.Equal(t, []string{"M"}, condition["gender"])
+	assert.Equal(t, []string{"TW"}, condition["country"])
+	assert.Equal(t, []string{"web"}, condition["platform"])
 }
 
 func TestGetByCondition_IfAdRepositoryFail_ShouldReturnError(t *testing.T) {
@@ -223,7 +395,7 @@ func TestGetByCondition_IfAdRepositoryFail_ShouldReturnError(t *testing.T) {
 	}
 
 	mockAdRepository := mocks.NewAdRepository(t)
-	mockAdRepository.On("GetByCondition", mock.Anything, condition).Return([]domain.Ad{}, errors.New("Fail")).Once()
+	mockAdRepository.On("GetByCondition", mock.Anything, candidateQuery(domain.SortPriority, 5, nil)).Return([]domain.Ad{}, errors.New("Fail")).Once()
 
 	testAdUsecase := usecase.NewAdUsecase(mockAdRepository, time.Second*1)
 
@@ -231,3 +403,405 @@ func TestGetByCondition_IfAdRepositoryFail_ShouldReturnError(t *testing.T) {
 
 	assert.Error(t, err)
 }
+
+func TestFetch_Success_ShouldChangeTimeToUTC(t *testing.T) {
+	mockAds := []domain.Ad{
+		{
+			Title:     "Test AD",
+			StartAt:   "2024-01-01 08:00:00",
+			EndAt:     "2025-01-01 08:00:00",
+			Condition: &domain.Condition{},
+		},
+	}
+
+	mockAdRepository := mocks.NewAdRepository(t)
+	mockAdRepository.On("Fetch", mock.Anything, int64(0)).Return(mockAds, nil).Once()
+
+	testAdUsecase := usecase.NewAdUsecase(mockAdRepository, time.Second*1)
+
+	ads, err := testAdUsecase.Fetch(context.Background(), 0)
+
+	assert.NoError(t, err)
+	if assert.Len(t, ads, 1) {
+		assert.Equal(t, "2024-01-01T00:00:00Z", ads[0].StartAt)
+		assert.Equal(t, "2025-01-01T00:00:00Z", ads[0].EndAt)
+	}
+}
+
+func TestFetch_AdRepositoryFail_ShouldReturnError(t *testing.T) {
+	mockAdRepository := mocks.NewAdRepository(t)
+	mockAdRepository.On("Fetch", mock.Anything, int64(0)).Return(nil, errors.New("Fail")).Once()
+
+	testAdUsecase := usecase.NewAdUsecase(mockAdRepository, time.Second*1)
+
+	_, err := testAdUsecase.Fetch(context.Background(), 0)
+
+	assert.Error(t, err)
+}
+
+func TestCreate_AdRepositoryDeadlineExceeded_ShouldReturnErrTimeout(t *testing.T) {
+	mockAd := domain.Ad{
+		Title:   "Test AD",
+		StartAt: "2024-01-01T00:00:00.000Z",
+		EndAt:   "2025-01-01T00:00:00.000Z",
+	}
+	mockAdRepository := mocks.NewAdRepository(t)
+	mockAdRepository.On("Create", mock.Anything, &mockAd, mock.Anything).Return(context.DeadlineExceeded).Once()
+
+	testAdUsecase := usecase.NewAdUsecase(mockAdRepository, time.Second*1)
+
+	err := testAdUsecase.Create(context.Background(), &mockAd)
+
+	assert.ErrorIs(t, err, domain.ErrTimeout)
+}
+
+func TestGetByCondition_IfAdRepositoryDeadlineExceeded_ShouldReturnErrTimeout(t *testing.T) {
+	condition := map[string][]string{
+		"offset": {"0"},
+	}
+
+	mockAdRepository := mocks.NewAdRepository(t)
+	mockAdRepository.On("GetByCondition", mock.Anything, candidateQuery(domain.SortPriority, 5, nil)).Return(nil, context.DeadlineExceeded).Once()
+
+	testAdUsecase := usecase.NewAdUsecase(mockAdRepository, time.Second*1)
+
+	_, err := testAdUsecase.GetByCondition(context.Background(), condition)
+
+	assert.ErrorIs(t, err, domain.ErrTimeout)
+}
+
+// resolveConflicts runs the conflict check passed to the repository on the conflicting ads,
+// which the repository finds in the transaction writing the ad
+func resolveConflicts(conflicting []domain.Ad) func(context.Context, *domain.Ad, domain.ConflictCheck) error {
+	return func(_ context.Context, _ *domain.Ad, check domain.ConflictCheck) error {
+		if check.Resolve == nil {
+			return nil
+		}
+		return check.Resolve(conflicting)
+	}
+}
+
+func TestCreate_ConflictPolicyReject_SameTitleOverlapping_ShouldReturnErrConflict(t *testing.T) {
+	mockAd := domain.Ad{
+		Title:   "Test  AD ",
+		StartAt: "2024-01-01T00:00:00.000Z",
+		EndAt:   "2025-01-01T00:00:00.000Z",
+	}
+	existingAds := []domain.Ad{{ID: 3, Title: "test ad"}}
+
+	mockAdRepository := mocks.NewAdRepository(t)
+	mockAdRepository.On("Create", mock.Anything, mock.MatchedBy(func(ad *domain.Ad) bool {
+		return ad.StartAt == "2024-01-01T08:00:00+08:00" && ad.EndAt == "2025-01-01T08:00:00+08:00"
+	}), mock.Anything).Return(resolveConflicts(existingAds)).Once()
+
+	testAdUsecase := usecase.NewAdUsecase(mockAdRepository, time.Second*1, usecase.WithConflictPolicy(domain.ConflictPolicyReject))
+
+	err := testAdUsecase.Create(context.Background(), &mockAd)
+
+	assert.ErrorIs(t, err, domain.ErrConflict)
+}
+
+func TestCreate_ConflictPolicyReject_NoConflictingAd_ShouldCreate(t *testing.T) {
+	mockAd := domain.Ad{
+		Title:   "Test AD",
+		StartAt: "2024-01-01T00:00:00.000Z",
+		EndAt:   "2025-01-01T00:00:00.000Z",
+	}
+
+	mockAdRepository := mocks.NewAdRepository(t)
+	mockAdRepository.On("Create", mock.Anything, &mockAd, mock.Anything).Return(resolveConflicts([]domain.Ad{})).Once()
+
+	testAdUsecase := usecase.NewAdUsecase(mockAdRepository, time.Second*1, usecase.WithConflictPolicy(domain.ConflictPolicyReject))
+
+	err := testAdUsecase.Create(context.Background(), &mockAd)
+
+	assert.NoError(t, err)
+}
+
+func TestCreate_ConflictPolicyWarn_SameTitleOverlapping_ShouldCreate(t *testing.T) {
+	mockAd := domain.Ad{
+		Title:   "Test AD",
+		StartAt: "2024-01-01T00:00:00.000Z",
+		EndAt:   "2025-01-01T00:00:00.000Z",
+	}
+	existingAds := []domain.Ad{{ID: 3, Title: "TEST AD"}}
+
+	mockAdRepository := mocks.NewAdRepository(t)
+	mockAdRepository.On("Create", mock.Anything, &mockAd, mock.Anything).Return(resolveConflicts(existingAds)).Once()
+
+	testAdUsecase := usecase.NewAdUsecase(mockAdRepository, time.Second*1, usecase.WithConflictPolicy(domain.ConflictPolicyWarn))
+
+	err := testAdUsecase.Create(context.Background(), &mockAd)
+
+	assert.NoError(t, err)
+}
+
+func TestCreate_ConflictPolicyAllow_ShouldSkipConflictCheck(t *testing.T) {
+	mockAd := domain.Ad{
+		Title:   "Test AD",
+		StartAt: "2024-01-01T00:00:00.000Z",
+		EndAt:   "2025-01-01T00:00:00.000Z",
+	}
+
+	mockAdRepository := mocks.NewAdRepository(t)
+	mockAdRepository.On("Create", mock.Anything, &mockAd, mock.MatchedBy(func(check domain.ConflictCheck) bool {
+		return check.Resolve == nil
+	})).Return(nil).Once()
+
+	testAdUsecase := usecase.NewAdUsecase(mockAdRepository, time.Second*1, usecase.WithConflictPolicy(domain.ConflictPolicyAllow))
+
+	err := testAdUsecase.Create(context.Background(), &mockAd)
+
+	assert.NoError(t, err)
+}
+
+func TestGetOverlapping_ShouldReturnAdsWithOverlappingTargeting(t *testing.T) {
+	proposedAd := domain.Ad{
+		Title:   "Test AD",
+		StartAt: "2024-01-01T00:00:00.000Z",
+		EndAt:   "2025-01-01T00:00:00.000Z",
+		Condition: &domain.Condition{
+			AgeStart: 20,
+			AgeEnd:   30,
+			Country:  []string{"TW"},
+		},
+	}
+	existingAds := []domain.Ad{
+		{
+			ID: 1, StartAt: "2024-01-01 08:00:00", EndAt: "2024-02-01 08:00:00",
+			Condition: &domain.Condition{AgeStart: 25, AgeEnd: 40, Gender: []string{"F"}, Country: []string{"AY"}, Platform: []string{"ios"}},
+		},
+		{
+			ID: 2, StartAt: "2024-01-01 08:00:00", EndAt: "2024-02-01 08:00:00",
+			Condition: &domain.Condition{AgeStart: 31, AgeEnd: 40, Gender: []string{"A"}, Country: []string{"TW"}, Platform: []string{"any"}},
+		},
+		{
+			ID: 3, StartAt: "2024-01-01 08:00:00", EndAt: "2024-02-01 08:00:00",
+			Condition: &domain.Condition{AgeStart: 1, AgeEnd: 100, Gender: []string{"A"}, Country: []string{"JP"}, Platform: []string{"any"}},
+		},
+	}
+
+	mockAdRepository := mocks.NewAdRepository(t)
+	mockAdRepository.On("GetByWindow", mock.Anything, "2024-01-01T08:00:00+08:00", "2025-01-01T08:00:00+08:00", int64(0)).Return(existingAds, nil).Once()
+
+	testAdUsecase := usecase.NewAdUsecase(mockAdRepository, time.Second*1)
+
+	ads, err := testAdUsecase.GetOverlapping(context.Background(), &proposedAd)
+
+	assert.NoError(t, err)
+	if assert.Len(t, ads, 1) {
+		assert.Equal(t, int64(1), ads[0].ID)
+		assert.Equal(t, "2024-01-01T00:00:00Z", ads[0].StartAt)
+	}
+}
+
+func TestGetOverlapping_ExcludedValues_ShouldNotOverlap(t *testing.T) {
+	proposedAd := domain.Ad{
+		Title:   "Test AD",
+		StartAt: "2024-01-01T00:00:00.000Z",
+		EndAt:   "2025-01-01T00:00:00.000Z",
+		Condition: &domain.Condition{
+			Country: []string{"CN"},
+		},
+	}
+	existingAds := []domain.Ad{
+		{
+			ID: 1, StartAt: "2024-01-01 08:00:00", EndAt: "2024-02-01 08:00:00",
+			Condition: &domain.Condition{AgeStart: 1, AgeEnd: 100, Gender: []string{"A"}, Country: []string{"AY"}, Platform: []string{"any"}, ExcludeCountry: []string{"CN"}},
+		},
+		{
+			ID: 2, StartAt: "2024-01-01 08:00:00", EndAt: "2024-02-01 08:00:00",
+			Condition: &domain.Condition{AgeStart: 1, AgeEnd: 100, Gender: []string{"A"}, Country: []string{"AY"}, Platform: []string{"any"}, ExcludeCountry: []string{"RU"}},
+		},
+	}
+
+	mockAdRepository := mocks.NewAdRepository(t)
+	mockAdRepository.On("GetByWindow", mock.Anything, mock.Anything, mock.Anything, int64(0)).Return(existingAds, nil).Once()
+
+	testAdUsecase := usecase.NewAdUsecase(mockAdRepository, time.Second*1)
+
+	ads, err := testAdUsecase.GetOverlapping(context.Background(), &proposedAd)
+
+	assert.NoError(t, err)
+	if assert.Len(t, ads, 1) {
+		assert.Equal(t, int64(2), ads[0].ID)
+	}
+}
+
+func TestGetByID_Success_ShouldChangeTimeToUTC(t *testing.T) {
+	mockAdRepository := mocks.NewAdRepository(t)
+	mockAdRepository.On("GetByID", mock.Anything, int64(1)).Return(domain.Ad{
+		ID:      1,
+		Title:   "Test AD",
+		StartAt: "2024-01-01 08:00:00",
+		EndAt:   "2025-01-01 08:00:00",
+		Status:  domain.AdPaused,
+	}, nil).Once()
+
+	testAdUsecase := usecase.NewAdUsecase(mockAdRepository, time.Second*1)
+
+	ad, err := testAdUsecase.GetByID(context.Background(), 1)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "2024-01-01T00:00:00Z", ad.StartAt)
+	assert.Equal(t, "2025-01-01T00:00:00Z", ad.EndAt)
+	assert.Equal(t, domain.AdPaused, ad.Status)
+}
+
+func TestUpdate_ConflictPolicyReject_SameTitleOverlapping_ShouldReturnErrConflict(t *testing.T) {
+	mockAd := domain.Ad{
+		ID:      3,
+		Title:   "Test AD",
+		StartAt: "2024-01-01T00:00:00.000Z",
+		EndAt:   "2025-01-01T00:00:00.000Z",
+	}
+	existingAds := []domain.Ad{{ID: 4, Title: "Test AD"}}
+
+	mockAdRepository := mocks.NewAdRepository(t)
+	mockAdRepository.On("Update", mock.Anything, &mockAd, mock.Anything).Return(resolveConflicts(existingAds)).Once()
+
+	testAdUsecase := usecase.NewAdUsecase(mockAdRepository, time.Second*1, usecase.WithConflictPolicy(domain.ConflictPolicyReject))
+
+	err := testAdUsecase.Update(context.Background(), &mockAd)
+
+	assert.ErrorIs(t, err, domain.ErrConflict)
+}
+
+func TestUpdate_InvalidAd_ShouldReturnErrBadParamInput(t *testing.T) {
+	mockAd := domain.Ad{ID: 3, Title: "Test AD"}
+	mockAdRepository := mocks.NewAdRepository(t)
+
+	testAdUsecase := usecase.NewAdUsecase(mockAdRepository, time.Second*1)
+
+	err := testAdUsecase.Update(context.Background(), &mockAd)
+
+	assert.ErrorIs(t, err, domain.ErrBadParamInput)
+}
+
+func TestUpdate_NotExist_ShouldReturnErrNotFound(t *testing.T) {
+	mockAd := domain.Ad{
+		ID:      3,
+		Title:   "Test AD",
+		StartAt: "2024-01-01T00:00:00.000Z",
+		EndAt:   "2025-01-01T00:00:00.000Z",
+	}
+	mockAdRepository := mocks.NewAdRepository(t)
+	mockAdRepository.On("Update", mock.Anything, &mockAd, mock.Anything).Return(domain.ErrNotFound).Once()
+
+	testAdUsecase := usecase.NewAdUsecase(mockAdRepository, time.Second*1)
+
+	err := testAdUsecase.Update(context.Background(), &mockAd)
+
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+}
+
+func TestGetVersion_InvalidVersion_ShouldReturnErrBadParamInput(t *testing.T) {
+	mockAdRepository := mocks.NewAdRepository(t)
+
+	testAdUsecase := usecase.NewAdUsecase(mockAdRepository, time.Second*1)
+
+	_, err := testAdUsecase.GetVersion(context.Background(), 1, 0)
+
+	assert.ErrorIs(t, err, domain.ErrBadParamInput)
+}
+
+func TestGetVersion_Success_ShouldChangeTimeToUTC(t *testing.T) {
+	mockAdRepository := mocks.NewAdRepository(t)
+	mockAdRepository.On("GetVersion", mock.Anything, int64(1), int64(2)).Return(domain.Ad{
+		ID:      1,
+		Title:   "Test AD",
+		StartAt: "2024-01-01T08:00:00+08:00",
+		EndAt:   "2025-01-01T08:00:00+08:00",
+		Version: 2,
+	}, nil).Once()
+
+	testAdUsecase := usecase.NewAdUsecase(mockAdRepository, time.Second*1)
+
+	ad, err := testAdUsecase.GetVersion(context.Background(), 1, 2)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "2024-01-01T00:00:00Z", ad.StartAt)
+	assert.Equal(t, "2025-01-01T00:00:00Z", ad.EndAt)
+	assert.Equal(t, int64(2), ad.Version)
+}
+
+func TestRollback_Success_ShouldReplaceAdAtExpectedVersionWithSnapshot(t *testing.T) {
+	snapshot := domain.Ad{
+		ID:        1,
+		Title:     "Test AD",
+		StartAt:   "2024-01-01T08:00:00+08:00",
+		EndAt:     "2025-01-01T08:00:00+08:00",
+		Condition: &domain.Condition{AgeStart: 1, AgeEnd: 100, Gender: []string{"F"}, Country: []string{"TW"}, Platform: []string{"any"}, Language: []string{"any"}},
+		Version:   2,
+	}
+
+	mockAdRepository := mocks.NewAdRepository(t)
+	mockAdRepository.On("GetVersion", mock.Anything, int64(1), int64(2)).Return(snapshot, nil).Once()
+	mockAdRepository.On("Rollback", mock.Anything, mock.MatchedBy(func(ad *domain.Ad) bool {
+		return ad.ID == 1 && assert.Equal(t, []int64{5}, ad.IfMatch) && ad.Title == "Test AD" && ad.Condition.Gender[0] == "F"
+	}), int64(2), mock.Anything).Run(func(args mock.Arguments) {
+		args.Get(1).(*domain.Ad).Version = 6
+	}).Return(nil).Once()
+
+	testAdUsecase := usecase.NewAdUsecase(mockAdRepository, time.Second*1, usecase.WithConflictPolicy(domain.ConflictPolicyReject))
+
+	ad, err := testAdUsecase.Rollback(context.Background(), 1, 2, []int64{5})
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(6), ad.Version)
+	assert.Equal(t, "2024-01-01T00:00:00Z", ad.StartAt)
+}
+
+func TestRollback_UnknownVersion_ShouldReturnErrNotFound(t *testing.T) {
+	mockAdRepository := mocks.NewAdRepository(t)
+	mockAdRepository.On("GetVersion", mock.Anything, int64(1), int64(9)).Return(domain.Ad{}, domain.ErrNotFound).Once()
+
+	testAdUsecase := usecase.NewAdUsecase(mockAdRepository, time.Second*1)
+
+	_, err := testAdUsecase.Rollback(context.Background(), 1, 9, nil)
+
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+}
+
+func TestUpdateStatus_UnknownStatus_ShouldReturnErrBadParamInput(t *testing.T) {
+	mockAdRepository := mocks.NewAdRepository(t)
+
+	testAdUsecase := usecase.NewAdUsecase(mockAdRepository, time.Second*1)
+
+	err := testAdUsecase.UpdateStatus(context.Background(), 1, "stopped")
+
+	assert.ErrorIs(t, err, domain.ErrBadParamInput)
+}
+
+func TestUpdateStatus_Paused_ShouldUpdateStatus(t *testing.T) {
+	mockAdRepository := mocks.NewAdRepository(t)
+	mockAdRepository.On("UpdateStatus", mock.Anything, int64(1), domain.AdPaused).Return(nil).Once()
+
+	testAdUsecase := usecase.NewAdUsecase(mockAdRepository, time.Second*1)
+
+	err := testAdUsecase.UpdateStatus(context.Background(), 1, domain.AdPaused)
+
+	assert.NoError(t, err)
+}
+
+func TestDelete_AdRepositoryDeadlineExceeded_ShouldReturnErrTimeout(t *testing.T) {
+	mockAdRepository := mocks.NewAdRepository(t)
+	mockAdRepository.On("Delete", mock.Anything, int64(1)).Return(context.DeadlineExceeded).Once()
+
+	testAdUsecase := usecase.NewAdUsecase(mockAdRepository, time.Second*1)
+
+	err := testAdUsecase.Delete(context.Background(), 1)
+
+	assert.ErrorIs(t, err, domain.ErrTimeout)
+}
+
+func TestRestore_AdNotDeleted_ShouldReturnErrNotFound(t *testing.T) {
+	mockAdRepository := mocks.NewAdRepository(t)
+	mockAdRepository.On("Restore", mock.Anything, int64(1)).Return(domain.ErrNotFound).Once()
+
+	testAdUsecase := usecase.NewAdUsecase(mockAdRepository, time.Second*1)
+
+	err := testAdUsecase.Restore(context.Background(), 1)
+
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+}
@@ -0,0 +1,80 @@
+package usecase
+
+import (
+	"context"
+	"dcard-backend/domain"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// maxNameLength is the length of the name columns of advertisers and campaigns
+const maxNameLength = 128
+
+type advertiserUsecase struct {
+	advertiserRepository domain.AdvertiserRepository
+	contextTimeout       time.Duration
+}
+
+func NewAdvertiserUsecase(advertiserRepository domain.AdvertiserRepository, timeout time.Duration) domain.AdvertiserUsecase {
+	return &advertiserUsecase{
+		advertiserRepository: advertiserRepository,
+		contextTimeout:       timeout,
+	}
+}
+
+// validateName trims a name, which should neither be empty nor exceed the column
+func validateName(name *string) error {
+	*name = strings.TrimSpace(*name)
+	if *name == "" || utf8.RuneCountInString(*name) > maxNameLength {
+		return fmt.Errorf("%w: name should have 1 to %d characters", domain.ErrBadParamInput, maxNameLength)
+	}
+	return nil
+}
+
+func (au *advertiserUsecase) Create(c context.Context, advertiser *domain.Advertiser) error {
+	ctx, cancel := context.WithTimeout(c, au.contextTimeout)
+	defer cancel()
+
+	if err := validateName(&advertiser.Name); err != nil {
+		return err
+	}
+	return toDomainError(au.advertiserRepository.Create(ctx, advertiser))
+}
+
+func (au *advertiserUsecase) GetByID(c context.Context, id int64) (domain.Advertiser, error) {
+	ctx, cancel := context.WithTimeout(c, au.contextTimeout)
+	defer cancel()
+
+	advertiser, err := au.advertiserRepository.GetByID(ctx, id)
+	return advertiser, toDomainError(err)
+}
+
+func (au *advertiserUsecase) Fetch(c context.Context) ([]domain.Advertiser, error) {
+	ctx, cancel := context.WithTimeout(c, au.contextTimeout)
+	defer cancel()
+
+	advertisers, err := au.advertiserRepository.Fetch(ctx)
+	return advertisers, toDomainError(err)
+}
+
+func (au *advertiserUsecase) Update(c context.Context, advertiser *domain.Advertiser) error {
+	ctx, cancel := context.WithTimeout(c, au.contextTimeout)
+	defer cancel()
+
+	if err := validateName(&advertiser.Name); err != nil {
+		return err
+	}
+	if _, err := au.advertiserRepository.GetByID(ctx, advertiser.ID); err != nil {
+		return toDomainError(err)
+	}
+	return toDomainError(au.advertiserRepository.Update(ctx, advertiser))
+}
+
+func (au *advertiserUsecase) Delete(c context.Context, id int64) error {
+	ctx, cancel := context.WithTimeout(c, au.contextTimeout)
+	defer cancel()
+
+	return toDomainError(au.advertiserRepository.Delete(ctx, id))
+}
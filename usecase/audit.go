@@ -0,0 +1,126 @@
+package usecase
+
+import (
+	"context"
+	"dcard-backend/domain"
+	"fmt"
+	"time"
+)
+
+// Page sizes of the audit log
+const (
+	defaultAuditLimit = 50
+	maxAuditLimit     = 500
+)
+
+type auditUsecase struct {
+	auditRepository domain.AuditRepository
+	contextTimeout  time.Duration
+}
+
+func NewAuditUsecase(auditRepository domain.AuditRepository, timeout time.Duration) domain.AuditUsecase {
+	return &auditUsecase{
+		auditRepository: auditRepository,
+		contextTimeout:  timeout,
+	}
+}
+
+// changeSnapshotTimeToUTC converts a time of an ad in the audit log, which is in RFC 3339 when
+// the ad was written by the change and in the format of the database when it was read before it
+func changeSnapshotTimeToUTC(timeStr *string) error {
+	if t, err := time.Parse(time.RFC3339, *timeStr); err == nil {
+		*timeStr = t.UTC().Format(time.RFC3339)
+		return nil
+	}
+	return changeTimeToUTC(timeStr)
+}
+
+func changeAuditTimeToUTC(entry *domain.AuditEntry) error {
+	if err := changeTimeToUTC(&entry.CreatedAt); err != nil {
+		return err
+	}
+	for _, snapshot := range []*domain.Ad{entry.Before, entry.After} {
+		if snapshot == nil {
+			continue
+		}
+		if err := changeSnapshotTimeToUTC(&snapshot.StartAt); err != nil {
+			return err
+		}
+		if err := changeSnapshotTimeToUTC(&snapshot.EndAt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (au *auditUsecase) fetch(c context.Context, filter domain.AuditFilter) ([]domain.AuditEntry, error) {
+	ctx, cancel := context.WithTimeout(c, au.contextTimeout)
+	defer cancel()
+
+	entries, err := au.auditRepository.Fetch(ctx, filter)
+	if err != nil {
+		return nil, toDomainError(err)
+	}
+	for i := range entries {
+		if err := changeAuditTimeToUTC(&entries[i]); err != nil {
+			return nil, err
+		}
+	}
+	return entries, nil
+}
+
+// GetHistory returns a page of the changes of an ad from the newest, including the changes of
+// deleted ads. An ad without changes does not exist, while a page after its last change is empty.
+func (au *auditUsecase) GetHistory(c context.Context, adID int64, limit int, offset int) ([]domain.AuditEntry, error) {
+	filter := domain.AuditFilter{AdID: adID, Limit: limit, Offset: offset}
+	if err := validateAuditFilter(&filter); err != nil {
+		return nil, err
+	}
+	entries, err := au.fetch(c, filter)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 && offset == 0 {
+		return nil, domain.ErrNotFound
+	}
+	return entries, nil
+}
+
+// validateAuditFilter checks a filter of the audit log, converting its times to the timezone of
+// the database and limiting its page size
+func validateAuditFilter(filter *domain.AuditFilter) error {
+	if filter.AdID < 0 {
+		return fmt.Errorf("%w: adId should be a positive integer", domain.ErrBadParamInput)
+	}
+
+	switch filter.Action {
+	case "", domain.AuditCreate, domain.AuditUpdate, domain.AuditDelete, domain.AuditPause, domain.AuditResume, domain.AuditRollback, domain.AuditRestore:
+	default:
+		return fmt.Errorf("%w: unknown action %q", domain.ErrBadParamInput, filter.Action)
+	}
+
+	for _, timeStr := range []*string{&filter.Since, &filter.Until} {
+		if *timeStr == "" {
+			continue
+		}
+		if err := changeTimeToUTF8(timeStr); err != nil {
+			return fmt.Errorf("%w: %s", domain.ErrBadParamInput, err.Error())
+		}
+	}
+
+	if filter.Limit < 0 || filter.Limit > maxAuditLimit || filter.Offset < 0 {
+		return fmt.Errorf("%w: limit should be 1 to %d and offset should be non-negative", domain.ErrBadParamInput, maxAuditLimit)
+	}
+	if filter.Limit == 0 {
+		filter.Limit = defaultAuditLimit
+	}
+	return nil
+}
+
+// Fetch returns a page of the audit log of the tenant from the newest
+func (au *auditUsecase) Fetch(c context.Context, filter domain.AuditFilter) ([]domain.AuditEntry, error) {
+	if err := validateAuditFilter(&filter); err != nil {
+		return nil, err
+	}
+	return au.fetch(c, filter)
+}
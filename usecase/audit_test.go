@@ -0,0 +1,114 @@
+package usecase_test
+
+import (
+	"context"
+	"dcard-backend/domain"
+	"dcard-backend/domain/mocks"
+	"dcard-backend/usecase"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestGetHistory_Success_ShouldChangeTimesToUTC(t *testing.T) {
+	entries := []domain.AuditEntry{
+		{
+			ID:        2,
+			AdID:      1,
+			Action:    domain.AuditUpdate,
+			CreatedAt: "2024-01-02 08:00:00",
+			Before:    &domain.Ad{Title: "AD 0", StartAt: "2024-01-01 08:00:00", EndAt: "2025-01-01 08:00:00"},
+			After:     &domain.Ad{Title: "AD 1", StartAt: "2024-01-01T08:00:00+08:00", EndAt: "2025-01-01T08:00:00+08:00"},
+		},
+		{
+			ID:        1,
+			AdID:      1,
+			Action:    domain.AuditCreate,
+			CreatedAt: "2024-01-01 08:00:00",
+			After:     &domain.Ad{Title: "AD 0", StartAt: "2024-01-01T08:00:00+08:00", EndAt: "2025-01-01T08:00:00+08:00"},
+		},
+	}
+	mockAuditRepository := mocks.NewAuditRepository(t)
+	mockAuditRepository.On("Fetch", mock.Anything, domain.AuditFilter{AdID: 1, Limit: 50}).Return(entries, nil).Once()
+
+	testAuditUsecase := usecase.NewAuditUsecase(mockAuditRepository, time.Second)
+
+	history, err := testAuditUsecase.GetHistory(context.Background(), 1, 0, 0)
+
+	assert.NoError(t, err)
+	if assert.Len(t, history, 2) {
+		assert.Equal(t, "2024-01-02T00:00:00Z", history[0].CreatedAt)
+		assert.Equal(t, "2024-01-01T00:00:00Z", history[0].Before.StartAt)
+		assert.Equal(t, "2024-01-01T00:00:00Z", history[0].After.StartAt)
+		assert.Equal(t, "2025-01-01T00:00:00Z", history[1].After.EndAt)
+		assert.Nil(t, history[1].Before)
+	}
+}
+
+func TestGetHistory_NoEntries_ShouldReturnErrNotFound(t *testing.T) {
+	mockAuditRepository := mocks.NewAuditRepository(t)
+	mockAuditRepository.On("Fetch", mock.Anything, domain.AuditFilter{AdID: 1, Limit: 50}).Return([]domain.AuditEntry{}, nil).Once()
+
+	testAuditUsecase := usecase.NewAuditUsecase(mockAuditRepository, time.Second)
+
+	_, err := testAuditUsecase.GetHistory(context.Background(), 1, 0, 0)
+
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+}
+
+func TestGetHistory_PageAfterLastChange_ShouldReturnEmptyPage(t *testing.T) {
+	mockAuditRepository := mocks.NewAuditRepository(t)
+	mockAuditRepository.On("Fetch", mock.Anything, domain.AuditFilter{AdID: 1, Limit: 10, Offset: 20}).Return([]domain.AuditEntry{}, nil).Once()
+
+	testAuditUsecase := usecase.NewAuditUsecase(mockAuditRepository, time.Second)
+
+	history, err := testAuditUsecase.GetHistory(context.Background(), 1, 10, 20)
+
+	assert.NoError(t, err)
+	assert.Empty(t, history)
+}
+
+func TestGetHistory_LimitTooLarge_ShouldReturnErrBadParamInput(t *testing.T) {
+	testAuditUsecase := usecase.NewAuditUsecase(mocks.NewAuditRepository(t), time.Second)
+
+	_, err := testAuditUsecase.GetHistory(context.Background(), 1, 501, 0)
+
+	assert.ErrorIs(t, err, domain.ErrBadParamInput)
+}
+
+func TestAuditFetch_FilterProvided_ShouldConvertTimesAndDefaultLimit(t *testing.T) {
+	expectedFilter := domain.AuditFilter{
+		Action: domain.AuditDelete,
+		Since:  "2024-01-01T08:00:00+08:00",
+		Limit:  50,
+	}
+	mockAuditRepository := mocks.NewAuditRepository(t)
+	mockAuditRepository.On("Fetch", mock.Anything, expectedFilter).Return([]domain.AuditEntry{}, nil).Once()
+
+	testAuditUsecase := usecase.NewAuditUsecase(mockAuditRepository, time.Second)
+
+	entries, err := testAuditUsecase.Fetch(context.Background(), domain.AuditFilter{Action: domain.AuditDelete, Since: "2024-01-01T00:00:00Z"})
+
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestAuditFetch_InvalidFilter_ShouldReturnErrBadParamInput(t *testing.T) {
+	filters := []domain.AuditFilter{
+		{Action: "rename"},
+		{Since: "yesterday"},
+		{Limit: 501},
+		{Offset: -1},
+		{AdID: -1},
+	}
+	mockAuditRepository := mocks.NewAuditRepository(t)
+
+	testAuditUsecase := usecase.NewAuditUsecase(mockAuditRepository, time.Second)
+
+	for _, filter := range filters {
+		_, err := testAuditUsecase.Fetch(context.Background(), filter)
+		assert.ErrorIs(t, err, domain.ErrBadParamInput, "%+v", filter)
+	}
+}
@@ -0,0 +1,401 @@
+package usecase
+
+import (
+	"context"
+	"dcard-backend/domain"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type campaignUsecase struct {
+	campaignRepository   domain.CampaignRepository
+	advertiserRepository domain.AdvertiserRepository
+	adUsecase            domain.AdUsecase
+	contextTimeout       time.Duration
+}
+
+type CampaignUsecaseOption func(*campaignUsecase)
+
+// WithCampaignAds sets the usecase of the ads of campaigns, so that an update of a campaign
+// reaches the fields its ads inherit. Without it, ads keep the defaults they inherited.
+func WithCampaignAds(adUsecase domain.AdUsecase) CampaignUsecaseOption {
+	return func(cu *campaignUsecase) {
+		cu.adUsecase = adUsecase
+	}
+}
+
+func NewCampaignUsecase(campaignRepository domain.CampaignRepository, advertiserRepository domain.AdvertiserRepository, timeout time.Duration, options ...CampaignUsecaseOption) domain.CampaignUsecase {
+	cu := &campaignUsecase{
+		campaignRepository:   campaignRepository,
+		advertiserRepository: advertiserRepository,
+		contextTimeout:       timeout,
+	}
+	for _, option := range options {
+		option(cu)
+	}
+	return cu
+}
+
+// WithCampaignRepository sets where the campaigns of ads are read, so that ads inherit their
+// defaults and are scoped by advertiser. Without it, ads can not be created in campaigns.
+func WithCampaignRepository(campaignRepository domain.CampaignRepository) AdUsecaseOption {
+	return func(au *adUsecase) {
+		au.campaignRepository = campaignRepository
+	}
+}
+
+func validateCampaignStatus(status domain.CampaignStatus) error {
+	if status != domain.CampaignActive && status != domain.CampaignPaused {
+		return fmt.Errorf("%w: status should be %s or %s", domain.ErrBadParamInput, domain.CampaignActive, domain.CampaignPaused)
+	}
+	return nil
+}
+
+// normalizeCampaign validates a campaign, whose defaults are optional. Unlike ads, the empty
+// targeting of a campaign is kept empty, so that ads only inherit what the campaign sets.
+func normalizeCampaign(campaign *domain.Campaign) error {
+	if err := validateName(&campaign.Name); err != nil {
+		return err
+	}
+
+	if campaign.Status == "" {
+		campaign.Status = domain.CampaignActive
+	}
+	if err := validateCampaignStatus(campaign.Status); err != nil {
+		return err
+	}
+
+	for _, timeStr := range []*string{&campaign.StartAt, &campaign.EndAt} {
+		if *timeStr == "" {
+			continue
+		}
+		if err := changeTimeToUTF8(timeStr); err != nil {
+			return fmt.Errorf("%w: %s", domain.ErrBadParamInput, err.Error())
+		}
+	}
+
+	if campaign.Condition == nil {
+		return nil
+	}
+	for _, dimension := range domain.Dimensions() {
+		values, excludedValues := dimension.Values(campaign.Condition), dimension.ExcludedValues(campaign.Condition)
+		if err := validateTargeting(dimension, *values, *excludedValues); err != nil {
+			return err
+		}
+	}
+	return validateSchedule(campaign.Condition.Schedule)
+}
+
+// changeCampaignTimeToUTC converts the flight dates of a campaign from the database, which
+// may be empty
+func changeCampaignTimeToUTC(campaign *domain.Campaign) error {
+	for _, timeStr := range []*string{&campaign.StartAt, &campaign.EndAt} {
+		if *timeStr == "" {
+			continue
+		}
+		if err := changeTimeToUTC(timeStr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkAdvertiser returns domain.ErrBadParamInput when the advertiser of a campaign does not exist
+func (cu *campaignUsecase) checkAdvertiser(c context.Context, advertiserID int64) error {
+	if advertiserID <= 0 {
+		return fmt.Errorf("%w: advertiserId should be a positive integer", domain.ErrBadParamInput)
+	}
+	_, err := cu.advertiserRepository.GetByID(c, advertiserID)
+	if errors.Is(err, domain.ErrNotFound) {
+		return fmt.Errorf("%w: advertiser %d does not exist", domain.ErrBadParamInput, advertiserID)
+	}
+	return toDomainError(err)
+}
+
+func (cu *campaignUsecase) Create(c context.Context, campaign *domain.Campaign) error {
+	ctx, cancel := context.WithTimeout(c, cu.contextTimeout)
+	defer cancel()
+
+	if err := normalizeCampaign(campaign); err != nil {
+		return err
+	}
+	if err := cu.checkAdvertiser(ctx, campaign.AdvertiserID); err != nil {
+		return err
+	}
+	return toDomainError(cu.campaignRepository.Create(ctx, campaign))
+}
+
+func (cu *campaignUsecase) GetByID(c context.Context, id int64) (domain.Campaign, error) {
+	ctx, cancel := context.WithTimeout(c, cu.contextTimeout)
+	defer cancel()
+
+	campaign, err := cu.campaignRepository.GetByID(ctx, id)
+	if err != nil {
+		return domain.Campaign{}, toDomainError(err)
+	}
+	return campaign, changeCampaignTimeToUTC(&campaign)
+}
+
+func (cu *campaignUsecase) FetchByAdvertiser(c context.Context, advertiserID int64) ([]domain.Campaign, error) {
+	ctx, cancel := context.WithTimeout(c, cu.contextTimeout)
+	defer cancel()
+
+	if _, err := cu.advertiserRepository.GetByID(ctx, advertiserID); err != nil {
+		return nil, toDomainError(err)
+	}
+	campaigns, err := cu.campaignRepository.FetchByAdvertiser(ctx, advertiserID)
+	if err != nil {
+		return nil, toDomainError(err)
+	}
+	for i := range campaigns {
+		if err := changeCampaignTimeToUTC(&campaigns[i]); err != nil {
+			return nil, err
+		}
+	}
+	return campaigns, nil
+}
+
+// Update replaces a campaign, which stays with its advertiser, and then the fields its ads
+// inherit. An ad that can not follow the campaign is reported after the campaign is updated,
+// and updating the campaign again retries it.
+func (cu *campaignUsecase) Update(c context.Context, campaign *domain.Campaign) error {
+	ctx, cancel := context.WithTimeout(c, cu.contextTimeout)
+	defer cancel()
+
+	if err := normalizeCampaign(campaign); err != nil {
+		return err
+	}
+	existing, err := cu.campaignRepository.GetByID(ctx, campaign.ID)
+	if err != nil {
+		return toDomainError(err)
+	}
+	campaign.AdvertiserID = existing.AdvertiserID
+	if err := cu.campaignRepository.Update(ctx, campaign); err != nil {
+		return toDomainError(err)
+	}
+
+	if cu.adUsecase == nil {
+		return nil
+	}
+	// The ads are replaced with a timeout of their own, since a campaign may have many ads
+	return cu.adUsecase.ApplyCampaign(c, campaign.ID)
+}
+
+// UpdateStatus pauses or resumes serving every ad of a campaign
+func (cu *campaignUsecase) UpdateStatus(c context.Context, id int64, status domain.CampaignStatus) error {
+	ctx, cancel := context.WithTimeout(c, cu.contextTimeout)
+	defer cancel()
+
+	if err := validateCampaignStatus(status); err != nil {
+		return err
+	}
+	if _, err := cu.campaignRepository.GetByID(ctx, id); err != nil {
+		return toDomainError(err)
+	}
+	return toDomainError(cu.campaignRepository.UpdateStatus(ctx, id, status))
+}
+
+func (cu *campaignUsecase) Delete(c context.Context, id int64) error {
+	ctx, cancel := context.WithTimeout(c, cu.contextTimeout)
+	defer cancel()
+
+	return toDomainError(cu.campaignRepository.Delete(ctx, id))
+}
+
+// Names of the fields of an ad inherited from its campaign besides the targeting dimensions,
+// which are inherited under the name of the dimension
+const (
+	inheritedStartAt  = "startAt"
+	inheritedEndAt    = "endAt"
+	inheritedAgeStart = "ageStart"
+	inheritedAgeEnd   = "ageEnd"
+	inheritedSchedule = "schedule"
+)
+
+// clearInherited empties the fields of an ad listed as inherited, so that they are inherited
+// again from the current campaign
+func clearInherited(ad *domain.Ad, inherited []string) error {
+	for _, field := range inherited {
+		switch field {
+		case inheritedStartAt:
+			ad.StartAt = ""
+		case inheritedEndAt:
+			ad.EndAt = ""
+		case inheritedAgeStart:
+			ad.Condition.AgeStart = 0
+		case inheritedAgeEnd:
+			ad.Condition.AgeEnd = 0
+		case inheritedSchedule:
+			ad.Condition.Schedule = nil
+		default:
+			dimension, ok := domain.DimensionByName(field)
+			if !ok {
+				return fmt.Errorf("%w: %q can not be inherited from campaigns", domain.ErrBadParamInput, field)
+			}
+			*dimension.Values(ad.Condition) = nil
+			*dimension.ExcludedValues(ad.Condition) = nil
+		}
+	}
+	return nil
+}
+
+// inheritCampaign fills the flight dates and the targeting that an ad leaves empty or lists as
+// inherited with the defaults of its campaign, records them in ad.Inherited, and returns the
+// advertiser of the campaign, which is 0 for ads outside campaigns. A targeting dimension is
+// inherited as a whole, with its excluded values, unless the ad sets any of its values.
+func (au *adUsecase) inheritCampaign(c context.Context, ad *domain.Ad) (int64, error) {
+	inherited := ad.Inherited
+	ad.Inherited = nil
+	if ad.CampaignID == 0 {
+		return 0, nil
+	}
+	if au.campaignRepository == nil {
+		return 0, fmt.Errorf("%w: ads can not be created in campaigns", domain.ErrBadParamInput)
+	}
+
+	campaign, err := au.campaignRepository.GetByID(c, ad.CampaignID)
+	if errors.Is(err, domain.ErrNotFound) {
+		return 0, fmt.Errorf("%w: campaign %d does not exist", domain.ErrBadParamInput, ad.CampaignID)
+	}
+	if err != nil {
+		return 0, toDomainError(err)
+	}
+	if err := changeCampaignTimeToUTC(&campaign); err != nil {
+		return 0, err
+	}
+
+	if ad.Condition == nil {
+		ad.Condition = &domain.Condition{}
+	}
+	if err := clearInherited(ad, inherited); err != nil {
+		return 0, err
+	}
+	defaults := campaign.Condition
+	if defaults == nil {
+		defaults = &domain.Condition{}
+	}
+
+	if ad.StartAt == "" {
+		ad.StartAt = campaign.StartAt
+		ad.Inherited = append(ad.Inherited, inheritedStartAt)
+	}
+	if ad.EndAt == "" {
+		ad.EndAt = campaign.EndAt
+		ad.Inherited = append(ad.Inherited, inheritedEndAt)
+	}
+	if ad.Condition.AgeStart == 0 {
+		ad.Condition.AgeStart = defaults.AgeStart
+		ad.Inherited = append(ad.Inherited, inheritedAgeStart)
+	}
+	if ad.Condition.AgeEnd == 0 {
+		ad.Condition.AgeEnd = defaults.AgeEnd
+		ad.Inherited = append(ad.Inherited, inheritedAgeEnd)
+	}
+	for _, dimension := range domain.Dimensions() {
+		values, excludedValues := dimension.Values(ad.Condition), dimension.ExcludedValues(ad.Condition)
+		if len(*values) == 0 && len(*excludedValues) == 0 {
+			*values = append([]string{}, *dimension.Values(defaults)...)
+			*excludedValues = append([]string(nil), *dimension.ExcludedValues(defaults)...)
+			ad.Inherited = append(ad.Inherited, dimension.Name)
+		}
+	}
+	if ad.Condition.Schedule == nil {
+		ad.Condition.Schedule = defaults.Schedule
+		ad.Inherited = append(ad.Inherited, inheritedSchedule)
+	}
+	return campaign.AdvertiserID, nil
+}
+
+// inheritedState describes the fields of an ad that may be inherited, with the values of each
+// dimension sorted, so that an ad is only replaced when a change of its campaign reaches it
+func inheritedState(ad *domain.Ad) (string, error) {
+	condition := ad.Condition
+	if condition == nil {
+		condition = &domain.Condition{}
+	}
+	targeting := map[string][2][]string{}
+	for _, dimension := range domain.Dimensions() {
+		values := append([]string{}, *dimension.Values(condition)...)
+		excludedValues := append([]string{}, *dimension.ExcludedValues(condition)...)
+		sort.Strings(values)
+		sort.Strings(excludedValues)
+		targeting[dimension.Name] = [2][]string{values, excludedValues}
+	}
+	state, err := json.Marshal([]interface{}{ad.StartAt, ad.EndAt, condition.AgeStart, condition.AgeEnd, condition.Schedule, targeting})
+	return string(state), err
+}
+
+// ApplyCampaign replaces the ads of a campaign whose inherited fields changed with the campaign,
+// which records a version and an event of each ad like an update does. Every ad is tried, and
+// the first error is returned along with the ads that do not follow the campaign, such as ads
+// whose new flight or title conflicts.
+func (au *adUsecase) ApplyCampaign(c context.Context, campaignID int64) error {
+	ctx, cancel := context.WithTimeout(c, au.contextTimeout)
+	defer cancel()
+
+	ads, err := au.adRepository.FetchByCampaign(ctx, campaignID)
+	if err != nil {
+		return toDomainError(err)
+	}
+
+	var failed []string
+	var firstErr error
+	for _, ad := range ads {
+		if len(ad.Inherited) == 0 {
+			continue
+		}
+		if err := au.applyCampaign(ctx, ad); err != nil {
+			log.Printf("Error created when applying campaign %d to ad %d: %s", campaignID, ad.ID, err.Error())
+			failed = append(failed, strconv.FormatInt(ad.ID, 10))
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	if firstErr != nil {
+		return fmt.Errorf("ads %s do not follow campaign %d: %w", strings.Join(failed, ", "), campaignID, firstErr)
+	}
+	return nil
+}
+
+// applyCampaign inherits the fields of a stored ad from its campaign again, and replaces the ad
+// at the version it was read at if they changed
+func (au *adUsecase) applyCampaign(c context.Context, ad domain.Ad) error {
+	if err := changeTimeToUTC(&ad.StartAt); err != nil {
+		return err
+	}
+	if err := changeTimeToUTC(&ad.EndAt); err != nil {
+		return err
+	}
+	// The stored ad is compared in the time zone the ad is normalized to, before inheriting
+	// changes the condition it shares
+	stored := ad
+	if err := changeTimeToUTF8(&stored.StartAt); err != nil {
+		return err
+	}
+	if err := changeTimeToUTF8(&stored.EndAt); err != nil {
+		return err
+	}
+	before, err := inheritedState(&stored)
+	if err != nil {
+		return err
+	}
+
+	check, err := au.checkReplacement(c, &ad)
+	if err != nil {
+		return err
+	}
+	ad.IfMatch = []int64{ad.Version}
+	after, err := inheritedState(&ad)
+	if err != nil || after == before {
+		return err
+	}
+
+	return toDomainError(au.adRepository.Update(c, &ad, check))
+}
@@ -0,0 +1,358 @@
+package usecase_test
+
+import (
+	"context"
+	"dcard-backend/domain"
+	"dcard-backend/domain/mocks"
+	"dcard-backend/usecase"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var mockCampaign = domain.Campaign{
+	ID:           3,
+	AdvertiserID: 2,
+	Name:         "Spring Sale",
+	Status:       domain.CampaignActive,
+	StartAt:      "2024-03-01 08:00:00",
+	EndAt:        "2024-04-01 08:00:00",
+	Condition: &domain.Condition{
+		AgeStart: 18,
+		AgeEnd:   35,
+		Gender:   []string{"F"},
+		Country:  []string{"TW", "JP"},
+		Platform: []string{"ios"},
+	},
+}
+
+func TestCreate_InCampaign_ShouldInheritEmptyFieldsFromCampaign(t *testing.T) {
+	mockAd := domain.Ad{
+		Title:      "Test AD",
+		CampaignID: 3,
+		EndAt:      "2024-03-15T00:00:00.000Z",
+		Condition: &domain.Condition{
+			Country:         []string{"JP"},
+			ExcludePlatform: []string{"android"},
+		},
+	}
+
+	mockCampaignRepository := mocks.NewCampaignRepository(t)
+	mockCampaignRepository.On("GetByID", mock.Anything, int64(3)).Return(mockCampaign, nil).Once()
+	mockAdRepository := mocks.NewAdRepository(t)
+	mockAdRepository.On("Create", mock.Anything, mock.MatchedBy(func(ad *domain.Ad) bool {
+		return assert.Equal(t, "2024-03-01T08:00:00+08:00", ad.StartAt) &&
+			assert.Equal(t, "2024-03-15T08:00:00+08:00", ad.EndAt) &&
+			assert.Equal(t, 18, ad.Condition.AgeStart) &&
+			assert.Equal(t, 35, ad.Condition.AgeEnd) &&
+			assert.Equal(t, []string{"F"}, ad.Condition.Gender) &&
+			assert.Equal(t, []string{"JP"}, ad.Condition.Country) &&
+			// The ad excludes platforms, so it does not inherit the platforms of the campaign
+			assert.Equal(t, []string{"any"}, ad.Condition.Platform) &&
+			assert.Equal(t, []string{"startAt", "ageStart", "ageEnd", "gender", "language", "schedule"}, ad.Inherited)
+	}), mock.Anything).Return(nil).Once()
+
+	testAdUsecase := usecase.NewAdUsecase(mockAdRepository, time.Second*1, usecase.WithCampaignRepository(mockCampaignRepository))
+
+	err := testAdUsecase.Create(context.Background(), &mockAd)
+
+	assert.NoError(t, err)
+}
+
+func TestUpdate_InCampaign_ShouldInheritListedFieldsAgain(t *testing.T) {
+	mockAd := domain.Ad{
+		ID:         7,
+		Title:      "Test AD",
+		CampaignID: 3,
+		EndAt:      "2024-03-15T00:00:00.000Z",
+		Condition: &domain.Condition{
+			Gender:  []string{"M"},
+			Country: []string{"JP"},
+		},
+		Inherited: []string{"gender"},
+	}
+
+	mockCampaignRepository := mocks.NewCampaignRepository(t)
+	mockCampaignRepository.On("GetByID", mock.Anything, int64(3)).Return(mockCampaign, nil).Once()
+	mockAdRepository := mocks.NewAdRepository(t)
+	mockAdRepository.On("Update", mock.Anything, mock.MatchedBy(func(ad *domain.Ad) bool {
+		return assert.Equal(t, []string{"F"}, ad.Condition.Gender) &&
+			assert.Equal(t, []string{"JP"}, ad.Condition.Country) &&
+			assert.NotContains(t, ad.Inherited, "country")
+	}), mock.Anything).Return(nil).Once()
+
+	testAdUsecase := usecase.NewAdUsecase(mockAdRepository, time.Second*1, usecase.WithCampaignRepository(mockCampaignRepository))
+
+	err := testAdUsecase.Update(context.Background(), &mockAd)
+
+	assert.NoError(t, err)
+}
+
+func TestUpdate_UnknownInheritedField_ShouldReturnErrBadParamInput(t *testing.T) {
+	mockAd := domain.Ad{ID: 7, Title: "Test AD", CampaignID: 3, Inherited: []string{"title"}}
+
+	mockCampaignRepository := mocks.NewCampaignRepository(t)
+	mockCampaignRepository.On("GetByID", mock.Anything, int64(3)).Return(mockCampaign, nil).Once()
+
+	testAdUsecase := usecase.NewAdUsecase(mocks.NewAdRepository(t), time.Second*1, usecase.WithCampaignRepository(mockCampaignRepository))
+
+	err := testAdUsecase.Update(context.Background(), &mockAd)
+
+	assert.ErrorIs(t, err, domain.ErrBadParamInput)
+}
+
+// storedCampaignAd returns an ad of mockCampaign as it is read from the repository
+func storedCampaignAd(id int64, gender string) domain.Ad {
+	return domain.Ad{
+		ID:         id,
+		Title:      "Test AD",
+		CampaignID: 3,
+		Version:    2,
+		StartAt:    "2024-03-01 08:00:00",
+		EndAt:      "2024-03-15 08:00:00",
+		Condition: &domain.Condition{
+			AgeStart:        18,
+			AgeEnd:          35,
+			Gender:          []string{gender},
+			Country:         []string{"JP"},
+			Platform:        []string{"any"},
+			ExcludePlatform: []string{"android"},
+			Language:        []string{"any"},
+		},
+		Inherited: []string{"startAt", "ageStart", "ageEnd", "gender", "language", "schedule"},
+	}
+}
+
+func TestApplyCampaign_ShouldOnlyReplaceAdsWhoseInheritedFieldsChanged(t *testing.T) {
+	following := storedCampaignAd(7, "F")
+	// The campaign targeted men when the ad was created
+	stale := storedCampaignAd(8, "M")
+	overriding := storedCampaignAd(9, "M")
+	overriding.Inherited = nil
+
+	mockCampaignRepository := mocks.NewCampaignRepository(t)
+	mockCampaignRepository.On("GetByID", mock.Anything, int64(3)).Return(mockCampaign, nil).Twice()
+	mockAdRepository := mocks.NewAdRepository(t)
+	mockAdRepository.On("FetchByCampaign", mock.Anything, int64(3)).Return([]domain.Ad{following, stale, overriding}, nil).Once()
+	mockAdRepository.On("Update", mock.Anything, mock.MatchedBy(func(ad *domain.Ad) bool {
+		return assert.Equal(t, int64(8), ad.ID) &&
+			assert.Equal(t, []int64{2}, ad.IfMatch) &&
+			assert.Equal(t, []string{"F"}, ad.Condition.Gender) &&
+			assert.Equal(t, "2024-03-01T08:00:00+08:00", ad.StartAt)
+	}), mock.Anything).Return(nil).Once()
+
+	testAdUsecase := usecase.NewAdUsecase(mockAdRepository, time.Second*1, usecase.WithCampaignRepository(mockCampaignRepository))
+
+	err := testAdUsecase.ApplyCampaign(context.Background(), 3)
+
+	assert.NoError(t, err)
+}
+
+func TestApplyCampaign_UpdateFail_ShouldTryEveryAdAndReturnError(t *testing.T) {
+	mockCampaignRepository := mocks.NewCampaignRepository(t)
+	mockCampaignRepository.On("GetByID", mock.Anything, int64(3)).Return(mockCampaign, nil).Twice()
+	mockAdRepository := mocks.NewAdRepository(t)
+	mockAdRepository.On("FetchByCampaign", mock.Anything, int64(3)).
+		Return([]domain.Ad{storedCampaignAd(7, "M"), storedCampaignAd(8, "M")}, nil).Once()
+	mockAdRepository.On("Update", mock.Anything, mock.MatchedBy(func(ad *domain.Ad) bool { return ad.ID == 7 }), mock.Anything).
+		Return(domain.ErrConflict).Once()
+	mockAdRepository.On("Update", mock.Anything, mock.MatchedBy(func(ad *domain.Ad) bool { return ad.ID == 8 }), mock.Anything).
+		Return(nil).Once()
+
+	testAdUsecase := usecase.NewAdUsecase(mockAdRepository, time.Second*1, usecase.WithCampaignRepository(mockCampaignRepository))
+
+	err := testAdUsecase.ApplyCampaign(context.Background(), 3)
+
+	assert.ErrorIs(t, err, domain.ErrConflict)
+	assert.Contains(t, err.Error(), "ads 7 do not follow campaign 3")
+}
+
+func TestCreate_CampaignNotExist_ShouldReturnErrBadParamInput(t *testing.T) {
+	mockAd := domain.Ad{Title: "Test AD", CampaignID: 4}
+
+	mockCampaignRepository := mocks.NewCampaignRepository(t)
+	mockCampaignRepository.On("GetByID", mock.Anything, int64(4)).Return(domain.Campaign{}, domain.ErrNotFound).Once()
+
+	testAdUsecase := usecase.NewAdUsecase(mocks.NewAdRepository(t), time.Second*1, usecase.WithCampaignRepository(mockCampaignRepository))
+
+	err := testAdUsecase.Create(context.Background(), &mockAd)
+
+	assert.ErrorIs(t, err, domain.ErrBadParamInput)
+}
+
+func TestCreate_CampaignWithoutDates_AdWithoutDates_ShouldReturnErrBadParamInput(t *testing.T) {
+	mockAd := domain.Ad{Title: "Test AD", CampaignID: 3}
+	campaign := mockCampaign
+	campaign.StartAt, campaign.EndAt = "", ""
+
+	mockCampaignRepository := mocks.NewCampaignRepository(t)
+	mockCampaignRepository.On("GetByID", mock.Anything, int64(3)).Return(campaign, nil).Once()
+
+	testAdUsecase := usecase.NewAdUsecase(mocks.NewAdRepository(t), time.Second*1, usecase.WithCampaignRepository(mockCampaignRepository))
+
+	err := testAdUsecase.Create(context.Background(), &mockAd)
+
+	assert.ErrorIs(t, err, domain.ErrBadParamInput)
+}
+
+func TestCreate_InCampaign_ShouldCheckConflictsWithinAdvertiser(t *testing.T) {
+	mockAd := domain.Ad{Title: "Test AD", CampaignID: 3}
+
+	mockCampaignRepository := mocks.NewCampaignRepository(t)
+	mockCampaignRepository.On("GetByID", mock.Anything, int64(3)).Return(mockCampaign, nil).Once()
+	mockAdRepository := mocks.NewAdRepository(t)
+	mockAdRepository.On("Create", mock.Anything, mock.MatchedBy(func(ad *domain.Ad) bool {
+		return ad.StartAt == "2024-03-01T08:00:00+08:00" && ad.EndAt == "2024-04-01T08:00:00+08:00"
+	}), mock.MatchedBy(func(check domain.ConflictCheck) bool {
+		return check.AdvertiserID == 2
+	})).Return(resolveConflicts([]domain.Ad{{ID: 5, Title: "test ad"}})).Once()
+
+	testAdUsecase := usecase.NewAdUsecase(mockAdRepository, time.Second*1,
+		usecase.WithCampaignRepository(mockCampaignRepository),
+		usecase.WithConflictPolicy(domain.ConflictPolicyReject))
+
+	err := testAdUsecase.Create(context.Background(), &mockAd)
+
+	assert.ErrorIs(t, err, domain.ErrConflict)
+}
+
+func TestGetByCondition_InvalidAdvertiser_ShouldReturnErrBadParamInput(t *testing.T) {
+	testAdUsecase := usecase.NewAdUsecase(mocks.NewAdRepository(t), time.Second*1)
+
+	_, err := testAdUsecase.GetByCondition(context.Background(), map[string][]string{
+		"offset":       {"0"},
+		"advertiserId": {"0"},
+	})
+
+	assert.ErrorIs(t, err, domain.ErrBadParamInput)
+}
+
+func TestCampaignCreate_StatusNotProvided_ShouldBeActive(t *testing.T) {
+	campaign := domain.Campaign{AdvertiserID: 2, Name: " Spring Sale ", StartAt: "2024-03-01T00:00:00.000Z"}
+
+	mockAdvertiserRepository := mocks.NewAdvertiserRepository(t)
+	mockAdvertiserRepository.On("GetByID", mock.Anything, int64(2)).Return(domain.Advertiser{ID: 2, Name: "Dcard"}, nil).Once()
+	mockCampaignRepository := mocks.NewCampaignRepository(t)
+	mockCampaignRepository.On("Create", mock.Anything, mock.MatchedBy(func(campaign *domain.Campaign) bool {
+		return campaign.Status == domain.CampaignActive && campaign.Name == "Spring Sale" &&
+			campaign.StartAt == "2024-03-01T08:00:00+08:00" && campaign.EndAt == ""
+	})).Return(nil).Once()
+
+	testCampaignUsecase := usecase.NewCampaignUsecase(mockCampaignRepository, mockAdvertiserRepository, time.Second*1)
+
+	err := testCampaignUsecase.Create(context.Background(), &campaign)
+
+	assert.NoError(t, err)
+}
+
+func TestCampaignCreate_AdvertiserNotExist_ShouldReturnErrBadParamInput(t *testing.T) {
+	campaign := domain.Campaign{AdvertiserID: 2, Name: "Spring Sale"}
+
+	mockAdvertiserRepository := mocks.NewAdvertiserRepository(t)
+	mockAdvertiserRepository.On("GetByID", mock.Anything, int64(2)).Return(domain.Advertiser{}, domain.ErrNotFound).Once()
+
+	testCampaignUsecase := usecase.NewCampaignUsecase(mocks.NewCampaignRepository(t), mockAdvertiserRepository, time.Second*1)
+
+	err := testCampaignUsecase.Create(context.Background(), &campaign)
+
+	assert.ErrorIs(t, err, domain.ErrBadParamInput)
+}
+
+func TestCampaignCreate_InvalidCampaign_ShouldReturnErrBadParamInput(t *testing.T) {
+	campaigns := []domain.Campaign{
+		{AdvertiserID: 2, Name: " "},
+		{AdvertiserID: 2, Name: "Spring Sale", Status: "stopped"},
+		{AdvertiserID: 2, Name: "Spring Sale", StartAt: "2024-03-01"},
+		{AdvertiserID: 2, Name: "Spring Sale", Condition: &domain.Condition{Gender: []string{"X"}}},
+		{Name: "Spring Sale"},
+	}
+
+	for _, campaign := range campaigns {
+		testCampaignUsecase := usecase.NewCampaignUsecase(mocks.NewCampaignRepository(t), mocks.NewAdvertiserRepository(t), time.Second*1)
+
+		err := testCampaignUsecase.Create(context.Background(), &campaign)
+		assert.ErrorIs(t, err, domain.ErrBadParamInput)
+	}
+}
+
+func TestCampaignUpdate_ShouldKeepAdvertiser(t *testing.T) {
+	campaign := domain.Campaign{ID: 3, AdvertiserID: 9, Name: "Summer Sale", Status: domain.CampaignPaused}
+
+	mockCampaignRepository := mocks.NewCampaignRepository(t)
+	mockCampaignRepository.On("GetByID", mock.Anything, int64(3)).Return(mockCampaign, nil).Once()
+	mockCampaignRepository.On("Update", mock.Anything, mock.MatchedBy(func(campaign *domain.Campaign) bool {
+		return campaign.AdvertiserID == 2 && campaign.Name == "Summer Sale"
+	})).Return(nil).Once()
+
+	testCampaignUsecase := usecase.NewCampaignUsecase(mockCampaignRepository, mocks.NewAdvertiserRepository(t), time.Second*1)
+
+	err := testCampaignUsecase.Update(context.Background(), &campaign)
+
+	assert.NoError(t, err)
+}
+
+func TestCampaignUpdate_WithCampaignAds_ShouldApplyCampaignToAds(t *testing.T) {
+	campaign := domain.Campaign{ID: 3, Name: "Summer Sale", Status: domain.CampaignActive}
+
+	mockCampaignRepository := mocks.NewCampaignRepository(t)
+	mockCampaignRepository.On("GetByID", mock.Anything, int64(3)).Return(mockCampaign, nil).Once()
+	mockCampaignRepository.On("Update", mock.Anything, mock.Anything).Return(nil).Once()
+	mockAdUsecase := mocks.NewAdUsecase(t)
+	mockAdUsecase.On("ApplyCampaign", mock.Anything, int64(3)).Return(domain.ErrConflict).Once()
+
+	testCampaignUsecase := usecase.NewCampaignUsecase(mockCampaignRepository, mocks.NewAdvertiserRepository(t), time.Second*1,
+		usecase.WithCampaignAds(mockAdUsecase))
+
+	err := testCampaignUsecase.Update(context.Background(), &campaign)
+
+	assert.ErrorIs(t, err, domain.ErrConflict)
+}
+
+func TestCampaignUpdateStatus_NotExist_ShouldReturnErrNotFound(t *testing.T) {
+	mockCampaignRepository := mocks.NewCampaignRepository(t)
+	mockCampaignRepository.On("GetByID", mock.Anything, int64(3)).Return(domain.Campaign{}, domain.ErrNotFound).Once()
+
+	testCampaignUsecase := usecase.NewCampaignUsecase(mockCampaignRepository, mocks.NewAdvertiserRepository(t), time.Second*1)
+
+	err := testCampaignUsecase.UpdateStatus(context.Background(), 3, domain.CampaignPaused)
+
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+}
+
+func TestCampaignGetByID_ShouldReturnTimesInUTC(t *testing.T) {
+	mockCampaignRepository := mocks.NewCampaignRepository(t)
+	mockCampaignRepository.On("GetByID", mock.Anything, int64(3)).Return(mockCampaign, nil).Once()
+
+	testCampaignUsecase := usecase.NewCampaignUsecase(mockCampaignRepository, mocks.NewAdvertiserRepository(t), time.Second*1)
+
+	campaign, err := testCampaignUsecase.GetByID(context.Background(), 3)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "2024-03-01T00:00:00Z", campaign.StartAt)
+	assert.Equal(t, "2024-04-01T00:00:00Z", campaign.EndAt)
+}
+
+func TestAdvertiserUpdate_NotExist_ShouldReturnErrNotFound(t *testing.T) {
+	mockAdvertiserRepository := mocks.NewAdvertiserRepository(t)
+	mockAdvertiserRepository.On("GetByID", mock.Anything, int64(2)).Return(domain.Advertiser{}, domain.ErrNotFound).Once()
+
+	testAdvertiserUsecase := usecase.NewAdvertiserUsecase(mockAdvertiserRepository, time.Second*1)
+
+	err := testAdvertiserUsecase.Update(context.Background(), &domain.Advertiser{ID: 2, Name: "Dcard"})
+
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+}
+
+func TestAdvertiserCreate_NameTooLong_ShouldReturnErrBadParamInput(t *testing.T) {
+	testAdvertiserUsecase := usecase.NewAdvertiserUsecase(mocks.NewAdvertiserRepository(t), time.Second*1)
+
+	name := ""
+	for i := 0; i < 129; i++ {
+		name += "廣"
+	}
+	err := testAdvertiserUsecase.Create(context.Background(), &domain.Advertiser{Name: name})
+
+	assert.ErrorIs(t, err, domain.ErrBadParamInput)
+}
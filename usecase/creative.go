@@ -0,0 +1,85 @@
+package usecase
+
+import (
+	"dcard-backend/domain"
+	"fmt"
+	"net/url"
+	"unicode/utf8"
+)
+
+// Length limits of the creative fields, which match the columns of the database
+const (
+	maxDescriptionLength  = 512
+	maxURLLength          = 2048
+	maxCallToActionLength = 32
+)
+
+func validateURL(field string, value string) error {
+	if value == "" {
+		return nil
+	}
+	if len(value) > maxURLLength {
+		return fmt.Errorf("%w: %s should have at most %d characters", domain.ErrBadParamInput, field, maxURLLength)
+	}
+
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: %s should be an absolute http or https URL", domain.ErrBadParamInput, field)
+	}
+	return nil
+}
+
+func validateCreativeFields(description string, imageURL string, clickURL string, callToAction string) error {
+	if utf8.RuneCountInString(description) > maxDescriptionLength {
+		return fmt.Errorf("%w: description should have at most %d characters", domain.ErrBadParamInput, maxDescriptionLength)
+	}
+	if utf8.RuneCountInString(callToAction) > maxCallToActionLength {
+		return fmt.Errorf("%w: callToAction should have at most %d characters", domain.ErrBadParamInput, maxCallToActionLength)
+	}
+	if err := validateURL("imageUrl", imageURL); err != nil {
+		return err
+	}
+	return validateURL("clickUrl", clickURL)
+}
+
+// validateCreatives checks the default creative of an ad and its variants, where each
+// variant targets a different platform
+func validateCreatives(ad *domain.Ad) error {
+	if err := validateCreativeFields(ad.Description, ad.ImageURL, ad.ClickURL, ad.CallToAction); err != nil {
+		return err
+	}
+
+	platform, _ := domain.DimensionByName("platform")
+	platforms := []string{}
+	for _, creative := range ad.Creatives {
+		if !platform.Valid(creative.Platform) || creative.Platform == platform.AnyValue {
+			return fmt.Errorf("%w: creative platform %q is not a valid platform", domain.ErrBadParamInput, creative.Platform)
+		}
+		if contains(platforms, creative.Platform) {
+			return fmt.Errorf("%w: ad has more than one creative for platform %q", domain.ErrBadParamInput, creative.Platform)
+		}
+		platforms = append(platforms, creative.Platform)
+
+		err := validateCreativeFields(creative.Description, creative.ImageURL, creative.ClickURL, creative.CallToAction)
+		if err != nil {
+			return fmt.Errorf("creative for %s: %w", creative.Platform, err)
+		}
+	}
+	return nil
+}
+
+// applyCreative replaces the default creative of an ad with the non-empty fields of a variant
+func applyCreative(ad *domain.Ad, creative domain.Creative) {
+	if creative.Description != "" {
+		ad.Description = creative.Description
+	}
+	if creative.ImageURL != "" {
+		ad.ImageURL = creative.ImageURL
+	}
+	if creative.ClickURL != "" {
+		ad.ClickURL = creative.ClickURL
+	}
+	if creative.CallToAction != "" {
+		ad.CallToAction = creative.CallToAction
+	}
+}
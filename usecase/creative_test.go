@@ -0,0 +1,89 @@
+package usecase_test
+
+import (
+	"context"
+	"dcard-backend/domain"
+	"dcard-backend/domain/mocks"
+	"dcard-backend/usecase"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCreate_InvalidCreative_ShouldReturnErrBadParamInput(t *testing.T) {
+	ads := []domain.Ad{
+		{ClickURL: "javascript:alert(1)"},
+		{ClickURL: "/relative/path"},
+		{ImageURL: "ftp://example.com/ad.png"},
+		{ImageURL: "https://example.com/" + strings.Repeat("a", 2048)},
+		{Description: strings.Repeat("廣", 513)},
+		{CallToAction: strings.Repeat("a", 33)},
+		{Creatives: []domain.Creative{{Platform: "any", ClickURL: "https://example.com"}}},
+		{Creatives: []domain.Creative{{Platform: "tv", ClickURL: "https://example.com"}}},
+		{Creatives: []domain.Creative{{Platform: "ios"}, {Platform: "ios"}}},
+		{Creatives: []domain.Creative{{Platform: "ios", ClickURL: "itms-apps://apps.apple.com/app/id1"}}},
+	}
+
+	for _, mockAd := range ads {
+		mockAd.Title = "Test AD"
+		mockAd.StartAt = "2024-01-01T00:00:00.000Z"
+		mockAd.EndAt = "2025-01-01T00:00:00.000Z"
+		mockAdRepository := mocks.NewAdRepository(t)
+
+		testAdUsecase := usecase.NewAdUsecase(mockAdRepository, time.Second*1)
+
+		err := testAdUsecase.Create(context.Background(), &mockAd)
+		assert.ErrorIs(t, err, domain.ErrBadParamInput)
+	}
+}
+
+func TestCreate_ValidCreatives_ShouldCreate(t *testing.T) {
+	mockAd := domain.Ad{
+		Title:        "Test AD",
+		StartAt:      "2024-01-01T00:00:00.000Z",
+		EndAt:        "2025-01-01T00:00:00.000Z",
+		Description:  strings.Repeat("廣", 512),
+		ImageURL:     "https://example.com/ad.png",
+		ClickURL:     "http://example.com/landing?utm_source=dcard",
+		CallToAction: "Shop now",
+		Creatives: []domain.Creative{
+			{Platform: "android", ClickURL: "https://play.google.com/store/apps/details?id=com.example"},
+			{Platform: "ios", ClickURL: "https://apps.apple.com/app/id1", CallToAction: "Install"},
+		},
+	}
+	mockAdRepository := mocks.NewAdRepository(t)
+	mockAdRepository.On("Create", mock.Anything, &mockAd, mock.Anything).Return(nil).Once()
+
+	testAdUsecase := usecase.NewAdUsecase(mockAdRepository, time.Second*1)
+
+	err := testAdUsecase.Create(context.Background(), &mockAd)
+	assert.NoError(t, err)
+}
+
+func TestGetByCondition_PlatformProvided_ShouldPickCreativeOfPlatform(t *testing.T) {
+	ads := []domain.Ad{
+		{ID: 1, Title: "AD 1", EndAt: "2025-01-01 08:00:00", ClickURL: "https://example.com/1", CallToAction: "Shop now"},
+		{ID: 2, Title: "AD 2", EndAt: "2025-01-01 08:00:00", ClickURL: "https://example.com/2", CallToAction: "Shop now"},
+	}
+	mockAdRepository := mocks.NewAdRepository(t)
+	mockAdRepository.On("GetByCondition", mock.Anything, mock.Anything).Return(ads, nil).Once()
+	mockAdRepository.On("GetCreativesByPlatform", mock.Anything, []int64{1, 2}, "ios").
+		Return(map[int64]domain.Creative{2: {Platform: "ios", ClickURL: "https://apps.apple.com/app/id2"}}, nil).Once()
+
+	testAdUsecase := usecase.NewAdUsecase(mockAdRepository, time.Second*1)
+
+	served, err := testAdUsecase.GetByCondition(context.Background(), map[string][]string{
+		"offset":   {"0"},
+		"platform": {"ios"},
+		"sort":     {"endAt"},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.Ad{
+		{ID: 1, Title: "AD 1", EndAt: "2025-01-01T00:00:00Z", ClickURL: "https://example.com/1", CallToAction: "Shop now"},
+		{ID: 2, Title: "AD 2", EndAt: "2025-01-01T00:00:00Z", ClickURL: "https://apps.apple.com/app/id2", CallToAction: "Shop now"},
+	}, served)
+}
@@ -0,0 +1,347 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"dcard-backend/domain"
+	"encoding/hex"
+	"errors"
+	"log"
+	"slices"
+	"time"
+)
+
+const (
+	// outboxBatchSize is the number of events read from the outbox at once
+	outboxBatchSize = 500
+	// dispatchTimeout bounds the handling of a subscription at each poll. The checkpoint is
+	// leased for longer, so that it is advanced before another server may take it over.
+	dispatchTimeout = time.Minute
+	// maxOutboxGaps bounds the missing ids a cursor waits for, beyond which they are skipped
+	maxOutboxGaps = 1000
+	// maxDispatchErrorLength bounds the error kept with a dead-lettered event
+	maxDispatchErrorLength = 512
+)
+
+// errNotDue stops a subscription at an event which failed until it is retried
+var errNotDue = errors.New("the failed event is not due yet")
+
+type dispatcherSubscription struct {
+	name    string
+	handler domain.EventHandler
+}
+
+type eventDispatcher struct {
+	outboxRepository   domain.OutboxRepository
+	eventBus           domain.AdEventBus
+	contextTimeout     time.Duration
+	retention          time.Duration
+	transactionTimeout time.Duration
+	maxAttempts        int
+	now                func() time.Time
+	// owner tells the leases of this server from those of the others
+	owner string
+
+	subscriptions []dispatcherSubscription
+}
+
+type EventDispatcherOption func(*eventDispatcher)
+
+// WithOutboxRetention sets how long events are kept in the outbox once every subscription
+// handled them, which is a day by default
+func WithOutboxRetention(retention time.Duration) EventDispatcherOption {
+	return func(ed *eventDispatcher) {
+		if retention > 0 {
+			ed.retention = retention
+		}
+	}
+}
+
+// WithTransactionTimeout sets how long a transaction recording events may stay open, which is
+// twice the timeout of the context by default. A missing event is waited for that long, and the
+// event bus of a server starting receives the events of that long before it starts.
+func WithTransactionTimeout(timeout time.Duration) EventDispatcherOption {
+	return func(ed *eventDispatcher) {
+		if timeout > 0 {
+			ed.transactionTimeout = timeout
+		}
+	}
+}
+
+// WithDispatcherMaxAttempts sets how many times a subscription handles an event before it is
+// dead-lettered, which is 8 by default
+func WithDispatcherMaxAttempts(attempts int) EventDispatcherOption {
+	return func(ed *eventDispatcher) {
+		if attempts > 0 {
+			ed.maxAttempts = attempts
+		}
+	}
+}
+
+// WithDispatcherClock sets the clock the missing and failed events are waited for with, which
+// is time.Now by default
+func WithDispatcherClock(now func() time.Time) EventDispatcherOption {
+	return func(ed *eventDispatcher) {
+		ed.now = now
+	}
+}
+
+func NewEventDispatcher(outboxRepository domain.OutboxRepository, eventBus domain.AdEventBus, timeout time.Duration, options ...EventDispatcherOption) domain.EventDispatcher {
+	ed := &eventDispatcher{
+		outboxRepository:   outboxRepository,
+		eventBus:           eventBus,
+		contextTimeout:     timeout,
+		retention:          24 * time.Hour,
+		transactionTimeout: 2 * timeout,
+		maxAttempts:        8,
+		now:                time.Now,
+		owner:              newDispatcherOwner(),
+	}
+	for _, option := range options {
+		option(ed)
+	}
+	return ed
+}
+
+func newDispatcherOwner() string {
+	owner := make([]byte, 16)
+	if _, err := rand.Read(owner); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(owner)
+}
+
+func (ed *eventDispatcher) Subscribe(subscription string, handler domain.EventHandler) {
+	ed.subscriptions = append(ed.subscriptions, dispatcherSubscription{name: subscription, handler: handler})
+}
+
+// handleGaps handles the events of the gaps of the cursor which committed since, and stops
+// waiting for those whose transactions can no longer be open
+func (ed *eventDispatcher) handleGaps(c context.Context, cursor *domain.OutboxCheckpoint, handle func(event domain.AdEvent) error) error {
+	if len(cursor.Gaps) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, 0, len(cursor.Gaps))
+	for id := range cursor.Gaps {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+
+	ctx, cancel := context.WithTimeout(c, ed.contextTimeout)
+	events, err := ed.outboxRepository.FetchIDs(ctx, ids)
+	cancel()
+	if err != nil {
+		return toDomainError(err)
+	}
+
+	for _, event := range events {
+		if err := changeTimeToUTC(&event.At); err != nil {
+			return err
+		}
+		if err := handle(event); err != nil {
+			return err
+		}
+		delete(cursor.Gaps, event.ID)
+	}
+	for id, since := range cursor.Gaps {
+		if ed.now().Sub(since) >= ed.transactionTimeout {
+			log.Printf("Skipping the event %d of the outbox, which was not committed", id)
+			delete(cursor.Gaps, id)
+		}
+	}
+	return nil
+}
+
+// follow handles a batch of the events after the cursor in the order of their ids, keeping the
+// ids missing before them as gaps, and reports whether events may be left
+func (ed *eventDispatcher) follow(c context.Context, cursor *domain.OutboxCheckpoint, handle func(event domain.AdEvent) error) (bool, error) {
+	ctx, cancel := context.WithTimeout(c, ed.contextTimeout)
+	events, err := ed.outboxRepository.FetchAfter(ctx, cursor.LastID, outboxBatchSize)
+	cancel()
+	if err != nil {
+		return false, toDomainError(err)
+	}
+
+	for _, event := range events {
+		for id := cursor.LastID + 1; id < event.ID; id++ {
+			if len(cursor.Gaps) >= maxOutboxGaps {
+				log.Printf("Skipping the events %d to %d of the outbox, which are too many to wait for", id, event.ID-1)
+				break
+			}
+			if cursor.Gaps == nil {
+				cursor.Gaps = map[int64]time.Time{}
+			}
+			cursor.Gaps[id] = ed.now()
+		}
+		// The cursor moves past the gaps even if the event fails, since they are kept
+		cursor.LastID = event.ID - 1
+
+		if err := changeTimeToUTC(&event.At); err != nil {
+			return false, err
+		}
+		if err := handle(event); err != nil {
+			return false, err
+		}
+		cursor.LastID = event.ID
+	}
+	return len(events) == outboxBatchSize, nil
+}
+
+// relay publishes the events after the cursor on the event bus of the server
+func (ed *eventDispatcher) relay(c context.Context, cursor *domain.OutboxCheckpoint) {
+	publish := func(event domain.AdEvent) error {
+		ed.eventBus.Publish(event)
+		return nil
+	}
+
+	err := ed.handleGaps(c, cursor, publish)
+	for more := err == nil; more; {
+		more, err = ed.follow(c, cursor, publish)
+	}
+	if err != nil {
+		log.Println("Error created when relaying the outbox:", err.Error())
+	}
+}
+
+// retryDelay is the time before an event which failed attempts times is handled again, which
+// doubles from a second up to a minute
+func retryDelay(attempts int) time.Duration {
+	return min(time.Second<<(attempts-1), time.Minute)
+}
+
+// handler calls the handler of a subscription in the tenant of the event. An event which fails
+// is kept as the failure of the checkpoint until it is handled again, and is dead-lettered
+// once it failed maxAttempts times, so that it does not hold the subscription up for good.
+func (ed *eventDispatcher) handler(c context.Context, subscription dispatcherSubscription, checkpoint *domain.OutboxCheckpoint) func(event domain.AdEvent) error {
+	return func(event domain.AdEvent) error {
+		failure := checkpoint.Failure
+		if failure != nil && failure.EventID == event.ID && ed.now().Before(failure.RetryAt) {
+			return errNotDue
+		}
+
+		err := subscription.handler(domain.WithTenant(c, event.TenantID), event)
+		if err == nil {
+			if failure != nil && failure.EventID == event.ID {
+				checkpoint.Failure = nil
+			}
+			return nil
+		}
+
+		// An event interrupted by the server stopping is not counted as a failed attempt
+		if errors.Is(err, context.Canceled) {
+			return err
+		}
+		attempts := 1
+		if failure != nil && failure.EventID == event.ID {
+			attempts = failure.Attempts + 1
+		}
+		if attempts < ed.maxAttempts {
+			checkpoint.Failure = &domain.OutboxFailure{EventID: event.ID, Attempts: attempts, RetryAt: ed.now().Add(retryDelay(attempts))}
+			return err
+		}
+
+		lastError := err.Error()
+		if len(lastError) > maxDispatchErrorLength {
+			lastError = lastError[:maxDispatchErrorLength]
+		}
+		ctx, cancel := context.WithTimeout(c, ed.contextTimeout)
+		defer cancel()
+		if err := ed.outboxRepository.DeadLetter(ctx, subscription.name, event, lastError); err != nil {
+			return toDomainError(err)
+		}
+		log.Printf("Dead-lettered the event %d of the outbox for %s after %d attempts: %s", event.ID, subscription.name, attempts, lastError)
+		checkpoint.Failure = nil
+		return nil
+	}
+}
+
+// dispatch handles the events of a subscription, if no other server leases its checkpoint.
+// The checkpoint is advanced after each batch, which renews the lease, and the subscription
+// stops when another server took the lease over meanwhile.
+func (ed *eventDispatcher) dispatch(c context.Context, subscription dispatcherSubscription) {
+	ctx, cancel := context.WithTimeout(c, dispatchTimeout)
+	defer cancel()
+	lease := dispatchTimeout + 2*ed.contextTimeout
+
+	acquireCtx, cancelAcquire := context.WithTimeout(ctx, ed.contextTimeout)
+	checkpoint, acquired, err := ed.outboxRepository.Acquire(acquireCtx, subscription.name, ed.owner, lease)
+	cancelAcquire()
+	if err != nil {
+		log.Printf("Error created when leasing the checkpoint of %s: %s", subscription.name, toDomainError(err).Error())
+		return
+	}
+	if !acquired {
+		return
+	}
+
+	// advance saves the checkpoint, even when the dispatch timed out or the server stops
+	advance := func() bool {
+		advanceCtx, cancelAdvance := context.WithTimeout(context.WithoutCancel(c), ed.contextTimeout)
+		defer cancelAdvance()
+		if err := ed.outboxRepository.Advance(advanceCtx, subscription.name, ed.owner, checkpoint, lease); err != nil {
+			log.Printf("Error created when advancing the checkpoint of %s: %s", subscription.name, toDomainError(err).Error())
+			return false
+		}
+		return true
+	}
+
+	handle := ed.handler(ctx, subscription, &checkpoint)
+	err = ed.handleGaps(ctx, &checkpoint, handle)
+	for more := err == nil; more; {
+		if more, err = ed.follow(ctx, &checkpoint, handle); more && !advance() {
+			return
+		}
+	}
+	if err != nil && !errors.Is(err, errNotDue) {
+		log.Printf("Error created when dispatching the outbox to %s: %s", subscription.name, err.Error())
+	}
+	advance()
+}
+
+// Run relays the events recorded after it starts to the event bus of the server, and the
+// events after the checkpoint of each subscription to its handler. The relay starts at the
+// events recorded within the transaction timeout before, which may commit after it starts.
+// Events recorded longer than the retention ago which every subscription handled are trimmed.
+func (ed *eventDispatcher) Run(c context.Context, interval time.Duration) {
+	relayed := &domain.OutboxCheckpoint{LastID: -1}
+
+	var tick <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	trimmer := time.NewTicker(min(ed.retention, time.Hour))
+	defer trimmer.Stop()
+	for {
+		if relayed.LastID < 0 {
+			ctx, cancel := context.WithTimeout(c, ed.contextTimeout)
+			lastID, err := ed.outboxRepository.LastIDBefore(ctx, ed.transactionTimeout)
+			cancel()
+			if err != nil {
+				log.Println("Error created when reading the end of the outbox:", toDomainError(err).Error())
+			} else {
+				relayed.LastID = lastID
+			}
+		}
+		if relayed.LastID >= 0 {
+			ed.relay(c, relayed)
+		}
+		for _, subscription := range ed.subscriptions {
+			ed.dispatch(c, subscription)
+		}
+
+		select {
+		case <-c.Done():
+			return
+		case <-trimmer.C:
+			ctx, cancel := context.WithTimeout(c, ed.contextTimeout)
+			if err := ed.outboxRepository.Trim(ctx, ed.retention); err != nil {
+				log.Println("Error created when trimming the outbox:", toDomainError(err).Error())
+			}
+			cancel()
+		case <-tick:
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package usecase_test
+
+import (
+	"context"
+	"dcard-backend/domain"
+	"dcard-backend/domain/mocks"
+	"dcard-backend/usecase"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// Times of the outbox are recorded in Asia/Taipei, 8 hours ahead of UTC
+var testOutboxEvents = []domain.AdEvent{
+	{ID: 1, Type: domain.AdEventCreated, TenantID: "team-a", AdID: 1, At: "2024-01-01 08:00:00"},
+	{ID: 2, Type: domain.AdEventPaused, TenantID: "team-b", AdID: 2, At: "2024-01-01 08:00:01"},
+}
+
+// dispatchOnce runs the dispatcher once, since it stops as soon as c is done
+func dispatchOnce(dispatcher domain.EventDispatcher) {
+	c, cancel := context.WithCancel(context.Background())
+	cancel()
+	dispatcher.Run(c, 0)
+}
+
+// expectIdleRelay expects the relay to start after the event 9, which is the last one
+func expectIdleRelay(mockOutboxRepository *mocks.OutboxRepository) {
+	mockOutboxRepository.On("LastIDBefore", mock.Anything, 2*time.Second).Return(int64(9), nil)
+	mockOutboxRepository.On("FetchAfter", mock.Anything, int64(9), 500).Return([]domain.AdEvent{}, nil)
+}
+
+// expectLease expects the checkpoint of the subscription to be leased once, and sends the
+// checkpoint it is advanced to on saved
+func expectLease(mockOutboxRepository *mocks.OutboxRepository, subscription string, checkpoint domain.OutboxCheckpoint, saved chan<- domain.OutboxCheckpoint) {
+	mockOutboxRepository.On("Acquire", mock.Anything, subscription, mock.Anything, 62*time.Second).Return(checkpoint, true, nil).Once()
+	mockOutboxRepository.On("Advance", mock.Anything, subscription, mock.Anything, mock.Anything, 62*time.Second).Return(nil).Once().
+		Run(func(args mock.Arguments) { saved <- args.Get(3).(domain.OutboxCheckpoint) })
+}
+
+func TestDispatcherRun_Subscription_ShouldHandleEventsAfterCheckpointInTheirTenant(t *testing.T) {
+	saved := make(chan domain.OutboxCheckpoint, 1)
+	mockOutboxRepository := mocks.NewOutboxRepository(t)
+	expectIdleRelay(mockOutboxRepository)
+	expectLease(mockOutboxRepository, "webhooks", domain.OutboxCheckpoint{}, saved)
+	mockOutboxRepository.On("FetchAfter", mock.Anything, int64(0), 500).Return(testOutboxEvents, nil).Once()
+
+	handled := []string{}
+	testDispatcher := usecase.NewEventDispatcher(mockOutboxRepository, usecase.NewAdEventBus(), time.Second*1)
+	testDispatcher.Subscribe("webhooks", func(c context.Context, event domain.AdEvent) error {
+		tenantID, _ := domain.TenantFromContext(c)
+		handled = append(handled, tenantID+" "+event.At)
+		return nil
+	})
+	dispatchOnce(testDispatcher)
+
+	assert.Equal(t, []string{"team-a 2024-01-01T00:00:00Z", "team-b 2024-01-01T00:00:01Z"}, handled)
+	assert.Equal(t, domain.OutboxCheckpoint{LastID: 2}, <-saved)
+}
+
+func TestDispatcherRun_LeasedByAnotherServer_ShouldNotHandle(t *testing.T) {
+	mockOutboxRepository := mocks.NewOutboxRepository(t)
+	expectIdleRelay(mockOutboxRepository)
+	mockOutboxRepository.On("Acquire", mock.Anything, "webhooks", mock.Anything, 62*time.Second).Return(domain.OutboxCheckpoint{}, false, nil).Once()
+
+	testDispatcher := usecase.NewEventDispatcher(mockOutboxRepository, usecase.NewAdEventBus(), time.Second*1)
+	testDispatcher.Subscribe("webhooks", func(c context.Context, event domain.AdEvent) error {
+		t.Error("the subscription should not be handled")
+		return nil
+	})
+	dispatchOnce(testDispatcher)
+}
+
+func TestDispatcherRun_HandlerFailed_ShouldRetryEventLater(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	saved := make(chan domain.OutboxCheckpoint, 1)
+	mockOutboxRepository := mocks.NewOutboxRepository(t)
+	expectIdleRelay(mockOutboxRepository)
+	expectLease(mockOutboxRepository, "stream", domain.OutboxCheckpoint{}, saved)
+	mockOutboxRepository.On("FetchAfter", mock.Anything, int64(0), 500).Return(testOutboxEvents, nil).Once()
+
+	handled := []int64{}
+	failing := true
+	testDispatcher := usecase.NewEventDispatcher(mockOutboxRepository, usecase.NewAdEventBus(), time.Second*1,
+		usecase.WithDispatcherClock(func() time.Time { return now }))
+	testDispatcher.Subscribe("stream", func(c context.Context, event domain.AdEvent) error {
+		handled = append(handled, event.ID)
+		if event.ID == 2 && failing {
+			return errors.New("unavailable")
+		}
+		return nil
+	})
+
+	dispatchOnce(testDispatcher)
+	checkpoint := <-saved
+	assert.Equal(t, domain.OutboxCheckpoint{LastID: 1,
+		Failure: &domain.OutboxFailure{EventID: 2, Attempts: 1, RetryAt: now.Add(time.Second)}}, checkpoint)
+
+	// The event is not handled again before it is due
+	expectLease(mockOutboxRepository, "stream", checkpoint, saved)
+	mockOutboxRepository.On("FetchAfter", mock.Anything, int64(1), 500).Return(testOutboxEvents[1:], nil).Once()
+	dispatchOnce(testDispatcher)
+	checkpoint = <-saved
+	assert.Equal(t, int64(1), checkpoint.LastID)
+
+	now = now.Add(time.Second)
+	failing = false
+	expectLease(mockOutboxRepository, "stream", checkpoint, saved)
+	mockOutboxRepository.On("FetchAfter", mock.Anything, int64(1), 500).Return(testOutboxEvents[1:], nil).Once()
+	dispatchOnce(testDispatcher)
+
+	assert.Equal(t, domain.OutboxCheckpoint{LastID: 2}, <-saved)
+	assert.Equal(t, []int64{1, 2, 2}, handled)
+}
+
+func TestDispatcherRun_HandlerFailedMaxAttempts_ShouldDeadLetterEventAndMoveOn(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	saved := make(chan domain.OutboxCheckpoint, 1)
+	mockOutboxRepository := mocks.NewOutboxRepository(t)
+	expectIdleRelay(mockOutboxRepository)
+	expectLease(mockOutboxRepository, "stream", domain.OutboxCheckpoint{LastID: 1,
+		Failure: &domain.OutboxFailure{EventID: 2, Attempts: 2, RetryAt: now}}, saved)
+	mockOutboxRepository.On("FetchAfter", mock.Anything, int64(1), 500).Return(testOutboxEvents[1:], nil).Once()
+	mockOutboxRepository.On("DeadLetter", mock.Anything, "stream", mock.MatchedBy(func(event domain.AdEvent) bool {
+		return event.ID == 2
+	}), "unavailable").Return(nil).Once()
+
+	testDispatcher := usecase.NewEventDispatcher(mockOutboxRepository, usecase.NewAdEventBus(), time.Second*1,
+		usecase.WithDispatcherClock(func() time.Time { return now }),
+		usecase.WithDispatcherMaxAttempts(3))
+	testDispatcher.Subscribe("stream", func(c context.Context, event domain.AdEvent) error {
+		return errors.New("unavailable")
+	})
+	dispatchOnce(testDispatcher)
+
+	assert.Equal(t, domain.OutboxCheckpoint{LastID: 2}, <-saved)
+}
+
+func TestDispatcherRun_MissingEvent_ShouldHandleItOnceCommitted(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	late := domain.AdEvent{ID: 2, Type: domain.AdEventPaused, TenantID: "team-b", AdID: 2, At: "2024-01-01 08:00:01"}
+	saved := make(chan domain.OutboxCheckpoint, 1)
+	mockOutboxRepository := mocks.NewOutboxRepository(t)
+	expectIdleRelay(mockOutboxRepository)
+	expectLease(mockOutboxRepository, "webhooks", domain.OutboxCheckpoint{}, saved)
+	mockOutboxRepository.On("FetchAfter", mock.Anything, int64(0), 500).Return([]domain.AdEvent{
+		testOutboxEvents[0],
+		{ID: 3, Type: domain.AdEventDeleted, TenantID: "team-a", AdID: 1, At: "2024-01-01 08:00:02"},
+	}, nil).Once()
+
+	handled := []int64{}
+	testDispatcher := usecase.NewEventDispatcher(mockOutboxRepository, usecase.NewAdEventBus(), time.Second*1,
+		usecase.WithDispatcherClock(func() time.Time { return now }))
+	testDispatcher.Subscribe("webhooks", func(c context.Context, event domain.AdEvent) error {
+		handled = append(handled, event.ID)
+		return nil
+	})
+
+	// Event 2 is not committed yet, and is kept as a gap instead of holding the others up
+	dispatchOnce(testDispatcher)
+	checkpoint := <-saved
+	assert.Equal(t, domain.OutboxCheckpoint{LastID: 3, Gaps: map[int64]time.Time{2: now}}, checkpoint)
+
+	// It commits after the events recorded after it, while it is still waited for
+	now = now.Add(time.Second)
+	expectLease(mockOutboxRepository, "webhooks", checkpoint, saved)
+	mockOutboxRepository.On("FetchIDs", mock.Anything, []int64{2}).Return([]domain.AdEvent{late}, nil).Once()
+	mockOutboxRepository.On("FetchAfter", mock.Anything, int64(3), 500).Return([]domain.AdEvent{}, nil).Once()
+	dispatchOnce(testDispatcher)
+
+	assert.Equal(t, domain.OutboxCheckpoint{LastID: 3, Gaps: map[int64]time.Time{}}, <-saved)
+	assert.Equal(t, []int64{1, 3, 2}, handled)
+}
+
+func TestDispatcherRun_MissingEventRolledBack_ShouldStopWaitingAfterTransactionTimeout(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	saved := make(chan domain.OutboxCheckpoint, 1)
+	mockOutboxRepository := mocks.NewOutboxRepository(t)
+	mockOutboxRepository.On("LastIDBefore", mock.Anything, 10*time.Second).Return(int64(9), nil)
+	mockOutboxRepository.On("FetchAfter", mock.Anything, int64(9), 500).Return([]domain.AdEvent{}, nil)
+	expectLease(mockOutboxRepository, "webhooks", domain.OutboxCheckpoint{LastID: 3, Gaps: map[int64]time.Time{2: now}}, saved)
+	mockOutboxRepository.On("FetchIDs", mock.Anything, []int64{2}).Return([]domain.AdEvent{}, nil).Once()
+	mockOutboxRepository.On("FetchAfter", mock.Anything, int64(3), 500).Return([]domain.AdEvent{}, nil).Once()
+
+	now = now.Add(10 * time.Second)
+	testDispatcher := usecase.NewEventDispatcher(mockOutboxRepository, usecase.NewAdEventBus(), time.Second*1,
+		usecase.WithDispatcherClock(func() time.Time { return now }),
+		usecase.WithTransactionTimeout(10*time.Second))
+	testDispatcher.Subscribe("webhooks", func(c context.Context, event domain.AdEvent) error {
+		t.Error("no event should be handled")
+		return nil
+	})
+	dispatchOnce(testDispatcher)
+
+	assert.Equal(t, domain.OutboxCheckpoint{LastID: 3, Gaps: map[int64]time.Time{}}, <-saved)
+}
+
+func TestDispatcherRun_EventRecorded_ShouldRelayToEventBus(t *testing.T) {
+	mockOutboxRepository := mocks.NewOutboxRepository(t)
+	// The relay starts before the events which may still commit when the server starts
+	mockOutboxRepository.On("LastIDBefore", mock.Anything, 2*time.Second).Return(int64(0), nil).Once()
+	mockOutboxRepository.On("FetchAfter", mock.Anything, int64(0), 500).Return(testOutboxEvents, nil).Once()
+
+	eventBus := usecase.NewAdEventBus()
+	relayed := []domain.AdEvent{}
+	eventBus.Subscribe(func(event domain.AdEvent) { relayed = append(relayed, event) })
+	dispatchOnce(usecase.NewEventDispatcher(mockOutboxRepository, eventBus, time.Second*1))
+
+	assert.Equal(t, []domain.AdEvent{
+		{ID: 1, Type: domain.AdEventCreated, TenantID: "team-a", AdID: 1, At: "2024-01-01T00:00:00Z"},
+		{ID: 2, Type: domain.AdEventPaused, TenantID: "team-b", AdID: 2, At: "2024-01-01T00:00:01Z"},
+	}, relayed)
+}
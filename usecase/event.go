@@ -0,0 +1,46 @@
+package usecase
+
+import (
+	"dcard-backend/domain"
+	"sync"
+)
+
+type adEventBus struct {
+	mutex       sync.RWMutex
+	subscribers map[int]domain.AdEventHandler
+	next        int
+}
+
+func NewAdEventBus() domain.AdEventBus {
+	return &adEventBus{
+		subscribers: map[int]domain.AdEventHandler{},
+	}
+}
+
+func (eb *adEventBus) Publish(event domain.AdEvent) {
+	eb.mutex.RLock()
+	handlers := make([]domain.AdEventHandler, 0, len(eb.subscribers))
+	for _, handler := range eb.subscribers {
+		handlers = append(handlers, handler)
+	}
+	eb.mutex.RUnlock()
+
+	// Handlers are called without the lock, so that they can subscribe and unsubscribe
+	for _, handler := range handlers {
+		handler(event)
+	}
+}
+
+func (eb *adEventBus) Subscribe(handler domain.AdEventHandler) func() {
+	eb.mutex.Lock()
+	defer eb.mutex.Unlock()
+
+	id := eb.next
+	eb.next++
+	eb.subscribers[id] = handler
+	return func() {
+		eb.mutex.Lock()
+		defer eb.mutex.Unlock()
+		delete(eb.subscribers, id)
+	}
+}
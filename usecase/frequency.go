@@ -0,0 +1,84 @@
+package usecase
+
+import (
+	"context"
+	"dcard-backend/domain"
+	"fmt"
+	"log"
+	"time"
+)
+
+// maxFrequencyCapWindow bounds how long a count is kept for a user
+const maxFrequencyCapWindow = 30 * 24 * time.Hour
+
+// WithFrequencyRepository sets where the ads served to each user are counted. Without it,
+// frequency caps are not applied.
+func WithFrequencyRepository(frequencyRepository domain.FrequencyRepository) AdUsecaseOption {
+	return func(au *adUsecase) {
+		au.frequencyRepository = frequencyRepository
+	}
+}
+
+func validateFrequencyCap(frequencyCap *domain.FrequencyCap) error {
+	if frequencyCap == nil {
+		return nil
+	}
+
+	if frequencyCap.Count < 1 {
+		return fmt.Errorf("%w: frequencyCap count should be a positive integer", domain.ErrBadParamInput)
+	}
+	window, err := time.ParseDuration(frequencyCap.Window)
+	if err != nil || window < time.Minute || window > maxFrequencyCapWindow {
+		return fmt.Errorf("%w: frequencyCap window should be a duration between 1m and %s, e.g. 24h",
+			domain.ErrBadParamInput, maxFrequencyCapWindow)
+	}
+	return nil
+}
+
+// frequencyCaps returns the caps of the ads having one, by the id of the ad
+func frequencyCaps(ads []domain.Ad) map[int64]domain.FrequencyCap {
+	caps := map[int64]domain.FrequencyCap{}
+	for _, ad := range ads {
+		if ad.FrequencyCap != nil {
+			caps[ad.ID] = *ad.FrequencyCap
+		}
+	}
+	return caps
+}
+
+// servedCounts returns how many times the capped ads were served to the user within their
+// windows, which is empty for anonymous users
+func (au *adUsecase) servedCounts(c context.Context, userID string, caps map[int64]domain.FrequencyCap) (map[int64]int, error) {
+	if au.frequencyRepository == nil || userID == "" || len(caps) == 0 {
+		return map[int64]int{}, nil
+	}
+
+	adIDs := make([]int64, 0, len(caps))
+	for adID := range caps {
+		adIDs = append(adIDs, adID)
+	}
+	counts, err := au.frequencyRepository.GetCounts(c, userID, adIDs)
+	return counts, toDomainError(err)
+}
+
+// countServed counts the capped ads served to the user. A failure only loses the count, so
+// it is logged instead of failing the request.
+func (au *adUsecase) countServed(c context.Context, userID string, ads []domain.Ad, caps map[int64]domain.FrequencyCap) {
+	if au.frequencyRepository == nil || userID == "" {
+		return
+	}
+
+	for _, ad := range ads {
+		frequencyCap, ok := caps[ad.ID]
+		if !ok {
+			continue
+		}
+		window, err := time.ParseDuration(frequencyCap.Window)
+		if err != nil {
+			continue
+		}
+		if err := au.frequencyRepository.Increment(c, userID, ad.ID, window); err != nil {
+			log.Printf("Error created when counting ad %d served to user %q: %s", ad.ID, userID, err.Error())
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package usecase_test
+
+import (
+	"context"
+	"dcard-backend/domain"
+	"dcard-backend/domain/mocks"
+	"dcard-backend/usecase"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCreate_InvalidFrequencyCap_ShouldReturnErrBadParamInput(t *testing.T) {
+	frequencyCaps := []*domain.FrequencyCap{
+		{Count: 0, Window: "24h"},
+		{Count: 3, Window: "1 day"},
+		{Count: 3, Window: "30s"},
+		{Count: 3, Window: "8760h"},
+	}
+
+	for _, frequencyCap := range frequencyCaps {
+		mockAd := domain.Ad{
+			Title:        "Test AD",
+			StartAt:      "2024-01-01T00:00:00.000Z",
+			EndAt:        "2025-01-01T00:00:00.000Z",
+			FrequencyCap: frequencyCap,
+		}
+		mockAdRepository := mocks.NewAdRepository(t)
+
+		testAdUsecase := usecase.NewAdUsecase(mockAdRepository, time.Second*1)
+
+		err := testAdUsecase.Create(context.Background(), &mockAd)
+		assert.ErrorIs(t, err, domain.ErrBadParamInput)
+	}
+}
+
+func TestGetByCondition_UserReachedFrequencyCap_ShouldExcludeAdBeforePagination(t *testing.T) {
+	dailyCap := &domain.FrequencyCap{Count: 2, Window: "24h"}
+	ads := []domain.Ad{
+		{ID: 1, Title: "AD 1", EndAt: "2025-01-01 08:00:00", FrequencyCap: dailyCap},
+		{ID: 2, Title: "AD 2", EndAt: "2025-01-01 08:00:00", FrequencyCap: dailyCap},
+		{ID: 3, Title: "AD 3", EndAt: "2025-01-01 08:00:00"},
+		{ID: 4, Title: "AD 4", EndAt: "2025-01-01 08:00:00", FrequencyCap: dailyCap},
+	}
+	mockAdRepository := mocks.NewAdRepository(t)
+	mockAdRepository.On("GetByCondition", mock.Anything, candidateQuery(domain.SortEndAt, 3, nil)).Return(ads, nil).Once()
+
+	mockFrequencyRepository := mocks.NewFrequencyRepository(t)
+	mockFrequencyRepository.On("GetCounts", mock.Anything, "user-1", mock.MatchedBy(func(adIDs []int64) bool {
+		return assert.ElementsMatch(t, []int64{1, 2, 4}, adIDs)
+	})).Return(map[int64]int{1: 2, 2: 1}, nil).Once()
+	mockFrequencyRepository.On("Increment", mock.Anything, "user-1", int64(4), 24*time.Hour).Return(nil).Once()
+
+	testAdUsecase := usecase.NewAdUsecase(mockAdRepository, time.Second*1, usecase.WithFrequencyRepository(mockFrequencyRepository))
+
+	served, err := testAdUsecase.GetByCondition(context.Background(), map[string][]string{
+		"offset": {"1"},
+		"limit":  {"2"},
+		"userId": {"user-1"},
+		"sort":   {"endAt"},
+	})
+
+	assert.NoError(t, err)
+	if assert.Len(t, served, 2) {
+		assert.Equal(t, "AD 3", served[0].Title)
+		assert.Equal(t, "AD 4", served[1].Title)
+	}
+}
+
+func TestGetByCondition_AnonymousUser_ShouldNotApplyFrequencyCap(t *testing.T) {
+	ads := []domain.Ad{
+		{ID: 1, Title: "AD 1", EndAt: "2025-01-01 08:00:00", FrequencyCap: &domain.FrequencyCap{Count: 1, Window: "24h"}},
+	}
+	mockAdRepository := mocks.NewAdRepository(t)
+	mockAdRepository.On("GetByCondition", mock.Anything, candidateQuery(domain.SortPriority, 5, nil)).Return(ads, nil).Once()
+
+	testAdUsecase := usecase.NewAdUsecase(mockAdRepository, time.Second*1, usecase.WithFrequencyRepository(mocks.NewFrequencyRepository(t)))
+
+	served, err := testAdUsecase.GetByCondition(context.Background(), map[string][]string{"offset": {"0"}})
+
+	assert.NoError(t, err)
+	assert.Len(t, served, 1)
+}
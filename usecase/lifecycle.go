@@ -0,0 +1,350 @@
+package usecase
+
+import (
+	"container/heap"
+	"context"
+	"dcard-backend/domain"
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+// lifecycleRetryDelay is how long an ad whose lifecycle could not be read or changed, or a
+// failed reload of every ad, waits before it is tried again
+const lifecycleRetryDelay = time.Minute
+
+// lifecyclePageSize is the number of ads read by each query of a reload, so that a reload never
+// holds every ad of every tenant in memory at once besides their boundaries
+const lifecyclePageSize = 1000
+
+// Clock tells the time and sets timers, so that tests can move the time by hand
+type Clock interface {
+	Now() time.Time
+	// NewTimer sends the time on the channel after d, unless stop is called before
+	NewTimer(d time.Duration) (c <-chan time.Time, stop func() bool)
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) NewTimer(d time.Duration) (<-chan time.Time, func() bool) {
+	timer := time.NewTimer(d)
+	return timer.C, timer.Stop
+}
+
+type lifecycleKey struct {
+	tenantID string
+	id       int64
+}
+
+// boundary is the time the lifecycle of an ad changes next
+type boundary struct {
+	at  time.Time
+	key lifecycleKey
+}
+
+type boundaryHeap []boundary
+
+func (bh boundaryHeap) Len() int           { return len(bh) }
+func (bh boundaryHeap) Less(i, j int) bool { return bh[i].at.Before(bh[j].at) }
+func (bh boundaryHeap) Swap(i, j int)      { bh[i], bh[j] = bh[j], bh[i] }
+
+func (bh *boundaryHeap) Push(x any) {
+	*bh = append(*bh, x.(boundary))
+}
+
+func (bh *boundaryHeap) Pop() any {
+	old := *bh
+	last := old[len(old)-1]
+	*bh = old[:len(old)-1]
+	return last
+}
+
+// lifecycleSchedule is a min-heap of the next boundary of every ad. The boundaries of ads
+// which moved or were removed are left in the heap, and skipped since they are not in next.
+type lifecycleSchedule struct {
+	boundaries boundaryHeap
+	next       map[lifecycleKey]time.Time
+}
+
+func newLifecycleSchedule() *lifecycleSchedule {
+	return &lifecycleSchedule{next: map[lifecycleKey]time.Time{}}
+}
+
+func (ls *lifecycleSchedule) set(key lifecycleKey, at time.Time) {
+	if next, ok := ls.next[key]; ok && next.Equal(at) {
+		return
+	}
+	ls.next[key] = at
+	heap.Push(&ls.boundaries, boundary{at: at, key: key})
+}
+
+func (ls *lifecycleSchedule) remove(key lifecycleKey) {
+	delete(ls.next, key)
+}
+
+// peek returns the earliest boundary, and false when there is none
+func (ls *lifecycleSchedule) peek() (boundary, bool) {
+	for ls.boundaries.Len() > 0 {
+		first := ls.boundaries[0]
+		if next, ok := ls.next[first.key]; ok && next.Equal(first.at) {
+			return first, true
+		}
+		heap.Pop(&ls.boundaries)
+	}
+	return boundary{}, false
+}
+
+// popDue removes and returns the ads whose boundary is not after now
+func (ls *lifecycleSchedule) popDue(now time.Time) []lifecycleKey {
+	var keys []lifecycleKey
+	for {
+		first, ok := ls.peek()
+		if !ok || first.at.After(now) {
+			return keys
+		}
+		heap.Pop(&ls.boundaries)
+		delete(ls.next, first.key)
+		keys = append(keys, first.key)
+	}
+}
+
+// parseStoredTime parses a time of an ad as it is stored in the database, in Asia/Taipei
+func parseStoredTime(value string) (time.Time, error) {
+	loc, err := time.LoadLocation("Asia/Taipei")
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.ParseInLocation("2006-01-02 15:04:05", value, loc)
+}
+
+// lifecycleAt returns the lifecycle of an ad at now and when it changes next, which is zero
+// once the ad expired. An ad is served until the last second of its end, since NOW() is
+// compared to end_at in seconds, so it expires a second after end_at.
+func lifecycleAt(startAt time.Time, endAt time.Time, now time.Time) (domain.AdLifecycle, time.Time) {
+	expireAt := endAt.Add(time.Second)
+	switch {
+	case now.Before(startAt):
+		return domain.AdScheduled, startAt
+	case now.Before(expireAt):
+		return domain.AdLive, expireAt
+	default:
+		return domain.AdExpired, time.Time{}
+	}
+}
+
+type adLifecycleUsecase struct {
+	adRepository   domain.AdRepository
+	eventBus       domain.AdEventBus
+	contextTimeout time.Duration
+	clock          Clock
+	invalidators   []domain.AdCacheInvalidator
+}
+
+type AdLifecycleUsecaseOption func(*adLifecycleUsecase)
+
+// WithLifecycleClock sets the clock the boundaries of ads are waited for with, which is the
+// system clock by default
+func WithLifecycleClock(clock Clock) AdLifecycleUsecaseOption {
+	return func(lu *adLifecycleUsecase) {
+		lu.clock = clock
+	}
+}
+
+// WithLifecycleInvalidators sets the caches which drop an ad at its start and end, at the
+// moment the ad changes on every server instead of when the event of the change is relayed
+func WithLifecycleInvalidators(invalidators ...domain.AdCacheInvalidator) AdLifecycleUsecaseOption {
+	return func(lu *adLifecycleUsecase) {
+		lu.invalidators = append(lu.invalidators, invalidators...)
+	}
+}
+
+func NewAdLifecycleUsecase(adRepository domain.AdRepository, eventBus domain.AdEventBus, timeout time.Duration, options ...AdLifecycleUsecaseOption) domain.AdLifecycleUsecase {
+	lu := &adLifecycleUsecase{
+		adRepository:   adRepository,
+		eventBus:       eventBus,
+		contextTimeout: timeout,
+		clock:          systemClock{},
+	}
+	for _, option := range options {
+		option(lu)
+	}
+	return lu
+}
+
+// Run loads every ad, and then sleeps until the earliest boundary, when the ads of the
+// boundary are dropped from the caches and checked. Ads created, updated or restored through the event bus are checked
+// as soon as they are published, since their times may have moved.
+func (lu *adLifecycleUsecase) Run(c context.Context, resyncInterval time.Duration) {
+	// Changed ads are collected by the handler, which must not block the publisher, and are
+	// checked by the loop when it is woken up
+	var mutex sync.Mutex
+	changed := map[lifecycleKey]bool{}
+	wake := make(chan struct{}, 1)
+	unsubscribe := lu.eventBus.Subscribe(func(event domain.AdEvent) {
+		switch event.Type {
+		case domain.AdEventCreated, domain.AdEventUpdated, domain.AdEventRestored:
+		default:
+			return
+		}
+		mutex.Lock()
+		changed[lifecycleKey{tenantID: event.TenantID, id: event.AdID}] = true
+		mutex.Unlock()
+		select {
+		case wake <- struct{}{}:
+		default:
+		}
+	})
+	defer unsubscribe()
+
+	schedule := newLifecycleSchedule()
+	resyncAt := lu.clock.Now()
+	for {
+		now := lu.clock.Now()
+		if !resyncAt.IsZero() && !resyncAt.After(now) {
+			resyncAt = time.Time{}
+			if err := lu.resync(c, schedule); err != nil {
+				log.Println("Error created when loading the lifecycles of ads:", err.Error())
+				resyncAt = now.Add(lifecycleRetryDelay)
+			} else if resyncInterval > 0 {
+				resyncAt = now.Add(resyncInterval)
+			}
+		}
+		for _, key := range schedule.popDue(now) {
+			lu.invalidate(key)
+			lu.check(c, schedule, key)
+		}
+
+		// The loop sleeps until the earliest boundary or reload, or until an ad changes
+		wakeAt := resyncAt
+		if first, ok := schedule.peek(); ok && (wakeAt.IsZero() || first.at.Before(wakeAt)) {
+			wakeAt = first.at
+		}
+		var timer <-chan time.Time
+		stop := func() bool { return false }
+		if !wakeAt.IsZero() {
+			timer, stop = lu.clock.NewTimer(wakeAt.Sub(now))
+		}
+
+		select {
+		case <-c.Done():
+			stop()
+			return
+		case <-timer:
+		case <-wake:
+			stop()
+			mutex.Lock()
+			keys := changed
+			changed = map[lifecycleKey]bool{}
+			mutex.Unlock()
+			for key := range keys {
+				lu.check(c, schedule, key)
+			}
+		}
+	}
+}
+
+// resync replaces the schedule with the boundaries of every ad, read a page at a time. The
+// schedule is kept when a page fails, so that ads are still checked until the next reload.
+func (lu *adLifecycleUsecase) resync(c context.Context, schedule *lifecycleSchedule) error {
+	loaded := newLifecycleSchedule()
+	var afterID int64
+	for {
+		states, err := lu.fetchLifecycles(c, afterID)
+		if err != nil {
+			return err
+		}
+		for _, state := range states {
+			lu.apply(c, loaded, state)
+		}
+		if len(states) < lifecyclePageSize {
+			*schedule = *loaded
+			return nil
+		}
+		afterID = states[len(states)-1].ID
+	}
+}
+
+// fetchLifecycles reads a page of a reload, each with a timeout of its own
+func (lu *adLifecycleUsecase) fetchLifecycles(c context.Context, afterID int64) ([]domain.AdLifecycleState, error) {
+	ctx, cancel := context.WithTimeout(c, lu.contextTimeout)
+	defer cancel()
+
+	states, err := lu.adRepository.FetchLifecycles(ctx, afterID, lifecyclePageSize)
+	return states, toDomainError(err)
+}
+
+// invalidate drops an ad from the caches at its boundary. The ad may be changed by another
+// server, so the caches are dropped whether or not this server records the change.
+func (lu *adLifecycleUsecase) invalidate(key lifecycleKey) {
+	for _, invalidator := range lu.invalidators {
+		invalidator.Invalidate(key.tenantID, key.id)
+	}
+}
+
+// check reads the lifecycle of an ad again and applies it, and drops ads which are deleted
+func (lu *adLifecycleUsecase) check(c context.Context, schedule *lifecycleSchedule, key lifecycleKey) {
+	ctx, cancel := context.WithTimeout(domain.WithTenant(c, key.tenantID), lu.contextTimeout)
+	defer cancel()
+
+	state, err := lu.adRepository.GetLifecycle(ctx, key.id)
+	if errors.Is(err, domain.ErrNotFound) {
+		schedule.remove(key)
+		return
+	}
+	if err != nil {
+		log.Printf("Error created when reading the lifecycle of ad %d: %s", key.id, toDomainError(err).Error())
+		schedule.set(key, lu.clock.Now().Add(lifecycleRetryDelay))
+		return
+	}
+	lu.apply(c, schedule, state)
+}
+
+// apply changes the lifecycle of an ad to the one at the current time, and schedules its next
+// boundary
+func (lu *adLifecycleUsecase) apply(c context.Context, schedule *lifecycleSchedule, state domain.AdLifecycleState) {
+	key := lifecycleKey{tenantID: state.TenantID, id: state.ID}
+	startAt, err := parseStoredTime(state.StartAt)
+	if err != nil {
+		log.Printf("Error created when parsing the start of ad %d: %s", state.ID, err.Error())
+		schedule.remove(key)
+		return
+	}
+	endAt, err := parseStoredTime(state.EndAt)
+	if err != nil {
+		log.Printf("Error created when parsing the end of ad %d: %s", state.ID, err.Error())
+		schedule.remove(key)
+		return
+	}
+
+	now := lu.clock.Now()
+	lifecycle, next := lifecycleAt(startAt, endAt, now)
+	if lifecycle != state.Lifecycle {
+		ctx, cancel := context.WithTimeout(domain.WithTenant(c, state.TenantID), lu.contextTimeout)
+		// The start or the end is recorded in the outbox along with the change, unless another
+		// server changed the ad in the meantime
+		changed, err := lu.adRepository.UpdateLifecycle(ctx, state.ID, state.Lifecycle, lifecycle)
+		cancel()
+		if err != nil {
+			log.Printf("Error created when changing the lifecycle of ad %d: %s", state.ID, toDomainError(err).Error())
+			schedule.set(key, now.Add(lifecycleRetryDelay))
+			return
+		}
+		if !changed {
+			// The ad was changed or deleted since it was read, so its times may have moved too.
+			// It is read again at once instead of being scheduled by the times read before.
+			schedule.set(key, now)
+			return
+		}
+	}
+
+	if next.IsZero() {
+		schedule.remove(key)
+		return
+	}
+	schedule.set(key, next)
+}
@@ -0,0 +1,202 @@
+package usecase_test
+
+import (
+	"context"
+	"dcard-backend/domain"
+	"dcard-backend/domain/mocks"
+	"dcard-backend/usecase"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type fakeTimer struct {
+	at      time.Time
+	c       chan time.Time
+	stopped bool
+}
+
+// fakeClock only moves when Advance is called, and reports the duration of every timer it
+// sets on waits, so that tests know when the worker is asleep
+type fakeClock struct {
+	mutex  sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+	waits  chan time.Duration
+}
+
+func newFakeClock(now time.Time) *fakeClock {
+	return &fakeClock{now: now, waits: make(chan time.Duration, 16)}
+}
+
+func (fc *fakeClock) Now() time.Time {
+	fc.mutex.Lock()
+	defer fc.mutex.Unlock()
+	return fc.now
+}
+
+func (fc *fakeClock) NewTimer(d time.Duration) (<-chan time.Time, func() bool) {
+	fc.mutex.Lock()
+	timer := &fakeTimer{at: fc.now.Add(d), c: make(chan time.Time, 1)}
+	fc.timers = append(fc.timers, timer)
+	fc.mutex.Unlock()
+
+	fc.waits <- d
+	return timer.c, func() bool {
+		fc.mutex.Lock()
+		defer fc.mutex.Unlock()
+		timer.stopped = true
+		return true
+	}
+}
+
+func (fc *fakeClock) Advance(d time.Duration) {
+	fc.mutex.Lock()
+	defer fc.mutex.Unlock()
+	fc.now = fc.now.Add(d)
+	for _, timer := range fc.timers {
+		if !timer.stopped && !timer.at.After(fc.now) {
+			timer.stopped = true
+			timer.c <- fc.now
+		}
+	}
+}
+
+// Times of ads are read from the database in Asia/Taipei, 8 hours ahead of UTC
+var (
+	testLifecycleNow = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	testLifecycleAd  = domain.AdLifecycleState{TenantID: "team-a", ID: 1, StartAt: "2024-01-01 09:00:00", EndAt: "2024-01-01 10:00:00"}
+)
+
+// runLifecycle runs the worker until the test ends
+func runLifecycle(t *testing.T, adRepository domain.AdRepository, eventBus domain.AdEventBus, clock usecase.Clock, options ...usecase.AdLifecycleUsecaseOption) {
+	c, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	options = append([]usecase.AdLifecycleUsecaseOption{usecase.WithLifecycleClock(clock)}, options...)
+	go func() {
+		defer close(stopped)
+		usecase.NewAdLifecycleUsecase(adRepository, eventBus, time.Second*1, options...).Run(c, 0)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-stopped
+	})
+}
+
+// updatedTo sends the lifecycle an ad is changed to on updated
+func updatedTo(updated chan<- domain.AdLifecycle) func(mock.Arguments) {
+	return func(args mock.Arguments) {
+		updated <- args.Get(3).(domain.AdLifecycle)
+	}
+}
+
+func withLifecycle(state domain.AdLifecycleState, lifecycle domain.AdLifecycle) domain.AdLifecycleState {
+	state.Lifecycle = lifecycle
+	return state
+}
+
+func TestLifecycleRun_ScheduledAd_ShouldStartAndExpireAtItsTimes(t *testing.T) {
+	mockAdRepository := mocks.NewAdRepository(t)
+	mockAdRepository.On("FetchLifecycles", mock.Anything, int64(0), 1000).Return([]domain.AdLifecycleState{withLifecycle(testLifecycleAd, domain.AdScheduled)}, nil).Once()
+	mockAdRepository.On("GetLifecycle", mock.Anything, int64(1)).Return(withLifecycle(testLifecycleAd, domain.AdScheduled), nil).Once()
+	updated := make(chan domain.AdLifecycle, 2)
+	mockAdRepository.On("UpdateLifecycle", mock.Anything, int64(1), domain.AdScheduled, domain.AdLive).Return(true, nil).Once().Run(updatedTo(updated))
+	mockAdRepository.On("GetLifecycle", mock.Anything, int64(1)).Return(withLifecycle(testLifecycleAd, domain.AdLive), nil).Once()
+	mockAdRepository.On("UpdateLifecycle", mock.Anything, int64(1), domain.AdLive, domain.AdExpired).Return(true, nil).Once().Run(updatedTo(updated))
+
+	clock := newFakeClock(testLifecycleNow)
+	runLifecycle(t, mockAdRepository, usecase.NewAdEventBus(), clock)
+
+	assert.Equal(t, time.Hour, <-clock.waits)
+	clock.Advance(time.Hour)
+	assert.Equal(t, domain.AdLive, <-updated)
+
+	// The ad is served until the last second of its end
+	assert.Equal(t, time.Hour+time.Second, <-clock.waits)
+	clock.Advance(time.Hour + time.Second)
+	assert.Equal(t, domain.AdExpired, <-updated)
+}
+
+func TestLifecycleRun_ScheduledAd_ShouldInvalidateCachesAtItsStartAndEnd(t *testing.T) {
+	mockAdRepository := mocks.NewAdRepository(t)
+	mockAdRepository.On("FetchLifecycles", mock.Anything, int64(0), 1000).Return([]domain.AdLifecycleState{withLifecycle(testLifecycleAd, domain.AdScheduled)}, nil).Once()
+	// Another server records both changes, and the caches are still dropped on this one
+	mockAdRepository.On("GetLifecycle", mock.Anything, int64(1)).Return(withLifecycle(testLifecycleAd, domain.AdLive), nil).Once()
+	mockAdRepository.On("GetLifecycle", mock.Anything, int64(1)).Return(withLifecycle(testLifecycleAd, domain.AdExpired), nil).Once()
+	invalidated := make(chan struct{}, 2)
+	mockAdCacheInvalidator := mocks.NewAdCacheInvalidator(t)
+	mockAdCacheInvalidator.On("Invalidate", "team-a", int64(1)).Return().Twice().
+		Run(func(mock.Arguments) { invalidated <- struct{}{} })
+
+	clock := newFakeClock(testLifecycleNow)
+	runLifecycle(t, mockAdRepository, usecase.NewAdEventBus(), clock, usecase.WithLifecycleInvalidators(mockAdCacheInvalidator))
+
+	assert.Equal(t, time.Hour, <-clock.waits)
+	assert.Empty(t, invalidated)
+	clock.Advance(time.Hour)
+	<-invalidated
+
+	assert.Equal(t, time.Hour+time.Second, <-clock.waits)
+	assert.Empty(t, invalidated)
+	clock.Advance(time.Hour + time.Second)
+	<-invalidated
+}
+
+func TestLifecycleRun_AdCreatedLive_ShouldStartAtOnce(t *testing.T) {
+	loaded := make(chan struct{})
+	mockAdRepository := mocks.NewAdRepository(t)
+	mockAdRepository.On("FetchLifecycles", mock.Anything, int64(0), 1000).Return([]domain.AdLifecycleState{}, nil).Once().Run(func(mock.Arguments) { close(loaded) })
+	mockAdRepository.On("GetLifecycle", mock.Anything, int64(1)).Return(withLifecycle(testLifecycleAd, domain.AdScheduled), nil).Once()
+	updated := make(chan domain.AdLifecycle, 1)
+	mockAdRepository.On("UpdateLifecycle", mock.Anything, int64(1), domain.AdScheduled, domain.AdLive).Return(true, nil).Once().Run(updatedTo(updated))
+
+	clock := newFakeClock(testLifecycleNow.Add(90 * time.Minute))
+	eventBus := usecase.NewAdEventBus()
+	runLifecycle(t, mockAdRepository, eventBus, clock)
+
+	<-loaded
+	eventBus.Publish(domain.AdEvent{Type: domain.AdEventCreated, TenantID: "team-a", AdID: 1})
+
+	assert.Equal(t, domain.AdLive, <-updated)
+	assert.Equal(t, 30*time.Minute+time.Second, <-clock.waits)
+}
+
+func TestLifecycleRun_ChangedByAnotherServer_ShouldReadItAgainAndWaitForItsEnd(t *testing.T) {
+	// Another server started the ad and moved its end meanwhile
+	moved := withLifecycle(testLifecycleAd, domain.AdLive)
+	moved.EndAt = "2024-01-01 12:00:00"
+
+	mockAdRepository := mocks.NewAdRepository(t)
+	mockAdRepository.On("FetchLifecycles", mock.Anything, int64(0), 1000).Return([]domain.AdLifecycleState{withLifecycle(testLifecycleAd, domain.AdScheduled)}, nil).Once()
+	mockAdRepository.On("UpdateLifecycle", mock.Anything, int64(1), domain.AdScheduled, domain.AdLive).Return(false, nil).Once()
+	mockAdRepository.On("GetLifecycle", mock.Anything, int64(1)).Return(moved, nil).Once()
+
+	clock := newFakeClock(testLifecycleNow.Add(90 * time.Minute))
+	runLifecycle(t, mockAdRepository, usecase.NewAdEventBus(), clock)
+
+	// The worker waits for the end read again instead of the end read before
+	assert.Equal(t, 150*time.Minute+time.Second, <-clock.waits)
+}
+
+func TestLifecycleRun_ManyAds_ShouldLoadThemByPage(t *testing.T) {
+	page := make([]domain.AdLifecycleState, 1000)
+	for i := range page {
+		page[i] = withLifecycle(testLifecycleAd, domain.AdScheduled)
+		page[i].ID = int64(i + 1)
+	}
+	last := withLifecycle(testLifecycleAd, domain.AdScheduled)
+	last.ID, last.StartAt = 1001, "2024-01-01 08:30:00"
+
+	mockAdRepository := mocks.NewAdRepository(t)
+	mockAdRepository.On("FetchLifecycles", mock.Anything, int64(0), 1000).Return(page, nil).Once()
+	mockAdRepository.On("FetchLifecycles", mock.Anything, int64(1000), 1000).Return([]domain.AdLifecycleState{last}, nil).Once()
+
+	clock := newFakeClock(testLifecycleNow)
+	runLifecycle(t, mockAdRepository, usecase.NewAdEventBus(), clock)
+
+	// The earliest boundary is the start of the ad of the last page
+	assert.Equal(t, 30*time.Minute, <-clock.waits)
+}
@@ -0,0 +1,162 @@
+package usecase
+
+import (
+	"context"
+	"dcard-backend/domain"
+	"fmt"
+	"time"
+)
+
+// pacingLookahead is how far delivery may run ahead of the even schedule. The serving
+// probability drops linearly from 1 on schedule to 0 at the target of this much later.
+const pacingLookahead = time.Hour
+
+// WithDeliveryCounter sets where the tracked impressions of ads are read for pacing. Without
+// it, budgets are not applied.
+func WithDeliveryCounter(deliveryCounter domain.DeliveryCounter) AdUsecaseOption {
+	return func(au *adUsecase) {
+		au.deliveryCounter = deliveryCounter
+	}
+}
+
+// WithRandom sets the source of the random numbers in [0, 1) that throttle paced ads, which is
+// rand.Float64 by default
+func WithRandom(random func() float64) AdUsecaseOption {
+	return func(au *adUsecase) {
+		au.random = random
+	}
+}
+
+func validateBudget(budget *domain.Budget) error {
+	if budget == nil {
+		return nil
+	}
+
+	if budget.TotalImpressions < 0 || budget.DailyImpressions < 0 {
+		return fmt.Errorf("%w: budget impressions should not be negative", domain.ErrBadParamInput)
+	}
+	if budget.TotalImpressions == 0 && budget.DailyImpressions == 0 {
+		return fmt.Errorf("%w: budget should have totalImpressions or dailyImpressions", domain.ErrBadParamInput)
+	}
+	if budget.TotalImpressions > 0 && budget.DailyImpressions > budget.TotalImpressions {
+		return fmt.Errorf("%w: budget dailyImpressions should not exceed totalImpressions", domain.ErrBadParamInput)
+	}
+	return nil
+}
+
+// flight is the budget of an ad with the time it runs
+type flight struct {
+	budget  domain.Budget
+	startAt time.Time
+	endAt   time.Time
+}
+
+// flights returns the flights of the ads having a budget, by the id of the ad
+func flights(ads []domain.Ad) (map[int64]flight, error) {
+	loc, err := time.LoadLocation("Asia/Taipei")
+	if err != nil {
+		return nil, err
+	}
+
+	adFlights := map[int64]flight{}
+	for _, ad := range ads {
+		if ad.Budget == nil {
+			continue
+		}
+		startAt, err := time.ParseInLocation("2006-01-02 15:04:05", ad.StartAt, loc)
+		if err != nil {
+			return nil, err
+		}
+		endAt, err := time.ParseInLocation("2006-01-02 15:04:05", ad.EndAt, loc)
+		if err != nil {
+			return nil, err
+		}
+		adFlights[ad.ID] = flight{budget: *ad.Budget, startAt: startAt, endAt: endAt}
+	}
+	return adFlights, nil
+}
+
+// dayStart returns the midnight starting the day of t, where days are in the timezone of
+// the ad times
+func dayStart(t time.Time) (time.Time, error) {
+	loc, err := time.LoadLocation("Asia/Taipei")
+	if err != nil {
+		return time.Time{}, err
+	}
+	year, month, day := t.In(loc).Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, loc), nil
+}
+
+// deliveries returns the impressions of the budgeted ads, which is empty without a counter
+func (au *adUsecase) deliveries(c context.Context, adFlights map[int64]flight, today time.Time) (map[int64]domain.Delivery, error) {
+	if au.deliveryCounter == nil || len(adFlights) == 0 {
+		return map[int64]domain.Delivery{}, nil
+	}
+
+	adIDs := make([]int64, 0, len(adFlights))
+	for adID := range adFlights {
+		adIDs = append(adIDs, adID)
+	}
+	delivery, err := au.deliveryCounter.GetDelivery(c, adIDs, today)
+	return delivery, toDomainError(err)
+}
+
+// pacedProbability returns the probability to serve an ad that has delivered some of its
+// budget, so that delivery spreads evenly from start to end. Ads behind schedule are always
+// served, and ads that exhausted the budget are never served.
+func pacedProbability(budget, delivered int64, start, end, now time.Time) float64 {
+	if delivered >= budget {
+		return 0
+	}
+	if !end.After(start) {
+		return 1
+	}
+
+	target := func(t time.Time) float64 {
+		if t.Before(start) {
+			return 0
+		}
+		if t.After(end) {
+			return float64(budget)
+		}
+		return float64(budget) * float64(t.Sub(start)) / float64(end.Sub(start))
+	}
+
+	onSchedule, ahead := target(now), target(now.Add(pacingLookahead))
+	if float64(delivered) <= onSchedule {
+		return 1
+	}
+	if float64(delivered) >= ahead {
+		return 0
+	}
+	return (ahead - float64(delivered)) / (ahead - onSchedule)
+}
+
+// servingProbability returns the probability to serve a budgeted ad, which is the lower of
+// its total and daily pacing
+func servingProbability(adFlight flight, delivery domain.Delivery, today, now time.Time) float64 {
+	probability := 1.0
+	if total := adFlight.budget.TotalImpressions; total > 0 {
+		probability = pacedProbability(total, delivery.Impressions, adFlight.startAt, adFlight.endAt, now)
+	}
+	if daily := adFlight.budget.DailyImpressions; daily > 0 {
+		// The first and last days of the flight are paced over the hours the ad runs
+		start, end := today, today.AddDate(0, 0, 1)
+		if adFlight.startAt.After(start) {
+			start = adFlight.startAt
+		}
+		if adFlight.endAt.Before(end) {
+			end = adFlight.endAt
+		}
+		probability = min(probability, pacedProbability(daily, delivery.TodayImpressions, start, end, now))
+	}
+	return probability
+}
+
+// paced reports whether a budgeted ad is served in this request
+func (au *adUsecase) paced(adFlight flight, delivery domain.Delivery, today, now time.Time) bool {
+	if au.deliveryCounter == nil {
+		return true
+	}
+	return au.random() < servingProbability(adFlight, delivery, today, now)
+}
@@ -0,0 +1,183 @@
+package usecase_test
+
+import (
+	"context"
+	"dcard-backend/domain"
+	"dcard-backend/domain/mocks"
+	"dcard-backend/usecase"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// simulatedCounter counts every served ad as an impression at the time of the simulated clock
+type simulatedCounter struct {
+	impressions map[int64][]time.Time
+}
+
+func (sc *simulatedCounter) GetDelivery(c context.Context, adIDs []int64, dayStart time.Time) (map[int64]domain.Delivery, error) {
+	delivery := map[int64]domain.Delivery{}
+	for _, adID := range adIDs {
+		for _, impression := range sc.impressions[adID] {
+			adDelivery := delivery[adID]
+			adDelivery.Impressions++
+			if !impression.Before(dayStart) {
+				adDelivery.TodayImpressions++
+			}
+			delivery[adID] = adDelivery
+		}
+	}
+	return delivery, nil
+}
+
+// simulateTraffic replays requests for the ads from start to end, three per minute in the
+// daytime of Taipei and one per minute at night, and returns the served impressions
+func simulateTraffic(t *testing.T, ads []domain.Ad, start, end time.Time) map[int64][]time.Time {
+	loc, _ := time.LoadLocation("Asia/Taipei")
+	now := start
+	counter := &simulatedCounter{impressions: map[int64][]time.Time{}}
+
+	mockAdRepository := mocks.NewAdRepository(t)
+	mockAdRepository.On("GetByCondition", mock.Anything, candidateQuery(domain.SortPriority, 5, nil)).Return(ads, nil)
+
+	testAdUsecase := usecase.NewAdUsecase(mockAdRepository, time.Second*1,
+		usecase.WithClock(func() time.Time { return now }),
+		usecase.WithDeliveryCounter(counter),
+		usecase.WithRandom(rand.New(rand.NewSource(1)).Float64))
+
+	for ; now.Before(end); now = now.Add(time.Minute) {
+		requests := 1
+		if hour := now.In(loc).Hour(); hour >= 8 && hour < 22 {
+			requests = 3
+		}
+		for i := 0; i < requests; i++ {
+			served, err := testAdUsecase.GetByCondition(context.Background(), map[string][]string{"offset": {"0"}})
+			if !assert.NoError(t, err) {
+				return counter.impressions
+			}
+			for _, ad := range served {
+				counter.impressions[ad.ID] = append(counter.impressions[ad.ID], now)
+			}
+		}
+	}
+	return counter.impressions
+}
+
+func countBefore(impressions []time.Time, end time.Time) int64 {
+	var count int64
+	for _, impression := range impressions {
+		if impression.Before(end) {
+			count++
+		}
+	}
+	return count
+}
+
+func TestGetByCondition_TotalBudget_SimulatedTrafficShouldBeDeliveredEvenly(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Taipei")
+	start := time.Date(2024, 3, 1, 0, 0, 0, 0, loc)
+	end := start.AddDate(0, 0, 3)
+	ads := []domain.Ad{
+		{ID: 1, Title: "AD 1", StartAt: "2024-03-01 00:00:00", EndAt: "2024-03-04 00:00:00",
+			Budget: &domain.Budget{TotalImpressions: 3000}},
+	}
+
+	impressions := simulateTraffic(t, ads, start.In(time.UTC), end.In(time.UTC))[1]
+
+	total := int64(len(impressions))
+	assert.LessOrEqual(t, total, int64(3000))
+	assert.GreaterOrEqual(t, total, int64(2950))
+	for day := 1; day <= 3; day++ {
+		delivered := countBefore(impressions, start.AddDate(0, 0, day))
+		assert.InDelta(t, 1000*day, delivered, 60, "delivery at the end of day %d", day)
+	}
+}
+
+func TestGetByCondition_DailyBudget_SimulatedTrafficShouldDeliverEachDay(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Taipei")
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, loc)
+	end := time.Date(2024, 3, 4, 0, 0, 0, 0, loc)
+	ads := []domain.Ad{
+		{ID: 1, Title: "AD 1", StartAt: "2024-03-01 12:00:00", EndAt: "2024-03-04 00:00:00",
+			Budget: &domain.Budget{DailyImpressions: 500}},
+		{ID: 2, Title: "AD 2", StartAt: "2024-03-01 12:00:00", EndAt: "2024-03-04 00:00:00"},
+	}
+
+	served := simulateTraffic(t, ads, start.In(time.UTC), end.In(time.UTC))
+
+	dayEnds := []time.Time{
+		time.Date(2024, 3, 2, 0, 0, 0, 0, loc),
+		time.Date(2024, 3, 3, 0, 0, 0, 0, loc),
+		time.Date(2024, 3, 4, 0, 0, 0, 0, loc),
+	}
+	var previous int64
+	for _, dayEnd := range dayEnds {
+		delivered := countBefore(served[1], dayEnd) - previous
+		assert.LessOrEqual(t, delivered, int64(500))
+		assert.GreaterOrEqual(t, delivered, int64(490))
+		previous += delivered
+	}
+	// Ads without a budget are not paced
+	assert.Len(t, served[2], 10*3*60+2*60+2*(14*3*60+10*60))
+}
+
+func TestGetByCondition_BudgetExhausted_ShouldExcludeAdBeforePagination(t *testing.T) {
+	ads := []domain.Ad{
+		{ID: 1, Title: "AD 1", StartAt: "2024-03-01 00:00:00", EndAt: "2024-03-04 00:00:00",
+			Budget: &domain.Budget{TotalImpressions: 100}},
+		{ID: 2, Title: "AD 2", StartAt: "2024-03-01 00:00:00", EndAt: "2024-03-04 00:00:00",
+			Budget: &domain.Budget{TotalImpressions: 1000, DailyImpressions: 50}},
+		{ID: 3, Title: "AD 3", StartAt: "2024-03-01 00:00:00", EndAt: "2024-03-04 00:00:00"},
+	}
+	mockAdRepository := mocks.NewAdRepository(t)
+	mockAdRepository.On("GetByCondition", mock.Anything, candidateQuery(domain.SortPriority, 5, nil)).Return(ads, nil).Once()
+
+	loc, _ := time.LoadLocation("Asia/Taipei")
+	now := time.Date(2024, 3, 3, 23, 0, 0, 0, loc)
+	mockDeliveryCounter := mocks.NewDeliveryCounter(t)
+	mockDeliveryCounter.On("GetDelivery", mock.Anything, mock.MatchedBy(func(adIDs []int64) bool {
+		return assert.ElementsMatch(t, []int64{1, 2}, adIDs)
+	}), time.Date(2024, 3, 3, 0, 0, 0, 0, loc)).Return(map[int64]domain.Delivery{
+		1: {Impressions: 100, TodayImpressions: 30},
+		2: {Impressions: 400, TodayImpressions: 50},
+	}, nil).Once()
+
+	testAdUsecase := usecase.NewAdUsecase(mockAdRepository, time.Second*1,
+		usecase.WithClock(func() time.Time { return now }),
+		usecase.WithDeliveryCounter(mockDeliveryCounter),
+		usecase.WithRandom(func() float64 { return 0 }))
+
+	served, err := testAdUsecase.GetByCondition(context.Background(), map[string][]string{"offset": {"0"}})
+
+	assert.NoError(t, err)
+	if assert.Len(t, served, 1) {
+		assert.Equal(t, "AD 3", served[0].Title)
+	}
+}
+
+func TestCreate_InvalidBudget_ShouldReturnErrBadParamInput(t *testing.T) {
+	budgets := []*domain.Budget{
+		{},
+		{TotalImpressions: -1},
+		{TotalImpressions: 100, DailyImpressions: -1},
+		{TotalImpressions: 100, DailyImpressions: 200},
+	}
+
+	for _, budget := range budgets {
+		mockAd := domain.Ad{
+			Title:   "Test AD",
+			StartAt: "2024-01-01T00:00:00.000Z",
+			EndAt:   "2025-01-01T00:00:00.000Z",
+			Budget:  budget,
+		}
+		mockAdRepository := mocks.NewAdRepository(t)
+
+		testAdUsecase := usecase.NewAdUsecase(mockAdRepository, time.Second*1)
+
+		err := testAdUsecase.Create(context.Background(), &mockAd)
+		assert.ErrorIs(t, err, domain.ErrBadParamInput)
+	}
+}
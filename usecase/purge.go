@@ -0,0 +1,98 @@
+package usecase
+
+import (
+	"context"
+	"dcard-backend/domain"
+	"log"
+	"time"
+)
+
+type adPurgeUsecase struct {
+	adRepository   domain.AdRepository
+	contextTimeout time.Duration
+	retention      time.Duration
+	batchSize      int
+	now            func() time.Time
+}
+
+type AdPurgeUsecaseOption func(*adPurgeUsecase)
+
+// WithPurgeBatchSize sets the number of ads deleted in a transaction, which is 500 by default
+// and when size is not positive
+func WithPurgeBatchSize(size int) AdPurgeUsecaseOption {
+	return func(pu *adPurgeUsecase) {
+		if size > 0 {
+			pu.batchSize = size
+		}
+	}
+}
+
+// WithPurgeClock sets the clock the retention is counted from, which is time.Now by default
+func WithPurgeClock(now func() time.Time) AdPurgeUsecaseOption {
+	return func(pu *adPurgeUsecase) {
+		pu.now = now
+	}
+}
+
+func NewAdPurgeUsecase(adRepository domain.AdRepository, timeout time.Duration, retention time.Duration, options ...AdPurgeUsecaseOption) domain.AdPurgeUsecase {
+	pu := &adPurgeUsecase{
+		adRepository:   adRepository,
+		contextTimeout: timeout,
+		retention:      retention,
+		batchSize:      500,
+		now:            time.Now,
+	}
+	for _, option := range options {
+		option(pu)
+	}
+	return pu
+}
+
+// Purge deletes the ads soft deleted before the retention batch by batch, each with its own
+// timeout, until a batch is not full. It returns how many ads were deleted, including the
+// batches before an error.
+func (pu *adPurgeUsecase) Purge(c context.Context) (int, error) {
+	deletedBefore := pu.now().Add(-pu.retention).Format(time.RFC3339)
+	if err := changeTimeToUTF8(&deletedBefore); err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for {
+		ctx, cancel := context.WithTimeout(c, pu.contextTimeout)
+		count, err := pu.adRepository.Purge(ctx, deletedBefore, pu.batchSize)
+		cancel()
+		purged += count
+		if err != nil {
+			return purged, toDomainError(err)
+		}
+		if count < pu.batchSize {
+			return purged, nil
+		}
+	}
+}
+
+// Run purges every interval until c is done. A non-positive interval never purges.
+func (pu *adPurgeUsecase) Run(c context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-c.Done():
+			return
+		}
+
+		purged, err := pu.Purge(c)
+		if err != nil {
+			log.Println("Error created when purging deleted ads:", err.Error())
+		}
+		if purged > 0 {
+			log.Printf("Purged %d deleted ads", purged)
+		}
+	}
+}
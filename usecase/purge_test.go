@@ -0,0 +1,45 @@
+package usecase_test
+
+import (
+	"context"
+	"dcard-backend/domain/mocks"
+	"dcard-backend/usecase"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func testPurgeClock() time.Time {
+	return time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)
+}
+
+func TestPurge_FullBatches_ShouldPurgeUntilBatchNotFull(t *testing.T) {
+	mockAdRepository := mocks.NewAdRepository(t)
+	mockAdRepository.On("Purge", mock.Anything, "2024-01-01T08:00:00+08:00", 2).Return(2, nil).Twice()
+	mockAdRepository.On("Purge", mock.Anything, "2024-01-01T08:00:00+08:00", 2).Return(1, nil).Once()
+
+	testPurgeUsecase := usecase.NewAdPurgeUsecase(mockAdRepository, time.Second*1, 30*24*time.Hour,
+		usecase.WithPurgeBatchSize(2), usecase.WithPurgeClock(testPurgeClock))
+
+	purged, err := testPurgeUsecase.Purge(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 5, purged)
+}
+
+func TestPurge_AdRepositoryFail_ShouldStopAndReturnPurgedSoFar(t *testing.T) {
+	mockAdRepository := mocks.NewAdRepository(t)
+	mockAdRepository.On("Purge", mock.Anything, mock.Anything, 2).Return(2, nil).Once()
+	mockAdRepository.On("Purge", mock.Anything, mock.Anything, 2).Return(0, errors.New("Unexpected Error")).Once()
+
+	testPurgeUsecase := usecase.NewAdPurgeUsecase(mockAdRepository, time.Second*1, 30*24*time.Hour,
+		usecase.WithPurgeBatchSize(2), usecase.WithPurgeClock(testPurgeClock))
+
+	purged, err := testPurgeUsecase.Purge(context.Background())
+
+	assert.Error(t, err)
+	assert.Equal(t, 2, purged)
+}
@@ -0,0 +1,110 @@
+package usecase
+
+import (
+	"dcard-backend/domain"
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"sort"
+	"time"
+)
+
+// maxWeight bounds the weight of an ad, so that one ad can not take a whole priority
+const maxWeight = 1000
+
+// WithRanker sets the ranker used when the sort query is name, which may replace a built-in
+// ranker. The rankers of domain.SortPriority, the default, and domain.SortEndAt are built in.
+func WithRanker(name string, ranker domain.Ranker) AdUsecaseOption {
+	return func(au *adUsecase) {
+		au.rankers[name] = ranker
+	}
+}
+
+func defaultRankers() map[string]domain.Ranker {
+	return map[string]domain.Ranker{
+		domain.SortPriority: NewWeightedRanker(),
+		domain.SortEndAt:    NewEndAtRanker(),
+	}
+}
+
+func validateRanking(ad *domain.Ad) error {
+	if ad.Priority < 0 {
+		return fmt.Errorf("%w: priority should be a non-negative integer", domain.ErrBadParamInput)
+	}
+	if ad.Weight < 0 || ad.Weight > maxWeight {
+		return fmt.Errorf("%w: weight should be an integer between 1 and %d", domain.ErrBadParamInput, maxWeight)
+	}
+	if ad.Weight == 0 {
+		ad.Weight = 1
+	}
+	return nil
+}
+
+// rankingSeed returns the seed query, or the user when it is not provided, so that the pages
+// of a user see the same order. Anonymous requests without a seed share the order of the
+// tenant for the day, which keeps their pages consistent and still rotates the ads daily.
+func rankingSeed(condition map[string][]string, userID string, tenantID string, day time.Time) string {
+	if values, ok := condition["seed"]; ok {
+		return values[0]
+	}
+	if userID != "" {
+		return userID
+	}
+	return tenantID + "/" + day.Format("2006-01-02")
+}
+
+type endAtRanker struct{}
+
+// NewEndAtRanker returns a ranker serving the ads ending soonest first
+func NewEndAtRanker() domain.Ranker {
+	return endAtRanker{}
+}
+
+func (endAtRanker) Rank(ads []domain.Ad, seed string) {
+	// End times of the repository share one layout, so they sort as strings
+	sort.SliceStable(ads, func(i, j int) bool { return ads[i].EndAt < ads[j].EndAt })
+}
+
+type weightedRanker struct{}
+
+// NewWeightedRanker returns a ranker serving ads of a higher priority first, and shuffling
+// ads of the same priority so that an ad is ahead of another in proportion to its weight
+func NewWeightedRanker() domain.Ranker {
+	return weightedRanker{}
+}
+
+func (weightedRanker) Rank(ads []domain.Ad, seed string) {
+	// Each ad draws log(u)/weight, which orders a weighted random sample without replacement.
+	// u is hashed from the seed and the id of the ad, so an ad keeps its draw when other ads
+	// are filtered out between pages.
+	keys := make(map[int64]float64, len(ads))
+	for _, ad := range ads {
+		weight := ad.Weight
+		if weight <= 0 {
+			weight = 1
+		}
+		keys[ad.ID] = math.Log(uniformHash(seed, ad.ID)) / float64(weight)
+	}
+
+	sort.SliceStable(ads, func(i, j int) bool {
+		if ads[i].Priority != ads[j].Priority {
+			return ads[i].Priority > ads[j].Priority
+		}
+		return keys[ads[i].ID] > keys[ads[j].ID]
+	})
+}
+
+// uniformHash maps the seed and the id of an ad to a number in (0, 1)
+func uniformHash(seed string, adID int64) float64 {
+	hash := fnv.New64a()
+	hash.Write([]byte(seed))
+	binary.Write(hash, binary.BigEndian, adID)
+
+	// FNV mixes the last bytes poorly into the high bits, so they are mixed as in splitmix64
+	x := hash.Sum64()
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	x ^= x >> 31
+	return (float64(x>>11) + 0.5) / (1 << 53)
+}
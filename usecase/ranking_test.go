@@ -0,0 +1,210 @@
+package usecase_test
+
+import (
+	"context"
+	"dcard-backend/domain"
+	"dcard-backend/domain/mocks"
+	"dcard-backend/usecase"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func adIDs(ads []domain.Ad) []int64 {
+	ids := make([]int64, len(ads))
+	for i, ad := range ads {
+		ids[i] = ad.ID
+	}
+	return ids
+}
+
+func TestWeightedRanker_ShouldOrderByPriorityThenStablyBySeed(t *testing.T) {
+	ads := []domain.Ad{
+		{ID: 1}, {ID: 2, Priority: 1}, {ID: 3}, {ID: 4, Priority: 2}, {ID: 5}, {ID: 6, Priority: 1},
+	}
+	ranker := usecase.NewWeightedRanker()
+
+	ranked := append([]domain.Ad{}, ads...)
+	ranker.Rank(ranked, "user-1")
+	assert.Equal(t, int64(4), ranked[0].ID)
+	assert.ElementsMatch(t, []int64{2, 6}, adIDs(ranked[1:3]))
+	assert.ElementsMatch(t, []int64{1, 3, 5}, adIDs(ranked[3:]))
+
+	reranked := []domain.Ad{ads[5], ads[4], ads[3], ads[2], ads[1], ads[0]}
+	ranker.Rank(reranked, "user-1")
+	assert.Equal(t, adIDs(ranked), adIDs(reranked))
+
+	// Filtering out an ad keeps the order of the others
+	filtered := append([]domain.Ad{}, ads[1:]...)
+	ranker.Rank(filtered, "user-1")
+	var expected []int64
+	for _, id := range adIDs(ranked) {
+		if id != 1 {
+			expected = append(expected, id)
+		}
+	}
+	assert.Equal(t, expected, adIDs(filtered))
+}
+
+func TestWeightedRanker_ShouldRankFirstInProportionToWeight(t *testing.T) {
+	ranker := usecase.NewWeightedRanker()
+
+	first := map[int64]int{}
+	seeds := 20000
+	for seed := 0; seed < seeds; seed++ {
+		ads := []domain.Ad{{ID: 1, Weight: 1}, {ID: 2, Weight: 3}, {ID: 3}}
+		ranker.Rank(ads, strconv.Itoa(seed))
+		first[ads[0].ID]++
+	}
+
+	assert.InDelta(t, 0.2, float64(first[1])/float64(seeds), 0.02)
+	assert.InDelta(t, 0.6, float64(first[2])/float64(seeds), 0.02)
+	assert.InDelta(t, 0.2, float64(first[3])/float64(seeds), 0.02)
+}
+
+func TestGetByCondition_SameSeed_PagesShouldNotOverlap(t *testing.T) {
+	var ads []domain.Ad
+	for id := int64(1); id <= 10; id++ {
+		ads = append(ads, domain.Ad{ID: id, Title: "AD " + strconv.FormatInt(id, 10), EndAt: "2025-01-01 08:00:00"})
+	}
+	mockAdRepository := mocks.NewAdRepository(t)
+	mockAdRepository.On("GetByCondition", mock.Anything, mock.Anything).Return(pageOf(ads), nil)
+
+	testAdUsecase := usecase.NewAdUsecase(mockAdRepository, time.Second*1)
+
+	var served []int64
+	for _, offset := range []string{"0", "4", "8"} {
+		page, err := testAdUsecase.GetByCondition(context.Background(), map[string][]string{
+			"offset": {offset},
+			"limit":  {"4"},
+			"seed":   {"request-1"},
+		})
+		assert.NoError(t, err)
+		served = append(served, adIDs(page)...)
+	}
+	assert.ElementsMatch(t, adIDs(ads), served)
+}
+
+func TestGetByCondition_AnonymousWithoutSeed_ShouldKeepOrderOfTenantForTheDay(t *testing.T) {
+	var ads []domain.Ad
+	for id := int64(1); id <= 10; id++ {
+		ads = append(ads, domain.Ad{ID: id, Title: "AD " + strconv.FormatInt(id, 10), EndAt: "2025-01-01 08:00:00"})
+	}
+	mockAdRepository := mocks.NewAdRepository(t)
+	mockAdRepository.On("GetByCondition", mock.Anything, mock.Anything).Return(pageOf(ads), nil)
+
+	now := time.Date(2024, 3, 1, 8, 0, 0, 0, time.UTC)
+	testAdUsecase := usecase.NewAdUsecase(mockAdRepository, time.Second*1, usecase.WithClock(func() time.Time { return now }))
+	c := domain.WithTenant(context.Background(), "team-a")
+	condition := map[string][]string{"offset": {"0"}}
+
+	first, err := testAdUsecase.GetByCondition(c, condition)
+	assert.NoError(t, err)
+
+	now = now.Add(time.Hour)
+	second, err := testAdUsecase.GetByCondition(c, condition)
+	assert.NoError(t, err)
+	assert.Equal(t, adIDs(first), adIDs(second))
+
+	// The next day in Asia/Taipei starts at 16:00 UTC
+	now = now.Add(8 * time.Hour)
+	nextDay, err := testAdUsecase.GetByCondition(c, condition)
+	assert.NoError(t, err)
+	assert.NotEqual(t, adIDs(first), adIDs(nextDay))
+}
+
+func TestGetByCondition_SortEndAt_ShouldServeAdsEndingSoonestFirst(t *testing.T) {
+	ads := []domain.Ad{
+		{ID: 1, Title: "AD 1", EndAt: "2025-03-01 08:00:00", Priority: 5},
+		{ID: 2, Title: "AD 2", EndAt: "2025-01-01 08:00:00"},
+		{ID: 3, Title: "AD 3", EndAt: "2025-02-01 08:00:00", Weight: 1000},
+	}
+	mockAdRepository := mocks.NewAdRepository(t)
+	mockAdRepository.On("GetByCondition", mock.Anything, candidateQuery(domain.SortEndAt, 5, nil)).Return(ads, nil).Once()
+
+	testAdUsecase := usecase.NewAdUsecase(mockAdRepository, time.Second*1)
+
+	served, err := testAdUsecase.GetByCondition(context.Background(), map[string][]string{
+		"offset": {"0"},
+		"sort":   {"endAt"},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{2, 3, 1}, adIDs(served))
+}
+
+func TestGetByCondition_UnknownSort_ShouldReturnErrBadParamInput(t *testing.T) {
+	testAdUsecase := usecase.NewAdUsecase(mocks.NewAdRepository(t), time.Second*1)
+
+	_, err := testAdUsecase.GetByCondition(context.Background(), map[string][]string{
+		"offset": {"0"},
+		"sort":   {"title"},
+	})
+
+	assert.ErrorIs(t, err, domain.ErrBadParamInput)
+}
+
+type reverseRanker struct{}
+
+func (reverseRanker) Rank(ads []domain.Ad, seed string) {
+	for i, j := 0, len(ads)-1; i < j; i, j = i+1, j-1 {
+		ads[i], ads[j] = ads[j], ads[i]
+	}
+}
+
+func TestGetByCondition_WithRanker_ShouldRankWithRegisteredRanker(t *testing.T) {
+	ads := []domain.Ad{
+		{ID: 1, Title: "AD 1", EndAt: "2025-01-01 08:00:00"},
+		{ID: 2, Title: "AD 2", EndAt: "2025-01-01 08:00:00"},
+	}
+	mockAdRepository := mocks.NewAdRepository(t)
+	mockAdRepository.On("GetByCondition", mock.Anything, candidateQuery(domain.SortPriority, 5, nil)).Return(ads, nil).Once()
+
+	testAdUsecase := usecase.NewAdUsecase(mockAdRepository, time.Second*1, usecase.WithRanker("reverse", reverseRanker{}))
+
+	served, err := testAdUsecase.GetByCondition(context.Background(), map[string][]string{
+		"offset": {"0"},
+		"sort":   {"reverse"},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{2, 1}, adIDs(served))
+}
+
+func TestCreate_InvalidPriorityOrWeight_ShouldReturnErrBadParamInput(t *testing.T) {
+	ads := []domain.Ad{
+		{Priority: -1},
+		{Weight: -1},
+		{Weight: 1001},
+	}
+
+	for _, ad := range ads {
+		ad.Title = "Test AD"
+		ad.StartAt = "2024-01-01T00:00:00.000Z"
+		ad.EndAt = "2025-01-01T00:00:00.000Z"
+		testAdUsecase := usecase.NewAdUsecase(mocks.NewAdRepository(t), time.Second*1)
+
+		err := testAdUsecase.Create(context.Background(), &ad)
+		assert.ErrorIs(t, err, domain.ErrBadParamInput)
+	}
+}
+
+func TestCreate_WeightNotProvided_ShouldSetTo1(t *testing.T) {
+	mockAd := domain.Ad{
+		Title:   "Test AD",
+		StartAt: "2024-01-01T00:00:00.000Z",
+		EndAt:   "2025-01-01T00:00:00.000Z",
+	}
+	mockAdRepository := mocks.NewAdRepository(t)
+	mockAdRepository.On("Create", mock.Anything, mock.MatchedBy(func(ad *domain.Ad) bool {
+		return ad.Weight == 1
+	}), mock.Anything).Return(nil).Once()
+
+	testAdUsecase := usecase.NewAdUsecase(mockAdRepository, time.Second*1)
+
+	err := testAdUsecase.Create(context.Background(), &mockAd)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,79 @@
+package usecase
+
+import (
+	"dcard-backend/domain"
+	"fmt"
+	"time"
+)
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+func validateSchedule(schedule *domain.Schedule) error {
+	if schedule == nil {
+		return nil
+	}
+
+	if _, err := time.LoadLocation(schedule.Timezone); err != nil {
+		return fmt.Errorf("%w: unknown timezone %q", domain.ErrBadParamInput, schedule.Timezone)
+	}
+
+	if len(schedule.Windows) == 0 {
+		return fmt.Errorf("%w: schedule should have at least one window", domain.ErrBadParamInput)
+	}
+
+	for _, window := range schedule.Windows {
+		if _, ok := weekdays[window.Weekday]; !ok {
+			return fmt.Errorf("%w: unknown weekday %q, which should be one of sun, mon, tue, wed, thu, fri and sat", domain.ErrBadParamInput, window.Weekday)
+		}
+		if window.StartHour < 0 || window.StartHour > 23 || window.EndHour < 0 || window.EndHour > 24 || window.StartHour == window.EndHour {
+			return fmt.Errorf("%w: window %s %d-%d should have 0 <= startHour <= 23, 0 <= endHour <= 24 and different hours",
+				domain.ErrBadParamInput, window.Weekday, window.StartHour, window.EndHour)
+		}
+	}
+	return nil
+}
+
+// windowActive reports whether the window covers a local weekday and hour
+func windowActive(window domain.ScheduleWindow, weekday time.Weekday, hour int) bool {
+	day := weekdays[window.Weekday]
+	if window.StartHour < window.EndHour {
+		return weekday == day && hour >= window.StartHour && hour < window.EndHour
+	}
+
+	// The window crosses midnight, so it covers the end of its day and the start of the next day
+	nextDay := (day + 1) % 7
+	return (weekday == day && hour >= window.StartHour) || (weekday == nextDay && hour < window.EndHour)
+}
+
+// scheduleActive reports whether an ad with the schedule is served at the time. Hours are
+// compared on the wall clock of the timezone, so windows follow the local time across DST
+// transitions.
+func scheduleActive(schedule *domain.Schedule, now time.Time, viewerLocation *time.Location) bool {
+	if schedule == nil {
+		return true
+	}
+
+	location := viewerLocation
+	if schedule.Timezone != "" {
+		var err error
+		if location, err = time.LoadLocation(schedule.Timezone); err != nil {
+			return false
+		}
+	}
+
+	local := now.In(location)
+	for _, window := range schedule.Windows {
+		if windowActive(window, local.Weekday(), local.Hour()) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,175 @@
+package usecase_test
+
+import (
+	"context"
+	"dcard-backend/domain"
+	"dcard-backend/domain/mocks"
+	"dcard-backend/usecase"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func scheduledAd(title string, schedule *domain.Schedule) domain.Ad {
+	return domain.Ad{
+		Title:     title,
+		EndAt:     "2025-01-01 08:00:00",
+		Condition: &domain.Condition{Schedule: schedule},
+	}
+}
+
+// servedTitles returns the titles of the ads served at the time
+func servedTitles(t *testing.T, ads []domain.Ad, now time.Time, condition map[string][]string) []string {
+	mockAdRepository := mocks.NewAdRepository(t)
+	mockAdRepository.On("GetByCondition", mock.Anything, mock.Anything).Return(pageOf(ads), nil)
+
+	testAdUsecase := usecase.NewAdUsecase(mockAdRepository, time.Second*1, usecase.WithClock(func() time.Time { return now }))
+
+	if _, ok := condition["offset"]; !ok {
+		condition["offset"] = []string{"0"}
+	}
+	served, err := testAdUsecase.GetByCondition(context.Background(), condition)
+	assert.NoError(t, err)
+
+	titles := []string{}
+	for _, ad := range served {
+		titles = append(titles, ad.Title)
+	}
+	return titles
+}
+
+func TestGetByCondition_WindowCrossingMidnight_ShouldServeOnBothDays(t *testing.T) {
+	ads := []domain.Ad{
+		scheduledAd("Friday night", &domain.Schedule{
+			Timezone: "Asia/Taipei",
+			Windows:  []domain.ScheduleWindow{{Weekday: "fri", StartHour: 22, EndHour: 2}},
+		}),
+	}
+	taipei, _ := time.LoadLocation("Asia/Taipei")
+
+	tests := []struct {
+		now    time.Time
+		served bool
+	}{
+		{time.Date(2024, 3, 8, 21, 59, 0, 0, taipei), false}, // Friday
+		{time.Date(2024, 3, 8, 22, 0, 0, 0, taipei), true},
+		{time.Date(2024, 3, 8, 23, 59, 0, 0, taipei), true},
+		{time.Date(2024, 3, 9, 1, 59, 0, 0, taipei), true}, // Saturday
+		{time.Date(2024, 3, 9, 2, 0, 0, 0, taipei), false},
+		{time.Date(2024, 3, 7, 23, 0, 0, 0, taipei), false}, // Thursday
+		{time.Date(2024, 3, 8, 1, 0, 0, 0, taipei), false},  // Friday morning belongs to Thursday
+	}
+	for _, test := range tests {
+		titles := servedTitles(t, ads, test.now.UTC(), map[string][]string{})
+		assert.Equal(t, test.served, len(titles) == 1, "served at %s", test.now)
+	}
+}
+
+func TestGetByCondition_SpringForward_ShouldFollowLocalHours(t *testing.T) {
+	// On 2024-03-10 clocks in New York jump from 02:00 EST to 03:00 EDT
+	ads := []domain.Ad{
+		scheduledAd("Early Sunday", &domain.Schedule{
+			Timezone: "America/New_York",
+			Windows:  []domain.ScheduleWindow{{Weekday: "sun", StartHour: 1, EndHour: 3}},
+		}),
+		scheduledAd("Business hours", &domain.Schedule{
+			Timezone: "America/New_York",
+			Windows: []domain.ScheduleWindow{
+				{Weekday: "sat", StartHour: 9, EndHour: 17},
+				{Weekday: "mon", StartHour: 9, EndHour: 17},
+			},
+		}),
+	}
+
+	tests := []struct {
+		now    time.Time
+		titles []string
+	}{
+		{time.Date(2024, 3, 10, 6, 30, 0, 0, time.UTC), []string{"Early Sunday"}},    // 01:30 EST
+		{time.Date(2024, 3, 10, 7, 0, 0, 0, time.UTC), []string{}},                   // 03:00 EDT, the window lasted one hour
+		{time.Date(2024, 3, 9, 13, 30, 0, 0, time.UTC), []string{}},                  // 08:30 EST
+		{time.Date(2024, 3, 11, 13, 30, 0, 0, time.UTC), []string{"Business hours"}}, // 09:30 EDT
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.titles, servedTitles(t, ads, test.now, map[string][]string{}), "served at %s", test.now)
+	}
+}
+
+func TestGetByCondition_FallBack_ShouldServeBothRepeatedHours(t *testing.T) {
+	// On 2024-11-03 clocks in New York go back from 02:00 EDT to 01:00 EST
+	ads := []domain.Ad{
+		scheduledAd("Repeated hour", &domain.Schedule{
+			Timezone: "America/New_York",
+			Windows:  []domain.ScheduleWindow{{Weekday: "sun", StartHour: 1, EndHour: 2}},
+		}),
+	}
+
+	tests := []struct {
+		now    time.Time
+		served bool
+	}{
+		{time.Date(2024, 11, 3, 4, 59, 0, 0, time.UTC), false}, // 00:59 EDT
+		{time.Date(2024, 11, 3, 5, 30, 0, 0, time.UTC), true},  // 01:30 EDT
+		{time.Date(2024, 11, 3, 6, 30, 0, 0, time.UTC), true},  // 01:30 EST
+		{time.Date(2024, 11, 3, 7, 0, 0, 0, time.UTC), false},  // 02:00 EST
+	}
+	for _, test := range tests {
+		titles := servedTitles(t, ads, test.now, map[string][]string{})
+		assert.Equal(t, test.served, len(titles) == 1, "served at %s", test.now)
+	}
+}
+
+func TestGetByCondition_ScheduleWithoutTimezone_ShouldUseViewerTimezone(t *testing.T) {
+	ads := []domain.Ad{
+		scheduledAd("Lunch", &domain.Schedule{
+			Windows: []domain.ScheduleWindow{{Weekday: "mon", StartHour: 12, EndHour: 14}},
+		}),
+	}
+	now := time.Date(2024, 3, 11, 4, 30, 0, 0, time.UTC) // 12:30 in Taipei
+
+	assert.Equal(t, []string{"Lunch"}, servedTitles(t, ads, now, map[string][]string{"timezone": {"Asia/Taipei"}}))
+	assert.Equal(t, []string{}, servedTitles(t, ads, now, map[string][]string{}))
+}
+
+func TestGetByCondition_ScheduledAdsFiltered_ShouldPaginateServedAds(t *testing.T) {
+	closed := &domain.Schedule{Windows: []domain.ScheduleWindow{{Weekday: "sun", StartHour: 0, EndHour: 1}}}
+	ads := []domain.Ad{
+		{Title: "AD 0", EndAt: "2025-01-01 08:00:00"},
+		scheduledAd("AD 1", closed),
+		{Title: "AD 2", EndAt: "2025-01-01 08:00:00"},
+		scheduledAd("AD 3", closed),
+		{Title: "AD 4", EndAt: "2025-01-01 08:00:00"},
+	}
+	now := time.Date(2024, 3, 11, 12, 0, 0, 0, time.UTC)
+
+	titles := servedTitles(t, ads, now, map[string][]string{"limit": {"2"}, "offset": {"1"}})
+
+	assert.Equal(t, []string{"AD 2", "AD 4"}, titles)
+}
+
+func TestCreate_InvalidSchedule_ShouldReturnErrBadParamInput(t *testing.T) {
+	schedules := []*domain.Schedule{
+		{Timezone: "Mars/Olympus_Mons", Windows: []domain.ScheduleWindow{{Weekday: "mon", StartHour: 9, EndHour: 17}}},
+		{Windows: []domain.ScheduleWindow{}},
+		{Windows: []domain.ScheduleWindow{{Weekday: "monday", StartHour: 9, EndHour: 17}}},
+		{Windows: []domain.ScheduleWindow{{Weekday: "mon", StartHour: 9, EndHour: 9}}},
+		{Windows: []domain.ScheduleWindow{{Weekday: "mon", StartHour: 9, EndHour: 25}}},
+	}
+
+	for _, schedule := range schedules {
+		mockAd := domain.Ad{
+			Title:     "Test AD",
+			StartAt:   "2024-01-01T00:00:00.000Z",
+			EndAt:     "2025-01-01T00:00:00.000Z",
+			Condition: &domain.Condition{Schedule: schedule},
+		}
+		mockAdRepository := mocks.NewAdRepository(t)
+
+		testAdUsecase := usecase.NewAdUsecase(mockAdRepository, time.Second*1)
+
+		err := testAdUsecase.Create(context.Background(), &mockAd)
+		assert.ErrorIs(t, err, domain.ErrBadParamInput)
+	}
+}
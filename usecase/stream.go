@@ -0,0 +1,306 @@
+package usecase
+
+import (
+	"context"
+	"dcard-backend/domain"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// streamBatchSize is the number of events read from the log at once
+const streamBatchSize = 500
+
+// streamEventTypes maps the events of the outbox to those of the stream. A change of status is an
+// update of the ad, and a restored ad is created again.
+var streamEventTypes = map[domain.AdEventType]domain.AdEventType{
+	domain.AdEventCreated:  domain.AdEventCreated,
+	domain.AdEventUpdated:  domain.AdEventUpdated,
+	domain.AdEventPaused:   domain.AdEventUpdated,
+	domain.AdEventResumed:  domain.AdEventUpdated,
+	domain.AdEventRestored: domain.AdEventCreated,
+	domain.AdEventStarted:  domain.AdEventStarted,
+	domain.AdEventExpired:  domain.AdEventExpired,
+	domain.AdEventDeleted:  domain.AdEventDeleted,
+}
+
+// streamSubscriber receives the events of its tenant in inbox, which is closed when the
+// subscriber is dropped
+type streamSubscriber struct {
+	tenantID string
+	inbox    chan domain.AdStreamEvent
+}
+
+type adStreamUsecase struct {
+	eventLogRepository domain.AdEventLogRepository
+	contextTimeout     time.Duration
+	retention          time.Duration
+	bufferSize         int
+	// wake tells Run that events were logged
+	wake chan struct{}
+
+	mutex       sync.Mutex
+	subscribers map[int]*streamSubscriber
+	next        int
+	closed      bool
+}
+
+type AdStreamUsecaseOption func(*adStreamUsecase)
+
+// WithStreamRetention sets how long events are kept in the log to be replayed, which is a day
+// by default
+func WithStreamRetention(retention time.Duration) AdStreamUsecaseOption {
+	return func(su *adStreamUsecase) {
+		if retention > 0 {
+			su.retention = retention
+		}
+	}
+}
+
+// WithStreamBuffer sets the number of events a subscriber may fall behind before it is
+// dropped, which is 256 by default
+func WithStreamBuffer(size int) AdStreamUsecaseOption {
+	return func(su *adStreamUsecase) {
+		if size > 0 {
+			su.bufferSize = size
+		}
+	}
+}
+
+func NewAdStreamUsecase(eventLogRepository domain.AdEventLogRepository, timeout time.Duration, options ...AdStreamUsecaseOption) domain.AdStreamUsecase {
+	su := &adStreamUsecase{
+		eventLogRepository: eventLogRepository,
+		contextTimeout:     timeout,
+		retention:          24 * time.Hour,
+		bufferSize:         256,
+		wake:               make(chan struct{}, 1),
+		subscribers:        map[int]*streamSubscriber{},
+	}
+	for _, option := range options {
+		option(su)
+	}
+	return su
+}
+
+func (su *adStreamUsecase) Subscribe(c context.Context, lastEventID int64) (<-chan domain.AdStreamEvent, error) {
+	tenantID, err := domain.TenantFromContext(c)
+	if err != nil {
+		return nil, err
+	}
+	if lastEventID < 0 {
+		return nil, fmt.Errorf("%w: Last-Event-ID should be a non-negative integer", domain.ErrBadParamInput)
+	}
+
+	events := make(chan domain.AdStreamEvent)
+	subscriber := &streamSubscriber{tenantID: tenantID, inbox: make(chan domain.AdStreamEvent, su.bufferSize)}
+	su.mutex.Lock()
+	if su.closed {
+		su.mutex.Unlock()
+		close(events)
+		return events, nil
+	}
+	id := su.next
+	su.next++
+	su.subscribers[id] = subscriber
+	su.mutex.Unlock()
+
+	// The subscriber is added before the log is replayed, so no event falls between the two
+	go su.feed(c, id, subscriber, lastEventID, events)
+	return events, nil
+}
+
+// drop removes a subscriber, which then sends the events left in its inbox and ends
+func (su *adStreamUsecase) drop(id int) {
+	if subscriber, ok := su.subscribers[id]; ok {
+		delete(su.subscribers, id)
+		close(subscriber.inbox)
+	}
+}
+
+// feed sends the events after lastEventID in the log and then the events of the inbox, skipping
+// those already sent
+func (su *adStreamUsecase) feed(c context.Context, id int, subscriber *streamSubscriber, lastEventID int64, events chan<- domain.AdStreamEvent) {
+	defer close(events)
+	defer func() {
+		su.mutex.Lock()
+		defer su.mutex.Unlock()
+		su.drop(id)
+	}()
+
+	send := func(event domain.AdStreamEvent) bool {
+		select {
+		case events <- event:
+			lastEventID = event.ID
+			return true
+		case <-c.Done():
+			return false
+		}
+	}
+
+	for replay := lastEventID > 0; replay; {
+		ctx, cancel := context.WithTimeout(c, su.contextTimeout)
+		logged, err := su.eventLogRepository.FetchAfter(ctx, lastEventID, streamBatchSize)
+		cancel()
+		if err != nil {
+			log.Println("Error created when replaying the event log:", toDomainError(err).Error())
+			return
+		}
+		for _, event := range logged {
+			if !send(event) {
+				return
+			}
+		}
+		replay = len(logged) == streamBatchSize
+	}
+
+	for {
+		select {
+		case event, ok := <-subscriber.inbox:
+			if !ok {
+				return
+			}
+			if event.ID > lastEventID && !send(event) {
+				return
+			}
+		case <-c.Done():
+			return
+		}
+	}
+}
+
+// fanOut hands an event to the subscribers of its tenant. A subscriber whose inbox is full is
+// dropped rather than holding up the others, and resumes from the log when it reconnects.
+func (su *adStreamUsecase) fanOut(event domain.AdStreamEvent) {
+	su.mutex.Lock()
+	defer su.mutex.Unlock()
+
+	for id, subscriber := range su.subscribers {
+		if subscriber.tenantID != event.TenantID {
+			continue
+		}
+		select {
+		case subscriber.inbox <- event:
+		default:
+			su.drop(id)
+		}
+	}
+}
+
+func (su *adStreamUsecase) Close() {
+	su.mutex.Lock()
+	defer su.mutex.Unlock()
+
+	su.closed = true
+	for id := range su.subscribers {
+		su.drop(id)
+	}
+}
+
+// Append logs an event of the tenant of c with the ad as the change left it, and wakes Run up
+// to send it. Events which are not streamed are ignored. Append must only be called by the
+// stream subscription of the outbox, which keeps the ids of the log in the order of the events.
+func (su *adStreamUsecase) Append(c context.Context, event domain.AdEvent) error {
+	eventType, ok := streamEventTypes[event.Type]
+	if !ok {
+		return nil
+	}
+
+	logged := domain.AdStreamEvent{Type: eventType, AdID: event.AdID, At: event.At}
+	if event.Ad != nil {
+		ad := *event.Ad
+		if err := changeSnapshotTimeToUTC(&ad.StartAt); err != nil {
+			return err
+		}
+		if err := changeSnapshotTimeToUTC(&ad.EndAt); err != nil {
+			return err
+		}
+		logged.Ad = &ad
+	}
+
+	ctx, cancel := context.WithTimeout(c, su.contextTimeout)
+	defer cancel()
+
+	if err := su.eventLogRepository.Append(ctx, &logged); err != nil {
+		return toDomainError(err)
+	}
+	select {
+	case su.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// tail sends the events logged after the cursor to the subscribers, and returns the new cursor
+func (su *adStreamUsecase) tail(c context.Context, cursor int64) int64 {
+	for {
+		ctx, cancel := context.WithTimeout(c, su.contextTimeout)
+		logged, err := su.eventLogRepository.Tail(ctx, cursor, streamBatchSize)
+		cancel()
+		if err != nil {
+			log.Println("Error created when reading the event log:", toDomainError(err).Error())
+			return cursor
+		}
+		for _, event := range logged {
+			su.fanOut(event)
+			cursor = event.ID
+		}
+		if len(logged) < streamBatchSize {
+			return cursor
+		}
+	}
+}
+
+// lastID returns the id of the newest event in the log, which is -1 when it cannot be read
+func (su *adStreamUsecase) lastID(c context.Context) int64 {
+	ctx, cancel := context.WithTimeout(c, su.contextTimeout)
+	defer cancel()
+
+	id, err := su.eventLogRepository.LastID(ctx)
+	if err != nil {
+		log.Println("Error created when reading the end of the event log:", toDomainError(err).Error())
+		return -1
+	}
+	return id
+}
+
+// Run streams the events from the end of the log when it starts. Events are sent to
+// subscribers once they are read back from the log, so every server sends the events logged by
+// the others in the same order. The log is read when an event is appended on this server and
+// every interval.
+func (su *adStreamUsecase) Run(c context.Context, interval time.Duration) {
+	defer su.Close()
+
+	cursor := su.lastID(c)
+
+	var tick <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	trimmer := time.NewTicker(min(su.retention, time.Hour))
+	defer trimmer.Stop()
+	for {
+		select {
+		case <-c.Done():
+			return
+		case <-trimmer.C:
+			ctx, cancel := context.WithTimeout(c, su.contextTimeout)
+			if err := su.eventLogRepository.Trim(ctx, su.retention); err != nil {
+				log.Println("Error created when trimming the event log:", toDomainError(err).Error())
+			}
+			cancel()
+			continue
+		case <-su.wake:
+		case <-tick:
+		}
+
+		if cursor < 0 {
+			if cursor = su.lastID(c); cursor < 0 {
+				continue
+			}
+		}
+		cursor = su.tail(c, cursor)
+	}
+}
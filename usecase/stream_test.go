@@ -0,0 +1,176 @@
+package usecase_test
+
+import (
+	"context"
+	"dcard-backend/domain"
+	"dcard-backend/usecase"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryEventLog keeps the event log in memory, and reports every time it is tailed on tailed
+type memoryEventLog struct {
+	mutex  sync.Mutex
+	events []domain.AdStreamEvent
+	tailed chan struct{}
+}
+
+func newMemoryEventLog(events ...domain.AdStreamEvent) *memoryEventLog {
+	return &memoryEventLog{events: events, tailed: make(chan struct{}, 1)}
+}
+
+func (ml *memoryEventLog) Append(c context.Context, event *domain.AdStreamEvent) error {
+	tenantID, err := domain.TenantFromContext(c)
+	if err != nil {
+		return err
+	}
+	ml.mutex.Lock()
+	defer ml.mutex.Unlock()
+	event.ID, event.TenantID = int64(len(ml.events)+1), tenantID
+	ml.events = append(ml.events, *event)
+	return nil
+}
+
+func (ml *memoryEventLog) after(tenantID string, afterID int64, limit int) []domain.AdStreamEvent {
+	ml.mutex.Lock()
+	defer ml.mutex.Unlock()
+	events := []domain.AdStreamEvent{}
+	for _, event := range ml.events {
+		if event.ID > afterID && (tenantID == "" || event.TenantID == tenantID) && len(events) < limit {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+func (ml *memoryEventLog) FetchAfter(c context.Context, afterID int64, limit int) ([]domain.AdStreamEvent, error) {
+	tenantID, err := domain.TenantFromContext(c)
+	if err != nil {
+		return nil, err
+	}
+	return ml.after(tenantID, afterID, limit), nil
+}
+
+func (ml *memoryEventLog) Tail(c context.Context, afterID int64, limit int) ([]domain.AdStreamEvent, error) {
+	defer func() {
+		select {
+		case ml.tailed <- struct{}{}:
+		default:
+		}
+	}()
+	return ml.after("", afterID, limit), nil
+}
+
+func (ml *memoryEventLog) LastID(c context.Context) (int64, error) {
+	ml.mutex.Lock()
+	defer ml.mutex.Unlock()
+	return int64(len(ml.events)), nil
+}
+
+func (ml *memoryEventLog) Trim(c context.Context, retention time.Duration) error {
+	return nil
+}
+
+// runStream runs the worker until the test ends, once it is following the log
+func runStream(t *testing.T, streamUsecase domain.AdStreamUsecase, eventLog *memoryEventLog) {
+	c, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		streamUsecase.Run(c, 10*time.Millisecond)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-stopped
+	})
+	<-eventLog.tailed
+}
+
+// appendEvent appends an event as the dispatcher does, in the tenant of the event
+func appendEvent(t *testing.T, streamUsecase domain.AdStreamUsecase, event domain.AdEvent) {
+	assert.NoError(t, streamUsecase.Append(domain.WithTenant(context.Background(), event.TenantID), event))
+}
+
+func subscribe(t *testing.T, streamUsecase domain.AdStreamUsecase, tenantID string, lastEventID int64) <-chan domain.AdStreamEvent {
+	c, cancel := context.WithCancel(domain.WithTenant(context.Background(), tenantID))
+	t.Cleanup(cancel)
+	events, err := streamUsecase.Subscribe(c, lastEventID)
+	assert.NoError(t, err)
+	return events
+}
+
+func TestStreamRun_EventAppended_ShouldSendAdOfEventToSubscribersOfTenant(t *testing.T) {
+	eventLog := newMemoryEventLog()
+	testStreamUsecase := usecase.NewAdStreamUsecase(eventLog, time.Second*1)
+	runStream(t, testStreamUsecase, eventLog)
+
+	teamA := subscribe(t, testStreamUsecase, "team-a", 0)
+	teamB := subscribe(t, testStreamUsecase, "team-b", 0)
+	// The ad is logged as the event recorded it, with its times in UTC
+	appendEvent(t, testStreamUsecase, domain.AdEvent{Type: domain.AdEventPaused, TenantID: "team-a", AdID: 1, At: "2024-01-01T00:00:00Z",
+		Ad: &domain.Ad{ID: 1, Title: "AD 1", StartAt: "2024-01-01 08:00:00", EndAt: "2024-01-02T08:00:00+08:00", Status: domain.AdPaused}})
+	appendEvent(t, testStreamUsecase, domain.AdEvent{Type: domain.AdEventBudgetExhausted, TenantID: "team-a", AdID: 1, At: "2024-01-01T00:00:00Z"})
+	appendEvent(t, testStreamUsecase, domain.AdEvent{Type: domain.AdEventDeleted, TenantID: "team-a", AdID: 1, At: "2024-01-01T00:00:01Z"})
+
+	// Pausing is an update, and deleted ads are left out
+	assert.Equal(t, domain.AdStreamEvent{ID: 1, TenantID: "team-a", Type: domain.AdEventUpdated, AdID: 1, At: "2024-01-01T00:00:00Z",
+		Ad: &domain.Ad{ID: 1, Title: "AD 1", StartAt: "2024-01-01T00:00:00Z", EndAt: "2024-01-02T00:00:00Z", Status: domain.AdPaused}}, <-teamA)
+	assert.Equal(t, domain.AdStreamEvent{ID: 2, TenantID: "team-a", Type: domain.AdEventDeleted, AdID: 1, At: "2024-01-01T00:00:01Z"}, <-teamA)
+	assert.Empty(t, teamB)
+}
+
+func TestStreamSubscribe_LastEventID_ShouldReplayLogBeforeNewEvents(t *testing.T) {
+	eventLog := newMemoryEventLog(
+		domain.AdStreamEvent{ID: 1, TenantID: "team-a", Type: domain.AdEventCreated, AdID: 1},
+		domain.AdStreamEvent{ID: 2, TenantID: "team-b", Type: domain.AdEventCreated, AdID: 2},
+		domain.AdStreamEvent{ID: 3, TenantID: "team-a", Type: domain.AdEventStarted, AdID: 1},
+		domain.AdStreamEvent{ID: 4, TenantID: "team-a", Type: domain.AdEventExpired, AdID: 1},
+	)
+	testStreamUsecase := usecase.NewAdStreamUsecase(eventLog, time.Second*1)
+	runStream(t, testStreamUsecase, eventLog)
+
+	events := subscribe(t, testStreamUsecase, "team-a", 1)
+	appendEvent(t, testStreamUsecase, domain.AdEvent{Type: domain.AdEventDeleted, TenantID: "team-a", AdID: 1})
+
+	ids := []int64{}
+	for event := range events {
+		if ids = append(ids, event.ID); len(ids) == 3 {
+			break
+		}
+	}
+	assert.Equal(t, []int64{3, 4, 5}, ids)
+}
+
+func TestStreamSubscribe_SlowConsumer_ShouldBeDropped(t *testing.T) {
+	eventLog := newMemoryEventLog()
+	testStreamUsecase := usecase.NewAdStreamUsecase(eventLog, time.Second*1, usecase.WithStreamBuffer(1))
+	runStream(t, testStreamUsecase, eventLog)
+
+	events := subscribe(t, testStreamUsecase, "team-a", 0)
+	for i := 0; i < 3; i++ {
+		appendEvent(t, testStreamUsecase, domain.AdEvent{Type: domain.AdEventDeleted, TenantID: "team-a", AdID: int64(i + 1)})
+	}
+
+	// The stream ends before the last event, which the client replays when it reconnects
+	received := 0
+	for range events {
+		received++
+	}
+	assert.Less(t, received, 3)
+}
+
+func TestStreamSubscribe_Closed_ShouldEndStreams(t *testing.T) {
+	testStreamUsecase := usecase.NewAdStreamUsecase(newMemoryEventLog(), time.Second*1)
+
+	events := subscribe(t, testStreamUsecase, "team-a", 0)
+	testStreamUsecase.Close()
+	_, open := <-events
+	assert.False(t, open)
+
+	events = subscribe(t, testStreamUsecase, "team-a", 0)
+	_, open = <-events
+	assert.False(t, open)
+}
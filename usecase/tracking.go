@@ -0,0 +1,382 @@
+package usecase
+
+import (
+	"context"
+	"dcard-backend/domain"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// knownAdTTL is how long an ad checked to be of a tenant is trusted before it is checked again,
+// so that recording an event does not query the ad every time. Ads that change are forgotten
+// as soon as their event is published, and the bound only covers events that are missed.
+const knownAdTTL = time.Minute
+
+// maxKnownAds bounds the ads remembered to be of their tenant, which are forgotten at once
+// when the bound is reached
+const maxKnownAds = 10000
+
+// eventKey identifies the hourly counter of an ad of a tenant
+type eventKey struct {
+	tenantID string
+	adID     int64
+	hour     time.Time
+}
+
+// knownAd is an ad of a tenant
+type knownAd struct {
+	tenantID string
+	adID     int64
+}
+
+type trackingUsecase struct {
+	trackingRepository domain.TrackingRepository
+	contextTimeout     time.Duration
+	batchSize          int
+	maxBuffered        int
+	now                func() time.Time
+	eventBus           domain.AdEventBus
+
+	mutex  sync.Mutex
+	buffer map[eventKey]*domain.EventCount
+	// flushing holds the counts of the flush in flight until they are written or put back into
+	// the buffer, so that stats and delivery count them meanwhile
+	flushing []domain.EventCount
+	// dropped is the number of events dropped since the last flush because the buffer was full
+	dropped int64
+	// known holds the ads checked to be of their tenant with the time the check expires
+	known map[knownAd]time.Time
+	// full is signaled when the buffer holds a batch, so that Run flushes before the interval
+	full chan struct{}
+	// flushMutex serializes flushes, so that counts of a failed flush are put back into the
+	// buffer before the next flush takes it
+	flushMutex sync.Mutex
+}
+
+type TrackingUsecaseOption func(*trackingUsecase)
+
+// WithBatchSize sets the number of buffered hourly counters that triggers a flush, which is
+// 1000 by default
+func WithBatchSize(size int) TrackingUsecaseOption {
+	return func(tu *trackingUsecase) {
+		tu.batchSize = size
+	}
+}
+
+// WithMaxBufferedCounts bounds the hourly counters kept in memory, which is 100000 by default.
+// Events of new counters beyond the bound are dropped and counted, so that a database that is
+// down for long does not grow the buffer without limit.
+func WithMaxBufferedCounts(max int) TrackingUsecaseOption {
+	return func(tu *trackingUsecase) {
+		tu.maxBuffered = max
+	}
+}
+
+// WithTrackingEvents sets the event bus that Run follows to forget the ads checked before as
+// soon as they change, so that an ad that expires, is paused or is deleted stops counting
+// events at once
+func WithTrackingEvents(eventBus domain.AdEventBus) TrackingUsecaseOption {
+	return func(tu *trackingUsecase) {
+		tu.eventBus = eventBus
+	}
+}
+
+// WithTrackingClock sets the clock used to bucket events into hours, which is time.Now by default
+func WithTrackingClock(now func() time.Time) TrackingUsecaseOption {
+	return func(tu *trackingUsecase) {
+		tu.now = now
+	}
+}
+
+func NewTrackingUsecase(trackingRepository domain.TrackingRepository, timeout time.Duration, options ...TrackingUsecaseOption) domain.TrackingUsecase {
+	tu := &trackingUsecase{
+		trackingRepository: trackingRepository,
+		contextTimeout:     timeout,
+		batchSize:          1000,
+		maxBuffered:        100000,
+		now:                time.Now,
+		buffer:             map[eventKey]*domain.EventCount{},
+		known:              map[knownAd]time.Time{},
+		full:               make(chan struct{}, 1),
+	}
+	for _, option := range options {
+		option(tu)
+	}
+	return tu
+}
+
+// checkAd returns domain.ErrNotFound unless the ad is a live ad of the tenant. Ads found are
+// remembered for knownAdTTL.
+func (tu *trackingUsecase) checkAd(c context.Context, tenantID string, adID int64) error {
+	ad := knownAd{tenantID: tenantID, adID: adID}
+	now := tu.now()
+
+	tu.mutex.Lock()
+	expiresAt, ok := tu.known[ad]
+	tu.mutex.Unlock()
+	if ok && now.Before(expiresAt) {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(c, tu.contextTimeout)
+	defer cancel()
+
+	if err := tu.trackingRepository.CheckAd(ctx, adID); err != nil {
+		return toDomainError(err)
+	}
+
+	tu.mutex.Lock()
+	defer tu.mutex.Unlock()
+	if len(tu.known) >= maxKnownAds {
+		tu.known = map[knownAd]time.Time{}
+	}
+	tu.known[ad] = now.Add(knownAdTTL)
+	return nil
+}
+
+// Record counts an event of an ad of the tenant in memory until the next flush, and returns
+// domain.ErrNotFound if the ad is not a live ad of the tenant
+func (tu *trackingUsecase) Record(c context.Context, adID int64, eventType domain.EventType) error {
+	if adID <= 0 {
+		return fmt.Errorf("%w: ad id should be a positive integer", domain.ErrBadParamInput)
+	}
+	if eventType != domain.EventImpression && eventType != domain.EventClick {
+		return fmt.Errorf("%w: unknown event %q", domain.ErrBadParamInput, eventType)
+	}
+	tenantID, err := domain.TenantFromContext(c)
+	if err != nil {
+		return err
+	}
+	if err := tu.checkAd(c, tenantID, adID); err != nil {
+		return err
+	}
+
+	count := domain.EventCount{TenantID: tenantID, AdID: adID, Hour: tu.now().UTC().Truncate(time.Hour)}
+	if eventType == domain.EventImpression {
+		count.Impressions = 1
+	} else {
+		count.Clicks = 1
+	}
+
+	tu.mutex.Lock()
+	defer tu.mutex.Unlock()
+
+	tu.merge(count)
+	if len(tu.buffer) >= tu.batchSize {
+		select {
+		case tu.full <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// merge adds a count to its counter in the buffer. A count of a new counter is dropped and
+// counted once the buffer holds maxBuffered counters. The mutex must be held.
+func (tu *trackingUsecase) merge(count domain.EventCount) {
+	key := eventKey{tenantID: count.TenantID, adID: count.AdID, hour: count.Hour}
+	if buffered, ok := tu.buffer[key]; ok {
+		buffered.Impressions += count.Impressions
+		buffered.Clicks += count.Clicks
+		return
+	}
+	if len(tu.buffer) >= tu.maxBuffered {
+		tu.dropped += count.Impressions + count.Clicks
+		return
+	}
+	tu.buffer[key] = &count
+}
+
+// Flush writes the buffered counts to the repository. If the write fails, the counts are put
+// back into the buffer for the next flush, as far as it has room for them.
+func (tu *trackingUsecase) Flush(c context.Context) error {
+	tu.flushMutex.Lock()
+	defer tu.flushMutex.Unlock()
+
+	tu.mutex.Lock()
+	buffer := tu.buffer
+	tu.buffer = map[eventKey]*domain.EventCount{}
+	if tu.dropped > 0 {
+		log.Printf("Dropped %d ad events since the buffer of counters was full", tu.dropped)
+		tu.dropped = 0
+	}
+
+	counts := make([]domain.EventCount, 0, len(buffer))
+	for _, count := range buffer {
+		counts = append(counts, *count)
+	}
+	tu.flushing = counts
+	tu.mutex.Unlock()
+
+	if len(counts) == 0 {
+		return nil
+	}
+
+	// A stable order keeps concurrent upserts of the same rows from deadlocking, and keeps the
+	// counts of a tenant together
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].TenantID != counts[j].TenantID {
+			return counts[i].TenantID < counts[j].TenantID
+		}
+		if counts[i].AdID != counts[j].AdID {
+			return counts[i].AdID < counts[j].AdID
+		}
+		return counts[i].Hour.Before(counts[j].Hour)
+	})
+
+	ctx, cancel := context.WithTimeout(c, tu.contextTimeout)
+	defer cancel()
+
+	err := tu.trackingRepository.AddCounts(ctx, counts)
+
+	tu.mutex.Lock()
+	defer tu.mutex.Unlock()
+
+	tu.flushing = nil
+	if err != nil {
+		for _, count := range counts {
+			tu.merge(count)
+		}
+		return toDomainError(err)
+	}
+	return nil
+}
+
+// Run flushes the buffered counts every interval, or as soon as the buffer holds a batch,
+// until c is done. The remaining counts are flushed before it returns. A non-positive
+// interval only flushes full batches. Meanwhile it forgets the checks of ads as they change.
+func (tu *trackingUsecase) Run(c context.Context, interval time.Duration) {
+	if tu.eventBus != nil {
+		unsubscribe := tu.eventBus.Subscribe(tu.forget)
+		defer unsubscribe()
+	}
+
+	var tick <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-tick:
+		case <-tu.full:
+		case <-c.Done():
+			if err := tu.Flush(context.Background()); err != nil {
+				log.Println("Error created when flushing ad events on shutdown:", err.Error())
+			}
+			return
+		}
+
+		if err := tu.Flush(c); err != nil {
+			log.Println("Error created when flushing ad events:", err.Error())
+		}
+	}
+}
+
+// forget drops the check of an ad that changed, so that the next event of the ad checks it again
+func (tu *trackingUsecase) forget(event domain.AdEvent) {
+	tu.Invalidate(event.TenantID, event.AdID)
+}
+
+// Invalidate drops the check of an ad, which the lifecycle worker calls at the start and end
+// of the ad before the event of the change is relayed
+func (tu *trackingUsecase) Invalidate(tenantID string, adID int64) {
+	tu.mutex.Lock()
+	defer tu.mutex.Unlock()
+	delete(tu.known, knownAd{tenantID: tenantID, adID: adID})
+}
+
+// unflushed returns the buffered counts and the counts of the flush in flight. It is read before
+// the repository, so a flush that commits in between is counted twice for that moment rather
+// than left out while it is written.
+func (tu *trackingUsecase) unflushed() []domain.EventCount {
+	tu.mutex.Lock()
+	defer tu.mutex.Unlock()
+
+	counts := make([]domain.EventCount, 0, len(tu.buffer)+len(tu.flushing))
+	for _, count := range tu.buffer {
+		counts = append(counts, *count)
+	}
+	return append(counts, tu.flushing...)
+}
+
+// GetStats returns the hourly counts of an ad, including the counts not flushed yet
+func (tu *trackingUsecase) GetStats(c context.Context, adID int64) (domain.AdStats, error) {
+	tenantID, err := domain.TenantFromContext(c)
+	if err != nil {
+		return domain.AdStats{}, err
+	}
+	unflushed := tu.unflushed()
+
+	ctx, cancel := context.WithTimeout(c, tu.contextTimeout)
+	defer cancel()
+
+	counts, err := tu.trackingRepository.GetCounts(ctx, adID)
+	if err != nil {
+		return domain.AdStats{}, toDomainError(err)
+	}
+	for _, count := range unflushed {
+		if count.TenantID == tenantID && count.AdID == adID {
+			counts = append(counts, count)
+		}
+	}
+
+	hourly := map[time.Time]*domain.HourlyStats{}
+	stats := domain.AdStats{AdID: adID, Hourly: []domain.HourlyStats{}}
+	for _, count := range counts {
+		hour := count.Hour.UTC()
+		if _, ok := hourly[hour]; !ok {
+			hourly[hour] = &domain.HourlyStats{Hour: hour.Format(time.RFC3339)}
+		}
+		hourly[hour].Impressions += count.Impressions
+		hourly[hour].Clicks += count.Clicks
+		stats.Impressions += count.Impressions
+		stats.Clicks += count.Clicks
+	}
+
+	for _, hourStats := range hourly {
+		stats.Hourly = append(stats.Hourly, *hourStats)
+	}
+	sort.Slice(stats.Hourly, func(i, j int) bool { return stats.Hourly[i].Hour < stats.Hourly[j].Hour })
+	return stats, nil
+}
+
+// GetDelivery returns the impressions of the ads in total and since dayStart, including the
+// counts not flushed yet, so that pacing sees impressions as soon as they are recorded
+func (tu *trackingUsecase) GetDelivery(c context.Context, adIDs []int64, dayStart time.Time) (map[int64]domain.Delivery, error) {
+	tenantID, err := domain.TenantFromContext(c)
+	if err != nil {
+		return nil, err
+	}
+	unflushed := tu.unflushed()
+
+	ctx, cancel := context.WithTimeout(c, tu.contextTimeout)
+	defer cancel()
+
+	delivery, err := tu.trackingRepository.GetDelivery(ctx, adIDs, dayStart)
+	if err != nil {
+		return nil, toDomainError(err)
+	}
+
+	requested := make(map[int64]bool, len(adIDs))
+	for _, adID := range adIDs {
+		requested[adID] = true
+	}
+	for _, count := range unflushed {
+		if count.TenantID != tenantID || !requested[count.AdID] || count.Impressions == 0 {
+			continue
+		}
+		adDelivery := delivery[count.AdID]
+		adDelivery.Impressions += count.Impressions
+		if !count.Hour.Before(dayStart) {
+			adDelivery.TodayImpressions += count.Impressions
+		}
+		delivery[count.AdID] = adDelivery
+	}
+	return delivery, nil
+}
@@ -0,0 +1,263 @@
+package usecase_test
+
+import (
+	"context"
+	"dcard-backend/domain"
+	"dcard-backend/domain/mocks"
+	"dcard-backend/usecase"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var trackingNow = time.Date(2024, 3, 1, 8, 30, 0, 0, time.UTC)
+
+// trackingContext records events of the ads of team-a
+var trackingContext = domain.WithTenant(context.Background(), "team-a")
+
+// newMockTrackingRepository returns a repository where every ad is of the tenant
+func newMockTrackingRepository(t *testing.T) *mocks.TrackingRepository {
+	mockTrackingRepository := mocks.NewTrackingRepository(t)
+	mockTrackingRepository.On("CheckAd", mock.Anything, mock.Anything).Return(nil).Maybe()
+	return mockTrackingRepository
+}
+
+func newTestTrackingUsecase(repository domain.TrackingRepository, options ...usecase.TrackingUsecaseOption) domain.TrackingUsecase {
+	options = append([]usecase.TrackingUsecaseOption{usecase.WithTrackingClock(func() time.Time { return trackingNow })}, options...)
+	return usecase.NewTrackingUsecase(repository, time.Second*1, options...)
+}
+
+func TestRecord_InvalidEvent_ShouldReturnErrBadParamInput(t *testing.T) {
+	testTrackingUsecase := newTestTrackingUsecase(mocks.NewTrackingRepository(t))
+
+	assert.ErrorIs(t, testTrackingUsecase.Record(trackingContext, 0, domain.EventClick), domain.ErrBadParamInput)
+	assert.ErrorIs(t, testTrackingUsecase.Record(trackingContext, 1, "view"), domain.ErrBadParamInput)
+}
+
+func TestRecord_AdNotOfTenant_ShouldReturnErrNotFoundWithoutCounting(t *testing.T) {
+	mockTrackingRepository := mocks.NewTrackingRepository(t)
+	mockTrackingRepository.On("CheckAd", mock.Anything, int64(1)).Return(domain.ErrNotFound).Once()
+
+	testTrackingUsecase := newTestTrackingUsecase(mockTrackingRepository)
+
+	assert.ErrorIs(t, testTrackingUsecase.Record(trackingContext, 1, domain.EventImpression), domain.ErrNotFound)
+	assert.ErrorIs(t, testTrackingUsecase.Record(context.Background(), 1, domain.EventImpression), domain.ErrUnauthorized)
+	// Nothing was counted, so nothing is written
+	assert.NoError(t, testTrackingUsecase.Flush(context.Background()))
+}
+
+func TestRecord_AdChecked_ShouldNotCheckAgainWithinTTL(t *testing.T) {
+	mockTrackingRepository := mocks.NewTrackingRepository(t)
+	mockTrackingRepository.On("CheckAd", mock.Anything, int64(1)).Return(nil).Once()
+
+	testTrackingUsecase := newTestTrackingUsecase(mockTrackingRepository)
+
+	assert.NoError(t, testTrackingUsecase.Record(trackingContext, 1, domain.EventImpression))
+	assert.NoError(t, testTrackingUsecase.Record(trackingContext, 1, domain.EventClick))
+}
+
+func TestRun_AdChanged_ShouldCheckAdAgain(t *testing.T) {
+	mockTrackingRepository := mocks.NewTrackingRepository(t)
+	mockTrackingRepository.On("CheckAd", mock.Anything, int64(1)).Return(nil).Once()
+	mockTrackingRepository.On("CheckAd", mock.Anything, int64(1)).Return(domain.ErrNotFound).Once()
+	mockTrackingRepository.On("AddCounts", mock.Anything, mock.Anything).Return(nil).Maybe()
+
+	handlers := make(chan domain.AdEventHandler, 1)
+	mockEventBus := mocks.NewAdEventBus(t)
+	mockEventBus.On("Subscribe", mock.Anything).
+		Run(func(args mock.Arguments) { handlers <- args.Get(0).(domain.AdEventHandler) }).
+		Return(func() {}).Once()
+
+	testTrackingUsecase := newTestTrackingUsecase(mockTrackingRepository, usecase.WithTrackingEvents(mockEventBus))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go testTrackingUsecase.Run(ctx, time.Hour)
+	handler := <-handlers
+
+	assert.NoError(t, testTrackingUsecase.Record(trackingContext, 1, domain.EventImpression))
+	// The ad expires within the time it is trusted, and is checked again at its next event
+	handler(domain.AdEvent{Type: domain.AdEventExpired, TenantID: "team-a", AdID: 1})
+	assert.ErrorIs(t, testTrackingUsecase.Record(trackingContext, 1, domain.EventImpression), domain.ErrNotFound)
+}
+
+func TestInvalidate_AdChecked_ShouldCheckAdAgain(t *testing.T) {
+	mockTrackingRepository := mocks.NewTrackingRepository(t)
+	mockTrackingRepository.On("CheckAd", mock.Anything, int64(1)).Return(nil).Once()
+	mockTrackingRepository.On("CheckAd", mock.Anything, int64(1)).Return(domain.ErrNotFound).Once()
+
+	testTrackingUsecase := newTestTrackingUsecase(mockTrackingRepository)
+
+	assert.NoError(t, testTrackingUsecase.Record(trackingContext, 1, domain.EventImpression))
+	// An ad of another tenant with the same id leaves the check in place
+	testTrackingUsecase.Invalidate("team-b", 1)
+	assert.NoError(t, testTrackingUsecase.Record(trackingContext, 1, domain.EventImpression))
+	testTrackingUsecase.Invalidate("team-a", 1)
+	assert.ErrorIs(t, testTrackingUsecase.Record(trackingContext, 1, domain.EventImpression), domain.ErrNotFound)
+}
+
+func TestFlush_EventsRecorded_ShouldAddHourlyCounts(t *testing.T) {
+	hour := time.Date(2024, 3, 1, 8, 0, 0, 0, time.UTC)
+	mockTrackingRepository := newMockTrackingRepository(t)
+	mockTrackingRepository.On("AddCounts", mock.Anything, []domain.EventCount{
+		{TenantID: "team-a", AdID: 1, Hour: hour, Impressions: 2, Clicks: 1},
+		{TenantID: "team-a", AdID: 2, Hour: hour, Impressions: 1},
+	}).Return(nil).Once()
+
+	testTrackingUsecase := newTestTrackingUsecase(mockTrackingRepository)
+	testTrackingUsecase.Record(trackingContext, 2, domain.EventImpression)
+	testTrackingUsecase.Record(trackingContext, 1, domain.EventImpression)
+	testTrackingUsecase.Record(trackingContext, 1, domain.EventImpression)
+	testTrackingUsecase.Record(trackingContext, 1, domain.EventClick)
+
+	assert.NoError(t, testTrackingUsecase.Flush(context.Background()))
+	// The buffer is empty after a flush, so nothing is written again
+	assert.NoError(t, testTrackingUsecase.Flush(context.Background()))
+}
+
+func TestFlush_AddCountsFail_ShouldKeepCountsForNextFlush(t *testing.T) {
+	hour := time.Date(2024, 3, 1, 8, 0, 0, 0, time.UTC)
+	mockTrackingRepository := newMockTrackingRepository(t)
+	mockTrackingRepository.On("AddCounts", mock.Anything, []domain.EventCount{{TenantID: "team-a", AdID: 1, Hour: hour, Impressions: 1}}).
+		Return(errors.New("Fail")).Once()
+	mockTrackingRepository.On("AddCounts", mock.Anything, []domain.EventCount{{TenantID: "team-a", AdID: 1, Hour: hour, Impressions: 2}}).
+		Return(nil).Once()
+
+	testTrackingUsecase := newTestTrackingUsecase(mockTrackingRepository)
+	testTrackingUsecase.Record(trackingContext, 1, domain.EventImpression)
+	assert.Error(t, testTrackingUsecase.Flush(context.Background()))
+
+	testTrackingUsecase.Record(trackingContext, 1, domain.EventImpression)
+	assert.NoError(t, testTrackingUsecase.Flush(context.Background()))
+}
+
+func TestFlush_AddCountsFailWithFullBuffer_ShouldDropCountsBeyondMax(t *testing.T) {
+	hour := time.Date(2024, 3, 1, 8, 0, 0, 0, time.UTC)
+	mockTrackingRepository := newMockTrackingRepository(t)
+	testTrackingUsecase := newTestTrackingUsecase(mockTrackingRepository, usecase.WithMaxBufferedCounts(2))
+
+	// Counters of ads 3 and 4 fill the buffer while the flush of ads 1 and 2 fails, so the
+	// counts put back are dropped
+	mockTrackingRepository.On("AddCounts", mock.Anything, []domain.EventCount{
+		{TenantID: "team-a", AdID: 1, Hour: hour, Impressions: 1},
+		{TenantID: "team-a", AdID: 2, Hour: hour, Impressions: 1},
+	}).Run(func(args mock.Arguments) {
+		testTrackingUsecase.Record(trackingContext, 3, domain.EventImpression)
+		testTrackingUsecase.Record(trackingContext, 4, domain.EventImpression)
+	}).Return(errors.New("Fail")).Once()
+	mockTrackingRepository.On("AddCounts", mock.Anything, []domain.EventCount{
+		{TenantID: "team-a", AdID: 3, Hour: hour, Impressions: 1},
+		{TenantID: "team-a", AdID: 4, Hour: hour, Impressions: 2},
+	}).Return(nil).Once()
+
+	testTrackingUsecase.Record(trackingContext, 1, domain.EventImpression)
+	testTrackingUsecase.Record(trackingContext, 2, domain.EventImpression)
+	assert.Error(t, testTrackingUsecase.Flush(context.Background()))
+
+	// Events of counters in the buffer are still counted, but not those of new counters
+	testTrackingUsecase.Record(trackingContext, 4, domain.EventImpression)
+	testTrackingUsecase.Record(trackingContext, 5, domain.EventImpression)
+	assert.NoError(t, testTrackingUsecase.Flush(context.Background()))
+}
+
+func TestRun_ContextDone_ShouldFlushRemainingCounts(t *testing.T) {
+	mockTrackingRepository := newMockTrackingRepository(t)
+	mockTrackingRepository.On("AddCounts", mock.Anything, mock.Anything).Return(nil).Once()
+
+	testTrackingUsecase := newTestTrackingUsecase(mockTrackingRepository)
+	testTrackingUsecase.Record(trackingContext, 1, domain.EventImpression)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		testTrackingUsecase.Run(ctx, time.Hour)
+	}()
+	cancel()
+	wg.Wait()
+
+	mockTrackingRepository.AssertNumberOfCalls(t, "AddCounts", 1)
+}
+
+func TestRun_BatchFull_ShouldFlushBeforeInterval(t *testing.T) {
+	flushed := make(chan []domain.EventCount, 1)
+	mockTrackingRepository := newMockTrackingRepository(t)
+	mockTrackingRepository.On("AddCounts", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) { flushed <- args.Get(1).([]domain.EventCount) }).
+		Return(nil).Once()
+
+	testTrackingUsecase := newTestTrackingUsecase(mockTrackingRepository, usecase.WithBatchSize(2))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go testTrackingUsecase.Run(ctx, time.Hour)
+
+	testTrackingUsecase.Record(trackingContext, 1, domain.EventImpression)
+	testTrackingUsecase.Record(trackingContext, 2, domain.EventImpression)
+
+	select {
+	case counts := <-flushed:
+		assert.Len(t, counts, 2)
+	case <-time.After(time.Second):
+		t.Fatal("a full batch was not flushed")
+	}
+}
+
+func TestGetStats_ShouldMergeStoredAndBufferedCounts(t *testing.T) {
+	mockTrackingRepository := newMockTrackingRepository(t)
+	mockTrackingRepository.On("GetCounts", mock.Anything, int64(1)).Return([]domain.EventCount{
+		{TenantID: "team-a", AdID: 1, Hour: time.Date(2024, 3, 1, 7, 0, 0, 0, time.UTC), Impressions: 10, Clicks: 1},
+		{TenantID: "team-a", AdID: 1, Hour: time.Date(2024, 3, 1, 8, 0, 0, 0, time.UTC), Impressions: 3},
+	}, nil).Once()
+
+	testTrackingUsecase := newTestTrackingUsecase(mockTrackingRepository)
+	testTrackingUsecase.Record(trackingContext, 1, domain.EventImpression)
+	testTrackingUsecase.Record(trackingContext, 1, domain.EventClick)
+	testTrackingUsecase.Record(trackingContext, 2, domain.EventClick)
+
+	stats, err := testTrackingUsecase.GetStats(trackingContext, 1)
+
+	assert.NoError(t, err)
+	assert.Equal(t, domain.AdStats{
+		AdID:        1,
+		Impressions: 14,
+		Clicks:      2,
+		Hourly: []domain.HourlyStats{
+			{Hour: "2024-03-01T07:00:00Z", Impressions: 10, Clicks: 1},
+			{Hour: "2024-03-01T08:00:00Z", Impressions: 4, Clicks: 1},
+		},
+	}, stats)
+}
+
+func TestGetStats_AdOfAnotherTenant_ShouldNotReturnBufferedCounts(t *testing.T) {
+	mockTrackingRepository := newMockTrackingRepository(t)
+	mockTrackingRepository.On("GetCounts", mock.Anything, int64(1)).Return(nil, domain.ErrNotFound).Once()
+
+	testTrackingUsecase := newTestTrackingUsecase(mockTrackingRepository)
+	testTrackingUsecase.Record(trackingContext, 1, domain.EventImpression)
+
+	stats, err := testTrackingUsecase.GetStats(trackingContext, 1)
+
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+	assert.Equal(t, domain.AdStats{}, stats)
+}
+
+func TestGetStats_FlushInFlight_ShouldCountFlushingCounts(t *testing.T) {
+	mockTrackingRepository := newMockTrackingRepository(t)
+	mockTrackingRepository.On("GetCounts", mock.Anything, int64(1)).Return([]domain.EventCount{}, nil).Once()
+	testTrackingUsecase := newTestTrackingUsecase(mockTrackingRepository)
+
+	var stats domain.AdStats
+	mockTrackingRepository.On("AddCounts", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) { stats, _ = testTrackingUsecase.GetStats(trackingContext, 1) }).
+		Return(nil).Once()
+
+	testTrackingUsecase.Record(trackingContext, 1, domain.EventImpression)
+	assert.NoError(t, testTrackingUsecase.Flush(context.Background()))
+
+	assert.Equal(t, int64(1), stats.Impressions)
+}
@@ -0,0 +1,403 @@
+package usecase
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"dcard-backend/domain"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// csvColumns returns the header written on export, with the included and then the excluded
+// values of each targeting dimension, the schedule as JSON, the default creative and its
+// variants as JSON, the frequency cap and the budget as JSON, the priority, the weight and the
+// campaign id. On import the columns may come in any order, but title, startAt and endAt must
+// be present.
+func csvColumns() []string {
+	columns := []string{"title", "startAt", "endAt", "ageStart", "ageEnd"}
+	var excludeColumns []string
+	for _, dimension := range domain.Dimensions() {
+		columns = append(columns, dimension.Name)
+		excludeColumns = append(excludeColumns, dimension.ExcludeName())
+	}
+	columns = append(append(columns, excludeColumns...), "schedule")
+	return append(columns, "description", "imageUrl", "clickUrl", "callToAction", "creatives", "frequencyCap", "budget", "priority", "weight", "campaignId")
+}
+
+// csvValueSeparator separates the values of a multi-valued column in a single CSV cell
+const csvValueSeparator = "|"
+
+// adRecord is an ad in the transfer formats, where the condition is flattened into the record
+type adRecord struct {
+	Title   string `json:"title"`
+	StartAt string `json:"startAt"`
+	EndAt   string `json:"endAt"`
+	*domain.Condition
+	Description  string               `json:"description,omitempty"`
+	ImageURL     string               `json:"imageUrl,omitempty"`
+	ClickURL     string               `json:"clickUrl,omitempty"`
+	CallToAction string               `json:"callToAction,omitempty"`
+	Creatives    []domain.Creative    `json:"creatives,omitempty"`
+	FrequencyCap *domain.FrequencyCap `json:"frequencyCap,omitempty"`
+	Budget       *domain.Budget       `json:"budget,omitempty"`
+	Priority     int                  `json:"priority,omitempty"`
+	Weight       int                  `json:"weight,omitempty"`
+	CampaignID   int64                `json:"campaignId,omitempty"`
+}
+
+func (record adRecord) toAd() domain.Ad {
+	return domain.Ad{
+		Title:        record.Title,
+		StartAt:      record.StartAt,
+		EndAt:        record.EndAt,
+		Condition:    record.Condition,
+		Description:  record.Description,
+		ImageURL:     record.ImageURL,
+		ClickURL:     record.ClickURL,
+		CallToAction: record.CallToAction,
+		Creatives:    record.Creatives,
+		FrequencyCap: record.FrequencyCap,
+		Budget:       record.Budget,
+		Priority:     record.Priority,
+		Weight:       record.Weight,
+		CampaignID:   record.CampaignID,
+	}
+}
+
+func newAdRecord(ad domain.Ad) adRecord {
+	return adRecord{
+		Title:        ad.Title,
+		StartAt:      ad.StartAt,
+		EndAt:        ad.EndAt,
+		Condition:    ad.Condition,
+		Description:  ad.Description,
+		ImageURL:     ad.ImageURL,
+		ClickURL:     ad.ClickURL,
+		CallToAction: ad.CallToAction,
+		Creatives:    ad.Creatives,
+		FrequencyCap: ad.FrequencyCap,
+		Budget:       ad.Budget,
+		Priority:     ad.Priority,
+		Weight:       ad.Weight,
+		CampaignID:   ad.CampaignID,
+	}
+}
+
+type adTransferUsecase struct {
+	adUsecase domain.AdUsecase
+}
+
+func NewAdTransferUsecase(adUsecase domain.AdUsecase) domain.AdTransferUsecase {
+	return &adTransferUsecase{
+		adUsecase: adUsecase,
+	}
+}
+
+func (atu *adTransferUsecase) Import(c context.Context, r io.Reader, format string) (domain.ImportResult, error) {
+	result := domain.ImportResult{Errors: []domain.ImportError{}}
+
+	create := func(line int, record adRecord) {
+		ad := record.toAd()
+		if err := atu.adUsecase.Create(c, &ad); err != nil {
+			result.Errors = append(result.Errors, domain.ImportError{Line: line, Message: err.Error()})
+			return
+		}
+		result.Imported++
+	}
+
+	var err error
+	switch format {
+	case domain.FormatCSV:
+		err = readCSV(r, create, &result)
+	case domain.FormatNDJSON:
+		err = readNDJSON(r, create, &result)
+	default:
+		err = fmt.Errorf("unsupported format %q", format)
+	}
+	return result, err
+}
+
+func splitCSVValues(value string) []string {
+	if value == "" {
+		return nil
+	}
+
+	values := strings.Split(value, csvValueSeparator)
+	for i := range values {
+		values[i] = strings.TrimSpace(values[i])
+	}
+	return values
+}
+
+func parseCSVInt(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(strings.TrimSpace(value))
+}
+
+func parseCSVID(value string) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	return strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+}
+
+func readCSV(r io.Reader, create func(int, adRecord), result *domain.ImportResult) error {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	index := map[string]int{}
+	for i, column := range header {
+		index[strings.TrimSpace(column)] = i
+	}
+	for _, column := range []string{"title", "startAt", "endAt"} {
+		if _, ok := index[column]; !ok {
+			result.Errors = append(result.Errors, domain.ImportError{Line: 1, Message: "missing column " + column})
+			return nil
+		}
+	}
+
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			result.Errors = append(result.Errors, domain.ImportError{Line: parseErr.Line, Message: parseErr.Err.Error()})
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		line, _ := reader.FieldPos(0)
+		field := func(column string) string {
+			if i, ok := index[column]; ok && i < len(row) {
+				return strings.TrimSpace(row[i])
+			}
+			return ""
+		}
+
+		record := adRecord{
+			Title:        field("title"),
+			StartAt:      field("startAt"),
+			EndAt:        field("endAt"),
+			Condition:    &domain.Condition{},
+			Description:  field("description"),
+			ImageURL:     field("imageUrl"),
+			ClickURL:     field("clickUrl"),
+			CallToAction: field("callToAction"),
+		}
+		for _, dimension := range domain.Dimensions() {
+			*dimension.Values(record.Condition) = splitCSVValues(field(dimension.Name))
+			*dimension.ExcludedValues(record.Condition) = splitCSVValues(field(dimension.ExcludeName()))
+		}
+		if schedule := field("schedule"); schedule != "" {
+			if err := json.Unmarshal([]byte(schedule), &record.Schedule); err != nil {
+				result.Errors = append(result.Errors, domain.ImportError{Line: line, Message: "invalid schedule: " + err.Error()})
+				continue
+			}
+		}
+		if creatives := field("creatives"); creatives != "" {
+			if err := json.Unmarshal([]byte(creatives), &record.Creatives); err != nil {
+				result.Errors = append(result.Errors, domain.ImportError{Line: line, Message: "invalid creatives: " + err.Error()})
+				continue
+			}
+		}
+		if frequencyCap := field("frequencyCap"); frequencyCap != "" {
+			if err := json.Unmarshal([]byte(frequencyCap), &record.FrequencyCap); err != nil {
+				result.Errors = append(result.Errors, domain.ImportError{Line: line, Message: "invalid frequencyCap: " + err.Error()})
+				continue
+			}
+		}
+		if budget := field("budget"); budget != "" {
+			if err := json.Unmarshal([]byte(budget), &record.Budget); err != nil {
+				result.Errors = append(result.Errors, domain.ImportError{Line: line, Message: "invalid budget: " + err.Error()})
+				continue
+			}
+		}
+		if record.AgeStart, err = parseCSVInt(field("ageStart")); err != nil {
+			result.Errors = append(result.Errors, domain.ImportError{Line: line, Message: "invalid ageStart: " + err.Error()})
+			continue
+		}
+		if record.AgeEnd, err = parseCSVInt(field("ageEnd")); err != nil {
+			result.Errors = append(result.Errors, domain.ImportError{Line: line, Message: "invalid ageEnd: " + err.Error()})
+			continue
+		}
+		if record.Priority, err = parseCSVInt(field("priority")); err != nil {
+			result.Errors = append(result.Errors, domain.ImportError{Line: line, Message: "invalid priority: " + err.Error()})
+			continue
+		}
+		if record.Weight, err = parseCSVInt(field("weight")); err != nil {
+			result.Errors = append(result.Errors, domain.ImportError{Line: line, Message: "invalid weight: " + err.Error()})
+			continue
+		}
+		if record.CampaignID, err = parseCSVID(field("campaignId")); err != nil {
+			result.Errors = append(result.Errors, domain.ImportError{Line: line, Message: "invalid campaignId: " + err.Error()})
+			continue
+		}
+
+		create(line, record)
+	}
+}
+
+// maxNDJSONLineSize bounds a line of an NDJSON import, which holds one ad with its creatives.
+// Longer lines are reported as errors of their line and skipped, instead of being held in memory.
+const maxNDJSONLineSize = 1 << 20
+
+func readNDJSON(r io.Reader, create func(int, adRecord), result *domain.ImportResult) error {
+	reader := bufio.NewReader(r)
+	for line := 1; ; line++ {
+		text, tooLong, err := readLine(reader, maxNDJSONLineSize)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+
+		if tooLong {
+			result.Errors = append(result.Errors, domain.ImportError{Line: line, Message: fmt.Sprintf("line is longer than %d bytes", maxNDJSONLineSize)})
+		} else if text = bytes.TrimSpace(text); len(text) > 0 {
+			var record adRecord
+			if err := json.Unmarshal(text, &record); err != nil {
+				result.Errors = append(result.Errors, domain.ImportError{Line: line, Message: err.Error()})
+			} else {
+				create(line, record)
+			}
+		}
+
+		if err != nil {
+			return nil
+		}
+	}
+}
+
+// readLine reads the next line, and reports whether it is longer than max bytes without its
+// line break, in which case the rest of the line is skipped and no text is returned
+func readLine(reader *bufio.Reader, max int) ([]byte, bool, error) {
+	var text []byte
+	tooLong := false
+	for {
+		chunk, err := reader.ReadSlice('\n')
+		if !tooLong {
+			text = append(text, chunk...)
+			if tooLong = len(bytes.TrimRight(text, "\r\n")) > max; tooLong {
+				text = nil
+			}
+		}
+		if !errors.Is(err, bufio.ErrBufferFull) {
+			return text, tooLong, err
+		}
+	}
+}
+
+func (atu *adTransferUsecase) Export(c context.Context, w io.Writer, format string, advertiserID int64) error {
+	if format != domain.FormatCSV && format != domain.FormatNDJSON {
+		return fmt.Errorf("unsupported format %q", format)
+	}
+
+	ads, err := atu.adUsecase.Fetch(c, advertiserID)
+	if err != nil {
+		return err
+	}
+
+	if format == domain.FormatNDJSON {
+		encoder := json.NewEncoder(w)
+		for _, ad := range ads {
+			if err := encoder.Encode(newAdRecord(ad)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvColumns()); err != nil {
+		return err
+	}
+	for _, ad := range ads {
+		condition := ad.Condition
+		if condition == nil {
+			condition = &domain.Condition{}
+		}
+
+		row := []string{ad.Title, ad.StartAt, ad.EndAt, strconv.Itoa(condition.AgeStart), strconv.Itoa(condition.AgeEnd)}
+		var excludeRow []string
+		for _, dimension := range domain.Dimensions() {
+			row = append(row, strings.Join(*dimension.Values(condition), csvValueSeparator))
+			excludeRow = append(excludeRow, strings.Join(*dimension.ExcludedValues(condition), csvValueSeparator))
+		}
+		schedule := ""
+		if condition.Schedule != nil {
+			value, err := json.Marshal(condition.Schedule)
+			if err != nil {
+				return err
+			}
+			schedule = string(value)
+		}
+		creatives := ""
+		if len(ad.Creatives) > 0 {
+			value, err := json.Marshal(ad.Creatives)
+			if err != nil {
+				return err
+			}
+			creatives = string(value)
+		}
+		row = append(append(row, excludeRow...), schedule)
+		frequencyCap := ""
+		if ad.FrequencyCap != nil {
+			value, err := json.Marshal(ad.FrequencyCap)
+			if err != nil {
+				return err
+			}
+			frequencyCap = string(value)
+		}
+		budget := ""
+		if ad.Budget != nil {
+			value, err := json.Marshal(ad.Budget)
+			if err != nil {
+				return err
+			}
+			budget = string(value)
+		}
+		campaignID := ""
+		if ad.CampaignID != 0 {
+			campaignID = strconv.FormatInt(ad.CampaignID, 10)
+		}
+		row = append(row, ad.Description, ad.ImageURL, ad.ClickURL, ad.CallToAction, creatives, frequencyCap, budget,
+			strconv.Itoa(ad.Priority), strconv.Itoa(ad.Weight), campaignID)
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// FormatFromFilename guesses the transfer format from the extension of a file name
+func FormatFromFilename(name string) string {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".csv":
+		return domain.FormatCSV
+	case ".ndjson", ".jsonl":
+		return domain.FormatNDJSON
+	}
+	return ""
+}
@@ -0,0 +1,235 @@
+package usecase_test
+
+import (
+	"bytes"
+	"context"
+	"dcard-backend/domain"
+	"dcard-backend/domain/mocks"
+	"dcard-backend/usecase"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var mockTransferAds = []domain.Ad{
+	{
+		Title:   "AD 0",
+		StartAt: "2024-01-01T00:00:00Z",
+		EndAt:   "2025-01-01T00:00:00Z",
+		Condition: &domain.Condition{
+			AgeStart: 10,
+			AgeEnd:   20,
+			Gender:   []string{"M", "F"},
+			Country:  []string{"TW"},
+			Platform: []string{"web", "ios"},
+		},
+	},
+}
+
+func TestImport_CSVProvided_ShouldCreateEachRow(t *testing.T) {
+	input := "title,startAt,endAt,ageStart,ageEnd,gender,country,platform\n"
+	input += "AD 0,2024-01-01T00:00:00Z,2025-01-01T00:00:00Z,10,20,M|F,TW,web|ios\n"
+
+	mockAdUsecase := mocks.NewAdUsecase(t)
+	mockAdUsecase.On("Create", mock.Anything, &mockTransferAds[0]).Return(nil).Once()
+
+	testAdTransferUsecase := usecase.NewAdTransferUsecase(mockAdUsecase)
+
+	result, err := testAdTransferUsecase.Import(context.Background(), strings.NewReader(input), domain.FormatCSV)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.Imported)
+	assert.Empty(t, result.Errors)
+}
+
+func TestImport_CSVWithExcludeColumns_ShouldCreateExcludingAd(t *testing.T) {
+	input := "title,startAt,endAt,excludeCountry\n"
+	input += "AD 0,2024-01-01T00:00:00Z,2025-01-01T00:00:00Z,CN|RU\n"
+
+	mockAdUsecase := mocks.NewAdUsecase(t)
+	mockAdUsecase.On("Create", mock.Anything, mock.MatchedBy(func(ad *domain.Ad) bool {
+		return assert.ObjectsAreEqual([]string{"CN", "RU"}, ad.Condition.ExcludeCountry)
+	})).Return(nil).Once()
+
+	testAdTransferUsecase := usecase.NewAdTransferUsecase(mockAdUsecase)
+
+	result, err := testAdTransferUsecase.Import(context.Background(), strings.NewReader(input), domain.FormatCSV)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.Imported)
+}
+
+func TestImport_CSVWithScheduleColumn_ShouldCreateScheduledAd(t *testing.T) {
+	input := "title,startAt,endAt,schedule\n"
+	input += `AD 0,2024-01-01T00:00:00Z,2025-01-01T00:00:00Z,"{""windows"":[{""weekday"":""mon"",""startHour"":18,""endHour"":22}]}"` + "\n"
+	input += "AD 1,2024-01-01T00:00:00Z,2025-01-01T00:00:00Z,{\n"
+
+	mockAdUsecase := mocks.NewAdUsecase(t)
+	mockAdUsecase.On("Create", mock.Anything, mock.MatchedBy(func(ad *domain.Ad) bool {
+		return assert.ObjectsAreEqual(&domain.Schedule{
+			Windows: []domain.ScheduleWindow{{Weekday: "mon", StartHour: 18, EndHour: 22}},
+		}, ad.Condition.Schedule)
+	})).Return(nil).Once()
+
+	testAdTransferUsecase := usecase.NewAdTransferUsecase(mockAdUsecase)
+
+	result, err := testAdTransferUsecase.Import(context.Background(), strings.NewReader(input), domain.FormatCSV)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.Imported)
+	if assert.Len(t, result.Errors, 1) {
+		assert.Equal(t, 3, result.Errors[0].Line)
+	}
+}
+
+func TestImport_CSVWithCampaignColumn_ShouldCreateAdInCampaign(t *testing.T) {
+	input := "title,startAt,endAt,campaignId\n"
+	input += "AD 0,,,3\n"
+	input += "AD 1,,,three\n"
+
+	mockAdUsecase := mocks.NewAdUsecase(t)
+	mockAdUsecase.On("Create", mock.Anything, mock.MatchedBy(func(ad *domain.Ad) bool {
+		return ad.CampaignID == 3 && ad.StartAt == ""
+	})).Return(nil).Once()
+
+	testAdTransferUsecase := usecase.NewAdTransferUsecase(mockAdUsecase)
+
+	result, err := testAdTransferUsecase.Import(context.Background(), strings.NewReader(input), domain.FormatCSV)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.Imported)
+	if assert.Len(t, result.Errors, 1) {
+		assert.Equal(t, 3, result.Errors[0].Line)
+	}
+}
+
+func TestImport_CSVWithInvalidRows_ShouldReportErrorsByLine(t *testing.T) {
+	input := "title,startAt,endAt,ageStart\n"
+	input += "AD 0,2024-01-01T00:00:00Z,2025-01-01T00:00:00Z,ten\n"
+	input += "AD 1,2024-01-01T00:00:00Z,2025-01-01T00:00:00Z,10\n"
+
+	mockAdUsecase := mocks.NewAdUsecase(t)
+	mockAdUsecase.On("Create", mock.Anything, mock.Anything).Return(errors.New("Fail")).Once()
+
+	testAdTransferUsecase := usecase.NewAdTransferUsecase(mockAdUsecase)
+
+	result, err := testAdTransferUsecase.Import(context.Background(), strings.NewReader(input), domain.FormatCSV)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0, result.Imported)
+	if assert.Len(t, result.Errors, 2) {
+		assert.Equal(t, 2, result.Errors[0].Line)
+		assert.Equal(t, 3, result.Errors[1].Line)
+		assert.Equal(t, "Fail", result.Errors[1].Message)
+	}
+}
+
+func TestImport_CSVMissingRequiredColumn_ShouldReportHeaderLine(t *testing.T) {
+	input := "title,startAt\nAD 0,2024-01-01T00:00:00Z\n"
+
+	mockAdUsecase := mocks.NewAdUsecase(t)
+
+	testAdTransferUsecase := usecase.NewAdTransferUsecase(mockAdUsecase)
+
+	result, err := testAdTransferUsecase.Import(context.Background(), strings.NewReader(input), domain.FormatCSV)
+
+	assert.NoError(t, err)
+	if assert.Len(t, result.Errors, 1) {
+		assert.Equal(t, 1, result.Errors[0].Line)
+	}
+}
+
+func TestImport_NDJSONProvided_ShouldReportErrorsByLine(t *testing.T) {
+	input := `{"title":"AD 0","startAt":"2024-01-01T00:00:00Z","endAt":"2025-01-01T00:00:00Z","ageStart":10,"ageEnd":20,"gender":["M","F"],"country":["TW"],"platform":["web","ios"]}` + "\n"
+	input += "\n"
+	input += `{"title": ` + "\n"
+
+	mockAdUsecase := mocks.NewAdUsecase(t)
+	mockAdUsecase.On("Create", mock.Anything, &mockTransferAds[0]).Return(nil).Once()
+
+	testAdTransferUsecase := usecase.NewAdTransferUsecase(mockAdUsecase)
+
+	result, err := testAdTransferUsecase.Import(context.Background(), strings.NewReader(input), domain.FormatNDJSON)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.Imported)
+	if assert.Len(t, result.Errors, 1) {
+		assert.Equal(t, 3, result.Errors[0].Line)
+	}
+}
+
+func TestImport_NDJSONLineTooLong_ShouldReportLineAndGoOn(t *testing.T) {
+	input := `{"title":"` + strings.Repeat("A", 1<<20) + `"}` + "\n"
+	input += `{"title":"AD 0","startAt":"2024-01-01T00:00:00Z","endAt":"2025-01-01T00:00:00Z","ageStart":10,"ageEnd":20,"gender":["M","F"],"country":["TW"],"platform":["web","ios"]}`
+
+	mockAdUsecase := mocks.NewAdUsecase(t)
+	mockAdUsecase.On("Create", mock.Anything, &mockTransferAds[0]).Return(nil).Once()
+
+	testAdTransferUsecase := usecase.NewAdTransferUsecase(mockAdUsecase)
+
+	result, err := testAdTransferUsecase.Import(context.Background(), strings.NewReader(input), domain.FormatNDJSON)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.Imported)
+	if assert.Len(t, result.Errors, 1) {
+		assert.Equal(t, 1, result.Errors[0].Line)
+		assert.Contains(t, result.Errors[0].Message, "longer than")
+	}
+}
+
+func TestImport_UnsupportedFormat_ShouldReturnError(t *testing.T) {
+	mockAdUsecase := mocks.NewAdUsecase(t)
+
+	testAdTransferUsecase := usecase.NewAdTransferUsecase(mockAdUsecase)
+
+	_, err := testAdTransferUsecase.Import(context.Background(), strings.NewReader(""), "xml")
+
+	assert.Error(t, err)
+}
+
+func TestExport_CSV_ShouldWriteHeaderAndRows(t *testing.T) {
+	mockAdUsecase := mocks.NewAdUsecase(t)
+	mockAdUsecase.On("Fetch", mock.Anything, int64(0)).Return(mockTransferAds, nil).Once()
+
+	testAdTransferUsecase := usecase.NewAdTransferUsecase(mockAdUsecase)
+
+	var buffer bytes.Buffer
+	err := testAdTransferUsecase.Export(context.Background(), &buffer, domain.FormatCSV, 0)
+
+	expected := "title,startAt,endAt,ageStart,ageEnd,gender,country,platform,language,excludeGender,excludeCountry,excludePlatform,excludeLanguage,schedule,"
+	expected += "description,imageUrl,clickUrl,callToAction,creatives,frequencyCap,budget,priority,weight,campaignId\n"
+	expected += "AD 0,2024-01-01T00:00:00Z,2025-01-01T00:00:00Z,10,20,M|F,TW,web|ios,,,,,,,,,,,,,,0,0,\n"
+
+	assert.NoError(t, err)
+	assert.Equal(t, expected, buffer.String())
+}
+
+func TestExport_NDJSON_ShouldWriteOneAdPerLine(t *testing.T) {
+	mockAdUsecase := mocks.NewAdUsecase(t)
+	mockAdUsecase.On("Fetch", mock.Anything, int64(0)).Return(mockTransferAds, nil).Once()
+
+	testAdTransferUsecase := usecase.NewAdTransferUsecase(mockAdUsecase)
+
+	var buffer bytes.Buffer
+	err := testAdTransferUsecase.Export(context.Background(), &buffer, domain.FormatNDJSON, 0)
+
+	expected := `{"title":"AD 0","startAt":"2024-01-01T00:00:00Z","endAt":"2025-01-01T00:00:00Z","ageStart":10,"ageEnd":20,"gender":["M","F"],"country":["TW"],"platform":["web","ios"]}` + "\n"
+
+	assert.NoError(t, err)
+	assert.Equal(t, expected, buffer.String())
+}
+
+func TestExport_AdUsecaseFail_ShouldReturnError(t *testing.T) {
+	mockAdUsecase := mocks.NewAdUsecase(t)
+	mockAdUsecase.On("Fetch", mock.Anything, int64(0)).Return(nil, errors.New("Fail")).Once()
+
+	testAdTransferUsecase := usecase.NewAdTransferUsecase(mockAdUsecase)
+
+	var buffer bytes.Buffer
+	err := testAdTransferUsecase.Export(context.Background(), &buffer, domain.FormatCSV, 0)
+
+	assert.Error(t, err)
+}
@@ -0,0 +1,401 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"dcard-backend/domain"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// maxWebhookURLLength and maxWebhookSecretLength are the lengths of the columns of webhooks
+	maxWebhookURLLength    = 2048
+	maxWebhookSecretLength = 256
+	// maxDeliveryErrorLength is the length of the last_error column of the outbox
+	maxDeliveryErrorLength = 512
+	// webhookBatchSize is the number of deliveries claimed and sent at once
+	webhookBatchSize = 100
+)
+
+// Page sizes of the dead deliveries
+const (
+	defaultDeadLetterLimit = 50
+	maxDeadLetterLimit     = 500
+)
+
+// Headers of the requests sent to webhooks
+const (
+	WebhookSignatureHeader = "X-Webhook-Signature"
+	WebhookTimestampHeader = "X-Webhook-Timestamp"
+	WebhookDeliveryHeader  = "X-Webhook-Delivery"
+	WebhookEventHeader     = "X-Webhook-Event"
+)
+
+type webhookUsecase struct {
+	webhookRepository domain.WebhookRepository
+	contextTimeout    time.Duration
+	maxAttempts       int
+	baseDelay         time.Duration
+	maxDelay          time.Duration
+	allowPrivate      bool
+	client            *http.Client
+	now               func() time.Time
+	// wake tells Run that deliveries were enqueued
+	wake chan struct{}
+}
+
+type WebhookUsecaseOption func(*webhookUsecase)
+
+// WithWebhookMaxAttempts sets the number of attempts to send a delivery before it is dead,
+// which is 8 by default and when attempts is not positive
+func WithWebhookMaxAttempts(attempts int) WebhookUsecaseOption {
+	return func(wu *webhookUsecase) {
+		if attempts > 0 {
+			wu.maxAttempts = attempts
+		}
+	}
+}
+
+// WithWebhookBackoff sets the delay before the second attempt, which doubles for every later
+// attempt up to maxDelay. They are 30 seconds and an hour by default.
+func WithWebhookBackoff(baseDelay time.Duration, maxDelay time.Duration) WebhookUsecaseOption {
+	return func(wu *webhookUsecase) {
+		wu.baseDelay, wu.maxDelay = baseDelay, maxDelay
+	}
+}
+
+// WithPrivateWebhookAddresses sets whether webhooks may reach loopback, private and link-local
+// addresses, which are refused by default so that a tenant cannot make the server call the
+// network it runs in
+func WithPrivateWebhookAddresses(allowed bool) WebhookUsecaseOption {
+	return func(wu *webhookUsecase) {
+		wu.allowPrivate = allowed
+	}
+}
+
+// WithWebhookClock sets the clock deliveries are timestamped with, which is time.Now by default
+func WithWebhookClock(now func() time.Time) WebhookUsecaseOption {
+	return func(wu *webhookUsecase) {
+		wu.now = now
+	}
+}
+
+func NewWebhookUsecase(webhookRepository domain.WebhookRepository, timeout time.Duration, options ...WebhookUsecaseOption) domain.WebhookUsecase {
+	wu := &webhookUsecase{
+		webhookRepository: webhookRepository,
+		contextTimeout:    timeout,
+		maxAttempts:       8,
+		baseDelay:         30 * time.Second,
+		maxDelay:          time.Hour,
+		now:               time.Now,
+		wake:              make(chan struct{}, 1),
+	}
+	for _, option := range options {
+		option(wu)
+	}
+	wu.client = newWebhookClient(wu.allowPrivate)
+	return wu
+}
+
+var errPrivateAddress = errors.New("webhooks cannot reach loopback, private or link-local addresses")
+
+// isPublicAddress tells whether a webhook may reach an address
+func isPublicAddress(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsValid() && !addr.IsLoopback() && !addr.IsPrivate() && !addr.IsLinkLocalUnicast() &&
+		!addr.IsLinkLocalMulticast() && !addr.IsInterfaceLocalMulticast() && !addr.IsMulticast() && !addr.IsUnspecified()
+}
+
+// newWebhookClient returns the client deliveries are sent with. The address is checked when
+// the connection is made, after the host is resolved, so that a host resolving to a private
+// address later is refused too. Redirects are not followed, since they could lead anywhere,
+// and proxies are not used, since the address of a proxy says nothing about the target.
+func newWebhookClient(allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 30 * time.Second,
+		Control: func(network string, address string, _ syscall.RawConn) error {
+			if allowPrivate {
+				return nil
+			}
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil || !isPublicAddress(addrPort.Addr()) {
+				return errPrivateAddress
+			}
+			return nil
+		},
+	}
+	return &http.Client{
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: 10 * time.Second,
+			MaxIdleConns:        100,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+func validateWebhook(webhook *domain.Webhook, allowPrivate bool) error {
+	target, err := url.Parse(webhook.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" || len(webhook.URL) > maxWebhookURLLength {
+		return fmt.Errorf("%w: url should be an http or https URL of at most %d characters", domain.ErrBadParamInput, maxWebhookURLLength)
+	}
+	if !allowPrivate {
+		// Other hosts given by name are checked when they are resolved for each delivery
+		addr, err := netip.ParseAddr(target.Hostname())
+		if target.Hostname() == "localhost" || (err == nil && !isPublicAddress(addr)) {
+			return fmt.Errorf("%w: %s", domain.ErrBadParamInput, errPrivateAddress.Error())
+		}
+	}
+	if webhook.Secret == "" || utf8.RuneCountInString(webhook.Secret) > maxWebhookSecretLength {
+		return fmt.Errorf("%w: secret should have 1 to %d characters", domain.ErrBadParamInput, maxWebhookSecretLength)
+	}
+	if len(webhook.EventTypes) == 0 {
+		return fmt.Errorf("%w: eventTypes should not be empty", domain.ErrBadParamInput)
+	}
+
+	eventTypes := []domain.AdEventType{}
+	for _, eventType := range webhook.EventTypes {
+		if !slices.Contains(domain.AdEventTypes(), eventType) {
+			return fmt.Errorf("%w: unknown event type %q", domain.ErrBadParamInput, eventType)
+		}
+		if !slices.Contains(eventTypes, eventType) {
+			eventTypes = append(eventTypes, eventType)
+		}
+	}
+	webhook.EventTypes = eventTypes
+	return nil
+}
+
+// Create adds a webhook, which is returned without its secret
+func (wu *webhookUsecase) Create(c context.Context, webhook *domain.Webhook) error {
+	ctx, cancel := context.WithTimeout(c, wu.contextTimeout)
+	defer cancel()
+
+	webhook.ID, webhook.CreatedAt = 0, ""
+	if err := validateWebhook(webhook, wu.allowPrivate); err != nil {
+		return err
+	}
+
+	if err := wu.webhookRepository.Create(ctx, webhook); err != nil {
+		return toDomainError(err)
+	}
+	webhook.Secret = ""
+	return nil
+}
+
+func (wu *webhookUsecase) Fetch(c context.Context) ([]domain.Webhook, error) {
+	ctx, cancel := context.WithTimeout(c, wu.contextTimeout)
+	defer cancel()
+
+	webhooks, err := wu.webhookRepository.Fetch(ctx)
+	if err != nil {
+		return nil, toDomainError(err)
+	}
+	for i := range webhooks {
+		if err := changeTimeToUTC(&webhooks[i].CreatedAt); err != nil {
+			return nil, err
+		}
+	}
+	return webhooks, nil
+}
+
+func (wu *webhookUsecase) Delete(c context.Context, id int64) error {
+	ctx, cancel := context.WithTimeout(c, wu.contextTimeout)
+	defer cancel()
+
+	return toDomainError(wu.webhookRepository.Delete(ctx, id))
+}
+
+// FetchDeadLetters returns the dead deliveries from the newest, 50 by default and at most 500
+func (wu *webhookUsecase) FetchDeadLetters(c context.Context, limit int, offset int) ([]domain.WebhookDelivery, error) {
+	ctx, cancel := context.WithTimeout(c, wu.contextTimeout)
+	defer cancel()
+
+	if limit < 0 || limit > maxDeadLetterLimit || offset < 0 {
+		return nil, fmt.Errorf("%w: limit should be 1 to %d and offset should be non-negative", domain.ErrBadParamInput, maxDeadLetterLimit)
+	}
+	if limit == 0 {
+		limit = defaultDeadLetterLimit
+	}
+
+	deliveries, err := wu.webhookRepository.FetchDeadLetters(ctx, limit, offset)
+	if err != nil {
+		return nil, toDomainError(err)
+	}
+	for i := range deliveries {
+		if err := changeTimeToUTC(&deliveries[i].CreatedAt); err != nil {
+			return nil, err
+		}
+	}
+	return deliveries, nil
+}
+
+func (wu *webhookUsecase) Redeliver(c context.Context, id int64) error {
+	ctx, cancel := context.WithTimeout(c, wu.contextTimeout)
+	defer cancel()
+
+	return toDomainError(wu.webhookRepository.Redeliver(ctx, id))
+}
+
+// signPayload returns the HMAC-SHA256 of the timestamp, a dot and the payload keyed by the
+// secret of the webhook in hex. The timestamp is signed so that a receiver can refuse a
+// delivery replayed long after it was sent.
+func signPayload(secret string, timestamp string, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "." + payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// backoff returns the delay after a failed attempt, doubling from the base delay
+func (wu *webhookUsecase) backoff(attempts int) time.Duration {
+	delay := wu.baseDelay
+	for i := 1; i < attempts && delay < wu.maxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, wu.maxDelay)
+}
+
+// post sends a delivery, which is delivered when the webhook answers with a 2xx status
+func (wu *webhookUsecase) post(c context.Context, delivery domain.WebhookDelivery) error {
+	ctx, cancel := context.WithTimeout(c, wu.contextTimeout)
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewBufferString(delivery.Payload))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	// Every attempt is timestamped and signed again when it is sent
+	timestamp := strconv.FormatInt(wu.now().Unix(), 10)
+	request.Header.Set(WebhookTimestampHeader, timestamp)
+	request.Header.Set(WebhookSignatureHeader, "sha256="+signPayload(delivery.Secret, timestamp, delivery.Payload))
+	request.Header.Set(WebhookDeliveryHeader, strconv.FormatInt(delivery.ID, 10))
+	request.Header.Set(WebhookEventHeader, string(delivery.EventType))
+
+	response, err := wu.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return fmt.Errorf("webhook answered with status %d", response.StatusCode)
+	}
+	return nil
+}
+
+// send posts a delivery and records the result, retrying it after the backoff until it runs
+// out of attempts
+func (wu *webhookUsecase) send(c context.Context, delivery domain.WebhookDelivery) {
+	sendErr := wu.post(c, delivery)
+
+	ctx, cancel := context.WithTimeout(c, wu.contextTimeout)
+	defer cancel()
+	if sendErr == nil {
+		if err := wu.webhookRepository.Complete(ctx, delivery.ID); err != nil {
+			log.Printf("Error created when completing webhook delivery %d: %s", delivery.ID, err.Error())
+		}
+		return
+	}
+
+	lastError := []rune(sendErr.Error())
+	lastError = lastError[:min(len(lastError), maxDeliveryErrorLength)]
+	retryAfter := time.Duration(0)
+	if delivery.Attempts < wu.maxAttempts {
+		retryAfter = wu.backoff(delivery.Attempts)
+	}
+	if err := wu.webhookRepository.Fail(ctx, delivery.ID, string(lastError), retryAfter); err != nil {
+		log.Printf("Error created when failing webhook delivery %d: %s", delivery.ID, err.Error())
+	}
+}
+
+// Deliver sends the deliveries of a batch at once. The lease covers sending them and recording
+// the results, after which other servers may claim them again.
+func (wu *webhookUsecase) Deliver(c context.Context) (int, error) {
+	ctx, cancel := context.WithTimeout(c, wu.contextTimeout)
+	deliveries, err := wu.webhookRepository.Claim(ctx, webhookBatchSize, max(time.Minute, 3*wu.contextTimeout))
+	cancel()
+	if err != nil {
+		return 0, toDomainError(err)
+	}
+
+	var wg sync.WaitGroup
+	for _, delivery := range deliveries {
+		wg.Add(1)
+		go func(delivery domain.WebhookDelivery) {
+			defer wg.Done()
+			wu.send(c, delivery)
+		}(delivery)
+	}
+	wg.Wait()
+	return len(deliveries), nil
+}
+
+// Enqueue adds the deliveries of an event to the outbox, and wakes Run up to send them. An
+// event which cannot be encoded is dropped, since it would fail again.
+func (wu *webhookUsecase) Enqueue(c context.Context, event domain.AdEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Error created when encoding the %s event of ad %d: %s", event.Type, event.AdID, err.Error())
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(c, wu.contextTimeout)
+	defer cancel()
+
+	if _, err := wu.webhookRepository.Enqueue(ctx, event, string(payload)); err != nil {
+		return toDomainError(err)
+	}
+	select {
+	case wu.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Run delivers the outbox when deliveries are enqueued on this server, and every interval for
+// those enqueued by the others and those to be retried
+func (wu *webhookUsecase) Run(c context.Context, interval time.Duration) {
+	var tick <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	for {
+		select {
+		case <-c.Done():
+			return
+		case <-wu.wake:
+		case <-tick:
+		}
+
+		for {
+			delivered, err := wu.Deliver(c)
+			if err != nil {
+				log.Println("Error created when delivering webhooks:", err.Error())
+			}
+			if err != nil || delivered < webhookBatchSize {
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,257 @@
+package usecase_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"dcard-backend/domain"
+	"dcard-backend/domain/mocks"
+	"dcard-backend/usecase"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// receivedDelivery is a request received by the test webhook, with the result of checking its
+// signature as a receiver would
+type receivedDelivery struct {
+	body      string
+	event     string
+	delivery  string
+	timestamp string
+	signedOK  bool
+}
+
+// newReceiver starts a webhook answering with status, which records the requests it receives
+func newReceiver(t *testing.T, secret string, status int) (*httptest.Server, <-chan receivedDelivery) {
+	received := make(chan receivedDelivery, 16)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		timestamp := r.Header.Get(usecase.WebhookTimestampHeader)
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(timestamp + "." + string(body)))
+		expected := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+		received <- receivedDelivery{
+			body:      string(body),
+			event:     r.Header.Get(usecase.WebhookEventHeader),
+			delivery:  r.Header.Get(usecase.WebhookDeliveryHeader),
+			timestamp: timestamp,
+			signedOK:  hmac.Equal([]byte(expected), []byte(r.Header.Get(usecase.WebhookSignatureHeader))),
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return server, received
+}
+
+func testDelivery(url string, attempts int) domain.WebhookDelivery {
+	return domain.WebhookDelivery{
+		ID:        3,
+		WebhookID: 7,
+		TenantID:  "team-a",
+		EventType: domain.AdEventStarted,
+		Payload:   `{"type":"started","adId":1,"at":"2024-01-01T01:00:00Z"}`,
+		Status:    domain.DeliveryPending,
+		Attempts:  attempts,
+		URL:       url,
+		Secret:    "s3cret",
+	}
+}
+
+func TestDeliver_Received_ShouldSignPayloadAndComplete(t *testing.T) {
+	server, received := newReceiver(t, "s3cret", http.StatusNoContent)
+
+	mockWebhookRepository := mocks.NewWebhookRepository(t)
+	mockWebhookRepository.On("Claim", mock.Anything, 100, mock.Anything).Return([]domain.WebhookDelivery{testDelivery(server.URL, 1)}, nil).Once()
+	mockWebhookRepository.On("Complete", mock.Anything, int64(3)).Return(nil).Once()
+
+	testWebhookUsecase := usecase.NewWebhookUsecase(mockWebhookRepository, time.Second*1, usecase.WithPrivateWebhookAddresses(true),
+		usecase.WithWebhookClock(func() time.Time { return time.Date(2024, 1, 1, 1, 0, 5, 0, time.UTC) }))
+	delivered, err := testWebhookUsecase.Deliver(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, delivered)
+	// The time it was sent is signed along with the body
+	assert.Equal(t, receivedDelivery{
+		body:      `{"type":"started","adId":1,"at":"2024-01-01T01:00:00Z"}`,
+		event:     "started",
+		delivery:  "3",
+		timestamp: "1704070805",
+		signedOK:  true,
+	}, <-received)
+}
+
+func TestDeliver_ReceiverFails_ShouldRetryWithBackoff(t *testing.T) {
+	server, _ := newReceiver(t, "s3cret", http.StatusInternalServerError)
+
+	mockWebhookRepository := mocks.NewWebhookRepository(t)
+	mockWebhookRepository.On("Claim", mock.Anything, 100, mock.Anything).Return([]domain.WebhookDelivery{testDelivery(server.URL, 3)}, nil).Once()
+	// The delay doubles after each of the 3 attempts
+	mockWebhookRepository.On("Fail", mock.Anything, int64(3), "webhook answered with status 500", 2*time.Minute).Return(nil).Once()
+
+	testWebhookUsecase := usecase.NewWebhookUsecase(mockWebhookRepository, time.Second*1, usecase.WithPrivateWebhookAddresses(true))
+	_, err := testWebhookUsecase.Deliver(context.Background())
+
+	assert.NoError(t, err)
+}
+
+func TestDeliver_LastAttemptFails_ShouldMoveToDeadLetters(t *testing.T) {
+	server, _ := newReceiver(t, "s3cret", http.StatusBadGateway)
+
+	mockWebhookRepository := mocks.NewWebhookRepository(t)
+	mockWebhookRepository.On("Claim", mock.Anything, 100, mock.Anything).Return([]domain.WebhookDelivery{testDelivery(server.URL, 5)}, nil).Once()
+	mockWebhookRepository.On("Fail", mock.Anything, int64(3), "webhook answered with status 502", time.Duration(0)).Return(nil).Once()
+
+	testWebhookUsecase := usecase.NewWebhookUsecase(mockWebhookRepository, time.Second*1,
+		usecase.WithWebhookMaxAttempts(5), usecase.WithPrivateWebhookAddresses(true))
+	_, err := testWebhookUsecase.Deliver(context.Background())
+
+	assert.NoError(t, err)
+}
+
+func TestDeliver_BackoffReachesMaxDelay_ShouldWaitMaxDelay(t *testing.T) {
+	server, _ := newReceiver(t, "s3cret", http.StatusInternalServerError)
+
+	mockWebhookRepository := mocks.NewWebhookRepository(t)
+	mockWebhookRepository.On("Claim", mock.Anything, 100, mock.Anything).Return([]domain.WebhookDelivery{testDelivery(server.URL, 8)}, nil).Once()
+	mockWebhookRepository.On("Fail", mock.Anything, int64(3), "webhook answered with status 500", time.Hour).Return(nil).Once()
+
+	testWebhookUsecase := usecase.NewWebhookUsecase(mockWebhookRepository, time.Second*1,
+		usecase.WithWebhookMaxAttempts(10), usecase.WithPrivateWebhookAddresses(true))
+	_, err := testWebhookUsecase.Deliver(context.Background())
+
+	assert.NoError(t, err)
+}
+
+func TestDeliver_PrivateAddress_ShouldFailWithoutSending(t *testing.T) {
+	server, received := newReceiver(t, "s3cret", http.StatusNoContent)
+
+	mockWebhookRepository := mocks.NewWebhookRepository(t)
+	mockWebhookRepository.On("Claim", mock.Anything, 100, mock.Anything).Return([]domain.WebhookDelivery{testDelivery(server.URL, 1)}, nil).Once()
+	mockWebhookRepository.On("Fail", mock.Anything, int64(3), mock.MatchedBy(func(lastError string) bool {
+		return assert.Contains(t, lastError, "webhooks cannot reach loopback, private or link-local addresses")
+	}), 30*time.Second).Return(nil).Once()
+
+	testWebhookUsecase := usecase.NewWebhookUsecase(mockWebhookRepository, time.Second*1)
+	_, err := testWebhookUsecase.Deliver(context.Background())
+
+	assert.NoError(t, err)
+	assert.Empty(t, received)
+}
+
+func TestDeliver_Redirected_ShouldFailWithoutFollowing(t *testing.T) {
+	target, received := newReceiver(t, "s3cret", http.StatusNoContent)
+	server := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusTemporaryRedirect))
+	t.Cleanup(server.Close)
+
+	mockWebhookRepository := mocks.NewWebhookRepository(t)
+	mockWebhookRepository.On("Claim", mock.Anything, 100, mock.Anything).Return([]domain.WebhookDelivery{testDelivery(server.URL, 1)}, nil).Once()
+	mockWebhookRepository.On("Fail", mock.Anything, int64(3), "webhook answered with status 307", 30*time.Second).Return(nil).Once()
+
+	testWebhookUsecase := usecase.NewWebhookUsecase(mockWebhookRepository, time.Second*1, usecase.WithPrivateWebhookAddresses(true))
+	_, err := testWebhookUsecase.Deliver(context.Background())
+
+	assert.NoError(t, err)
+	assert.Empty(t, received)
+}
+
+func TestCreateWebhook_Invalid_ShouldReturnErrBadParamInput(t *testing.T) {
+	webhooks := []domain.Webhook{
+		{URL: "example.com/webhook", EventTypes: []domain.AdEventType{domain.AdEventCreated}, Secret: "s3cret"},
+		{URL: "ftp://example.com/webhook", EventTypes: []domain.AdEventType{domain.AdEventCreated}, Secret: "s3cret"},
+		{URL: "https://example.com/webhook", EventTypes: []domain.AdEventType{}, Secret: "s3cret"},
+		{URL: "https://example.com/webhook", EventTypes: []domain.AdEventType{"clicked"}, Secret: "s3cret"},
+		{URL: "https://example.com/webhook", EventTypes: []domain.AdEventType{domain.AdEventCreated}},
+		{URL: "http://127.0.0.1:8080/webhook", EventTypes: []domain.AdEventType{domain.AdEventCreated}, Secret: "s3cret"},
+		{URL: "http://localhost/webhook", EventTypes: []domain.AdEventType{domain.AdEventCreated}, Secret: "s3cret"},
+		{URL: "http://169.254.169.254/latest/meta-data", EventTypes: []domain.AdEventType{domain.AdEventCreated}, Secret: "s3cret"},
+		{URL: "http://10.0.0.3/webhook", EventTypes: []domain.AdEventType{domain.AdEventCreated}, Secret: "s3cret"},
+		{URL: "http://[::ffff:192.168.0.1]/webhook", EventTypes: []domain.AdEventType{domain.AdEventCreated}, Secret: "s3cret"},
+	}
+
+	for _, webhook := range webhooks {
+		mockWebhookRepository := mocks.NewWebhookRepository(t)
+		testWebhookUsecase := usecase.NewWebhookUsecase(mockWebhookRepository, time.Second*1)
+
+		err := testWebhookUsecase.Create(context.Background(), &webhook)
+
+		assert.ErrorIs(t, err, domain.ErrBadParamInput)
+	}
+}
+
+func TestCreateWebhook_Success_ShouldNotReturnSecret(t *testing.T) {
+	mockWebhookRepository := mocks.NewWebhookRepository(t)
+	mockWebhookRepository.On("Create", mock.Anything, mock.MatchedBy(func(webhook *domain.Webhook) bool {
+		return webhook.Secret == "s3cret" && assert.Equal(t, []domain.AdEventType{domain.AdEventStarted, domain.AdEventExpired}, webhook.EventTypes)
+	})).Return(nil).Once()
+
+	webhook := domain.Webhook{
+		URL:        "https://example.com/webhook",
+		EventTypes: []domain.AdEventType{domain.AdEventStarted, domain.AdEventExpired, domain.AdEventStarted},
+		Secret:     "s3cret",
+	}
+	testWebhookUsecase := usecase.NewWebhookUsecase(mockWebhookRepository, time.Second*1)
+	err := testWebhookUsecase.Create(context.Background(), &webhook)
+
+	assert.NoError(t, err)
+	assert.Empty(t, webhook.Secret)
+}
+
+func TestWebhookRun_EventEnqueued_ShouldDeliverAtOnce(t *testing.T) {
+	claimed := make(chan struct{})
+	mockWebhookRepository := mocks.NewWebhookRepository(t)
+	mockWebhookRepository.On("Enqueue", mock.MatchedBy(func(c context.Context) bool {
+		tenantID, err := domain.TenantFromContext(c)
+		return err == nil && tenantID == "team-b"
+	}), domain.AdEvent{Type: domain.AdEventExpired, TenantID: "team-b", AdID: 1, At: "2024-01-01T02:00:01Z"},
+		`{"type":"expired","adId":1,"at":"2024-01-01T02:00:01Z"}`).Return(1, nil).Once()
+	mockWebhookRepository.On("Claim", mock.Anything, 100, mock.Anything).Return([]domain.WebhookDelivery{}, nil).Once().
+		Run(func(mock.Arguments) { close(claimed) })
+
+	testWebhookUsecase := usecase.NewWebhookUsecase(mockWebhookRepository, time.Second*1)
+
+	// The worker does not poll without an interval
+	c, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		testWebhookUsecase.Run(c, 0)
+	}()
+
+	err := testWebhookUsecase.Enqueue(domain.WithTenant(context.Background(), "team-b"),
+		domain.AdEvent{Type: domain.AdEventExpired, TenantID: "team-b", AdID: 1, At: "2024-01-01T02:00:01Z"})
+
+	assert.NoError(t, err)
+	<-claimed
+	cancel()
+	<-stopped
+}
+
+func TestWebhookEnqueue_Failed_ShouldReturnErrorToBeDispatchedAgain(t *testing.T) {
+	mockWebhookRepository := mocks.NewWebhookRepository(t)
+	mockWebhookRepository.On("Enqueue", mock.Anything, mock.Anything, mock.Anything).Return(0, context.DeadlineExceeded).Once()
+
+	testWebhookUsecase := usecase.NewWebhookUsecase(mockWebhookRepository, time.Second*1)
+	err := testWebhookUsecase.Enqueue(domain.WithTenant(context.Background(), "team-a"), domain.AdEvent{Type: domain.AdEventCreated, AdID: 1})
+
+	assert.ErrorIs(t, err, domain.ErrTimeout)
+}
+
+func TestFetchDeadLetters_LimitOmittedOrTooLarge_ShouldUseDefaultOrFail(t *testing.T) {
+	mockWebhookRepository := mocks.NewWebhookRepository(t)
+	mockWebhookRepository.On("FetchDeadLetters", mock.Anything, 50, 0).Return([]domain.WebhookDelivery{}, nil).Once()
+
+	testWebhookUsecase := usecase.NewWebhookUsecase(mockWebhookRepository, time.Second*1)
+
+	_, err := testWebhookUsecase.FetchDeadLetters(context.Background(), 0, 0)
+	assert.NoError(t, err)
+	_, err = testWebhookUsecase.FetchDeadLetters(context.Background(), 501, 0)
+	assert.ErrorIs(t, err, domain.ErrBadParamInput)
+}